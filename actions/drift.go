@@ -0,0 +1,192 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
+	"github.com/outblocks/outblocks-plugin-go/registry"
+)
+
+// DriftReport lists resources whose live configuration differs from what was recorded in state on last apply.
+type DriftReport struct {
+	Drifted      bool           `json:"drifted"`
+	Apps         []*DriftTarget `json:"apps"`
+	Dependencies []*DriftTarget `json:"dependencies"`
+	Other        []*DriftTarget `json:"other,omitempty"`
+}
+
+// DriftTarget groups drifted resources of a single app or dependency.
+type DriftTarget struct {
+	ID        string           `json:"id"`
+	Name      string           `json:"name"`
+	Type      string           `json:"type,omitempty"`
+	Resources []*DriftResource `json:"resources"`
+}
+
+// DriftResource describes a single drifted resource, Fields lists names of properties changed outside of Outblocks.
+type DriftResource struct {
+	ID          string   `json:"id"`
+	Type        string   `json:"type"`
+	ReferenceID string   `json:"ref_id,omitempty"`
+	Deleted     bool     `json:"deleted,omitempty"`
+	Fields      []string `json:"fields,omitempty"`
+}
+
+func decodeRegistry(data []byte) (map[registry.ResourceID]*registry.ResourceSerialized, error) {
+	ret := make(map[registry.ResourceID]*registry.ResourceSerialized)
+
+	if len(data) == 0 {
+		return ret, nil
+	}
+
+	var resources []*registry.ResourceSerialized
+
+	err := json.Unmarshal(data, &resources)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range resources {
+		ret[r.ResourceID] = r
+	}
+
+	return ret, nil
+}
+
+// driftedFields returns sorted names of properties that differ between recorded and live resource.
+func driftedFields(recorded, live *registry.ResourceSerialized) []string {
+	var ret []string
+
+	for k, v := range recorded.Properties {
+		if lv, ok := live.Properties[k]; ok && !reflect.DeepEqual(v, lv) {
+			ret = append(ret, k)
+		}
+	}
+
+	sort.Strings(ret)
+
+	return ret
+}
+
+// Drift plans with registry reading everything back from GCP and compares live values with ones recorded in state.
+// Registry passed to NewPlan has to have Read option enabled.
+func (p *PlanAction) Drift(ctx context.Context, appPlans []*apiv1.AppPlan, depPlans []*apiv1.DependencyPlan) (*DriftReport, error) {
+	recorded, err := decodeRegistry(p.State.Registry)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding state registry: %w", err)
+	}
+
+	_, err = p.process(ctx, appPlans, depPlans, false)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := p.registry.Dump()
+	if err != nil {
+		return nil, err
+	}
+
+	live, err := decodeRegistry(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// State holds resources of all apps and dependencies, only ones requested are checked.
+	requested := map[string]map[string]bool{
+		registry.SourceApp:        make(map[string]bool, len(appPlans)),
+		registry.SourceDependency: make(map[string]bool, len(depPlans)),
+	}
+
+	for _, plan := range appPlans {
+		requested[registry.SourceApp][plan.State.App.Id] = true
+	}
+
+	for _, plan := range depPlans {
+		requested[registry.SourceDependency][plan.State.Dependency.Id] = true
+	}
+
+	targets := make(map[string]*DriftTarget)
+	report := &DriftReport{
+		Apps:         []*DriftTarget{},
+		Dependencies: []*DriftTarget{},
+	}
+
+	ids := make([]registry.ResourceID, 0, len(recorded))
+
+	for id := range recorded {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i].Less(&ids[j])
+	})
+
+	for _, id := range ids {
+		rec := recorded[id]
+		if rec.IsNew {
+			continue
+		}
+
+		if ns, ok := requested[id.Source]; ok && !ns[id.Namespace] {
+			continue
+		}
+
+		res := &DriftResource{
+			ID:          id.ID,
+			Type:        id.Type,
+			ReferenceID: rec.ReferenceID,
+		}
+
+		cur, ok := live[id]
+
+		switch {
+		case !ok || cur.IsNew:
+			res.Deleted = true
+		default:
+			res.Fields = driftedFields(rec, cur)
+			if len(res.Fields) == 0 {
+				continue
+			}
+		}
+
+		key := id.Source + "/" + id.Namespace
+
+		target := targets[key]
+		if target == nil {
+			target = &DriftTarget{
+				ID:   id.Namespace,
+				Name: id.Namespace,
+			}
+
+			switch id.Source {
+			case registry.SourceApp:
+				if app := p.appIDMap[id.Namespace]; app != nil {
+					target.Name = app.Name
+					target.Type = app.Type
+				}
+
+				report.Apps = append(report.Apps, target)
+			case registry.SourceDependency:
+				if dep := p.depIDMap[id.Namespace]; dep != nil {
+					target.Name = dep.Name
+					target.Type = dep.Type
+				}
+
+				report.Dependencies = append(report.Dependencies, target)
+			default:
+				report.Other = append(report.Other, target)
+			}
+
+			targets[key] = target
+		}
+
+		target.Resources = append(target.Resources, res)
+		report.Drifted = true
+	}
+
+	return report, nil
+}
@@ -30,9 +30,14 @@ type PlanAction struct {
 	staticApps   map[string]*deploy.StaticApp
 	serviceApps  map[string]*deploy.ServiceApp
 	functionApps map[string]*deploy.FunctionApp
+	jobApps      map[string]*deploy.JobApp
 	databaseDeps map[string]*deploy.DatabaseDep
 	storageDeps  map[string]*deploy.StorageDep
+	pubsubDeps   map[string]*deploy.PubSubDep
+	redisDeps    map[string]*deploy.RedisDep
+	pubsubTopics map[string]*gcp.PubSubTopic
 	loadBalancer *deploy.LoadBalancer
+	logExport    *deploy.LogExport
 
 	cloudRunSettings *deploy.CloudRunSettings
 	dnsRecordsMap    map[string]*apiv1.DNSRecord
@@ -52,6 +57,17 @@ func NewPlan(pctx *config.PluginContext, logger log.Logger, state *apiv1.PluginS
 
 	gcp.RegisterTypes(reg)
 
+	logExportOpts, err := deploy.NewLogExportOptions(pctx.Settings().LogExport)
+	if err != nil {
+		return nil, err
+	}
+
+	var logExport *deploy.LogExport
+
+	if logExportOpts != nil {
+		logExport = deploy.NewLogExport(logExportOpts)
+	}
+
 	return &PlanAction{
 		pluginCtx: pctx,
 		log:       logger,
@@ -65,6 +81,8 @@ func NewPlan(pctx *config.PluginContext, logger log.Logger, state *apiv1.PluginS
 		depIDMap:       make(map[string]*apiv1.Dependency),
 		depDeployIDMap: make(map[string]any),
 		dnsRecordsMap:  make(map[string]*apiv1.DNSRecord),
+		pubsubTopics:   make(map[string]*gcp.PubSubTopic),
+		logExport:      logExport,
 
 		State:            state,
 		domainMatcher:    types.NewDomainInfoMatcher(domains),
@@ -81,6 +99,7 @@ func (p *PlanAction) planApps(ctx context.Context, appPlans []*apiv1.AppPlan, ap
 		staticAppsPlan   []*apiv1.AppPlan
 		serviceAppsPlan  []*apiv1.AppPlan
 		functionAppsPlan []*apiv1.AppPlan
+		jobAppsPlan      []*apiv1.AppPlan
 	)
 
 	apps := make([]*apiv1.App, 0, len(appPlans))
@@ -96,6 +115,8 @@ func (p *PlanAction) planApps(ctx context.Context, appPlans []*apiv1.AppPlan, ap
 			serviceAppsPlan = append(serviceAppsPlan, plan)
 		case deploy.AppTypeFunction:
 			functionAppsPlan = append(functionAppsPlan, plan)
+		case deploy.AppTypeJob:
+			jobAppsPlan = append(jobAppsPlan, plan)
 		}
 	}
 
@@ -119,6 +140,11 @@ func (p *PlanAction) planApps(ctx context.Context, appPlans []*apiv1.AppPlan, ap
 		return err
 	}
 
+	jobApps, err := p.prepareJobAppsDeploy(jobAppsPlan)
+	if err != nil {
+		return err
+	}
+
 	// Plan static app deployment.
 	p.staticApps, err = p.planStaticAppsDeploy(staticApps, staticAppsPlan)
 	if err != nil {
@@ -137,6 +163,12 @@ func (p *PlanAction) planApps(ctx context.Context, appPlans []*apiv1.AppPlan, ap
 		return err
 	}
 
+	// Plan job app deployment.
+	p.jobApps, err = p.planJobAppsDeploy(ctx, jobApps, jobAppsPlan)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -153,7 +185,7 @@ func (p *PlanAction) planDependencies(appPlans []*apiv1.AppPlan, depPlans []*api
 		}
 	}
 
-	var databasePlan, storagePlan []*apiv1.DependencyPlan
+	var databasePlan, storagePlan, pubsubPlan, redisPlan []*apiv1.DependencyPlan
 
 	for _, plan := range depPlans {
 		p.depIDMap[plan.State.Dependency.Id] = plan.State.Dependency
@@ -163,6 +195,10 @@ func (p *PlanAction) planDependencies(appPlans []*apiv1.AppPlan, depPlans []*api
 			databasePlan = append(databasePlan, plan)
 		case deploy.DepTypeStorage:
 			storagePlan = append(storagePlan, plan)
+		case deploy.DepTypePubSub:
+			pubsubPlan = append(pubsubPlan, plan)
+		case deploy.DepTypeRedis:
+			redisPlan = append(redisPlan, plan)
 		}
 	}
 
@@ -179,12 +215,32 @@ func (p *PlanAction) planDependencies(appPlans []*apiv1.AppPlan, depPlans []*api
 		return err
 	}
 
+	p.pubsubDeps, err = p.planPubSubDepsDeploy(pubsubPlan, allNeeds)
+	if err != nil {
+		return err
+	}
+
+	p.redisDeps, err = p.planRedisDepsDeploy(redisPlan, allNeeds)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
 func (p *PlanAction) enableAPIs(ctx context.Context) error {
+	apis := gcp.APISRequired
+
+	if p.logExport != nil {
+		apis = append(append([]string(nil), apis...), "logging.googleapis.com")
+
+		if p.logExport.Opts.Destination == deploy.LogExportDestinationBigQuery {
+			apis = append(apis, "bigquery.googleapis.com")
+		}
+	}
+
 	// Process API registry.
-	for _, api := range gcp.APISRequired {
+	for _, api := range apis {
 		s := &gcp.APIService{
 			ProjectNumber: fields.Int(int(p.pluginCtx.Settings().ProjectNumber)),
 			Name:          fields.String(api),
@@ -262,7 +318,24 @@ func (p *PlanAction) planAll(ctx context.Context, appPlans []*apiv1.AppPlan, dep
 		return err
 	}
 
-	return nil
+	if p.logExport == nil || p.destroy {
+		return nil
+	}
+
+	apps := make([]*apiv1.App, len(appPlans))
+	for i, plan := range appPlans {
+		apps[i] = plan.State.App
+	}
+
+	deps := make([]*apiv1.Dependency, len(depPlans))
+	for i, plan := range depPlans {
+		deps[i] = plan.State.Dependency
+	}
+
+	return p.logExport.Plan(p.pluginCtx, p.registry, apps, deps, &deploy.LogExportArgs{
+		ProjectID: p.pluginCtx.Settings().ProjectID,
+		Region:    p.pluginCtx.Settings().Region,
+	})
 }
 
 func (p *PlanAction) getOrCreateAppState(app *apiv1.App) *apiv1.AppState {
@@ -399,7 +472,11 @@ func (p *PlanAction) save() error {
 			state.Dns.CloudUrl = a.CloudRun.URL.Current()
 
 		case *deploy.FunctionApp:
-			state.Dns.CloudUrl = a.CloudFunction.URL.Current()
+			if a.CloudFunctionV2 != nil {
+				state.Dns.CloudUrl = a.CloudFunctionV2.URL.Current()
+			} else {
+				state.Dns.CloudUrl = a.CloudFunction.URL.Current()
+			}
 		}
 	}
 
@@ -113,6 +113,103 @@ func (p *PlanAction) planStorageDepsDeploy(depPlans []*apiv1.DependencyPlan, all
 	return ret, nil
 }
 
+func (p *PlanAction) planPubSubDepDeploy(depPlan *apiv1.DependencyPlan, needs map[*apiv1.App]*apiv1.AppNeed) (*deploy.PubSubDep, error) {
+	depDeploy, err := deploy.NewPubSubDep(depPlan.State.Dependency)
+	if err != nil {
+		return nil, err
+	}
+
+	pctx := p.pluginCtx
+
+	depNeeds := make(map[*apiv1.App]*deploy.PubSubDepNeed, len(needs))
+
+	for app, n := range needs {
+		need, err := deploy.NewPubSubDepNeed(n.Properties.AsMap())
+		if err != nil {
+			return nil, err
+		}
+
+		depNeeds[app] = need
+	}
+
+	err = depDeploy.Plan(pctx, p.registry, &deploy.PubSubDepArgs{
+		ProjectID:     pctx.Settings().ProjectID,
+		ProjectNumber: pctx.Settings().ProjectNumber,
+		Needs:         depNeeds,
+		Topics:        p.pubsubTopics,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p.depDeployIDMap[depPlan.State.Dependency.Id] = depDeploy
+
+	return depDeploy, nil
+}
+
+func (p *PlanAction) planPubSubDepsDeploy(depPlans []*apiv1.DependencyPlan, allNeeds map[string]map[*apiv1.App]*apiv1.AppNeed) (ret map[string]*deploy.PubSubDep, err error) {
+	ret = make(map[string]*deploy.PubSubDep, len(depPlans))
+
+	for _, plan := range depPlans {
+		dep, err := p.planPubSubDepDeploy(plan, allNeeds[plan.State.Dependency.Name])
+		if err != nil {
+			return ret, err
+		}
+
+		ret[plan.State.Dependency.Name] = dep
+	}
+
+	return ret, nil
+}
+
+func (p *PlanAction) planRedisDepDeploy(depPlan *apiv1.DependencyPlan, needs map[*apiv1.App]*apiv1.AppNeed) (*deploy.RedisDep, error) {
+	depDeploy, err := deploy.NewRedisDep(depPlan.State.Dependency)
+	if err != nil {
+		return nil, err
+	}
+
+	pctx := p.pluginCtx
+
+	depNeeds := make(map[*apiv1.App]*deploy.RedisDepNeed, len(needs))
+
+	for app, n := range needs {
+		need, err := deploy.NewRedisDepNeed(n.Properties.AsMap())
+		if err != nil {
+			return nil, err
+		}
+
+		depNeeds[app] = need
+	}
+
+	err = depDeploy.Plan(pctx, p.registry, &deploy.RedisDepArgs{
+		ProjectID: pctx.Settings().ProjectID,
+		Region:    pctx.Settings().Region,
+		Needs:     depNeeds,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p.depDeployIDMap[depPlan.State.Dependency.Id] = depDeploy
+
+	return depDeploy, nil
+}
+
+func (p *PlanAction) planRedisDepsDeploy(depPlans []*apiv1.DependencyPlan, allNeeds map[string]map[*apiv1.App]*apiv1.AppNeed) (ret map[string]*deploy.RedisDep, err error) {
+	ret = make(map[string]*deploy.RedisDep, len(depPlans))
+
+	for _, plan := range depPlans {
+		dep, err := p.planRedisDepDeploy(plan, allNeeds[plan.State.Dependency.Name])
+		if err != nil {
+			return ret, err
+		}
+
+		ret[plan.State.Dependency.Name] = dep
+	}
+
+	return ret, nil
+}
+
 func (p *PlanAction) findDependencyEnvVars(app *apiv1.App, need *apiv1.AppNeed) (map[string]any, error) {
 	if dep, ok := p.databaseDeps[need.Dependency]; ok {
 		depNeed := dep.Needs[app]
@@ -137,6 +234,29 @@ func (p *PlanAction) findDependencyEnvVars(app *apiv1.App, need *apiv1.AppNeed)
 		return vars, nil
 	}
 
+	if dep, ok := p.pubsubDeps[need.Dependency]; ok {
+		vars := make(map[string]any)
+		vars["project"] = dep.Topic.ProjectID
+		vars["topic"] = dep.Topic.Name
+		vars["topic_id"] = fields.Sprintf("projects/%s/topics/%s", dep.Topic.ProjectID, dep.Topic.Name)
+
+		if sub := dep.NeedSubscription(app); sub != nil {
+			vars["subscription"] = sub.Name
+			vars["subscription_id"] = fields.Sprintf("projects/%s/subscriptions/%s", sub.ProjectID, sub.Name)
+		}
+
+		return vars, nil
+	}
+
+	if dep, ok := p.redisDeps[need.Dependency]; ok {
+		vars := make(map[string]any)
+		vars["host"] = dep.Instance.Host
+		vars["port"] = dep.Instance.Port
+		vars["url"] = fields.Sprintf("redis://%s:%d", dep.Instance.Host, dep.Instance.Port)
+
+		return vars, nil
+	}
+
 	return nil, fmt.Errorf("unable to find dependency '%s'", need.Dependency)
 }
 
@@ -31,6 +31,9 @@ func (p *PlanAction) planFunctionAppDeploy(ctx context.Context, appDeploy *deplo
 		Env:       appPlan.State.App.Env,
 		Vars:      types.VarsForApp(p.appEnvVars, appPlan.State.App, depVars),
 		Databases: databases,
+
+		StorageDeps:  p.storageDeps,
+		PubSubTopics: p.pubsubTopics,
 	}, apply)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,205 @@
+package actions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
+	"github.com/outblocks/outblocks-plugin-go/registry"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+type goldenPlan struct {
+	Plan             any            `json:"plan"`
+	AppStates        map[string]any `json:"app_states"`
+	DependencyStates map[string]any `json:"dependency_states"`
+	DNSRecords       []any          `json:"dns_records"`
+}
+
+func protoToAny(t *testing.T, m proto.Message) any {
+	t.Helper()
+
+	data, err := protojson.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var v any
+
+	err = json.Unmarshal(data, &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return v
+}
+
+// normalizePlan sorts plan actions, their fields and dns records as their order depends on map iteration.
+func normalizePlan(plan *apiv1.Plan, a *PlanAction) {
+	for _, act := range plan.Actions {
+		sort.Strings(act.Fields)
+	}
+
+	sort.SliceStable(plan.Actions, func(i, j int) bool {
+		x, y := plan.Actions[i], plan.Actions[j]
+
+		switch {
+		case x.Source != y.Source:
+			return x.Source < y.Source
+		case x.Namespace != y.Namespace:
+			return x.Namespace < y.Namespace
+		case x.ObjectId != y.ObjectId:
+			return x.ObjectId < y.ObjectId
+		case x.ObjectType != y.ObjectType:
+			return x.ObjectType < y.ObjectType
+		case x.ObjectName != y.ObjectName:
+			return x.ObjectName < y.ObjectName
+		default:
+			return x.Type < y.Type
+		}
+	})
+
+	sort.Slice(a.DNSRecords, func(i, j int) bool {
+		return a.DNSRecords[i].Record < a.DNSRecords[j].Record
+	})
+}
+
+func marshalGoldenPlan(t *testing.T, plan *apiv1.Plan, a *PlanAction) []byte {
+	t.Helper()
+
+	normalizePlan(plan, a)
+
+	out := goldenPlan{
+		Plan:             protoToAny(t, plan),
+		AppStates:        make(map[string]any, len(a.AppStates)),
+		DependencyStates: make(map[string]any, len(a.DependencyStates)),
+		DNSRecords:       make([]any, len(a.DNSRecords)),
+	}
+
+	for k, v := range a.AppStates {
+		out.AppStates[k] = protoToAny(t, v)
+	}
+
+	for k, v := range a.DependencyStates {
+		out.DependencyStates[k] = protoToAny(t, v)
+	}
+
+	for i, v := range a.DNSRecords {
+		out.DNSRecords[i] = protoToAny(t, v)
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return append(data, '\n')
+}
+
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", "plan", name+".golden.json")
+
+	if *update {
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = os.WriteFile(path, got, 0o644) //nolint:gosec
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("error reading golden file, run with -update to create it: %s", err)
+	}
+
+	if !bytes.Equal(want, got) {
+		t.Errorf("plan output differs from %s, run with -update if change is expected.\n\ngot:\n%s", path, got)
+	}
+}
+
+func TestPlanGolden(t *testing.T) {
+	tests := []struct {
+		name string
+		// applied causes project to be applied first and its resulting state to be used for plan.
+		applied bool
+		modify  func(p *testProject)
+		destroy bool
+	}{
+		{
+			name: "fresh",
+		},
+		{
+			name:    "no_changes",
+			applied: true,
+		},
+		{
+			name:    "service_url_change",
+			applied: true,
+			modify: func(p *testProject) {
+				p.apps[1].State.App.Url = "https://backend.example.com/"
+			},
+		},
+		{
+			name:    "service_env_change",
+			applied: true,
+			modify: func(p *testProject) {
+				p.apps[1].State.App.Env["DEBUG"] = "1"
+			},
+		},
+		{
+			name:    "destroy",
+			applied: true,
+			destroy: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			p := newTestProject(t)
+
+			var state *apiv1.PluginState
+
+			if tt.applied {
+				a := p.newPlan(t, nil, &registry.Options{})
+
+				err := a.Apply(ctx, p.apps, p.deps, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				state = a.State
+			}
+
+			if tt.modify != nil {
+				tt.modify(p)
+			}
+
+			a := p.newPlan(t, state, &registry.Options{Destroy: tt.destroy})
+
+			plan, err := a.Plan(ctx, p.apps, p.deps)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertGolden(t, tt.name, marshalGoldenPlan(t, plan, a))
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package actions
+
+import (
+	"context"
+
+	"github.com/outblocks/cli-plugin-gcp/deploy"
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
+	"github.com/outblocks/outblocks-plugin-go/types"
+)
+
+func (p *PlanAction) planJobAppDeploy(ctx context.Context, appDeploy *deploy.JobApp, appPlan *apiv1.AppPlan) (*deploy.JobApp, error) {
+	pctx := p.pluginCtx
+
+	depVars, err := p.findDependenciesEnvVars(appPlan.State.App)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		databases []*deploy.DatabaseDep
+		redis     []*deploy.RedisDep
+	)
+
+	for _, need := range appPlan.State.App.Needs {
+		if dep, ok := p.databaseDeps[need.Dependency]; ok {
+			databases = append(databases, dep)
+		}
+
+		if dep, ok := p.redisDeps[need.Dependency]; ok {
+			redis = append(redis, dep)
+		}
+	}
+
+	err = appDeploy.Plan(ctx, pctx, p.registry, &deploy.ServiceAppArgs{
+		ProjectID: pctx.Settings().ProjectID,
+		Region:    pctx.Settings().Region,
+		Env:       appPlan.State.App.Env,
+		Vars:      types.VarsForApp(p.appEnvVars, appPlan.State.App, depVars),
+		Databases: databases,
+		Redis:     redis,
+		Settings:  p.cloudRunSettings,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p.appDeployIDMap[appPlan.State.App.Id] = appDeploy
+
+	return appDeploy, nil
+}
+
+func (p *PlanAction) prepareJobAppsDeploy(appPlans []*apiv1.AppPlan) (ret []*deploy.JobApp, err error) {
+	ret = make([]*deploy.JobApp, len(appPlans))
+
+	for i, plan := range appPlans {
+		appDeploy, err := deploy.NewJobApp(plan, p.destroy)
+		if err != nil {
+			return nil, err
+		}
+
+		ret[i] = appDeploy
+	}
+
+	return ret, nil
+}
+
+func (p *PlanAction) planJobAppsDeploy(ctx context.Context, apps []*deploy.JobApp, appPlans []*apiv1.AppPlan) (ret map[string]*deploy.JobApp, err error) {
+	ret = make(map[string]*deploy.JobApp, len(apps))
+
+	for i, plan := range appPlans {
+		app, err := p.planJobAppDeploy(ctx, apps[i], plan)
+		if err != nil {
+			return nil, err
+		}
+
+		ret[plan.State.App.Id] = app
+	}
+
+	return ret, nil
+}
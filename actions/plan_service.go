@@ -16,7 +16,7 @@ import (
 
 var cloudRunSuffixRegex = regexp.MustCompile(`^.+-([a-z0-9]+)-([a-z]{2})\.a\.run\.app$`)
 
-func (p *PlanAction) planServiceAppDeploy(ctx context.Context, appDeploy *deploy.ServiceApp, appPlan *apiv1.AppPlan, apply bool) (*deploy.ServiceApp, error) {
+func (p *PlanAction) planServiceAppDeploy(ctx context.Context, appDeploy *deploy.ServiceApp, appPlan *apiv1.AppPlan, secrets []*deploy.SecretValue, apply bool) (*deploy.ServiceApp, error) {
 	pctx := p.pluginCtx
 
 	depVars, err := p.findDependenciesEnvVars(appPlan.State.App)
@@ -24,12 +24,19 @@ func (p *PlanAction) planServiceAppDeploy(ctx context.Context, appDeploy *deploy
 		return nil, err
 	}
 
-	var databases []*deploy.DatabaseDep
+	var (
+		databases []*deploy.DatabaseDep
+		redis     []*deploy.RedisDep
+	)
 
 	for _, need := range appPlan.State.App.Needs {
 		if dep, ok := p.databaseDeps[need.Dependency]; ok {
 			databases = append(databases, dep)
 		}
+
+		if dep, ok := p.redisDeps[need.Dependency]; ok {
+			redis = append(redis, dep)
+		}
 	}
 
 	err = appDeploy.Plan(ctx, pctx, p.registry, &deploy.ServiceAppArgs{
@@ -38,7 +45,9 @@ func (p *PlanAction) planServiceAppDeploy(ctx context.Context, appDeploy *deploy
 		Env:       appPlan.State.App.Env,
 		Vars:      types.VarsForApp(p.appEnvVars, appPlan.State.App, depVars),
 		Databases: databases,
+		Redis:     redis,
 		Settings:  p.cloudRunSettings,
+		Secrets:   secrets,
 	}, apply)
 	if err != nil {
 		return nil, err
@@ -76,12 +85,32 @@ func (p *PlanAction) prepareServiceAppsDeploy(appPlans []*apiv1.AppPlan) (ret []
 func (p *PlanAction) planServiceAppsDeploy(ctx context.Context, apps []*deploy.ServiceApp, appPlans []*apiv1.AppPlan, apply bool) (ret map[string]*deploy.ServiceApp, err error) {
 	ret = make(map[string]*deploy.ServiceApp, len(apps))
 
+	// Secret values are only read if some service detects env vars holding them.
+	var secrets []*deploy.SecretValue
+
+	detect := false
+
+	for _, app := range apps {
+		detect = detect || app.DeployOpts.SecretEnvDetect
+	}
+
+	if detect && !p.destroy {
+		secrets, err = deploy.LatestSecretValues(ctx, p.pluginCtx, p.pluginCtx.Settings().ProjectID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	for i, plan := range appPlans {
-		app, err := p.planServiceAppDeploy(ctx, apps[i], plan, apply)
+		app, err := p.planServiceAppDeploy(ctx, apps[i], plan, secrets, apply)
 		if err != nil {
 			return nil, err
 		}
 
+		for _, w := range app.Warnings {
+			p.log.Warnln(w)
+		}
+
 		ret[plan.State.App.Id] = app
 	}
 
@@ -0,0 +1,1358 @@
+package actions
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/outblocks/cli-plugin-gcp/deploy"
+	"github.com/outblocks/cli-plugin-gcp/gcp"
+	"github.com/outblocks/cli-plugin-gcp/internal/fakegcp"
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
+	"github.com/outblocks/outblocks-plugin-go/registry"
+	"github.com/outblocks/outblocks-plugin-go/registry/fields"
+	"google.golang.org/api/secretmanager/v1"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type testProject struct {
+	srv     *fakegcp.Server
+	env     *fakegcp.Env
+	domains []*apiv1.DomainInfo
+	apps    []*apiv1.AppPlan
+	deps    []*apiv1.DependencyPlan
+}
+
+func mustStruct(t *testing.T, m map[string]any) *structpb.Struct {
+	t.Helper()
+
+	s, err := structpb.NewStruct(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func newTestProject(t *testing.T) *testProject {
+	t.Helper()
+
+	srv := fakegcp.New()
+	t.Cleanup(srv.Close)
+
+	dir := t.TempDir()
+
+	err := os.MkdirAll(filepath.Join(dir, "website", "build"), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, "website", "build", "index.html"), []byte("<html>hello</html>"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imageHash := srv.AddLocalImage("test/api:latest")
+
+	website := &apiv1.App{
+		Id:         "app_website",
+		Name:       "website",
+		Type:       deploy.AppTypeStatic,
+		Dir:        "website",
+		Url:        "https://example.com/",
+		Properties: mustStruct(t, map[string]any{"build": map[string]any{"dir": "build"}}),
+	}
+
+	api := &apiv1.App{
+		Id:   "app_api",
+		Name: "api",
+		Type: deploy.AppTypeService,
+		Dir:  "api",
+		Url:  "https://api.example.com/",
+		Env:  map[string]string{"FILES_BUCKET": "${dep.files.name}"},
+		Needs: map[string]*apiv1.AppNeed{
+			"files": {Dependency: "files"},
+		},
+		Properties: mustStruct(t, map[string]any{"container": map[string]any{"port": 8080}}),
+	}
+
+	files := &apiv1.Dependency{
+		Id:         "dep_files",
+		Name:       "files",
+		Type:       deploy.DepTypeStorage,
+		Properties: mustStruct(t, map[string]any{"name": "test-files"}),
+	}
+
+	return &testProject{
+		srv: srv,
+		env: fakegcp.NewEnv(dir),
+		domains: []*apiv1.DomainInfo{
+			{Domains: []string{"example.com", "*.example.com"}},
+		},
+		apps: []*apiv1.AppPlan{
+			{State: &apiv1.AppState{App: website}},
+			{
+				State: &apiv1.AppState{App: api},
+				Build: &apiv1.AppBuild{LocalDockerImage: "test/api:latest", LocalDockerHash: imageHash},
+			},
+		},
+		deps: []*apiv1.DependencyPlan{
+			{State: &apiv1.DependencyState{Dependency: files}},
+		},
+	}
+}
+
+func (p *testProject) newPlan(t *testing.T, state *apiv1.PluginState, opts *registry.Options) *PlanAction {
+	t.Helper()
+
+	a, err := NewPlan(p.srv.PluginContext(p.env), &fakegcp.Logger{}, state, p.domains, registry.NewRegistry(opts), opts.Destroy, opts.Read)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return a
+}
+
+func TestPlanApplyEndToEnd(t *testing.T) {
+	ctx := context.Background()
+	p := newTestProject(t)
+
+	// Initial plan should create everything, only required APIs get enabled.
+	plan, err := p.newPlan(t, nil, &registry.Options{}).Plan(ctx, p.apps, p.deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plan.Actions) == 0 {
+		t.Fatal("expected initial plan to contain actions")
+	}
+
+	for _, k := range p.srv.ResourceKeys() {
+		if !strings.HasPrefix(k, "serviceusage/") {
+			t.Fatalf("expected plan not to create any resources, got: %s", k)
+		}
+	}
+
+	// Apply.
+	a := p.newPlan(t, nil, &registry.Options{})
+
+	err = a.Apply(ctx, p.apps, p.deps, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := p.env
+	region := p.srv.Region
+
+	apiService := gcp.ID(e, "app_api")
+	if _, ok := p.srv.Resource("run/" + region + "/namespaces/" + p.srv.ProjectID + "/services/" + apiService); !ok {
+		t.Fatalf("expected cloud run service %s to be created", apiService)
+	}
+
+	if _, ok := p.srv.Resource("storage/b/test-files"); !ok {
+		t.Fatal("expected storage dependency bucket to be created")
+	}
+
+	if _, ok := p.srv.Object(gcp.GlobalID(e, p.srv.ProjectID, "app_website"), "index.html"); !ok {
+		t.Fatal("expected static app files to be uploaded")
+	}
+
+	apiState := a.AppStates["app_api"]
+	if apiState == nil || apiState.Dns == nil || apiState.Dns.CloudUrl != p.srv.CloudRunURL(apiService, region) {
+		t.Fatalf("unexpected api app state: %v", apiState)
+	}
+
+	if len(a.DNSRecords) != 2 {
+		t.Fatalf("expected 2 dns records, got: %v", a.DNSRecords)
+	}
+
+	// Plan after apply should be empty.
+	plan, err = p.newPlan(t, a.State, &registry.Options{}).Plan(ctx, p.apps, p.deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plan.Actions) != 0 {
+		t.Fatalf("expected no changes after apply, got: %v", plan.Actions)
+	}
+
+	// After reading everything back, defaulted fields that were never sent (e.g. probes) may show up as updates
+	// but nothing should get recreated.
+	plan, err = p.newPlan(t, a.State, &registry.Options{Read: true}).Plan(ctx, p.apps, p.deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, act := range plan.Actions {
+		if act.Type != apiv1.PlanType_PLAN_TYPE_UPDATE {
+			t.Fatalf("expected only updates after reading state back, got: %v", act)
+		}
+	}
+
+	// Destroy.
+	a = p.newPlan(t, a.State, &registry.Options{Destroy: true})
+
+	err = a.Apply(ctx, p.apps, p.deps, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Enabled APIs and artifact registry repositories are not managed by registry.
+	for _, k := range p.srv.ResourceKeys() {
+		if !strings.HasPrefix(k, "serviceusage/") && !strings.HasPrefix(k, "artifactregistry/") {
+			t.Errorf("expected resource %s to be deleted", k)
+		}
+	}
+}
+
+func TestPlanApplyJobApp(t *testing.T) {
+	ctx := context.Background()
+	p := newTestProject(t)
+
+	imageHash := p.srv.AddLocalImage("test/import:latest")
+
+	p.apps = append(p.apps, &apiv1.AppPlan{
+		State: &apiv1.AppState{App: &apiv1.App{
+			Id:   "app_import",
+			Name: "import",
+			Type: deploy.AppTypeJob,
+			Dir:  "import",
+			Env:  map[string]string{"FILES_BUCKET": "${dep.files.name}"},
+			Needs: map[string]*apiv1.AppNeed{
+				"files": {Dependency: "files"},
+			},
+			Properties: mustStruct(t, map[string]any{
+				"container": map[string]any{"command": "import --all"},
+				"scheduler": []any{map[string]any{"name": "nightly", "cron": "0 3 * * *"}},
+			}),
+		}},
+		Build: &apiv1.AppBuild{LocalDockerImage: "test/import:latest", LocalDockerHash: imageHash},
+	})
+
+	a := p.newPlan(t, nil, &registry.Options{})
+
+	err := a.Apply(ctx, p.apps, p.deps, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jobName := gcp.ID(p.env, "app_import")
+
+	job, ok := p.srv.Resource("run/" + p.srv.Region + "/namespaces/" + p.srv.ProjectID + "/jobs/" + jobName)
+	if !ok {
+		t.Fatalf("expected cloud run job %s to be created", jobName)
+	}
+
+	if _, ok := job["status"]; !ok {
+		t.Fatalf("expected cloud run job to have status, got: %v", job)
+	}
+
+	var scheduler map[string]any
+
+	for _, k := range p.srv.ResourceKeys() {
+		if strings.HasPrefix(k, "cloudscheduler/") {
+			scheduler, _ = p.srv.Resource(k)
+		}
+	}
+
+	if scheduler == nil {
+		t.Fatal("expected cloud scheduler job to be created")
+	}
+
+	target, _ := scheduler["httpTarget"].(map[string]any)
+	if uri, _ := target["uri"].(string); !strings.HasSuffix(uri, "/namespaces/"+p.srv.ProjectID+"/jobs/"+jobName+":run") {
+		t.Fatalf("expected scheduler to target run jobs api, got: %v", target)
+	}
+
+	if _, ok := target["oauthToken"]; !ok {
+		t.Fatalf("expected scheduler to use oauth token, got: %v", target)
+	}
+
+	state := a.AppStates["app_import"]
+	if state == nil || state.Deployment == nil || !state.Deployment.Ready {
+		t.Fatalf("unexpected job app state: %v", state)
+	}
+
+	// Plan after apply should be empty.
+	plan, err := p.newPlan(t, a.State, &registry.Options{}).Plan(ctx, p.apps, p.deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plan.Actions) != 0 {
+		t.Fatalf("expected no changes after apply, got: %v", plan.Actions)
+	}
+
+	// Destroy.
+	a = p.newPlan(t, a.State, &registry.Options{Destroy: true})
+
+	err = a.Apply(ctx, p.apps, p.deps, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, k := range p.srv.ResourceKeys() {
+		if !strings.HasPrefix(k, "serviceusage/") && !strings.HasPrefix(k, "artifactregistry/") {
+			t.Errorf("expected resource %s to be deleted", k)
+		}
+	}
+}
+
+func TestPlanApplyMultiRegionService(t *testing.T) {
+	ctx := context.Background()
+	p := newTestProject(t)
+
+	const secondaryRegion = "us-central1"
+
+	api := p.apps[1].State.App
+	api.Properties = mustStruct(t, map[string]any{
+		"container": map[string]any{"port": 8080},
+		"regions":   []any{p.srv.Region, secondaryRegion},
+	})
+
+	a := p.newPlan(t, nil, &registry.Options{})
+
+	err := a.Apply(ctx, p.apps, p.deps, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name := gcp.ID(p.env, api.Id)
+	backendKey := "compute/projects/" + p.srv.ProjectID + "/global/backendServices/" + name
+
+	for _, region := range []string{p.srv.Region, secondaryRegion} {
+		if _, ok := p.srv.Resource("run/" + region + "/namespaces/" + p.srv.ProjectID + "/services/" + name); !ok {
+			t.Fatalf("expected cloud run service to be created in %s", region)
+		}
+
+		if _, ok := p.srv.Resource("compute/projects/" + p.srv.ProjectID + "/regions/" + region + "/networkEndpointGroups/" + name); !ok {
+			t.Fatalf("expected serverless neg to be created in %s", region)
+		}
+	}
+
+	backend, ok := p.srv.Resource(backendKey)
+	if !ok {
+		t.Fatal("expected backend service to be created")
+	}
+
+	if backends, _ := backend["backends"].([]any); len(backends) != 2 {
+		t.Fatalf("expected backend service to have 2 backends, got: %v", backend["backends"])
+	}
+
+	// Plan after apply should be empty.
+	plan, err := p.newPlan(t, a.State, &registry.Options{}).Plan(ctx, p.apps, p.deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plan.Actions) != 0 {
+		t.Fatalf("expected no changes after apply, got: %v", plan.Actions)
+	}
+
+	// Going back to single region detaches and removes regional resources.
+	api.Properties = mustStruct(t, map[string]any{"container": map[string]any{"port": 8080}})
+
+	a = p.newPlan(t, a.State, &registry.Options{})
+
+	err = a.Apply(ctx, p.apps, p.deps, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := p.srv.Resource("run/" + secondaryRegion + "/namespaces/" + p.srv.ProjectID + "/services/" + name); ok {
+		t.Fatal("expected regional cloud run service to be deleted")
+	}
+
+	if _, ok := p.srv.Resource("compute/projects/" + p.srv.ProjectID + "/regions/" + secondaryRegion + "/networkEndpointGroups/" + name); ok {
+		t.Fatal("expected regional serverless neg to be deleted")
+	}
+
+	backend, _ = p.srv.Resource(backendKey)
+	if backends, _ := backend["backends"].([]any); len(backends) != 1 {
+		t.Fatalf("expected backend service to have 1 backend, got: %v", backend["backends"])
+	}
+}
+
+func TestPlanApplyServiceTrafficSplit(t *testing.T) {
+	ctx := context.Background()
+	p := newTestProject(t)
+
+	api := p.apps[1].State.App
+	api.Properties = mustStruct(t, map[string]any{
+		"container": map[string]any{"port": 8080},
+		"traffic":   map[string]any{"percent": 30},
+	})
+
+	a := p.newPlan(t, nil, &registry.Options{})
+
+	err := a.Apply(ctx, p.apps, p.deps, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := "run/" + p.srv.Region + "/namespaces/" + p.srv.ProjectID + "/services/" + gcp.ID(p.env, api.Id)
+
+	traffic := func() []any {
+		svc, _ := p.srv.Resource(key)
+		status, _ := svc["status"].(map[string]any)
+		ret, _ := status["traffic"].([]any)
+
+		return ret
+	}
+
+	// New service gets all the traffic.
+	if tr := traffic(); len(tr) != 1 {
+		t.Fatalf("expected all traffic to go to first revision, got: %v", tr)
+	}
+
+	// Deploying new revision splits traffic with previous one.
+	api.Env["DEBUG"] = "1"
+
+	a = p.newPlan(t, a.State, &registry.Options{})
+
+	err = a.Apply(ctx, p.apps, p.deps, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr := traffic()
+	if len(tr) != 2 {
+		t.Fatalf("expected traffic to be split between 2 revisions, got: %v", tr)
+	}
+
+	if percent := tr[0].(map[string]any)["percent"]; percent != 30.0 {
+		t.Fatalf("expected new revision to get 30%% of traffic, got: %v", tr)
+	}
+
+	// Progressive rollout ends with all traffic on latest revision.
+	api.Env["DEBUG"] = "2"
+	api.Properties = mustStruct(t, map[string]any{
+		"container": map[string]any{"port": 8080},
+		"traffic":   map[string]any{"rollout": map[string]any{"steps": []any{50}, "interval": 0}},
+	})
+
+	a = p.newPlan(t, a.State, &registry.Options{})
+
+	err = a.Apply(ctx, p.apps, p.deps, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr = traffic()
+	if len(tr) != 1 || tr[0].(map[string]any)["latestRevision"] != true {
+		t.Fatalf("expected all traffic to go to latest revision after rollout, got: %v", tr)
+	}
+
+	// Plan after apply should be empty.
+	plan, err := p.newPlan(t, a.State, &registry.Options{}).Plan(ctx, p.apps, p.deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plan.Actions) != 0 {
+		t.Fatalf("expected no changes after apply, got: %v", plan.Actions)
+	}
+}
+
+func TestPlanApplyFunctionAppUpgrade(t *testing.T) {
+	ctx := context.Background()
+	p := newTestProject(t)
+
+	archive := filepath.Join(t.TempDir(), "function.zip")
+
+	err := os.WriteFile(archive, []byte("zip"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fn := &apiv1.App{
+		Id:   "app_hook",
+		Name: "hook",
+		Type: deploy.AppTypeFunction,
+		Dir:  "hook",
+		Url:  "https://hook.example.com/",
+		Env:  map[string]string{},
+		Properties: mustStruct(t, map[string]any{
+			"entrypoint": "Handle",
+			"runtime":    "go122",
+		}),
+	}
+
+	p.apps = append(p.apps, &apiv1.AppPlan{
+		State: &apiv1.AppState{App: fn},
+		Build: &apiv1.AppBuild{LocalArchivePath: archive, LocalArchiveHash: "abc123"},
+	})
+
+	a := p.newPlan(t, nil, &registry.Options{})
+
+	err = a.Apply(ctx, p.apps, p.deps, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name := gcp.ID(p.env, fn.Id)
+	functionKey := "cloudfunctions/projects/" + p.srv.ProjectID + "/locations/" + p.srv.Region + "/functions/" + name
+	negKey := "compute/projects/" + p.srv.ProjectID + "/regions/" + p.srv.Region + "/networkEndpointGroups/" + name
+
+	cf, ok := p.srv.Resource(functionKey)
+	if !ok || cf["environment"] != "GEN_1" {
+		t.Fatalf("expected 1st gen cloud function to be created, got: %v", cf)
+	}
+
+	neg, ok := p.srv.Resource(negKey)
+	if !ok {
+		t.Fatal("expected serverless neg to be created")
+	}
+
+	// Switching to 2nd gen upgrades function in place.
+	fn.Properties = mustStruct(t, map[string]any{
+		"entrypoint":   "Handle",
+		"runtime":      "go122",
+		"generation":   2,
+		"concurrency":  80,
+		"memory_limit": 16384,
+		"timeout":      3600,
+	})
+
+	plan, err := p.newPlan(t, a.State, &registry.Options{}).Plan(ctx, p.apps, p.deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, act := range plan.Actions {
+		if act.Type == apiv1.PlanType_PLAN_TYPE_DELETE || act.Type == apiv1.PlanType_PLAN_TYPE_RECREATE {
+			t.Fatalf("expected upgrade not to delete anything, got: %v", act)
+		}
+
+		if act.ObjectType == "ServerlessNEG" || act.ObjectType == "BackendService" {
+			t.Fatalf("expected load balancer backend to stay unchanged, got: %v", act)
+		}
+	}
+
+	a = p.newPlan(t, a.State, &registry.Options{})
+
+	err = a.Apply(ctx, p.apps, p.deps, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cf, _ = p.srv.Resource(functionKey)
+	if cf["environment"] != "GEN_2" {
+		t.Fatalf("expected cloud function to be upgraded to 2nd gen, got: %v", cf)
+	}
+
+	svc, _ := cf["serviceConfig"].(map[string]any)
+	if svc["maxInstanceRequestConcurrency"] != "80" && svc["maxInstanceRequestConcurrency"] != float64(80) {
+		t.Fatalf("expected concurrency to be set, got: %v", svc)
+	}
+
+	if svc["availableCpu"] != "1" || svc["availableMemory"] != "16384Mi" {
+		t.Fatalf("expected cpu and memory to be set, got: %v", svc)
+	}
+
+	if newNEG, _ := p.srv.Resource(negKey); newNEG["id"] != neg["id"] {
+		t.Fatalf("expected serverless neg to be kept, got: %v", newNEG)
+	}
+
+	if a.AppStates[fn.Id].Dns.CloudUrl != cf["url"] {
+		t.Fatalf("expected cloud url to be kept, got: %v", a.AppStates[fn.Id].Dns)
+	}
+
+	// Plan after apply should be empty, reading state back should not recreate anything.
+	plan, err = p.newPlan(t, a.State, &registry.Options{}).Plan(ctx, p.apps, p.deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plan.Actions) != 0 {
+		t.Fatalf("expected no changes after apply, got: %v", plan.Actions)
+	}
+
+	plan, err = p.newPlan(t, a.State, &registry.Options{Read: true}).Plan(ctx, p.apps, p.deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, act := range plan.Actions {
+		if act.Type != apiv1.PlanType_PLAN_TYPE_UPDATE {
+			t.Fatalf("expected only updates after reading state back, got: %v", act)
+		}
+	}
+
+	// Downgrading is not supported.
+	fn.Properties = mustStruct(t, map[string]any{"entrypoint": "Handle", "runtime": "go122"})
+
+	_, err = p.newPlan(t, a.State, &registry.Options{}).Plan(ctx, p.apps, p.deps)
+	if err == nil || !strings.Contains(err.Error(), "downgrading is not supported") {
+		t.Fatalf("expected downgrade to fail, got: %v", err)
+	}
+}
+
+func TestPlanApplyFunctionAppTriggers(t *testing.T) {
+	ctx := context.Background()
+	p := newTestProject(t)
+
+	archive := filepath.Join(t.TempDir(), "function.zip")
+
+	err := os.WriteFile(archive, []byte("zip"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	thumbnail := &apiv1.App{
+		Id:   "app_thumbnail",
+		Name: "thumbnail",
+		Type: deploy.AppTypeFunction,
+		Dir:  "thumbnail",
+		Env:  map[string]string{},
+		Properties: mustStruct(t, map[string]any{
+			"entrypoint": "Thumbnail",
+			"runtime":    "go122",
+			"trigger": map[string]any{
+				"storage": map[string]any{"dependency": "files"},
+				"retry":   true,
+			},
+		}),
+	}
+
+	notify := &apiv1.App{
+		Id:   "app_notify",
+		Name: "notify",
+		Type: deploy.AppTypeFunction,
+		Dir:  "notify",
+		Env:  map[string]string{},
+		Properties: mustStruct(t, map[string]any{
+			"entrypoint": "Notify",
+			"runtime":    "go122",
+			"generation": 2,
+			"trigger": map[string]any{
+				"pubsub": map[string]any{"topic": "thumbnails"},
+			},
+		}),
+	}
+
+	for _, app := range []*apiv1.App{thumbnail, notify} {
+		p.apps = append(p.apps, &apiv1.AppPlan{
+			State: &apiv1.AppState{App: app},
+			Build: &apiv1.AppBuild{LocalArchivePath: archive, LocalArchiveHash: "abc123"},
+		})
+	}
+
+	a := p.newPlan(t, nil, &registry.Options{})
+
+	err = a.Apply(ctx, p.apps, p.deps, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	functionKey := func(app *apiv1.App) string {
+		return "cloudfunctions/projects/" + p.srv.ProjectID + "/locations/" + p.srv.Region + "/functions/" + gcp.ID(p.env, app.Id)
+	}
+
+	cf, _ := p.srv.Resource(functionKey(thumbnail))
+	trigger, _ := cf["eventTrigger"].(map[string]any)
+
+	if trigger["eventType"] != "google.storage.object.finalize" || trigger["resource"] != "projects/_/buckets/test-files" || trigger["failurePolicy"] == nil {
+		t.Fatalf("expected storage trigger to be set, got: %v", cf)
+	}
+
+	if _, ok := cf["httpsTrigger"]; ok {
+		t.Fatalf("expected no https trigger, got: %v", cf)
+	}
+
+	topicID := "projects/" + p.srv.ProjectID + "/topics/thumbnails"
+
+	if _, ok := p.srv.Resource("pubsub/" + topicID); !ok {
+		t.Fatal("expected pubsub topic to be created")
+	}
+
+	cf, _ = p.srv.Resource(functionKey(notify))
+	trigger, _ = cf["eventTrigger"].(map[string]any)
+
+	if trigger["eventType"] != "google.cloud.pubsub.topic.v1.messagePublished" || trigger["pubsubTopic"] != topicID {
+		t.Fatalf("expected pubsub trigger to be set, got: %v", cf)
+	}
+
+	// Reading state back should not change anything.
+	plan, err := p.newPlan(t, a.State, &registry.Options{Read: true}).Plan(ctx, p.apps, p.deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, act := range plan.Actions {
+		if act.ObjectType == "CloudFunction" || act.ObjectType == "CloudFunctionV2" || act.ObjectType == "PubSubTopic" {
+			t.Fatalf("expected no trigger changes after reading state back, got: %v", act)
+		}
+	}
+
+	// Triggers require existing storage dependency and cannot be combined with url.
+	thumbnail.Properties = mustStruct(t, map[string]any{
+		"entrypoint": "Thumbnail",
+		"runtime":    "go122",
+		"trigger": map[string]any{
+			"storage": map[string]any{"dependency": "images"},
+		},
+	})
+
+	_, err = p.newPlan(t, a.State, &registry.Options{}).Plan(ctx, p.apps, p.deps)
+	if err == nil || !strings.Contains(err.Error(), "unknown storage dependency 'images'") {
+		t.Fatalf("expected unknown dependency error, got: %v", err)
+	}
+
+	thumbnail.Properties = mustStruct(t, map[string]any{"entrypoint": "Thumbnail", "runtime": "go122"})
+	notify.Url = "https://notify.example.com/"
+
+	_, err = p.newPlan(t, a.State, &registry.Options{}).Plan(ctx, p.apps, p.deps)
+	if err == nil || !strings.Contains(err.Error(), "cannot be exposed with url") {
+		t.Fatalf("expected url error, got: %v", err)
+	}
+}
+
+func TestPlanApplyPubSubDependency(t *testing.T) {
+	ctx := context.Background()
+	p := newTestProject(t)
+
+	events := &apiv1.Dependency{
+		Id:   "dep_events",
+		Name: "events",
+		Type: deploy.DepTypePubSub,
+		Properties: mustStruct(t, map[string]any{
+			"topic":             "events",
+			"message_retention": 86400,
+			"subscriptions": map[string]any{
+				"workers": map[string]any{
+					"ack_deadline": 60,
+					"dead_letter":  map[string]any{"max_delivery_attempts": 10},
+				},
+			},
+		}),
+	}
+
+	p.deps = append(p.deps, &apiv1.DependencyPlan{State: &apiv1.DependencyState{Dependency: events}})
+
+	api := p.apps[1].State.App
+	api.Env["EVENTS_TOPIC"] = "${dep.events.topic}"
+	api.Env["EVENTS_SUBSCRIPTION"] = "${dep.events.subscription_id}"
+	api.Needs["events"] = &apiv1.AppNeed{Dependency: "events"}
+
+	a := p.newPlan(t, nil, &registry.Options{})
+
+	err := a.Apply(ctx, p.apps, p.deps, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	project := "projects/" + p.srv.ProjectID
+
+	topic, ok := p.srv.Resource("pubsub/" + project + "/topics/events")
+	if !ok || topic["messageRetentionDuration"] != "86400s" {
+		t.Fatalf("expected topic to be created, got: %v", topic)
+	}
+
+	sub, ok := p.srv.Resource("pubsub/" + project + "/subscriptions/events-workers")
+	if !ok || sub["ackDeadlineSeconds"] != float64(60) {
+		t.Fatalf("expected subscription to be created, got: %v", sub)
+	}
+
+	deadLetter, _ := sub["deadLetterPolicy"].(map[string]any)
+	if deadLetter["deadLetterTopic"] != project+"/topics/events-workers-dead-letter" || deadLetter["maxDeliveryAttempts"] != float64(10) {
+		t.Fatalf("expected dead letter policy to be set, got: %v", sub)
+	}
+
+	if _, ok := p.srv.Resource("pubsub/" + project + "/topics/events-workers-dead-letter"); !ok {
+		t.Fatal("expected dead letter topic to be created")
+	}
+
+	member := fmt.Sprintf("serviceAccount:%d-compute@developer.gserviceaccount.com", p.srv.ProjectNumber)
+
+	for key, role := range map[string]string{
+		"pubsub/" + project + "/topics/events:iam":                     "roles/pubsub.publisher",
+		"pubsub/" + project + "/subscriptions/events-workers:iam":      "roles/pubsub.subscriber",
+		"pubsub/" + project + "/topics/events-workers-dead-letter:iam": "roles/pubsub.publisher",
+	} {
+		policy, _ := p.srv.Resource(key)
+		if !strings.Contains(fmt.Sprint(policy), role) {
+			t.Fatalf("expected %s to be granted on %s, got: %v", role, key, policy)
+		}
+	}
+
+	if policy, _ := p.srv.Resource("pubsub/" + project + "/topics/events:iam"); !strings.Contains(fmt.Sprint(policy), member) {
+		t.Fatalf("expected app service account to be granted publisher role, got: %v", policy)
+	}
+
+	vars, err := a.findDependencyEnvVars(api, api.Needs["events"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for k, want := range map[string]string{
+		"topic":           "events",
+		"subscription":    "events-workers",
+		"subscription_id": project + "/subscriptions/events-workers",
+	} {
+		if f, ok := vars[k].(fields.StringInputField); !ok || f.Current() != want {
+			t.Fatalf("expected dependency var %s to be %s, got: %v", k, want, vars[k])
+		}
+	}
+
+	if dns := a.DependencyStates[events.Id].GetDns(); dns.GetConnectionInfo() != project+"/topics/events" {
+		t.Fatalf("unexpected dependency state: %v", dns)
+	}
+
+	// Plan after apply should be empty, reading state back should not change anything.
+	plan, err := p.newPlan(t, a.State, &registry.Options{}).Plan(ctx, p.apps, p.deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plan.Actions) != 0 {
+		t.Fatalf("expected no changes after apply, got: %v", plan.Actions)
+	}
+
+	plan, err = p.newPlan(t, a.State, &registry.Options{Read: true}).Plan(ctx, p.apps, p.deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, act := range plan.Actions {
+		if strings.HasPrefix(act.ObjectType, "PubSub") {
+			t.Fatalf("expected no pubsub changes after reading state back, got: %v", act)
+		}
+	}
+
+	// Dropping need revokes access.
+	delete(api.Needs, "events")
+	delete(api.Env, "EVENTS_TOPIC")
+	delete(api.Env, "EVENTS_SUBSCRIPTION")
+
+	a = p.newPlan(t, a.State, &registry.Options{})
+
+	err = a.Apply(ctx, p.apps, p.deps, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if policy, _ := p.srv.Resource("pubsub/" + project + "/topics/events:iam"); strings.Contains(fmt.Sprint(policy), member) {
+		t.Fatalf("expected app service account to be revoked, got: %v", policy)
+	}
+}
+
+func TestPlanApplyRedisDependency(t *testing.T) {
+	ctx := context.Background()
+	p := newTestProject(t)
+
+	cache := &apiv1.Dependency{
+		Id:         "dep_cache",
+		Name:       "cache",
+		Type:       deploy.DepTypeRedis,
+		Properties: mustStruct(t, map[string]any{"tier": "standard_ha", "memory_size": 2, "version": "7.0"}),
+	}
+
+	p.deps = append(p.deps, &apiv1.DependencyPlan{State: &apiv1.DependencyState{Dependency: cache}})
+
+	api := p.apps[1].State.App
+	api.Needs["cache"] = &apiv1.AppNeed{Dependency: "cache"}
+
+	a := p.newPlan(t, nil, &registry.Options{})
+
+	err := a.Apply(ctx, p.apps, p.deps, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	instanceKey := "redis/projects/" + p.srv.ProjectID + "/locations/" + p.srv.Region + "/instances/" + gcp.ID(p.env, cache.Id)
+
+	inst, ok := p.srv.Resource(instanceKey)
+	if !ok || inst["tier"] != "STANDARD_HA" || inst["memorySizeGb"] != float64(2) || inst["redisVersion"] != "REDIS_7_0" ||
+		inst["authorizedNetwork"] != "projects/"+p.srv.ProjectID+"/global/networks/default" {
+		t.Fatalf("expected redis instance to be created, got: %v", inst)
+	}
+
+	svc, _ := p.srv.Resource("run/" + p.srv.Region + "/namespaces/" + p.srv.ProjectID + "/services/" + gcp.ID(p.env, api.Id))
+	if !strings.Contains(fmt.Sprint(svc["spec"]), `run.googleapis.com/network-interfaces:[{"network":"default","subnetwork":"default"}]`) {
+		t.Fatalf("expected service to use direct vpc egress, got: %v", svc["spec"])
+	}
+
+	vars, err := a.findDependencyEnvVars(api, api.Needs["cache"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	host, _ := vars["host"].(fields.StringOutputField)
+	if host == nil || host.Current() != inst["host"] {
+		t.Fatalf("expected redis host var to be set, got: %v", vars)
+	}
+
+	if dns := a.DependencyStates[cache.Id].GetDns(); dns.GetInternalIp() != inst["host"] || !strings.HasPrefix(dns.GetConnectionInfo(), fmt.Sprintf("%s:6379", inst["host"])) {
+		t.Fatalf("unexpected dependency state: %v", dns)
+	}
+
+	// Plan after apply should be empty, reading state back should not change anything.
+	plan, err := p.newPlan(t, a.State, &registry.Options{}).Plan(ctx, p.apps, p.deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plan.Actions) != 0 {
+		t.Fatalf("expected no changes after apply, got: %v", plan.Actions)
+	}
+
+	plan, err = p.newPlan(t, a.State, &registry.Options{Read: true}).Plan(ctx, p.apps, p.deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, act := range plan.Actions {
+		if act.ObjectType == "RedisInstance" {
+			t.Fatalf("expected no redis changes after reading state back, got: %v", act)
+		}
+	}
+
+	// Resizing and upgrading is done in place.
+	cache.Properties = mustStruct(t, map[string]any{"tier": "standard_ha", "memory_size": 4, "version": "7.2"})
+
+	plan, err = p.newPlan(t, a.State, &registry.Options{}).Plan(ctx, p.apps, p.deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, act := range plan.Actions {
+		if act.ObjectType == "RedisInstance" && act.Type != apiv1.PlanType_PLAN_TYPE_UPDATE {
+			t.Fatalf("expected redis instance to be updated in place, got: %v", act)
+		}
+	}
+
+	err = p.newPlan(t, a.State, &registry.Options{}).Apply(ctx, p.apps, p.deps, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inst, _ = p.srv.Resource(instanceKey)
+	if inst["memorySizeGb"] != float64(4) || inst["redisVersion"] != "REDIS_7_2" {
+		t.Fatalf("expected redis instance to be resized and upgraded, got: %v", inst)
+	}
+
+	// Redis dependencies reachable through different networks cannot be used by the same app.
+	sessions := &apiv1.Dependency{
+		Id:         "dep_sessions",
+		Name:       "sessions",
+		Type:       deploy.DepTypeRedis,
+		Properties: mustStruct(t, map[string]any{"network": "other", "subnet": "other"}),
+	}
+
+	p.deps = append(p.deps, &apiv1.DependencyPlan{State: &apiv1.DependencyState{Dependency: sessions}})
+	api.Needs["sessions"] = &apiv1.AppNeed{Dependency: "sessions"}
+
+	_, err = p.newPlan(t, a.State, &registry.Options{}).Plan(ctx, p.apps, p.deps)
+	if err == nil || !strings.Contains(err.Error(), "different networks") {
+		t.Fatalf("expected redis dependencies on different networks to be refused, got: %v", err)
+	}
+
+	// Egress set explicitly on app is used as is.
+	props := api.Properties.AsMap()
+	props["egress_network"] = "other"
+	props["egress_subnet"] = "other"
+	api.Properties = mustStruct(t, props)
+
+	_, err = p.newPlan(t, a.State, &registry.Options{}).Plan(ctx, p.apps, p.deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPlanApplyServiceSecretEnv(t *testing.T) {
+	ctx := context.Background()
+	p := newTestProject(t)
+
+	cli, err := p.srv.PluginContext(p.env).GCPSecretManagerClient(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	project := p.srv.ProjectID
+	secret := gcp.SecretName(p.env, "db_password")
+
+	_, err = cli.Projects.Secrets.Create("projects/"+project, &secretmanager.Secret{
+		Replication: &secretmanager.Replication{Automatic: &secretmanager.Automatic{}},
+	}).SecretId(secret).Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addVersion := func(value string) {
+		_, err := cli.Projects.Secrets.AddVersion("projects/"+project+"/secrets/"+secret, &secretmanager.AddSecretVersionRequest{
+			Payload: &secretmanager.SecretPayload{Data: base64.StdEncoding.EncodeToString([]byte(value))},
+		}).Do()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	addVersion("correct-horse")
+
+	api := p.apps[1].State.App
+	api.Env["DB_PASSWORD"] = "correct-horse"
+	api.Properties = mustStruct(t, map[string]any{
+		"container":  map[string]any{"port": 8080},
+		"secret_env": map[string]any{"DB_PASSWORD": "db_password"},
+	})
+
+	a := p.newPlan(t, nil, &registry.Options{})
+
+	err = a.Apply(ctx, p.apps, p.deps, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serviceKey := "run/" + p.srv.Region + "/namespaces/" + project + "/services/" + gcp.ID(p.env, "app_api")
+
+	envOf := func() string {
+		svc, _ := p.srv.Resource(serviceKey)
+
+		container := svc["spec"].(map[string]any)["template"].(map[string]any)["spec"].(map[string]any)["containers"].([]any)[0]
+
+		return fmt.Sprint(container.(map[string]any)["env"])
+	}
+
+	env := envOf()
+	if strings.Contains(env, "correct-horse") || !strings.Contains(env, "secretKeyRef:map[key:1 name:"+secret+"]") {
+		t.Fatalf("expected secret env var to be passed as pinned reference, got: %s", env)
+	}
+
+	member := fmt.Sprintf("serviceAccount:%d-compute@developer.gserviceaccount.com", p.srv.ProjectNumber)
+	policyKey := "secretmanager/projects/" + project + "/secrets/" + secret + ":iam"
+
+	if policy, _ := p.srv.Resource(policyKey); !strings.Contains(fmt.Sprint(policy), member) || !strings.Contains(fmt.Sprint(policy), gcp.SecretAccessorRole) {
+		t.Fatalf("expected service account to be granted secret access, got: %v", policy)
+	}
+
+	plan, err := p.newPlan(t, a.State, &registry.Options{}).Plan(ctx, p.apps, p.deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plan.Actions) != 0 {
+		t.Fatalf("expected no changes after apply, got: %v", plan.Actions)
+	}
+
+	plan, err = p.newPlan(t, a.State, &registry.Options{Read: true}).Plan(ctx, p.apps, p.deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, act := range plan.Actions {
+		if act.ObjectType == "SecretIAMMember" || (act.ObjectType == "CloudRun" && act.Type != apiv1.PlanType_PLAN_TYPE_UPDATE) {
+			t.Fatalf("expected no secret changes after reading state back, got: %v", act)
+		}
+	}
+
+	// Rotating secret rolls out new revision pinned to new version.
+	addVersion("battery-staple")
+
+	a = p.newPlan(t, a.State, &registry.Options{})
+
+	err = a.Apply(ctx, p.apps, p.deps, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if env := envOf(); !strings.Contains(env, "secretKeyRef:map[key:2 name:"+secret+"]") {
+		t.Fatalf("expected secret env var to be pinned to new version, got: %s", env)
+	}
+
+	// Env var holding secret value stays plain unless detection is enabled.
+	api.Env["DB_PASSWORD"] = "battery-staple"
+	api.Properties = mustStruct(t, map[string]any{"container": map[string]any{"port": 8080}})
+
+	a = p.newPlan(t, a.State, &registry.Options{})
+
+	err = a.Apply(ctx, p.apps, p.deps, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if env := envOf(); strings.Contains(env, "secretKeyRef") {
+		t.Fatalf("expected env var to be passed as is without detection, got: %s", env)
+	}
+
+	// With detection enabled, env var holding whole secret value is passed as reference.
+	api.Properties = mustStruct(t, map[string]any{"container": map[string]any{"port": 8080}, "secret_env_detect": true})
+
+	a = p.newPlan(t, a.State, &registry.Options{})
+
+	err = a.Apply(ctx, p.apps, p.deps, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if env := envOf(); strings.Contains(env, "battery-staple") || !strings.Contains(env, "secretKeyRef:map[key:2 name:"+secret+"]") {
+		t.Fatalf("expected env var holding secret value to be passed as reference, got: %s", env)
+	}
+
+	// Secret value embedded in other value cannot be referenced, it is only warned about.
+	api.Env["DB_URL"] = "postgres://app:battery-staple@db/app"
+
+	a = p.newPlan(t, a.State, &registry.Options{})
+
+	_, err = a.Plan(ctx, p.apps, p.deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if msgs := a.log.(*fakegcp.Logger).Messages(); !strings.Contains(fmt.Sprint(msgs), "warn: App 'api' env var 'DB_URL' contains value of secret 'db_password'") { //nolint:errcheck
+		t.Fatalf("expected secret embedded in env var to be warned about, got: %v", msgs)
+	}
+
+	// Env vars no longer holding secrets fall back to plain env and access is revoked.
+	delete(api.Env, "DB_URL")
+	api.Env["DB_PASSWORD"] = "plain-value"
+
+	a = p.newPlan(t, a.State, &registry.Options{})
+
+	err = a.Apply(ctx, p.apps, p.deps, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if env := envOf(); strings.Contains(env, "secretKeyRef") || !strings.Contains(env, "plain-value") {
+		t.Fatalf("expected secret reference to be removed, got: %s", env)
+	}
+
+	if policy, _ := p.srv.Resource(policyKey); strings.Contains(fmt.Sprint(policy), member) {
+		t.Fatalf("expected secret access to be revoked, got: %v", policy)
+	}
+}
+
+func TestPlanApplyLogExport(t *testing.T) {
+	ctx := context.Background()
+	p := newTestProject(t)
+
+	logExport := map[string]any{"destination": "bigquery"}
+
+	newPlan := func(state *apiv1.PluginState, opts *registry.Options) *PlanAction {
+		pctx := p.srv.PluginContext(p.env)
+		pctx.Settings().LogExport = logExport
+
+		a, err := NewPlan(pctx, &fakegcp.Logger{}, state, p.domains, registry.NewRegistry(opts), opts.Destroy, opts.Read)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return a
+	}
+
+	a := newPlan(nil, &registry.Options{})
+
+	err := a.Apply(ctx, p.apps, p.deps, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	datasetName := strings.ReplaceAll(gcp.ID(p.env, deploy.LogExportName), "-", "_")
+	datasetKey := "bigquery/projects/" + p.srv.ProjectID + "/datasets/" + datasetName
+	sinkKey := "logging/projects/" + p.srv.ProjectID + "/sinks/" + gcp.ID(p.env, deploy.LogExportName)
+
+	sink, ok := p.srv.Resource(sinkKey)
+	if !ok || sink["destination"] != "bigquery.googleapis.com/projects/"+p.srv.ProjectID+"/datasets/"+datasetName {
+		t.Fatalf("expected log sink to be created, got: %v", sink)
+	}
+
+	filter, _ := sink["filter"].(string)
+	if !strings.Contains(filter, `resource.labels.service_name = ("`+gcp.ID(p.env, "app_website")+`" OR "`+gcp.ID(p.env, "app_api")+`")`) {
+		t.Fatalf("expected sink filter to match apps, got: %s", filter)
+	}
+
+	if opts, _ := sink["bigqueryOptions"].(map[string]any); opts == nil || opts["usePartitionedTables"] != false {
+		t.Fatalf("expected sink to write date-sharded tables, got: %v", sink["bigqueryOptions"])
+	}
+
+	identity := strings.TrimPrefix(sink["writerIdentity"].(string), "serviceAccount:") //nolint:errcheck
+
+	dataset, ok := p.srv.Resource(datasetKey)
+	if !ok || dataset["defaultTableExpirationMs"] != fmt.Sprint(365*24*60*60*1000) {
+		t.Fatalf("expected dataset with one year retention to be created, got: %v", dataset)
+	}
+
+	if !strings.Contains(fmt.Sprint(dataset["access"]), identity) {
+		t.Fatalf("expected sink writer to be granted access to dataset, got: %v", dataset["access"])
+	}
+
+	// Plan after apply should be empty.
+	plan, err := newPlan(a.State, &registry.Options{}).Plan(ctx, p.apps, p.deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plan.Actions) != 0 {
+		t.Fatalf("expected no changes after apply, got: %v", plan.Actions)
+	}
+
+	// Switching destination moves sink to a bucket, dataset is removed.
+	logExport = map[string]any{"destination": "storage", "sources": []any{"apps"}, "filter": "severity >= WARNING"}
+
+	a = newPlan(a.State, &registry.Options{})
+
+	err = a.Apply(ctx, p.apps, p.deps, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bucketName := gcp.GlobalID(p.env, p.srv.ProjectID, deploy.LogExportName)
+
+	bucket, ok := p.srv.Resource("storage/b/" + bucketName)
+	if !ok || !strings.Contains(fmt.Sprint(bucket["lifecycle"]), "age:365") {
+		t.Fatalf("expected bucket with one year retention to be created, got: %v", bucket)
+	}
+
+	if policy, _ := bucket["retentionPolicy"].(map[string]any); policy["retentionPeriod"] != fmt.Sprint(365*24*60*60) || policy["isLocked"] == true {
+		t.Fatalf("expected bucket to have unlocked one year retention policy, got: %v", bucket["retentionPolicy"])
+	}
+
+	sink, _ = p.srv.Resource(sinkKey)
+	if sink["destination"] != "storage.googleapis.com/"+bucketName || !strings.HasSuffix(sink["filter"].(string), " (severity >= WARNING)") { //nolint:errcheck
+		t.Fatalf("expected log sink to be updated, got: %v", sink)
+	}
+
+	if policy, _ := p.srv.Resource("storage/b/" + bucketName + ":iam"); !strings.Contains(fmt.Sprint(policy), identity) {
+		t.Fatalf("expected sink writer to be granted access to bucket, got: %v", policy)
+	}
+
+	if _, ok := p.srv.Resource(datasetKey); ok {
+		t.Fatal("expected dataset to be deleted")
+	}
+
+	// Locked retention policy cannot be unlocked.
+	logExport["retention_locked"] = true
+
+	a = newPlan(a.State, &registry.Options{})
+
+	err = a.Apply(ctx, p.apps, p.deps, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bucket, _ = p.srv.Resource("storage/b/" + bucketName)
+	if policy, _ := bucket["retentionPolicy"].(map[string]any); policy["isLocked"] != true {
+		t.Fatalf("expected bucket retention policy to be locked, got: %v", bucket["retentionPolicy"])
+	}
+
+	logExport["retention_locked"] = false
+
+	err = newPlan(a.State, &registry.Options{}).Apply(ctx, p.apps, p.deps, nil)
+	if err == nil || !strings.Contains(err.Error(), "cannot be unlocked") {
+		t.Fatalf("expected unlocking retention policy to be refused, got: %v", err)
+	}
+
+	// Destroy.
+	err = newPlan(a.State, &registry.Options{Destroy: true}).Apply(ctx, p.apps, p.deps, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, k := range p.srv.ResourceKeys() {
+		if !strings.HasPrefix(k, "serviceusage/") && !strings.HasPrefix(k, "artifactregistry/") {
+			t.Errorf("expected resource %s to be deleted", k)
+		}
+	}
+
+	// Build logs cannot be exported.
+	_, err = deploy.NewLogExportOptions(map[string]any{"sources": []any{"build"}})
+	if err == nil {
+		t.Fatal("expected build log source to be rejected")
+	}
+
+	// Only bucket retention policy can be locked.
+	_, err = deploy.NewLogExportOptions(map[string]any{"destination": "bigquery", "retention_locked": true})
+	if err == nil {
+		t.Fatal("expected locked retention to be rejected for bigquery destination")
+	}
+}
+
+func TestDriftReport(t *testing.T) {
+	ctx := context.Background()
+	p := newTestProject(t)
+
+	a := p.newPlan(t, nil, &registry.Options{})
+
+	err := a.Apply(ctx, p.apps, p.deps, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing was changed outside of apply.
+	report, err := p.newPlan(t, a.State, &registry.Options{Read: true}).Drift(ctx, p.apps, p.deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Drifted {
+		t.Fatalf("expected no drift right after apply, got: %s", mustJSON(t, report))
+	}
+
+	// Edit env vars and scaling of api service by hand and remove dependency bucket.
+	serviceKey := "run/" + p.srv.Region + "/namespaces/" + p.srv.ProjectID + "/services/" + gcp.ID(p.env, "app_api")
+
+	ok := p.srv.UpdateResource(serviceKey, func(svc map[string]any) {
+		tmpl := svc["spec"].(map[string]any)["template"].(map[string]any)
+		tmpl["metadata"].(map[string]any)["annotations"].(map[string]any)["autoscaling.knative.dev/minScale"] = "3"
+
+		container := tmpl["spec"].(map[string]any)["containers"].([]any)[0].(map[string]any)
+		container["env"] = append(container["env"].([]any), map[string]any{"name": "DEBUG", "value": "1"})
+	})
+	if !ok {
+		t.Fatal("expected api cloud run service to exist")
+	}
+
+	if !p.srv.DeleteResource("storage/b/test-files") {
+		t.Fatal("expected dependency bucket to exist")
+	}
+
+	report, err = p.newPlan(t, a.State, &registry.Options{Read: true}).Drift(ctx, p.apps, p.deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !report.Drifted || len(report.Apps) != 1 || len(report.Dependencies) != 1 {
+		t.Fatalf("expected drift in api app and files dependency, got: %s", mustJSON(t, report))
+	}
+
+	app := report.Apps[0]
+	if app.Name != "api" || len(app.Resources) != 1 || app.Resources[0].Type != "CloudRun" {
+		t.Fatalf("unexpected app drift: %s", mustJSON(t, app))
+	}
+
+	if got := strings.Join(app.Resources[0].Fields, ","); got != "EnvVars,MinScale" {
+		t.Fatalf("expected env vars and min scale to drift, got: %s", got)
+	}
+
+	dep := report.Dependencies[0]
+	if dep.Name != "files" || len(dep.Resources) == 0 || dep.Resources[0].Type != "Bucket" || !dep.Resources[0].Deleted {
+		t.Fatalf("expected dependency bucket to be reported as deleted, got: %s", mustJSON(t, dep))
+	}
+
+	// Apps and dependencies that were not requested are left out of report.
+	report, err = p.newPlan(t, a.State, &registry.Options{Read: true}).Drift(ctx, p.apps[:1], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Apps) != 0 || len(report.Dependencies) != 0 {
+		t.Fatalf("expected only requested apps and dependencies to be reported, got: %s", mustJSON(t, report))
+	}
+}
+
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
@@ -21,9 +21,25 @@ func computeAppDeploymentState(app any) *apiv1.DeploymentState {
 	case *deploy.ServiceApp:
 		ready, ok = appDeploy.CloudRun.Ready.LookupCurrent()
 		message = appDeploy.CloudRun.StatusMessage.Current()
+
+		// Service is ready only if it is ready in all regions.
+		for _, cr := range appDeploy.RegionalCloudRuns {
+			if regionReady, regionOK := cr.Ready.LookupCurrent(); ok && regionOK && ready && !regionReady {
+				ready = false
+				message = cr.StatusMessage.Current()
+			}
+		}
 	case *deploy.FunctionApp:
-		ready, ok = appDeploy.CloudFunction.Ready.LookupCurrent()
-		message = appDeploy.CloudFunction.StatusMessage.Current()
+		if cf := appDeploy.CloudFunctionV2; cf != nil {
+			ready, ok = cf.Ready.LookupCurrent()
+			message = cf.StatusMessage.Current()
+		} else {
+			ready, ok = appDeploy.CloudFunction.Ready.LookupCurrent()
+			message = appDeploy.CloudFunction.StatusMessage.Current()
+		}
+	case *deploy.JobApp:
+		ready, ok = appDeploy.CloudRunJob.Ready.LookupCurrent()
+		message = appDeploy.CloudRunJob.StatusMessage.Current()
 	}
 
 	if !ok {
@@ -78,6 +94,47 @@ func computeDependencyDNSState(dep any) *apiv1.DNSState {
 		dns = &apiv1.DNSState{
 			Properties: props,
 		}
+
+	case *deploy.RedisDep:
+		if !depDeploy.Instance.IsExisting() {
+			return nil
+		}
+
+		host := depDeploy.Instance.Host.Current()
+		port := depDeploy.Instance.Port.Current()
+
+		props := plugin_util.MustNewStruct(map[string]any{
+			"host":    host,
+			"port":    port,
+			"version": depDeploy.Instance.RedisVersion.Current(),
+		})
+
+		dns = &apiv1.DNSState{
+			InternalIp:     host,
+			ConnectionInfo: fmt.Sprintf("%s:%d (%s)", host, port, depDeploy.Instance.Name.Current()),
+			Properties:     props,
+		}
+
+	case *deploy.PubSubDep:
+		if !depDeploy.Topic.IsExisting() {
+			return nil
+		}
+
+		subs := make([]any, 0, len(depDeploy.Subscriptions))
+
+		for _, name := range depDeploy.SubscriptionNames() {
+			subs = append(subs, depDeploy.Subscriptions[name].Name.Current())
+		}
+
+		props := plugin_util.MustNewStruct(map[string]any{
+			"topic":         depDeploy.Topic.Name.Current(),
+			"subscriptions": subs,
+		})
+
+		dns = &apiv1.DNSState{
+			ConnectionInfo: fmt.Sprintf("projects/%s/topics/%s", depDeploy.Topic.ProjectID.Current(), depDeploy.Topic.Name.Current()),
+			Properties:     props,
+		}
 	}
 
 	return dns
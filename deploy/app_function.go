@@ -2,7 +2,10 @@ package deploy
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/creasty/defaults"
 	validation "github.com/go-ozzo/ozzo-validation/v4"
@@ -19,6 +22,7 @@ type FunctionApp struct {
 	Bucket             *gcp.Bucket
 	Archive            *gcp.BucketObject
 	CloudFunction      *gcp.CloudFunction
+	CloudFunctionV2    *gcp.CloudFunctionV2
 	CloudSchedulerJobs []*gcp.CloudSchedulerJob
 
 	App        *apiv1.App
@@ -34,10 +38,165 @@ type FunctionAppArgs struct {
 	Env       map[string]string
 	Vars      map[string]any
 	Databases []*DatabaseDep
+
+	// Used by event triggers, both are keyed by name.
+	StorageDeps  map[string]*StorageDep
+	PubSubTopics map[string]*gcp.PubSubTopic
 }
 
+var (
+	cloudFunctionV2StorageEvents = map[string]string{
+		"finalize":       "finalized",
+		"delete":         "deleted",
+		"archive":        "archived",
+		"metadataUpdate": "metadataUpdated",
+	}
+	cloudFunctionV2FirestoreEvents = map[string]string{
+		"write":  "written",
+		"create": "created",
+		"update": "updated",
+		"delete": "deleted",
+	}
+)
+
 type FunctionAppDeployOptions struct {
 	types.FunctionAppDeployOptions
+
+	// Generation selects Cloud Functions generation. Changing it from 1 to 2 upgrades existing function in place.
+	Generation int `json:"generation" default:"1"`
+
+	// Options below are only supported by 2nd gen functions.
+	CPULimit           float64                    `json:"cpu_limit"`
+	Concurrency        int                        `json:"concurrency"`
+	ServiceAccountName string                     `json:"service_account_name"`
+	VPCConnector       string                     `json:"vpc_connector"`
+	EgressMode         string                     `json:"egress_mode" default:"private-ranges-only"`
+	Trigger            *FunctionAppTriggerOptions `json:"trigger"`
+}
+
+// FunctionAppTriggerOptions define event trigger of function. Exactly one of pubsub, storage, firestore
+// or raw Eventarc event type has to be set.
+type FunctionAppTriggerOptions struct {
+	PubSub    *FunctionAppPubSubTrigger    `json:"pubsub"`
+	Storage   *FunctionAppStorageTrigger   `json:"storage"`
+	Firestore *FunctionAppFirestoreTrigger `json:"firestore"`
+	Retry     bool                         `json:"retry"`
+
+	// Options below are only supported by 2nd gen functions.
+	EventType   string            `json:"event_type"`
+	Filters     map[string]string `json:"filters"`
+	PubSubTopic string            `json:"pubsub_topic"`
+	Region      string            `json:"region"`
+}
+
+// FunctionAppPubSubTrigger triggers function on every message published to topic, topic is created if needed.
+type FunctionAppPubSubTrigger struct {
+	Topic string `json:"topic"`
+}
+
+// FunctionAppStorageTrigger triggers function on object changes in bucket of storage dependency.
+type FunctionAppStorageTrigger struct {
+	Dependency string `json:"dependency"`
+	Event      string `json:"event" default:"finalize"` // options: finalize, delete, archive, metadataUpdate
+}
+
+// FunctionAppFirestoreTrigger triggers function on changes of documents matching path pattern, e.g. "images/{id}".
+type FunctionAppFirestoreTrigger struct {
+	Document string `json:"document"`
+	Database string `json:"database" default:"(default)"`
+	Event    string `json:"event" default:"write"` // options: write, create, update, delete
+}
+
+func (o *FunctionAppTriggerOptions) Validate() error {
+	count := 0
+
+	for _, set := range []bool{o.PubSub != nil, o.Storage != nil, o.Firestore != nil, o.EventType != ""} {
+		if set {
+			count++
+		}
+	}
+
+	if count != 1 {
+		return errors.New("exactly one of pubsub, storage, firestore or event_type is required")
+	}
+
+	return validation.ValidateStruct(o,
+		validation.Field(&o.PubSub),
+		validation.Field(&o.Storage),
+		validation.Field(&o.Firestore),
+	)
+}
+
+func (o *FunctionAppTriggerOptions) validateGen1() error {
+	gen2Only := validation.Empty.Error("is only supported by 2nd gen functions")
+
+	return validation.ValidateStruct(o,
+		validation.Field(&o.EventType, gen2Only),
+		validation.Field(&o.Filters, gen2Only),
+		validation.Field(&o.PubSubTopic, gen2Only),
+		validation.Field(&o.Region, gen2Only),
+	)
+}
+
+func (o *FunctionAppPubSubTrigger) Validate() error {
+	return validation.ValidateStruct(o,
+		validation.Field(&o.Topic, validation.Required),
+	)
+}
+
+func (o *FunctionAppStorageTrigger) Validate() error {
+	return validation.ValidateStruct(o,
+		validation.Field(&o.Dependency, validation.Required),
+		validation.Field(&o.Event, validation.In("finalize", "delete", "archive", "metadataUpdate")),
+	)
+}
+
+func (o *FunctionAppFirestoreTrigger) Validate() error {
+	return validation.ValidateStruct(o,
+		validation.Field(&o.Document, validation.Required),
+		validation.Field(&o.Event, validation.In("write", "create", "update", "delete")),
+	)
+}
+
+func (o *FunctionAppDeployOptions) validateGen1() error {
+	gen2Only := validation.Empty.Error("is only supported by 2nd gen functions")
+
+	return validation.ValidateStruct(o,
+		validation.Field(&o.MemoryLimit, validation.Min(128), validation.Max(8192)),
+		validation.Field(&o.MinScale, validation.Min(0)),
+		validation.Field(&o.MaxScale, validation.Min(1)),
+		validation.Field(&o.Timeout, validation.Min(1), validation.Max(540)),
+		validation.Field(&o.CPULimit, gen2Only),
+		validation.Field(&o.Concurrency, validation.Max(1).Error("is only supported by 2nd gen functions")),
+		validation.Field(&o.ServiceAccountName, gen2Only),
+		validation.Field(&o.VPCConnector, gen2Only),
+		validation.Field(&o.Trigger, validation.By(func(any) error {
+			if o.Trigger == nil {
+				return nil
+			}
+
+			return o.Trigger.validateGen1()
+		})),
+	)
+}
+
+func (o *FunctionAppDeployOptions) validateGen2() error {
+	maxTimeout := 3600
+	if o.Trigger != nil {
+		maxTimeout = 540
+	}
+
+	return validation.ValidateStruct(o,
+		validation.Field(&o.MemoryLimit, validation.Min(128), validation.Max(32768)),
+		validation.Field(&o.MinScale, validation.Min(0)),
+		validation.Field(&o.MaxScale, validation.Min(1)),
+		validation.Field(&o.Timeout, validation.Min(1), validation.Max(maxTimeout)),
+		validation.Field(&o.CPULimit, validation.Min(0.0), validation.Max(8.0),
+			validation.When(o.Concurrency > 1, validation.Min(1.0).Error("must be at least 1 when concurrency is greater than 1"))),
+		validation.Field(&o.Concurrency, validation.Min(1), validation.Max(1000)),
+		validation.Field(&o.EgressMode, validation.In("private-ranges-only", "all-traffic")),
+		validation.Field(&o.Trigger),
+	)
 }
 
 func NewFunctionAppDeployOptions(in map[string]any) (*FunctionAppDeployOptions, error) {
@@ -62,11 +221,24 @@ func NewFunctionAppDeployOptions(in map[string]any) (*FunctionAppDeployOptions,
 		o.Timeout = 300
 	}
 
+	switch o.Generation {
+	case 1:
+		return o, o.validateGen1()
+	case 2:
+		if o.Concurrency == 0 {
+			o.Concurrency = 1
+		}
+
+		// Concurrent requests need at least one full CPU.
+		if o.Concurrency > 1 && o.CPULimit == 0 {
+			o.CPULimit = 1
+		}
+
+		return o, o.validateGen2()
+	}
+
 	return o, validation.ValidateStruct(o,
-		validation.Field(&o.MemoryLimit, validation.Min(128), validation.Max(8192)),
-		validation.Field(&o.MinScale, validation.Min(0)),
-		validation.Field(&o.MaxScale, validation.Min(1)),
-		validation.Field(&o.Timeout, validation.Min(1), validation.Max(540)),
+		validation.Field(&o.Generation, validation.In(1, 2)),
 	)
 }
 
@@ -100,6 +272,10 @@ func (o *FunctionApp) ID(pctx *config.PluginContext) string {
 
 func (o *FunctionApp) Plan(_ context.Context, pctx *config.PluginContext, r *registry.Registry, c *FunctionAppArgs, _ bool, // apply bool
 ) error {
+	if o.DeployOpts.Trigger != nil && o.App.Url != "" {
+		return fmt.Errorf("app '%s' has event trigger defined and cannot be exposed with url", o.App.Name)
+	}
+
 	// Add bucket.
 	o.Bucket = &gcp.Bucket{
 		Name:       gcp.GlobalIDField(pctx.Env(), c.ProjectID, o.App.Id),
@@ -149,6 +325,33 @@ func (o *FunctionApp) Plan(_ context.Context, pctx *config.PluginContext, r *reg
 		envVars[k] = exp
 	}
 
+	if o.DeployOpts.Generation == 2 {
+		err = o.planCloudFunctionV2(pctx, r, c, envVars)
+	} else {
+		err = o.planCloudFunction(pctx, r, c, envVars)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if o.App.Url != "" {
+		schedulers, err := addCloudSchedulers(pctx, r, o.App, c.ProjectID, c.Region, o.Props.Scheduler)
+		if err != nil {
+			return err
+		}
+
+		o.CloudSchedulerJobs = schedulers
+	}
+
+	return nil
+}
+
+func (o *FunctionApp) planCloudFunction(pctx *config.PluginContext, r *registry.Registry, c *FunctionAppArgs, envVars map[string]fields.Field) error {
+	if r.GetAppResource(o.App, "cloud_function_v2", &gcp.CloudFunctionV2{}) {
+		return fmt.Errorf("app '%s' was already upgraded to 2nd gen cloud function, downgrading is not supported", o.App.Name)
+	}
+
 	o.CloudFunction = &gcp.CloudFunction{
 		Name:         fields.String(o.ID(pctx)),
 		ProjectID:    fields.String(c.ProjectID),
@@ -157,7 +360,7 @@ func (o *FunctionApp) Plan(_ context.Context, pctx *config.PluginContext, r *reg
 		Runtime:      fields.String(o.Props.Runtime),
 		SourceBucket: o.Bucket.Name,
 		SourceObject: o.Archive.Name,
-		IsPublic:     fields.Bool(!o.Props.Private),
+		IsPublic:     fields.Bool(!o.Props.Private && o.DeployOpts.Trigger == nil),
 
 		MinScale:       fields.Int(o.DeployOpts.MinScale),
 		MaxScale:       fields.Int(o.DeployOpts.MaxScale),
@@ -166,19 +369,180 @@ func (o *FunctionApp) Plan(_ context.Context, pctx *config.PluginContext, r *reg
 		EnvVars:        fields.Map(envVars),
 	}
 
-	_, err = r.RegisterAppResource(o.App, "cloud_function", o.CloudFunction)
-	if err != nil {
-		return err
+	if t := o.DeployOpts.Trigger; t != nil {
+		topic, storage, err := o.triggerSource(r, c)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case t.PubSub != nil:
+			o.CloudFunction.EventType = fields.String("google.pubsub.topic.publish")
+			o.CloudFunction.EventResource = fields.Sprintf("projects/%s/topics/%s", topic.ProjectID, topic.Name)
+		case t.Storage != nil:
+			o.CloudFunction.EventType = fields.String("google.storage.object." + t.Storage.Event)
+			o.CloudFunction.EventResource = fields.Sprintf("projects/_/buckets/%s", storage.Bucket.Name)
+		case t.Firestore != nil:
+			o.CloudFunction.EventType = fields.String("providers/cloud.firestore/eventTypes/document." + t.Firestore.Event)
+			o.CloudFunction.EventResource = fields.String(fmt.Sprintf("projects/%s/databases/%s/documents/%s", c.ProjectID, t.Firestore.Database, t.Firestore.Document))
+		}
+
+		o.CloudFunction.EventRetry = fields.Bool(t.Retry)
 	}
 
-	if o.App.Url != "" {
-		schedulers, err := addCloudSchedulers(pctx, r, o.App, c.ProjectID, c.Region, o.Props.Scheduler)
+	_, err := r.RegisterAppResource(o.App, "cloud_function", o.CloudFunction)
+
+	return err
+}
+
+// planCloudFunctionV2 adds 2nd gen cloud function. As it shares reference ID with 1st gen one, function that
+// was previously deployed as 1st gen is not deleted but upgraded in place, keeping its name and load balancer backend.
+func (o *FunctionApp) planCloudFunctionV2(pctx *config.PluginContext, r *registry.Registry, c *FunctionAppArgs, envVars map[string]fields.Field) error {
+	opts := o.DeployOpts
+
+	o.CloudFunctionV2 = &gcp.CloudFunctionV2{
+		Name:         fields.String(o.ID(pctx)),
+		ProjectID:    fields.String(c.ProjectID),
+		Region:       fields.String(c.Region),
+		Entrypoint:   fields.String(o.Props.Entrypoint),
+		Runtime:      fields.String(o.Props.Runtime),
+		SourceBucket: o.Bucket.Name,
+		SourceObject: o.Archive.Name,
+		IsPublic:     fields.Bool(!o.Props.Private && opts.Trigger == nil),
+
+		MinScale:           fields.Int(opts.MinScale),
+		MemoryLimit:        fields.Int(opts.MemoryLimit),
+		Concurrency:        fields.Int(opts.Concurrency),
+		TimeoutSeconds:     fields.Int(opts.Timeout),
+		EnvVars:            fields.Map(envVars),
+		ServiceAccountName: fields.String(opts.ServiceAccountName),
+		VPCConnector:       fields.String(opts.VPCConnector),
+		EgressMode:         fields.String(opts.EgressMode),
+	}
+
+	if opts.MaxScale > 0 {
+		o.CloudFunctionV2.MaxScale = fields.Int(opts.MaxScale)
+	}
+
+	if opts.CPULimit > 0 {
+		o.CloudFunctionV2.CPULimit = fields.String(strconv.FormatFloat(opts.CPULimit, 'f', -1, 64))
+	}
+
+	if opts.Trigger != nil {
+		err := o.planTriggerV2(r, c)
 		if err != nil {
 			return err
 		}
+	}
 
-		o.CloudSchedulerJobs = schedulers
+	_, err := r.RegisterAppResource(o.App, "cloud_function_v2", o.CloudFunctionV2)
+	if err != nil {
+		return err
+	}
+
+	// Treat 1st gen function as existing one so that it is updated (and upgraded) instead of being created.
+	v1 := &gcp.CloudFunction{}
+
+	if o.CloudFunctionV2.IsNew() && r.GetAppResource(o.App, "cloud_function", v1) {
+		o.CloudFunctionV2.MarkAsExisting()
+		o.CloudFunctionV2.Name.SetCurrent(v1.Name.Current())
+		o.CloudFunctionV2.ProjectID.SetCurrent(v1.ProjectID.Current())
+		o.CloudFunctionV2.Region.SetCurrent(v1.Region.Current())
+		o.CloudFunctionV2.Environment.SetCurrent(gcp.CloudFunctionGen1)
+	}
+
+	return nil
+}
+
+func (o *FunctionApp) planTriggerV2(r *registry.Registry, c *FunctionAppArgs) error {
+	t := o.DeployOpts.Trigger
+	cf := o.CloudFunctionV2
+
+	topic, storage, err := o.triggerSource(r, c)
+	if err != nil {
+		return err
+	}
+
+	filters := make(map[string]fields.Field, len(t.Filters))
+
+	for k, v := range t.Filters {
+		filters[k] = fields.String(v)
+	}
+
+	region := t.Region
+	if region == "" {
+		region = c.Region
+	}
+
+	pubsubTopic := fields.String(t.PubSubTopic)
+	eventType := t.EventType
+
+	switch {
+	case t.PubSub != nil:
+		eventType = "google.cloud.pubsub.topic.v1.messagePublished"
+		pubsubTopic = fields.Sprintf("projects/%s/topics/%s", topic.ProjectID, topic.Name)
+	case t.Storage != nil:
+		eventType = "google.cloud.storage.object.v1." + cloudFunctionV2StorageEvents[t.Storage.Event]
+		filters["bucket"] = storage.Bucket.Name
+
+		// Storage triggers have to be in the same location as bucket.
+		if t.Region == "" {
+			region = strings.ToLower(storage.Bucket.Location.Wanted())
+		}
+	case t.Firestore != nil:
+		eventType = "google.cloud.firestore.document.v1." + cloudFunctionV2FirestoreEvents[t.Firestore.Event]
+		filters["database"] = fields.String(t.Firestore.Database)
+		cf.EventPathFilters = fields.Map(map[string]fields.Field{
+			"document": fields.String(t.Firestore.Document),
+		})
 	}
 
+	cf.EventType = fields.String(eventType)
+	cf.EventFilters = fields.Map(filters)
+	cf.EventPubSubTopic = pubsubTopic
+	cf.EventTriggerRegion = fields.String(region)
+	cf.EventRetry = fields.Bool(t.Retry)
+
 	return nil
 }
+
+// triggerSource returns pubsub topic or storage dependency that function trigger is attached to.
+func (o *FunctionApp) triggerSource(r *registry.Registry, c *FunctionAppArgs) (topic *gcp.PubSubTopic, storage *StorageDep, err error) {
+	t := o.DeployOpts.Trigger
+
+	if t.PubSub != nil {
+		topic, err = addPubSubTopic(r, c.PubSubTopics, c.ProjectID, t.PubSub.Topic)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if t.Storage != nil {
+		var ok bool
+
+		storage, ok = c.StorageDeps[t.Storage.Dependency]
+		if !ok {
+			return nil, nil, fmt.Errorf("app '%s' trigger references unknown storage dependency '%s'", o.App.Name, t.Storage.Dependency)
+		}
+	}
+
+	return topic, storage, nil
+}
+
+// CloudFunctionName returns name field of cloud function of either generation.
+func (o *FunctionApp) CloudFunctionName() fields.StringInputField {
+	if o.CloudFunctionV2 != nil {
+		return o.CloudFunctionV2.Name
+	}
+
+	return o.CloudFunction.Name
+}
+
+// IsExisting returns true if cloud function of either generation already exists.
+func (o *FunctionApp) IsExisting() bool {
+	if o.CloudFunctionV2 != nil {
+		return o.CloudFunctionV2.IsExisting()
+	}
+
+	return o.CloudFunction.IsExisting()
+}
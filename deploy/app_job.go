@@ -0,0 +1,208 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/creasty/defaults"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/outblocks/cli-plugin-gcp/gcp"
+	"github.com/outblocks/cli-plugin-gcp/internal/config"
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
+	"github.com/outblocks/outblocks-plugin-go/registry"
+	"github.com/outblocks/outblocks-plugin-go/registry/fields"
+	"github.com/outblocks/outblocks-plugin-go/types"
+	plugin_util "github.com/outblocks/outblocks-plugin-go/util"
+	"github.com/outblocks/outblocks-plugin-go/util/command"
+)
+
+type JobApp struct {
+	Image              *gcp.Image
+	CloudRunJob        *gcp.CloudRunJob
+	CloudSchedulerJobs []*gcp.CloudSchedulerJob
+
+	App        *apiv1.App
+	Skip       bool
+	Destroy    bool
+	Build      *apiv1.AppBuild
+	Props      *JobAppProperties
+	DeployOpts *JobAppDeployOptions
+}
+
+type JobAppContainer struct {
+	Entrypoint *command.StringCommand `json:"entrypoint,omitempty"`
+	Command    *command.StringCommand `json:"command,omitempty"`
+}
+
+type JobAppProperties struct {
+	Build     *types.ServiceAppBuild `json:"build,omitempty"`
+	Container *JobAppContainer       `json:"container,omitempty"`
+	Scheduler []*types.AppScheduler  `json:"scheduler,omitempty"`
+}
+
+func NewJobAppProperties(in map[string]any) (*JobAppProperties, error) {
+	o := &JobAppProperties{
+		Build:     &types.ServiceAppBuild{},
+		Container: &JobAppContainer{},
+	}
+
+	err := plugin_util.MapstructureJSONDecode(in, o)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding job app properties: %w", err)
+	}
+
+	return o, nil
+}
+
+type JobAppDeployOptions struct {
+	CPULimit    float64 `json:"cpu_limit"`
+	MemoryLimit int     `json:"memory_limit"`
+	Timeout     int     `json:"timeout" default:"600"`
+	MaxRetries  *int    `json:"max_retries" default:"3"`
+	TaskCount   int     `json:"task_count" default:"1"`
+	Parallelism int     `json:"parallelism"`
+
+	ServiceAccountName string `json:"service_account_name"`
+	EgressNetwork      string `json:"egress_network"`
+	EgressSubnet       string `json:"egress_subnet"`
+	EgressMode         string `json:"egress_mode" default:"private-ranges-only"`
+}
+
+func NewJobAppDeployOptions(in map[string]any) (*JobAppDeployOptions, error) {
+	o := &JobAppDeployOptions{}
+
+	err := plugin_util.MapstructureJSONDecode(in, o)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding job app deploy options: %w", err)
+	}
+
+	err = defaults.Set(o)
+	if err != nil {
+		return nil, err
+	}
+
+	// Manual defaults.
+	if o.CPULimit == 0 {
+		o.CPULimit = 1
+	}
+
+	if o.MemoryLimit == 0 {
+		o.MemoryLimit = 512
+	}
+
+	return o, validation.ValidateStruct(o,
+		validation.Field(&o.CPULimit, validation.In(1.0, 2.0, 4.0, 6.0, 8.0)),
+		validation.Field(&o.MemoryLimit, validation.Min(512), validation.Max(32768)),
+		validation.Field(&o.Timeout, validation.Min(1), validation.Max(86400)),
+		validation.Field(&o.MaxRetries, validation.Min(0), validation.Max(10)),
+		validation.Field(&o.TaskCount, validation.Min(1), validation.Max(10_000)),
+		validation.Field(&o.Parallelism, validation.Min(0), validation.Max(o.TaskCount)),
+	)
+}
+
+func NewJobApp(plan *apiv1.AppPlan, destroy bool) (*JobApp, error) {
+	opts, err := NewJobAppProperties(plan.State.App.Properties.AsMap())
+	if err != nil {
+		return nil, err
+	}
+
+	deployOpts, err := NewJobAppDeployOptions(plan.State.App.Properties.AsMap())
+	if err != nil {
+		return nil, err
+	}
+
+	if plan.Build == nil {
+		plan.Build = &apiv1.AppBuild{}
+	}
+
+	return &JobApp{
+		App:        plan.State.App,
+		Skip:       plan.Skip,
+		Destroy:    destroy,
+		Build:      plan.Build,
+		Props:      opts,
+		DeployOpts: deployOpts,
+	}, nil
+}
+
+func (o *JobApp) ID(pctx *config.PluginContext) string {
+	return gcp.ID(pctx.Env(), o.App.Id)
+}
+
+func (o *JobApp) Plan(_ context.Context, pctx *config.PluginContext, r *registry.Registry, c *ServiceAppArgs) error {
+	// Add GCR docker image.
+	o.Image = &gcp.Image{
+		Name:      fields.String(gcp.ImageID(pctx.Env(), o.App.Id)),
+		ProjectID: fields.String(c.ProjectID),
+		Region:    fields.String(c.Region),
+		Pull:      false,
+	}
+
+	if o.Build.LocalDockerImage != "" && o.Build.LocalDockerHash != "" {
+		o.Image.SourceHash = fields.String(o.Build.LocalDockerHash)
+		o.Image.Source = fields.String(o.Build.LocalDockerImage)
+	}
+
+	_, err := r.RegisterAppResource(o.App, "image", o.Image)
+	if err != nil {
+		return err
+	}
+
+	if !o.Image.IsExisting() && o.Build.LocalDockerHash == "" && !o.Skip && !o.Destroy {
+		return fmt.Errorf("image for app '%s' is missing", o.App.Name)
+	}
+
+	// Expand env vars.
+	envVars, err := expandCloudRunEnvVars(c.Env, c.Vars, c.Settings)
+	if err != nil {
+		return err
+	}
+
+	egressNetwork, egressSubnet, err := redisEgress(o.App, o.DeployOpts.EgressNetwork, o.DeployOpts.EgressSubnet, c.Redis)
+	if err != nil {
+		return err
+	}
+
+	// Add cloud run job.
+	o.CloudRunJob = &gcp.CloudRunJob{
+		Name:      fields.String(o.ID(pctx)),
+		ProjectID: fields.String(c.ProjectID),
+		Region:    fields.String(c.Region),
+		Command:   commandField(o.Props.Container.Entrypoint),
+		Args:      commandField(o.Props.Container.Command),
+		Image:     o.Image.ImageName(),
+
+		CloudSQLInstances:  cloudSQLInstancesField(c.Databases),
+		CPULimit:           fields.String(fmt.Sprintf("%dm", int(o.DeployOpts.CPULimit*1000))),
+		MemoryLimit:        fields.String(fmt.Sprintf("%dMi", o.DeployOpts.MemoryLimit)),
+		TimeoutSeconds:     fields.Int(o.DeployOpts.Timeout),
+		MaxRetries:         fields.Int(*o.DeployOpts.MaxRetries),
+		TaskCount:          fields.Int(o.DeployOpts.TaskCount),
+		Parallelism:        fields.Int(o.DeployOpts.Parallelism),
+		EnvVars:            envVars,
+		ServiceAccountName: fields.String(o.DeployOpts.ServiceAccountName),
+		EgressNetwork:      fields.String(egressNetwork),
+		EgressSubnet:       fields.String(egressSubnet),
+		EgressMode:         fields.String(o.DeployOpts.EgressMode),
+	}
+
+	_, err = r.RegisterAppResource(o.App, "cloud_run_job", o.CloudRunJob)
+	if err != nil {
+		return err
+	}
+
+	// Jobs are executed by Cloud Scheduler directly through Cloud Run Admin API.
+	serviceAccount := o.DeployOpts.ServiceAccountName
+	if serviceAccount == "" {
+		serviceAccount = fmt.Sprintf("%d-compute@developer.gserviceaccount.com", pctx.Settings().ProjectNumber)
+	}
+
+	schedulers, err := addCloudRunJobSchedulers(pctx, r, o.App, o.CloudRunJob, c.ProjectID, c.Region, serviceAccount, o.Props.Scheduler)
+	if err != nil {
+		return err
+	}
+
+	o.CloudSchedulerJobs = schedulers
+
+	return nil
+}
@@ -8,7 +8,9 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/creasty/defaults"
 	validation "github.com/go-ozzo/ozzo-validation/v4"
@@ -39,7 +41,9 @@ func (s *CloudRunSettings) URLSuffix() string {
 type ServiceApp struct {
 	Image              *gcp.Image
 	CloudRun           *gcp.CloudRun
+	RegionalCloudRuns  []*gcp.CloudRun
 	CloudSchedulerJobs []*gcp.CloudSchedulerJob
+	SecretAccessors    []*gcp.SecretIAMMember
 
 	App        *apiv1.App
 	Skip       bool
@@ -47,6 +51,7 @@ type ServiceApp struct {
 	Build      *apiv1.AppBuild
 	Props      *types.ServiceAppProperties
 	DeployOpts *ServiceAppDeployOptions
+	Warnings   []string
 }
 
 type ServiceAppArgs struct {
@@ -55,7 +60,9 @@ type ServiceAppArgs struct {
 	Env       map[string]string
 	Vars      map[string]any
 	Databases []*DatabaseDep
+	Redis     []*RedisDep
 	Settings  *CloudRunSettings
+	Secrets   []*SecretValue // latest secret values, only loaded if secret env detection is enabled
 }
 
 type ServiceAppDeployOptions struct {
@@ -71,6 +78,60 @@ type ServiceAppDeployOptions struct {
 	EgressNetwork        string `json:"egress_network"`
 	EgressSubnet         string `json:"egress_subnet"`
 	EgressMode           string `json:"egress_mode" default:"private-ranges-only"`
+
+	// Regions lists additional regions service is deployed to and served from through load balancer.
+	Regions []string `json:"regions"`
+
+	// SecretEnv maps env var names to Outblocks secret keys, optionally pinned to a version with "key:version".
+	SecretEnv map[string]string `json:"secret_env"`
+	// SecretEnvDetect references env vars whose whole value equals latest value of a secret as if listed in SecretEnv.
+	SecretEnvDetect bool `json:"secret_env_detect"`
+
+	Traffic *ServiceAppTrafficOptions `json:"traffic"`
+}
+
+type ServiceAppTrafficOptions struct {
+	// Percent of traffic routed to new revision, the rest of it stays on previous revision.
+	Percent int                       `json:"percent"`
+	Rollout *ServiceAppRolloutOptions `json:"rollout"`
+}
+
+type ServiceAppRolloutOptions struct {
+	// Steps are percentages of traffic routed to new revision during progressive rollout.
+	Steps        []int   `json:"steps"`
+	Interval     *int    `json:"interval" default:"60"`
+	MaxErrorRate float64 `json:"max_error_rate"`
+}
+
+func (o *ServiceAppTrafficOptions) Validate() error {
+	err := validation.ValidateStruct(o,
+		validation.Field(&o.Percent, validation.Min(1), validation.Max(100)),
+	)
+	if err != nil || o.Rollout == nil {
+		return err
+	}
+
+	return validation.ValidateStruct(o.Rollout,
+		validation.Field(&o.Rollout.Steps, validation.Required, validation.Each(validation.Min(1), validation.Max(100))),
+		validation.Field(&o.Rollout.Interval, validation.Min(0), validation.Max(3600)),
+		validation.Field(&o.Rollout.MaxErrorRate, validation.Min(0.0), validation.Max(1.0)),
+	)
+}
+
+func (o *ServiceAppTrafficOptions) CloudRunRollout() *gcp.CloudRunRollout {
+	if o == nil || o.Rollout == nil {
+		return nil
+	}
+
+	steps := make([]int, len(o.Rollout.Steps))
+	copy(steps, o.Rollout.Steps)
+	sort.Ints(steps)
+
+	return &gcp.CloudRunRollout{
+		Steps:        steps,
+		Interval:     time.Duration(*o.Rollout.Interval) * time.Second,
+		MaxErrorRate: o.Rollout.MaxErrorRate,
+	}
 }
 
 func NewServiceAppDeployOptions(in map[string]any) (*ServiceAppDeployOptions, error) {
@@ -107,6 +168,19 @@ func NewServiceAppDeployOptions(in map[string]any) (*ServiceAppDeployOptions, er
 		o.MinScale = o.MaxScale
 	}
 
+	if o.Traffic != nil {
+		if o.Traffic.Percent == 0 {
+			o.Traffic.Percent = 100
+		}
+
+		if o.Traffic.Rollout != nil {
+			err = defaults.Set(o.Traffic.Rollout)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	return o, validation.ValidateStruct(o,
 		validation.Field(&o.CPULimit, validation.In(1.0, 2.0, 4.0, 6.0, 8.0)),
 		validation.Field(&o.MemoryLimit, validation.Min(128), validation.Max(32768)),
@@ -114,6 +188,8 @@ func NewServiceAppDeployOptions(in map[string]any) (*ServiceAppDeployOptions, er
 		validation.Field(&o.MaxScale, validation.Min(1), validation.Max(20_000)),
 		validation.Field(&o.Timeout, validation.Min(1), validation.Max(3600)),
 		validation.Field(&o.ContainerConcurrency, validation.Min(1), validation.Max(1000)),
+		validation.Field(&o.Regions, validation.Each(validation.Required)),
+		validation.Field(&o.Traffic),
 	)
 }
 
@@ -302,60 +378,121 @@ func (o *ServiceApp) Plan(ctx context.Context, pctx *config.PluginContext, r *re
 		return fmt.Errorf("image for app '%s' is missing", o.App.Name)
 	}
 
-	// Expand env vars.
-	cloudRunHash := "unknown"
+	// Add secret env vars.
+	var secretEnvVars map[string]fields.Field
+
+	if !o.Destroy {
+		secretEnv := o.DeployOpts.SecretEnv
+
+		if o.DeployOpts.SecretEnvDetect {
+			secretEnv, o.Warnings, err = detectSecretEnv(o.App, c.Env, c.Vars, c.Secrets, secretEnv)
+			if err != nil {
+				return err
+			}
+		}
+
+		secretEnvVars, o.SecretAccessors, err = planSecretEnvVars(ctx, pctx, r, o.App, c.ProjectID, secretEnv)
+		if err != nil {
+			return err
+		}
+	}
 
-	if c.Settings != nil {
-		cloudRunHash = c.Settings.ProjectHash
+	// Add cloud run service.
+	o.CloudRun, err = o.makeCloudRun(pctx, c, c.Region, secretEnvVars)
+	if err != nil {
+		return err
 	}
 
-	if c.Env == nil {
-		c.Env = make(map[string]string)
+	_, err = r.RegisterAppResource(o.App, "cloud_run", o.CloudRun)
+	if err != nil {
+		return err
 	}
 
-	c.Env["CLOUD_RUN_PROJECT_HASH"] = cloudRunHash
+	resetCloudRunTraffic(o.CloudRun, o.DeployOpts.Traffic)
+	resetCloudRunSecretEnvVars(o.CloudRun, secretEnvVars)
 
-	envVars := make(map[string]fields.Field, len(c.Env))
-	eval := fields.NewFieldVarEvaluator(c.Vars)
+	// Add cloud run services in additional regions.
+	for _, region := range o.additionalRegions(c.Region) {
+		cr, err := o.makeCloudRun(pctx, c, region, secretEnvVars)
+		if err != nil {
+			return err
+		}
 
-	for k, v := range c.Env {
-		exp, err := eval.Expand(v)
+		_, err = r.RegisterAppResource(o.App, "cloud_run_"+region, cr)
 		if err != nil {
 			return err
 		}
 
-		envVars[k] = exp
+		resetCloudRunTraffic(cr, o.DeployOpts.Traffic)
+		resetCloudRunSecretEnvVars(cr, secretEnvVars)
+
+		o.RegionalCloudRuns = append(o.RegionalCloudRuns, cr)
 	}
 
-	// Add cloud run service.
-	cloudSQLconnFmt := make([]string, len(c.Databases))
-	cloudSQLconnNames := make([]any, len(c.Databases))
+	if o.App.Url != "" {
+		schedulers, err := addCloudSchedulers(pctx, r, o.App, c.ProjectID, c.Region, o.Props.Scheduler)
+		if err != nil {
+			return err
+		}
+
+		o.CloudSchedulerJobs = schedulers
+	}
+
+	return nil
+}
+
+func (o *ServiceApp) additionalRegions(region string) []string {
+	var ret []string
 
-	for i, db := range c.Databases {
-		cloudSQLconnFmt[i] = "%s"
-		cloudSQLconnNames[i] = db.CloudSQL.ConnectionName
+	seen := map[string]bool{region: true}
+
+	for _, r := range o.DeployOpts.Regions {
+		if seen[r] {
+			continue
+		}
+
+		seen[r] = true
+
+		ret = append(ret, r)
+	}
+
+	return ret
+}
+
+func (o *ServiceApp) makeCloudRun(pctx *config.PluginContext, c *ServiceAppArgs, region string, secretEnvVars map[string]fields.Field) (*gcp.CloudRun, error) {
+	// Expand env vars, ones backed by secrets are only passed as references.
+	env := c.Env
+
+	if len(secretEnvVars) != 0 {
+		env = make(map[string]string, len(c.Env))
+
+		for k, v := range c.Env {
+			if _, ok := secretEnvVars[k]; !ok {
+				env[k] = v
+			}
+		}
 	}
 
-	cmd := make([]fields.Field, len(o.Props.Container.Entrypoint.ArrayOrShell()))
-	for i, v := range o.Props.Container.Entrypoint.ArrayOrShell() {
-		cmd[i] = fields.String(v)
+	envVars, err := expandCloudRunEnvVars(env, c.Vars, c.Settings)
+	if err != nil {
+		return nil, err
 	}
 
-	args := make([]fields.Field, len(o.Props.Container.Command.ArrayOrShell()))
-	for i, v := range o.Props.Container.Command.ArrayOrShell() {
-		args[i] = fields.String(v)
+	egressNetwork, egressSubnet, err := redisEgress(o.App, o.DeployOpts.EgressNetwork, o.DeployOpts.EgressSubnet, c.Redis)
+	if err != nil {
+		return nil, err
 	}
 
-	o.CloudRun = &gcp.CloudRun{
+	cr := &gcp.CloudRun{
 		Name:      fields.String(o.ID(pctx)),
 		ProjectID: fields.String(c.ProjectID),
-		Region:    fields.String(c.Region),
-		Command:   fields.Array(cmd),
-		Args:      fields.Array(args),
+		Region:    fields.String(region),
+		Command:   commandField(o.Props.Container.Entrypoint),
+		Args:      commandField(o.Props.Container.Command),
 		Image:     o.Image.ImageName(),
 		IsPublic:  fields.Bool(!o.Props.Private),
 
-		CloudSQLInstances:    fields.Sprintf(strings.Join(cloudSQLconnFmt, ","), cloudSQLconnNames...),
+		CloudSQLInstances:    cloudSQLInstancesField(c.Databases),
 		MinScale:             fields.Int(o.DeployOpts.MinScale),
 		MaxScale:             fields.Int(o.DeployOpts.MaxScale),
 		CPULimit:             fields.String(fmt.Sprintf("%dm", int(o.DeployOpts.CPULimit*1000))),
@@ -363,14 +500,14 @@ func (o *ServiceApp) Plan(ctx context.Context, pctx *config.PluginContext, r *re
 		ContainerConcurrency: fields.Int(o.DeployOpts.ContainerConcurrency),
 		TimeoutSeconds:       fields.Int(o.DeployOpts.Timeout),
 		Port:                 fields.Int(o.Props.Container.Port),
-		EnvVars:              fields.Map(envVars),
+		EnvVars:              envVars,
 		// Ingress
 		ExecutionEnvironment: fields.String(o.DeployOpts.ExecutionEnvironment),
 		CPUThrottling:        fields.Bool(*o.DeployOpts.CPUThrottling),
 		StartupCPUBoost:      fields.Bool(*o.DeployOpts.StartupCPUBoost),
 		ServiceAccountName:   fields.String(o.DeployOpts.ServiceAccountName),
-		EgressNetwork:        fields.String(o.DeployOpts.EgressNetwork),
-		EgressSubnet:         fields.String(o.DeployOpts.EgressSubnet),
+		EgressNetwork:        fields.String(egressNetwork),
+		EgressSubnet:         fields.String(egressSubnet),
 		EgressMode:           fields.String(o.DeployOpts.EgressMode),
 
 		LivenessProbeHTTPPath:            fields.String(o.Props.Container.LivenessProbe.HTTPPath),
@@ -390,19 +527,37 @@ func (o *ServiceApp) Plan(ctx context.Context, pctx *config.PluginContext, r *re
 		StartupProbeFailureThreshold:    fields.Int(o.Props.Container.StartupProbe.FailureThreshold),
 	}
 
-	_, err = r.RegisterAppResource(o.App, "cloud_run", o.CloudRun)
-	if err != nil {
-		return err
+	if o.DeployOpts.Traffic != nil {
+		cr.TrafficPercent = fields.Int(o.DeployOpts.Traffic.Percent)
+		cr.Rollout = o.DeployOpts.Traffic.CloudRunRollout()
 	}
 
-	if o.App.Url != "" {
-		schedulers, err := addCloudSchedulers(pctx, r, o.App, c.ProjectID, c.Region, o.Props.Scheduler)
-		if err != nil {
-			return err
-		}
+	if len(secretEnvVars) != 0 {
+		cr.SecretEnvVars = fields.Map(secretEnvVars)
+		cr.SecretAccessors = o.SecretAccessors
+	}
 
-		o.CloudSchedulerJobs = schedulers
+	return cr, nil
+}
+
+// resetCloudRunTraffic routes all traffic back to latest revision if traffic splitting is no longer configured.
+func resetCloudRunTraffic(cr *gcp.CloudRun, traffic *ServiceAppTrafficOptions) {
+	if traffic != nil {
+		return
 	}
 
-	return nil
+	if cur, ok := cr.TrafficPercent.LookupCurrent(); ok && cur != 100 {
+		cr.TrafficPercent.SetWanted(100)
+	}
+}
+
+// resetCloudRunSecretEnvVars removes secret references from service if secret env vars are no longer configured.
+func resetCloudRunSecretEnvVars(cr *gcp.CloudRun, secretEnvVars map[string]fields.Field) {
+	if len(secretEnvVars) != 0 {
+		return
+	}
+
+	if cur, ok := cr.SecretEnvVars.LookupCurrent(); ok && len(cur) != 0 {
+		cr.SecretEnvVars.SetWanted(map[string]any{})
+	}
 }
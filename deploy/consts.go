@@ -4,12 +4,17 @@ const (
 	APIName          = "api"
 	CommonName       = "common"
 	LoadBalancerName = "loadbalancer"
+	PubSubName       = "pubsub"
+	LogExportName    = "logexport"
 
 	AppTypeStatic   = "static"
 	AppTypeService  = "service"
 	AppTypeFunction = "function"
+	AppTypeJob      = "job"
 
 	DepTypePostgreSQL = "postgresql"
 	DepTypeMySQL      = "mysql"
 	DepTypeStorage    = "storage"
+	DepTypePubSub     = "pubsub"
+	DepTypeRedis      = "redis"
 )
@@ -0,0 +1,338 @@
+package deploy
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/creasty/defaults"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/outblocks/cli-plugin-gcp/gcp"
+	"github.com/outblocks/cli-plugin-gcp/internal/config"
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
+	"github.com/outblocks/outblocks-plugin-go/registry"
+	"github.com/outblocks/outblocks-plugin-go/registry/fields"
+	plugin_util "github.com/outblocks/outblocks-plugin-go/util"
+)
+
+const (
+	PubSubRolePublisher  = "publisher"
+	PubSubRoleSubscriber = "subscriber"
+)
+
+type PubSubDep struct {
+	Topic            *gcp.PubSubTopic
+	Subscriptions    map[string]*gcp.PubSubSubscription
+	DeadLetterTopics map[string]*gcp.PubSubTopic
+	IAMMembers       []*gcp.PubSubIAMMember
+
+	Dep   *apiv1.Dependency
+	Opts  *PubSubDepOptions
+	Needs map[*apiv1.App]*PubSubDepNeed
+}
+
+type PubSubDepArgs struct {
+	ProjectID     string
+	ProjectNumber int64
+	Needs         map[*apiv1.App]*PubSubDepNeed
+
+	// Topics shared with function triggers, keyed by name.
+	Topics map[string]*gcp.PubSubTopic
+}
+
+type PubSubDepOptions struct {
+	Topic            string                                   `json:"topic"`
+	MessageRetention int                                      `json:"message_retention"`
+	Subscriptions    map[string]*PubSubDepSubscriptionOptions `json:"subscriptions"`
+}
+
+type PubSubDepSubscriptionOptions struct {
+	AckDeadline        int                         `json:"ack_deadline" default:"10"`
+	MessageRetention   int                         `json:"message_retention" default:"604800"`
+	Filter             string                      `json:"filter"`
+	PushEndpoint       string                      `json:"push_endpoint"`
+	PushServiceAccount string                      `json:"push_service_account"`
+	DeadLetter         *PubSubDepDeadLetterOptions `json:"dead_letter"`
+}
+
+type PubSubDepDeadLetterOptions struct {
+	Topic               string `json:"topic"`
+	MaxDeliveryAttempts int    `json:"max_delivery_attempts" default:"5"`
+}
+
+type PubSubDepNeed struct {
+	Role         string `json:"role"`         // options: publisher, subscriber, defaults to both
+	Subscription string `json:"subscription"` // subscription exposed in env vars, defaults to first one
+}
+
+func (o *PubSubDepOptions) Validate() error {
+	return validation.ValidateStruct(o,
+		validation.Field(&o.MessageRetention, validation.When(o.MessageRetention != 0, validation.Min(600), validation.Max(2678400))),
+		validation.Field(&o.Subscriptions),
+	)
+}
+
+func (o *PubSubDepSubscriptionOptions) Validate() error {
+	return validation.ValidateStruct(o,
+		validation.Field(&o.AckDeadline, validation.Min(10), validation.Max(600)),
+		validation.Field(&o.MessageRetention, validation.Min(600), validation.Max(604800)),
+		validation.Field(&o.PushServiceAccount, validation.When(o.PushEndpoint == "", validation.Empty.Error("requires push_endpoint"))),
+		validation.Field(&o.DeadLetter),
+	)
+}
+
+func (o *PubSubDepDeadLetterOptions) Validate() error {
+	return validation.ValidateStruct(o,
+		validation.Field(&o.MaxDeliveryAttempts, validation.Min(5), validation.Max(100)),
+	)
+}
+
+func NewPubSubDepOptions(in map[string]any) (*PubSubDepOptions, error) {
+	o := &PubSubDepOptions{}
+
+	err := plugin_util.MapstructureJSONDecode(in, o)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding pubsub dependency options: %w", err)
+	}
+
+	err = defaults.Set(o)
+	if err != nil {
+		return nil, err
+	}
+
+	return o, o.Validate()
+}
+
+func NewPubSubDepNeed(in map[string]any) (*PubSubDepNeed, error) {
+	o := &PubSubDepNeed{}
+
+	err := plugin_util.MapstructureJSONDecode(in, o)
+	if err != nil {
+		return nil, err
+	}
+
+	return o, validation.ValidateStruct(o,
+		validation.Field(&o.Role, validation.In(PubSubRolePublisher, PubSubRoleSubscriber)),
+	)
+}
+
+func NewPubSubDep(dep *apiv1.Dependency) (*PubSubDep, error) {
+	opts, err := NewPubSubDepOptions(dep.Properties.AsMap())
+	if err != nil {
+		return nil, err
+	}
+
+	return &PubSubDep{
+		Subscriptions:    make(map[string]*gcp.PubSubSubscription),
+		DeadLetterTopics: make(map[string]*gcp.PubSubTopic),
+		Dep:              dep,
+		Opts:             opts,
+	}, nil
+}
+
+// SubscriptionNames returns sorted names of subscriptions as defined in options.
+func (o *PubSubDep) SubscriptionNames() []string {
+	names := make([]string, 0, len(o.Opts.Subscriptions))
+
+	for name := range o.Opts.Subscriptions {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// NeedSubscription returns subscription exposed to app, nil if there are none.
+func (o *PubSubDep) NeedSubscription(app *apiv1.App) *gcp.PubSubSubscription {
+	if need := o.Needs[app]; need != nil && need.Subscription != "" {
+		return o.Subscriptions[need.Subscription]
+	}
+
+	names := o.SubscriptionNames()
+	if len(names) == 0 {
+		return nil
+	}
+
+	return o.Subscriptions[names[0]]
+}
+
+func (o *PubSubDep) addTopic(r *registry.Registry, c *PubSubDepArgs, resourceID, name string, retention int) (*gcp.PubSubTopic, error) {
+	if _, ok := c.Topics[name]; ok {
+		return nil, fmt.Errorf("dependency '%s' topic '%s' is already defined elsewhere", o.Dep.Name, name)
+	}
+
+	topic := &gcp.PubSubTopic{
+		Name:             fields.String(name),
+		ProjectID:        fields.String(c.ProjectID),
+		MessageRetention: fields.Int(retention),
+	}
+
+	_, err := r.RegisterDependencyResource(o.Dep, resourceID, topic)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Topics[name] = topic
+
+	return topic, nil
+}
+
+func (o *PubSubDep) addIAMMember(r *registry.Registry, resourceID string, resource fields.StringInputField, role, member string) error {
+	m := &gcp.PubSubIAMMember{
+		Resource: resource,
+		Role:     fields.String(role),
+		Member:   fields.String(member),
+	}
+
+	_, err := r.RegisterDependencyResource(o.Dep, resourceID, m)
+	if err != nil {
+		return err
+	}
+
+	o.IAMMembers = append(o.IAMMembers, m)
+
+	return nil
+}
+
+func (o *PubSubDep) Plan(pctx *config.PluginContext, r *registry.Registry, c *PubSubDepArgs) error {
+	o.Needs = c.Needs
+
+	for app, need := range c.Needs {
+		if need.Subscription != "" && o.Opts.Subscriptions[need.Subscription] == nil {
+			return fmt.Errorf("app '%s' needs unknown subscription '%s' of dependency '%s'", app.Name, need.Subscription, o.Dep.Name)
+		}
+	}
+
+	topicName := o.Opts.Topic
+	if topicName == "" {
+		topicName = gcp.ID(pctx.Env(), o.Dep.Id)
+	}
+
+	var err error
+
+	o.Topic, err = o.addTopic(r, c, "topic", topicName, o.Opts.MessageRetention)
+	if err != nil {
+		return err
+	}
+
+	topicID := fields.Sprintf("projects/%s/topics/%s", o.Topic.ProjectID, o.Topic.Name)
+	serviceAgent := fmt.Sprintf("serviceAccount:service-%d@gcp-sa-pubsub.iam.gserviceaccount.com", c.ProjectNumber)
+
+	for _, name := range o.SubscriptionNames() {
+		opts := o.Opts.Subscriptions[name]
+		subName := fmt.Sprintf("%s-%s", topicName, name)
+
+		sub := &gcp.PubSubSubscription{
+			Name:               fields.String(subName),
+			ProjectID:          fields.String(c.ProjectID),
+			Topic:              o.Topic.Name,
+			Filter:             fields.String(opts.Filter),
+			AckDeadline:        fields.Int(opts.AckDeadline),
+			MessageRetention:   fields.Int(opts.MessageRetention),
+			PushEndpoint:       fields.String(opts.PushEndpoint),
+			PushServiceAccount: fields.String(opts.PushServiceAccount),
+			DeadLetterTopic:    fields.String(""),
+		}
+
+		if dl := opts.DeadLetter; dl != nil {
+			dlName := dl.Topic
+			if dlName == "" {
+				dlName = subName + "-dead-letter"
+			}
+
+			dlTopic, err := o.addTopic(r, c, "dead_letter_topic_"+name, dlName, 0)
+			if err != nil {
+				return err
+			}
+
+			o.DeadLetterTopics[name] = dlTopic
+			sub.DeadLetterTopic = dlTopic.Name
+			sub.MaxDeliveryAttempts = fields.Int(dl.MaxDeliveryAttempts)
+		}
+
+		_, err = r.RegisterDependencyResource(o.Dep, "subscription_"+name, sub)
+		if err != nil {
+			return err
+		}
+
+		o.Subscriptions[name] = sub
+
+		if dlTopic := o.DeadLetterTopics[name]; dlTopic != nil {
+			// Pub/Sub service agent has to be able to forward undeliverable messages.
+			err = o.addIAMMember(r, "dead_letter_publisher_"+name, fields.Sprintf("projects/%s/topics/%s", dlTopic.ProjectID, dlTopic.Name), "roles/pubsub.publisher", serviceAgent)
+			if err != nil {
+				return err
+			}
+
+			err = o.addIAMMember(r, "dead_letter_subscriber_"+name, fields.Sprintf("projects/%s/subscriptions/%s", sub.ProjectID, sub.Name), "roles/pubsub.subscriber", serviceAgent)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return o.planNeedsIAM(r, c, topicID)
+}
+
+// planNeedsIAM grants publisher and subscriber roles to service accounts of apps needing dependency.
+func (o *PubSubDep) planNeedsIAM(r *registry.Registry, c *PubSubDepArgs, topicID fields.StringInputField) error {
+	publishers := make(map[string]struct{})
+	subscribers := make(map[string]map[string]struct{})
+
+	for app, need := range c.Needs {
+		member := "serviceAccount:" + appServiceAccount(app, c.ProjectID, c.ProjectNumber)
+
+		if need.Role != PubSubRoleSubscriber {
+			publishers[member] = struct{}{}
+		}
+
+		if need.Role == PubSubRolePublisher {
+			continue
+		}
+
+		subs := o.SubscriptionNames()
+		if need.Subscription != "" {
+			subs = []string{need.Subscription}
+		}
+
+		for _, name := range subs {
+			if subscribers[name] == nil {
+				subscribers[name] = make(map[string]struct{})
+			}
+
+			subscribers[name][member] = struct{}{}
+		}
+	}
+
+	for _, member := range sortedKeys(publishers) {
+		err := o.addIAMMember(r, "publisher_"+member, topicID, "roles/pubsub.publisher", member)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, name := range o.SubscriptionNames() {
+		sub := o.Subscriptions[name]
+
+		for _, member := range sortedKeys(subscribers[name]) {
+			err := o.addIAMMember(r, fmt.Sprintf("subscriber_%s_%s", name, member), fields.Sprintf("projects/%s/subscriptions/%s", sub.ProjectID, sub.Name), "roles/pubsub.subscriber", member)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	ret := make([]string, 0, len(m))
+
+	for k := range m {
+		ret = append(ret, k)
+	}
+
+	sort.Strings(ret)
+
+	return ret
+}
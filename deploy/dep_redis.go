@@ -0,0 +1,125 @@
+package deploy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/creasty/defaults"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/outblocks/cli-plugin-gcp/gcp"
+	"github.com/outblocks/cli-plugin-gcp/internal/config"
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
+	"github.com/outblocks/outblocks-plugin-go/registry"
+	"github.com/outblocks/outblocks-plugin-go/registry/fields"
+	plugin_util "github.com/outblocks/outblocks-plugin-go/util"
+)
+
+type RedisDep struct {
+	Instance *gcp.RedisInstance
+
+	Dep   *apiv1.Dependency
+	Opts  *RedisDepOptions
+	Needs map[*apiv1.App]*RedisDepNeed
+}
+
+type RedisDepArgs struct {
+	ProjectID string
+	Region    string
+	Needs     map[*apiv1.App]*RedisDepNeed
+}
+
+type RedisDepNeed struct{}
+
+type RedisDepOptions struct {
+	Tier       string `json:"tier" default:"basic"` // options: basic, standard_ha
+	MemorySize int    `json:"memory_size" default:"1"`
+	Version    string `json:"version" default:"7.2"`
+
+	// VPC network that instance is reachable from, services needing it use direct VPC egress through subnet.
+	Network string `json:"network" default:"default"`
+	Subnet  string `json:"subnet" default:"default"`
+}
+
+func (o *RedisDepOptions) Validate() error {
+	return validation.ValidateStruct(o,
+		validation.Field(&o.Tier, validation.In("basic", "standard_ha")),
+		validation.Field(&o.MemorySize, validation.Min(1), validation.Max(300)),
+		validation.Field(&o.Version, validation.In("6.x", "7.0", "7.2")),
+	)
+}
+
+// RedisVersion returns version in format used by Memorystore API, e.g. "REDIS_7_2".
+func (o *RedisDepOptions) RedisVersion() string {
+	return "REDIS_" + strings.ToUpper(strings.ReplaceAll(o.Version, ".", "_"))
+}
+
+func NewRedisDepOptions(in map[string]any) (*RedisDepOptions, error) {
+	o := &RedisDepOptions{}
+
+	err := plugin_util.MapstructureJSONDecode(in, o)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding redis dependency options: %w", err)
+	}
+
+	err = defaults.Set(o)
+	if err != nil {
+		return nil, err
+	}
+
+	return o, o.Validate()
+}
+
+func NewRedisDepNeed(in map[string]any) (*RedisDepNeed, error) {
+	o := &RedisDepNeed{}
+
+	return o, plugin_util.MapstructureJSONDecode(in, o)
+}
+
+func NewRedisDep(dep *apiv1.Dependency) (*RedisDep, error) {
+	opts, err := NewRedisDepOptions(dep.Properties.AsMap())
+	if err != nil {
+		return nil, err
+	}
+
+	return &RedisDep{
+		Dep:  dep,
+		Opts: opts,
+	}, nil
+}
+
+func (o *RedisDep) Plan(pctx *config.PluginContext, r *registry.Registry, c *RedisDepArgs) error {
+	o.Needs = c.Needs
+
+	o.Instance = &gcp.RedisInstance{
+		Name:              fields.String(gcp.ID(pctx.Env(), o.Dep.Id)),
+		ProjectID:         fields.String(c.ProjectID),
+		Region:            fields.String(c.Region),
+		Tier:              fields.String(strings.ToUpper(o.Opts.Tier)),
+		AuthorizedNetwork: fields.String(o.Opts.Network),
+		MemorySizeGB:      fields.Int(o.Opts.MemorySize),
+		RedisVersion:      fields.String(o.Opts.RedisVersion()),
+	}
+
+	_, err := r.RegisterDependencyResource(o.Dep, "redis", o.Instance)
+
+	return err
+}
+
+// redisEgress returns VPC network and subnet to use for direct VPC egress, preferring values set explicitly on app.
+// All redis dependencies needed by app have to be reachable through the same network and subnet.
+func redisEgress(app *apiv1.App, network, subnet string, deps []*RedisDep) (egressNetwork, egressSubnet string, err error) {
+	if network != "" || len(deps) == 0 {
+		return network, subnet, nil
+	}
+
+	first := deps[0]
+
+	for _, dep := range deps[1:] {
+		if dep.Opts.Network != first.Opts.Network || dep.Opts.Subnet != first.Opts.Subnet {
+			return "", "", fmt.Errorf("app '%s' needs redis dependencies '%s' and '%s' that use different networks or subnets, set egress network and subnet of app explicitly",
+				app.Name, first.Dep.Name, dep.Dep.Name)
+		}
+	}
+
+	return first.Opts.Network, first.Opts.Subnet, nil
+}
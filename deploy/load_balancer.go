@@ -61,7 +61,7 @@ func (o *LoadBalancer) createCloudFunctionServerlessNEG(pctx *config.PluginConte
 	}
 }
 
-func (o *LoadBalancer) addServerlessNEG(pctx *config.PluginContext, r *registry.Registry, app *apiv1.App, neg *gcp.ServerlessNEG, cdnEnabled bool, c *LoadBalancerArgs) error {
+func (o *LoadBalancer) addServerlessNEG(pctx *config.PluginContext, r *registry.Registry, app *apiv1.App, neg *gcp.ServerlessNEG, regionalNEGs []*gcp.ServerlessNEG, cdnEnabled bool, c *LoadBalancerArgs) error {
 	_, err := r.RegisterPluginResource(LoadBalancerName, app.Id, neg)
 	if err != nil {
 		return err
@@ -69,6 +69,18 @@ func (o *LoadBalancer) addServerlessNEG(pctx *config.PluginContext, r *registry.
 
 	o.ServerlessNEGs = append(o.ServerlessNEGs, neg)
 
+	additionalNEGs := make([]fields.Field, len(regionalNEGs))
+
+	for i, regionalNEG := range regionalNEGs {
+		_, err = r.RegisterPluginResource(LoadBalancerName, app.Id+"_"+regionalNEG.Region.Wanted(), regionalNEG)
+		if err != nil {
+			return err
+		}
+
+		o.ServerlessNEGs = append(o.ServerlessNEGs, regionalNEG)
+		additionalNEGs[i] = regionalNEG.RefField()
+	}
+
 	// Backend Services.
 	svc := &gcp.BackendService{
 		Name:      gcp.IDField(pctx.Env(), app.Id),
@@ -76,6 +88,10 @@ func (o *LoadBalancer) addServerlessNEG(pctx *config.PluginContext, r *registry.
 		NEG:       neg.RefField(),
 	}
 
+	if len(additionalNEGs) > 0 {
+		svc.AdditionalNEGs = fields.Array(additionalNEGs)
+	}
+
 	svc.CDN.Enabled = fields.Bool(cdnEnabled)
 
 	_, err = r.RegisterPluginResource(LoadBalancerName, app.Id, svc)
@@ -83,6 +99,11 @@ func (o *LoadBalancer) addServerlessNEG(pctx *config.PluginContext, r *registry.
 		return err
 	}
 
+	// Detach NEGs of regions that are no longer used.
+	if cur, ok := svc.AdditionalNEGs.LookupCurrent(); ok && len(cur) > 0 && len(additionalNEGs) == 0 {
+		svc.AdditionalNEGs.SetWanted([]any{})
+	}
+
 	o.BackendServices = append(o.BackendServices, svc)
 
 	// URL Mapping.
@@ -97,16 +118,27 @@ func (o *LoadBalancer) addServerlessNEG(pctx *config.PluginContext, r *registry.
 	return nil
 }
 
-func (o *LoadBalancer) addCloudRun(pctx *config.PluginContext, r *registry.Registry, app *apiv1.App, cloudrun fields.StringInputField, cdnEnabled bool, c *LoadBalancerArgs) error {
+func (o *LoadBalancer) addCloudRun(pctx *config.PluginContext, r *registry.Registry, app *apiv1.App, cloudrun fields.StringInputField, regionalCloudRuns []*gcp.CloudRun, cdnEnabled bool, c *LoadBalancerArgs) error {
 	neg := o.createCloudRunServerlessNEG(pctx, app.Id, cloudrun, c)
 
-	return o.addServerlessNEG(pctx, r, app, neg, cdnEnabled, c)
+	regionalNEGs := make([]*gcp.ServerlessNEG, len(regionalCloudRuns))
+
+	for i, cr := range regionalCloudRuns {
+		regionalNEGs[i] = &gcp.ServerlessNEG{
+			Name:      gcp.IDField(pctx.Env(), app.Id),
+			ProjectID: fields.String(c.ProjectID),
+			Region:    fields.String(cr.Region.Wanted()),
+			CloudRun:  cr.Name,
+		}
+	}
+
+	return o.addServerlessNEG(pctx, r, app, neg, regionalNEGs, cdnEnabled, c)
 }
 
 func (o *LoadBalancer) addCloudFunction(pctx *config.PluginContext, r *registry.Registry, app *apiv1.App, cloudfunction fields.StringInputField, cdnEnabled bool, c *LoadBalancerArgs) error {
 	neg := o.createCloudFunctionServerlessNEG(pctx, app.Id, cloudfunction, c)
 
-	return o.addServerlessNEG(pctx, r, app, neg, cdnEnabled, c)
+	return o.addServerlessNEG(pctx, r, app, neg, nil, cdnEnabled, c)
 }
 
 func (o *LoadBalancer) processServiceApps(pctx *config.PluginContext, r *registry.Registry, service map[string]*ServiceApp, c *LoadBalancerArgs) error {
@@ -115,7 +147,7 @@ func (o *LoadBalancer) processServiceApps(pctx *config.PluginContext, r *registr
 			continue
 		}
 
-		err := o.addCloudRun(pctx, r, app.App, app.CloudRun.Name, app.Props.CDN.Enabled, c)
+		err := o.addCloudRun(pctx, r, app.App, app.CloudRun.Name, app.RegionalCloudRuns, app.Props.CDN.Enabled, c)
 		if err != nil {
 			return err
 		}
@@ -130,7 +162,7 @@ func (o *LoadBalancer) processStaticApps(pctx *config.PluginContext, r *registry
 			continue
 		}
 
-		err := o.addCloudRun(pctx, r, app.App, app.CloudRun.Name, app.Props.CDN.Enabled, c)
+		err := o.addCloudRun(pctx, r, app.App, app.CloudRun.Name, nil, app.Props.CDN.Enabled, c)
 		if err != nil {
 			return err
 		}
@@ -141,11 +173,12 @@ func (o *LoadBalancer) processStaticApps(pctx *config.PluginContext, r *registry
 
 func (o *LoadBalancer) processFunctionApps(pctx *config.PluginContext, r *registry.Registry, function map[string]*FunctionApp, c *LoadBalancerArgs) error {
 	for _, app := range function {
-		if app.App.Url == "" || (app.Skip && !app.CloudFunction.IsExisting()) {
+		if app.App.Url == "" || (app.Skip && !app.IsExisting()) {
 			continue
 		}
 
-		err := o.addCloudFunction(pctx, r, app.App, app.CloudFunction.Name, app.Props.CDN.Enabled, c)
+		// Serverless NEG targets function by its name, so it is the same for both generations.
+		err := o.addCloudFunction(pctx, r, app.App, app.CloudFunctionName(), app.Props.CDN.Enabled, c)
 		if err != nil {
 			return err
 		}
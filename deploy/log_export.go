@@ -0,0 +1,176 @@
+package deploy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/creasty/defaults"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/outblocks/cli-plugin-gcp/gcp"
+	"github.com/outblocks/cli-plugin-gcp/internal/config"
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
+	"github.com/outblocks/outblocks-plugin-go/registry"
+	"github.com/outblocks/outblocks-plugin-go/registry/fields"
+	plugin_util "github.com/outblocks/outblocks-plugin-go/util"
+)
+
+const (
+	LogExportDestinationStorage  = "storage"
+	LogExportDestinationBigQuery = "bigquery"
+)
+
+// LogExport routes logs of apps and dependencies to a GCS bucket or BigQuery dataset kept for configured retention.
+type LogExport struct {
+	Bucket  *gcp.Bucket
+	Dataset *gcp.BigQueryDataset
+	Sink    *gcp.LogSink
+
+	Opts *LogExportOptions
+}
+
+type LogExportArgs struct {
+	ProjectID string
+	Region    string
+}
+
+// LogExportOptions are read from `log_export` plugin property, log export is disabled when it is not set.
+type LogExportOptions struct {
+	Destination     string   `json:"destination" default:"storage"` // options: storage, bigquery
+	Name            string   `json:"name"`                          // bucket or dataset name, generated if empty
+	Location        string   `json:"location"`                      // defaults to region
+	RetentionDays   int      `json:"retention_days" default:"365"`  // bucket retention policy or dataset default table expiration
+	RetentionLocked bool     `json:"retention_locked"`              // locks bucket retention policy, it cannot be shortened or removed afterwards
+	Sources         []string `json:"sources"`                       // log sources as in logs command, defaults to apps and databases
+	Filter          string   `json:"filter"`                        // additional filter that exported log entries have to match
+
+	sources map[string]bool
+}
+
+func (o *LogExportOptions) Validate() error {
+	return validation.ValidateStruct(o,
+		validation.Field(&o.Destination, validation.In(LogExportDestinationStorage, LogExportDestinationBigQuery)),
+		validation.Field(&o.RetentionDays, validation.Min(1)),
+		validation.Field(&o.RetentionLocked, validation.When(o.Destination == LogExportDestinationBigQuery,
+			validation.Empty.Error("retention can only be locked for storage destination"))),
+	)
+}
+
+// NewLogExportOptions decodes log export plugin properties, returns nil if log export is not configured.
+func NewLogExportOptions(in map[string]any) (*LogExportOptions, error) {
+	if in == nil {
+		return nil, nil
+	}
+
+	o := &LogExportOptions{}
+
+	err := plugin_util.MapstructureJSONDecode(in, o)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding log export options: %w", err)
+	}
+
+	err = defaults.Set(o)
+	if err != nil {
+		return nil, err
+	}
+
+	o.Destination = strings.ToLower(o.Destination)
+
+	err = o.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid log export options: %w", err)
+	}
+
+	o.sources, err = ParseLogSources(o.Sources)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log export options: %w", err)
+	}
+
+	// Builds are not known upfront so they cannot be part of sink filter.
+	for _, s := range o.Sources {
+		if strings.EqualFold(strings.TrimSpace(s), LogSourceBuild) {
+			return nil, fmt.Errorf("invalid log export options: '%s' log source cannot be exported", LogSourceBuild)
+		}
+	}
+
+	delete(o.sources, LogSourceBuild)
+
+	return o, nil
+}
+
+func NewLogExport(opts *LogExportOptions) *LogExport {
+	return &LogExport{
+		Opts: opts,
+	}
+}
+
+func (o *LogExport) Plan(pctx *config.PluginContext, r *registry.Registry, apps []*apiv1.App, deps []*apiv1.Dependency, c *LogExportArgs) error {
+	location := o.Opts.Location
+	if location == "" {
+		location = c.Region
+	}
+
+	var destination fields.StringInputField
+
+	switch o.Opts.Destination {
+	case LogExportDestinationBigQuery:
+		name := o.Opts.Name
+		if name == "" {
+			name = strings.ReplaceAll(gcp.ID(pctx.Env(), LogExportName), "-", "_")
+		}
+
+		o.Dataset = &gcp.BigQueryDataset{
+			Name:                fields.String(name),
+			ProjectID:           fields.String(c.ProjectID),
+			Location:            fields.String(location),
+			TableExpirationDays: fields.Int(o.Opts.RetentionDays),
+			Critical:            true,
+		}
+
+		_, err := r.RegisterPluginResource(LogExportName, "dataset", o.Dataset)
+		if err != nil {
+			return err
+		}
+
+		destination = fields.Sprintf("%sprojects/%s/datasets/%s", gcp.LogSinkBigQueryDestinationPrefix, o.Dataset.ProjectID, o.Dataset.Name)
+	default:
+		name := o.Opts.Name
+		if name == "" {
+			name = gcp.GlobalID(pctx.Env(), c.ProjectID, LogExportName)
+		}
+
+		o.Bucket = &gcp.Bucket{
+			Name:            fields.String(name),
+			Location:        fields.String(location),
+			ProjectID:       fields.String(c.ProjectID),
+			DeleteInDays:    fields.Int(o.Opts.RetentionDays),
+			RetentionDays:   fields.Int(o.Opts.RetentionDays),
+			RetentionLocked: fields.Bool(o.Opts.RetentionLocked),
+			Critical:        true,
+		}
+
+		_, err := r.RegisterPluginResource(LogExportName, "bucket", o.Bucket)
+		if err != nil {
+			return err
+		}
+
+		destination = fields.Sprintf("%s%s", gcp.LogSinkStorageDestinationPrefix, o.Bucket.Name)
+	}
+
+	filter := NewLogResourceFilter(pctx.Env(), c.ProjectID, r, apps, deps, o.Opts.sources)
+
+	// Sink without resource filter would export logs of whole project.
+	if filter.IsEmpty() {
+		return nil
+	}
+
+	o.Sink = &gcp.LogSink{
+		Name:        gcp.IDField(pctx.Env(), LogExportName),
+		ProjectID:   fields.String(c.ProjectID),
+		Destination: destination,
+		Filter:      filter.Field(o.Opts.Filter),
+	}
+
+	_, err := r.RegisterPluginResource(LogExportName, "sink", o.Sink)
+
+	return err
+}
@@ -0,0 +1,232 @@
+package deploy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/outblocks/cli-plugin-gcp/gcp"
+	"github.com/outblocks/outblocks-plugin-go/env"
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
+	"github.com/outblocks/outblocks-plugin-go/registry"
+	"github.com/outblocks/outblocks-plugin-go/registry/fields"
+)
+
+// Log sources of apps and dependencies.
+const (
+	LogSourceApps         = "apps"
+	LogSourceDatabases    = "databases"
+	LogSourceLoadBalancer = "load_balancer"
+	LogSourceScheduler    = "scheduler"
+	LogSourceBuild        = "build"
+	LogSourceAll          = "all"
+)
+
+var (
+	LogSourcesAll     = []string{LogSourceApps, LogSourceDatabases, LogSourceLoadBalancer, LogSourceScheduler, LogSourceBuild}
+	LogSourcesDefault = []string{LogSourceApps, LogSourceDatabases}
+)
+
+// ParseLogSources validates selected log sources, expanding 'all'. Default sources are used if none are selected.
+func ParseLogSources(in []string) (map[string]bool, error) {
+	var selected []string
+
+	for _, s := range in {
+		s = strings.ToLower(strings.TrimSpace(s))
+
+		switch s {
+		case LogSourceAll:
+			selected = append(selected, LogSourcesAll...)
+		case LogSourceApps, LogSourceDatabases, LogSourceLoadBalancer, LogSourceScheduler, LogSourceBuild:
+			selected = append(selected, s)
+		default:
+			return nil, fmt.Errorf("unknown log source '%s', supported sources: %s, %s", s, strings.Join(LogSourcesAll, ", "), LogSourceAll)
+		}
+	}
+
+	if len(selected) == 0 {
+		selected = LogSourcesDefault
+	}
+
+	ret := make(map[string]bool, len(selected))
+
+	for _, s := range selected {
+		ret[s] = true
+	}
+
+	return ret, nil
+}
+
+// LogResourceFilter is Cloud Logging filter matching resources of apps and dependencies.
+// Resource names are fields so that filter can be planned before resources with random names are created.
+type LogResourceFilter struct {
+	// IDMap maps resource names to app and dependency IDs.
+	IDMap map[string]string
+
+	parts []string
+	args  []any
+}
+
+// NewLogResourceFilter creates filter of selected sources for apps and dependencies with resources in registry.
+// Build logs are not included as build IDs have to be fetched from API.
+func NewLogResourceFilter(e env.Enver, projectID string, reg *registry.Registry, apps []*apiv1.App, deps []*apiv1.Dependency, sources map[string]bool) *LogResourceFilter {
+	f := &LogResourceFilter{
+		IDMap: make(map[string]string),
+	}
+
+	var (
+		cloudRunNames, cloudFunctionNames, cloudRunJobNames, backendNames []fields.Field
+		cloudSQLNames, schedulerJobNames, urlMapNames                     []fields.Field
+	)
+
+	for _, app := range apps {
+		gcpID := gcp.ID(e, app.Id)
+		f.IDMap[gcpID] = app.Id
+
+		if sources[LogSourceApps] {
+			switch app.Type {
+			case AppTypeFunction:
+				// 2nd gen functions log as Cloud Run services of the same name.
+				cloudFunctionNames = append(cloudFunctionNames, fields.String(gcpID))
+				cloudRunNames = append(cloudRunNames, fields.String(gcpID))
+			case AppTypeJob:
+				cloudRunJobNames = append(cloudRunJobNames, fields.String(gcpID))
+			default:
+				cloudRunNames = append(cloudRunNames, fields.String(gcpID))
+			}
+		}
+
+		if sources[LogSourceLoadBalancer] && app.Type != AppTypeJob {
+			// Backend services are named after apps.
+			backendNames = append(backendNames, fields.String(gcpID))
+		}
+
+		if sources[LogSourceScheduler] {
+			for i := 1; ; i++ {
+				job := &gcp.CloudSchedulerJob{}
+
+				if !reg.GetAppResource(app, fmt.Sprintf("cloud_scheduler_job_%d", i), job) {
+					break
+				}
+
+				schedulerJobNames = append(schedulerJobNames, job.Name)
+				f.IDMap[job.Name.Any()] = app.Id
+			}
+		}
+	}
+
+	if sources[LogSourceDatabases] {
+		for _, dep := range deps {
+			switch dep.Type {
+			case DepTypePostgreSQL, DepTypeMySQL:
+				db := &gcp.CloudSQL{}
+
+				if reg.GetDependencyResource(dep, "cloud_sql", db) {
+					name := fields.Sprintf("%s:%s", projectID, db.Name)
+					cloudSQLNames = append(cloudSQLNames, name)
+					f.IDMap[name.Any()] = dep.Id
+				}
+			}
+		}
+	}
+
+	if sources[LogSourceLoadBalancer] {
+		for _, id := range []string{"load_balancer-https-0", "load_balancer-http-0"} {
+			urlMap := &gcp.URLMap{}
+
+			if reg.GetPluginResource(LoadBalancerName, id, urlMap) {
+				urlMapNames = append(urlMapNames, urlMap.Name)
+				f.IDMap[urlMap.Name.Any()] = LoadBalancerName
+			}
+		}
+	}
+
+	f.add("cloud_run_revision", "service_name", cloudRunNames)
+	f.add("cloud_function", "function_name", cloudFunctionNames)
+	f.add("cloud_run_job", "job_name", cloudRunJobNames)
+	f.add("cloudsql_database", "database_id", cloudSQLNames)
+
+	if len(backendNames) > 0 {
+		// Requests rejected before reaching a backend (e.g. unmatched host or Cloud Armor denials) have no backend service set.
+		lbFilter := fmt.Sprintf(`resource.labels.backend_service_name = (%s)`, logFilterValues(len(backendNames))) //nolint:gocritic
+		args := append([]any(nil), fieldArgs(backendNames)...)
+
+		if len(urlMapNames) > 0 {
+			lbFilter = fmt.Sprintf(`(%s OR (resource.labels.url_map_name = (%s) resource.labels.backend_service_name = ""))`, lbFilter, logFilterValues(len(urlMapNames))) //nolint:gocritic
+			args = append(args, fieldArgs(urlMapNames)...)
+		}
+
+		f.parts = append(f.parts, fmt.Sprintf(`(resource.type = "http_load_balancer" %s)`, lbFilter))
+		f.args = append(f.args, args...)
+	}
+
+	f.add("cloud_scheduler_job", "job_id", schedulerJobNames)
+
+	return f
+}
+
+func logFilterValues(n int) string {
+	return strings.TrimSuffix(strings.Repeat(`"%s" OR `, n), " OR ")
+}
+
+func fieldArgs(in []fields.Field) []any {
+	ret := make([]any, len(in))
+
+	for i, v := range in {
+		ret[i] = v
+	}
+
+	return ret
+}
+
+func (f *LogResourceFilter) add(resourceType, label string, names []fields.Field) {
+	if len(names) == 0 {
+		return
+	}
+
+	f.parts = append(f.parts, fmt.Sprintf(`(resource.type = %q resource.labels.%s = (%s))`, resourceType, label, logFilterValues(len(names))))
+	f.args = append(f.args, fieldArgs(names)...)
+}
+
+// AddResources adds resources of given type, with names mapped to app or dependency IDs, to filter.
+func (f *LogResourceFilter) AddResources(resourceType, label string, names map[string]string) {
+	keys := make([]string, 0, len(names))
+
+	for k, id := range names {
+		keys = append(keys, k)
+		f.IDMap[k] = id
+	}
+
+	sort.Strings(keys)
+
+	values := make([]fields.Field, len(keys))
+
+	for i, k := range keys {
+		values[i] = fields.String(k)
+	}
+
+	f.add(resourceType, label, values)
+}
+
+// IsEmpty returns true if filter matches no resources.
+func (f *LogResourceFilter) IsEmpty() bool {
+	return len(f.parts) == 0
+}
+
+// Field returns filter as a field, resolved once names of all resources are known.
+// Non-empty additional filters are ANDed with resource filter.
+func (f *LogResourceFilter) Field(and ...string) fields.StringInputField {
+	format := "(" + strings.Join(f.parts, " OR ") + ")"
+	args := append([]any(nil), f.args...)
+
+	for _, a := range and {
+		if a == "" {
+			continue
+		}
+
+		format += " (%s)"
+		args = append(args, a)
+	}
+
+	return fields.Sprintf(format, args...)
+}
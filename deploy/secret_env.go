@@ -0,0 +1,231 @@
+package deploy
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"maps"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/outblocks/cli-plugin-gcp/gcp"
+	"github.com/outblocks/cli-plugin-gcp/internal/config"
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
+	"github.com/outblocks/outblocks-plugin-go/registry"
+	"github.com/outblocks/outblocks-plugin-go/registry/fields"
+	plugin_util "github.com/outblocks/outblocks-plugin-go/util"
+	"google.golang.org/api/secretmanager/v1"
+)
+
+// minDetectedSecretLength is minimum length of secret value that env vars are matched against,
+// shorter ones would match unrelated values such as "true" or "1" too often.
+const minDetectedSecretLength = 8
+
+// SecretValue is latest value of an Outblocks secret kept in Secret Manager.
+type SecretValue struct {
+	Key     string
+	Version string
+	Value   string
+}
+
+// LatestSecretValues returns latest values of Outblocks secrets sorted by key, secrets without enabled version are skipped.
+func LatestSecretValues(ctx context.Context, pctx *config.PluginContext, projectID string) ([]*SecretValue, error) {
+	cli, err := pctx.GCPSecretManagerClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := gcp.SecretNamePrefix(pctx.Env())
+
+	var ret []*SecretValue
+
+	err = cli.Projects.Secrets.List(fmt.Sprintf("projects/%s", projectID)).Filter(prefix).Pages(ctx, func(resp *secretmanager.ListSecretsResponse) error {
+		for _, s := range resp.Secrets {
+			name := s.Name[strings.LastIndex(s.Name, "/")+1:]
+			if !strings.HasPrefix(name, prefix) {
+				continue
+			}
+
+			ver, err := cli.Projects.Secrets.Versions.Access(s.Name + "/versions/latest").Context(ctx).Do()
+			if gcp.ErrIs404(err) || gcp.ErrIs400(err) {
+				continue
+			}
+
+			if err != nil {
+				return fmt.Errorf("error accessing secret '%s' value: %w", name, err)
+			}
+
+			data, err := base64.StdEncoding.DecodeString(ver.Payload.Data)
+			if err != nil {
+				return fmt.Errorf("error decoding secret '%s' value: %w", name, err)
+			}
+
+			ret = append(ret, &SecretValue{
+				Key:     strings.TrimPrefix(name, prefix),
+				Version: ver.Name[strings.LastIndex(ver.Name, "/")+1:],
+				Value:   string(data),
+			})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing secrets: %w", err)
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Key < ret[j].Key
+	})
+
+	return ret, nil
+}
+
+// detectSecretEnv returns secret env vars extended with plain env vars whose whole value equals latest value of an Outblocks secret,
+// e.g. expanded from it before being passed to plugin, so that they are passed as references instead of plain text.
+// Secret value embedded in a longer env var value cannot be referenced, warnings are returned for such env vars.
+func detectSecretEnv(app *apiv1.App, env map[string]string, vars map[string]any, secrets []*SecretValue, secretEnv map[string]string) (ret map[string]string, warnings []string, err error) {
+	ret = make(map[string]string, len(secretEnv))
+	maps.Copy(ret, secretEnv)
+
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	eval := fields.NewFieldVarEvaluator(vars)
+
+	for _, name := range names {
+		if _, ok := ret[name]; ok {
+			continue
+		}
+
+		exp, err := eval.Expand(env[name])
+		if err != nil {
+			return nil, nil, err
+		}
+
+		val, ok := exp.LookupWanted()
+		if !ok || len(val) < minDetectedSecretLength {
+			continue
+		}
+
+		for _, s := range secrets {
+			if len(s.Value) < minDetectedSecretLength {
+				continue
+			}
+
+			if val == s.Value {
+				ret[name] = fmt.Sprintf("%s:%s", s.Key, s.Version)
+
+				break
+			}
+
+			if strings.Contains(val, s.Value) {
+				warnings = append(warnings, fmt.Sprintf("App '%s' env var '%s' contains value of secret '%s' and is stored in plain text, pass it through secret_env instead.", app.Name, name, s.Key))
+
+				break
+			}
+		}
+	}
+
+	return ret, warnings, nil
+}
+
+// parseSecretEnvRef splits "key[:version]" reference to Outblocks secret.
+func parseSecretEnvRef(ref string) (key, version string, err error) {
+	key, version, _ = strings.Cut(ref, ":")
+
+	if key == "" || plugin_util.SanitizeName(key, true, false) != key {
+		return "", "", fmt.Errorf("invalid secret key '%s'", key)
+	}
+
+	if version != "" {
+		if _, err := strconv.Atoi(version); err != nil {
+			return "", "", fmt.Errorf("invalid version '%s' of secret '%s'", version, key)
+		}
+	}
+
+	return key, version, nil
+}
+
+func latestSecretVersion(ctx context.Context, pctx *config.PluginContext, projectID, secret string) (string, error) {
+	cli, err := pctx.GCPSecretManagerClient(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	ver, err := cli.Projects.Secrets.Versions.Get(fmt.Sprintf("projects/%s/secrets/%s/versions/latest", projectID, secret)).Context(ctx).Do()
+	if gcp.ErrIs404(err) {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("error fetching secret '%s' version: %w", secret, err)
+	}
+
+	return ver.Name[strings.LastIndex(ver.Name, "/")+1:], nil
+}
+
+// planSecretEnvVars resolves env vars backed by Outblocks secrets into Secret Manager references pinned to a version
+// (latest enabled one unless specified) and grants app's service account access to referenced secrets.
+func planSecretEnvVars(ctx context.Context, pctx *config.PluginContext, r *registry.Registry, app *apiv1.App, projectID string, secretEnv map[string]string) (map[string]fields.Field, []*gcp.SecretIAMMember, error) {
+	if len(secretEnv) == 0 {
+		return nil, nil, nil
+	}
+
+	envVars := make(map[string]fields.Field, len(secretEnv))
+	secrets := make(map[string]string)
+
+	for name, ref := range secretEnv {
+		key, version, err := parseSecretEnvRef(ref)
+		if err != nil {
+			return nil, nil, fmt.Errorf("app '%s' secret env var '%s': %w", app.Name, name, err)
+		}
+
+		secret := gcp.SecretName(pctx.Env(), key)
+
+		if version == "" {
+			version, err = latestSecretVersion(ctx, pctx, projectID, secret)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if version == "" {
+				return nil, nil, fmt.Errorf("app '%s' secret env var '%s': secret '%s' is not set", app.Name, name, key)
+			}
+		}
+
+		envVars[name] = fields.String(fmt.Sprintf("%s:%s", secret, version))
+		secrets[key] = secret
+	}
+
+	keys := make([]string, 0, len(secrets))
+	for k := range secrets {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	member := "serviceAccount:" + appServiceAccount(app, projectID, pctx.Settings().ProjectNumber)
+	accessors := make([]*gcp.SecretIAMMember, 0, len(keys))
+
+	for _, key := range keys {
+		m := &gcp.SecretIAMMember{
+			Secret: fields.String(fmt.Sprintf("projects/%s/secrets/%s", projectID, secrets[key])),
+			Role:   fields.String(gcp.SecretAccessorRole),
+			Member: fields.String(member),
+		}
+
+		_, err := r.RegisterAppResource(app, "secret_accessor_"+key, m)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		accessors = append(accessors, m)
+	}
+
+	return envVars, accessors, nil
+}
@@ -8,6 +8,7 @@ import (
 	"log"
 	"net/url"
 	"os"
+	"strings"
 
 	"github.com/outblocks/cli-plugin-gcp/gcp"
 	"github.com/outblocks/cli-plugin-gcp/internal/config"
@@ -16,6 +17,7 @@ import (
 	"github.com/outblocks/outblocks-plugin-go/registry/fields"
 	"github.com/outblocks/outblocks-plugin-go/types"
 	plugin_util "github.com/outblocks/outblocks-plugin-go/util"
+	"github.com/outblocks/outblocks-plugin-go/util/command"
 )
 
 var _ registry.ResourceDiffCalculator = (*CacheInvalidate)(nil)
@@ -125,3 +127,130 @@ func addCloudSchedulers(pctx *config.PluginContext, r *registry.Registry, app *a
 
 	return ret, nil
 }
+
+// expandCloudRunEnvVars expands app env vars for Cloud Run services and jobs, adding CLOUD_RUN_PROJECT_HASH.
+func expandCloudRunEnvVars(env map[string]string, vars map[string]any, settings *CloudRunSettings) (fields.MapInputField, error) {
+	cloudRunHash := "unknown"
+
+	if settings != nil {
+		cloudRunHash = settings.ProjectHash
+	}
+
+	if env == nil {
+		env = make(map[string]string)
+	}
+
+	env["CLOUD_RUN_PROJECT_HASH"] = cloudRunHash
+
+	envVars := make(map[string]fields.Field, len(env))
+	eval := fields.NewFieldVarEvaluator(vars)
+
+	for k, v := range env {
+		exp, err := eval.Expand(v)
+		if err != nil {
+			return nil, err
+		}
+
+		envVars[k] = exp
+	}
+
+	return fields.Map(envVars), nil
+}
+
+func cloudSQLInstancesField(databases []*DatabaseDep) fields.StringInputField {
+	cloudSQLconnFmt := make([]string, len(databases))
+	cloudSQLconnNames := make([]any, len(databases))
+
+	for i, db := range databases {
+		cloudSQLconnFmt[i] = "%s"
+		cloudSQLconnNames[i] = db.CloudSQL.ConnectionName
+	}
+
+	return fields.Sprintf(strings.Join(cloudSQLconnFmt, ","), cloudSQLconnNames...)
+}
+
+func commandField(cmd *command.StringCommand) fields.ArrayInputField {
+	arr := cmd.ArrayOrShell()
+	ret := make([]fields.Field, len(arr))
+
+	for i, v := range arr {
+		ret[i] = fields.String(v)
+	}
+
+	return fields.Array(ret)
+}
+
+func addCloudRunJobSchedulers(pctx *config.PluginContext, r *registry.Registry, app *apiv1.App, job *gcp.CloudRunJob, projectID, region, serviceAccount string, schedulers []*types.AppScheduler) ([]*gcp.CloudSchedulerJob, error) {
+	ret := make([]*gcp.CloudSchedulerJob, 0, len(schedulers))
+
+	for i, sch := range schedulers {
+		var cronName string
+
+		if sch.Name != "" {
+			cronName = gcp.ID(pctx.Env(), sch.Name)
+		} else {
+			cronName = gcp.ID(pctx.Env(), fmt.Sprintf("%s-%d", app.Id, i+1))
+		}
+
+		sj := &gcp.CloudSchedulerJob{
+			Name:                    fields.String(cronName),
+			ProjectID:               fields.String(projectID),
+			Region:                  fields.String(region),
+			Schedule:                fields.String(sch.Cron),
+			HTTPMethod:              fields.String("POST"),
+			HTTPURL:                 job.RunURL(),
+			HTTPOAuthServiceAccount: fields.String(serviceAccount),
+		}
+		ret = append(ret, sj)
+
+		_, err := r.RegisterAppResource(app, fmt.Sprintf("cloud_scheduler_job_%d", i+1), sj)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return ret, nil
+}
+
+// addPubSubTopic registers pubsub topic shared by all apps and dependencies referencing it by name.
+func addPubSubTopic(r *registry.Registry, topics map[string]*gcp.PubSubTopic, projectID, name string) (*gcp.PubSubTopic, error) {
+	if topic, ok := topics[name]; ok {
+		return topic, nil
+	}
+
+	topic := &gcp.PubSubTopic{
+		Name:      fields.String(name),
+		ProjectID: fields.String(projectID),
+	}
+
+	_, err := r.RegisterPluginResource(PubSubName, name, topic)
+	if err != nil {
+		return nil, err
+	}
+
+	topics[name] = topic
+
+	return topic, nil
+}
+
+// appServiceAccount returns email of service account that app runs as.
+func appServiceAccount(app *apiv1.App, projectID string, projectNumber int64) string {
+	var opts struct {
+		ServiceAccountName string `json:"service_account_name"`
+		Generation         int    `json:"generation"`
+	}
+
+	if app.Properties != nil {
+		_ = plugin_util.MapstructureJSONDecode(app.Properties.AsMap(), &opts)
+	}
+
+	switch {
+	case opts.ServiceAccountName != "":
+		return opts.ServiceAccountName
+	case app.Type == AppTypeFunction && opts.Generation != 2:
+		// 1st gen functions run as App Engine default service account.
+		return fmt.Sprintf("%s@appspot.gserviceaccount.com", projectID)
+	default:
+		return fmt.Sprintf("%d-compute@developer.gserviceaccount.com", projectNumber)
+	}
+}
@@ -16,6 +16,9 @@ type BackendService struct {
 	ProjectID fields.StringInputField `state:"force_new"`
 	NEG       fields.StringInputField `state:"force_new"`
 
+	// AdditionalNEGs are used for services deployed to multiple regions.
+	AdditionalNEGs fields.ArrayInputField
+
 	CDN struct {
 		Enabled        fields.BoolInputField
 		CacheMode      fields.StringInputField `default:"CACHE_ALL_STATIC"`
@@ -68,8 +71,16 @@ func (o *BackendService) Read(ctx context.Context, meta any) error {
 	o.ProjectID.SetCurrent(projectID)
 	o.Name.SetCurrent(name)
 
-	if len(svc.Backends) == 1 {
+	if len(svc.Backends) > 0 {
 		o.NEG.SetCurrent(svc.Backends[0].Group)
+
+		additionalNEGs := make([]any, 0, len(svc.Backends)-1)
+
+		for _, b := range svc.Backends[1:] {
+			additionalNEGs = append(additionalNEGs, b.Group)
+		}
+
+		o.AdditionalNEGs.SetCurrent(additionalNEGs)
 	}
 
 	o.CDN.Enabled.SetCurrent(svc.EnableCDN)
@@ -117,11 +128,7 @@ func (o *BackendService) Create(ctx context.Context, meta any) error {
 			MaxTtl:     int64(o.CDN.MaxTTL.Wanted()),
 			ClientTtl:  int64(o.CDN.ClientTTL.Wanted()),
 		},
-		Backends: []*compute.Backend{
-			{
-				Group: o.NEG.Wanted(),
-			},
-		},
+		Backends:            o.makeBackends(),
 		LoadBalancingScheme: "EXTERNAL_MANAGED",
 	}).Do()
 	if err != nil {
@@ -165,11 +172,7 @@ func (o *BackendService) Update(ctx context.Context, meta any) error {
 			MaxTtl:     int64(o.CDN.MaxTTL.Wanted()),
 			ClientTtl:  int64(o.CDN.ClientTTL.Wanted()),
 		},
-		Backends: []*compute.Backend{
-			{
-				Group: o.NEG.Wanted(),
-			},
-		},
+		Backends:    o.makeBackends(),
 		Fingerprint: o.Fingerprint,
 		// LoadBalancingScheme: "EXTERNAL_MANAGED",
 	}).Do()
@@ -195,3 +198,19 @@ func (o *BackendService) Delete(ctx context.Context, meta any) error {
 
 	return WaitForGlobalComputeOperation(cli, o.ProjectID.Current(), oper.Name)
 }
+
+func (o *BackendService) makeBackends() []*compute.Backend {
+	backends := []*compute.Backend{
+		{
+			Group: o.NEG.Wanted(),
+		},
+	}
+
+	for _, neg := range o.AdditionalNEGs.Wanted() {
+		backends = append(backends, &compute.Backend{
+			Group: neg.(string), //nolint:errcheck
+		})
+	}
+
+	return backends
+}
@@ -0,0 +1,133 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/outblocks/cli-plugin-gcp/internal/config"
+	"github.com/outblocks/outblocks-plugin-go/registry"
+	"github.com/outblocks/outblocks-plugin-go/registry/fields"
+	"google.golang.org/api/bigquery/v2"
+)
+
+const dayInMilliseconds = 24 * 60 * 60 * 1000
+
+type BigQueryDataset struct {
+	registry.ResourceBase
+
+	Name                fields.StringInputField `state:"force_new"` // dataset ID, only letters, numbers and underscores are allowed
+	ProjectID           fields.StringInputField `state:"force_new"`
+	Location            fields.StringInputField `state:"force_new"`
+	TableExpirationDays fields.IntInputField    // default expiration of new tables, 0 keeps them forever
+
+	Critical bool `state:"-"`
+}
+
+func (o *BigQueryDataset) ReferenceID() string {
+	return fields.GenerateID("projects/%s/datasets/%s", o.ProjectID, o.Name)
+}
+
+func (o *BigQueryDataset) GetName() string {
+	return fields.VerboseString(o.Name)
+}
+
+func (o *BigQueryDataset) Read(ctx context.Context, meta any) error {
+	pctx := meta.(*config.PluginContext) //nolint:errcheck
+
+	projectID := o.ProjectID.Any()
+	name := o.Name.Any()
+
+	cli, err := pctx.GCPBigQueryClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	ds, err := cli.Datasets.Get(projectID, name).Do()
+	if ErrIs404(err) {
+		o.MarkAsNew()
+
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("error fetching bigquery dataset: %w", err)
+	}
+
+	o.MarkAsExisting()
+	o.ProjectID.SetCurrent(projectID)
+	o.Name.SetCurrent(name)
+	o.Location.SetCurrent(strings.ToLower(ds.Location))
+	o.TableExpirationDays.SetCurrent(int(ds.DefaultTableExpirationMs / dayInMilliseconds))
+
+	return nil
+}
+
+func (o *BigQueryDataset) makeDataset() *bigquery.Dataset {
+	ds := &bigquery.Dataset{
+		DatasetReference: &bigquery.DatasetReference{
+			ProjectId: o.ProjectID.Wanted(),
+			DatasetId: o.Name.Wanted(),
+		},
+		Location:                 o.Location.Wanted(),
+		DefaultTableExpirationMs: int64(o.TableExpirationDays.Wanted()) * dayInMilliseconds,
+	}
+
+	if ds.DefaultTableExpirationMs == 0 {
+		ds.NullFields = append(ds.NullFields, "DefaultTableExpirationMs")
+	}
+
+	return ds
+}
+
+func (o *BigQueryDataset) Create(ctx context.Context, meta any) error {
+	pctx := meta.(*config.PluginContext) //nolint:errcheck
+
+	cli, err := pctx.GCPBigQueryClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = cli.Datasets.Insert(o.ProjectID.Wanted(), o.makeDataset()).Do()
+	if err != nil {
+		return fmt.Errorf("error creating bigquery dataset: %w", err)
+	}
+
+	return nil
+}
+
+func (o *BigQueryDataset) Update(ctx context.Context, meta any) error {
+	pctx := meta.(*config.PluginContext) //nolint:errcheck
+
+	cli, err := pctx.GCPBigQueryClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = cli.Datasets.Patch(o.ProjectID.Wanted(), o.Name.Wanted(), o.makeDataset()).Do()
+	if err != nil {
+		return fmt.Errorf("error updating bigquery dataset: %w", err)
+	}
+
+	return nil
+}
+
+func (o *BigQueryDataset) Delete(ctx context.Context, meta any) error {
+	pctx := meta.(*config.PluginContext) //nolint:errcheck
+
+	cli, err := pctx.GCPBigQueryClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = cli.Datasets.Delete(o.ProjectID.Current(), o.Name.Current()).DeleteContents(true).Do()
+	if err != nil && !ErrIs404(err) {
+		return fmt.Errorf("error deleting bigquery dataset: %w", err)
+	}
+
+	return nil
+}
+
+func (o *BigQueryDataset) IsCritical(t registry.DiffType, fieldList []string) bool {
+	return o.Critical && (t == registry.DiffTypeDelete || t == registry.DiffTypeRecreate)
+}
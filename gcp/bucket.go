@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"cloud.google.com/go/storage"
 	"github.com/outblocks/cli-plugin-gcp/internal/config"
@@ -58,6 +59,8 @@ type Bucket struct {
 	ExpireVersionsInDays fields.IntInputField
 	MaxVersions          fields.IntInputField
 	Public               fields.BoolInputField
+	RetentionDays        fields.IntInputField  // objects cannot be deleted or overwritten until they are this old
+	RetentionLocked      fields.BoolInputField // locked retention policy cannot be removed or shortened, locking is irreversible
 
 	CORS fields.ArrayInputField
 
@@ -103,6 +106,14 @@ func (o *Bucket) Read(ctx context.Context, meta any) error {
 
 	o.CORS.SetCurrent(BucketCORS(attrs.CORS).Wanted())
 
+	if rp := attrs.RetentionPolicy; rp != nil {
+		o.RetentionDays.SetCurrent(int(rp.RetentionPeriod / bucketRetentionDay))
+		o.RetentionLocked.SetCurrent(rp.IsLocked)
+	} else {
+		o.RetentionDays.SetCurrent(0)
+		o.RetentionLocked.SetCurrent(false)
+	}
+
 	policy, err := b.IAM().Policy(ctx)
 	if err != nil {
 		return fmt.Errorf("error fetching bucket policy: %w", err)
@@ -113,6 +124,24 @@ func (o *Bucket) Read(ctx context.Context, meta any) error {
 	return nil
 }
 
+const bucketRetentionDay = 24 * time.Hour
+
+func (o *Bucket) retentionPolicy() *storage.RetentionPolicy {
+	return &storage.RetentionPolicy{
+		RetentionPeriod: time.Duration(o.RetentionDays.Wanted()) * bucketRetentionDay,
+	}
+}
+
+// lockRetentionPolicy locks retention policy of bucket in given metageneration.
+func lockRetentionPolicy(ctx context.Context, b *storage.BucketHandle, metageneration int64) error {
+	err := b.If(storage.BucketConditions{MetagenerationMatch: metageneration}).LockRetentionPolicy(ctx)
+	if err != nil {
+		return fmt.Errorf("error locking bucket retention policy: %w", err)
+	}
+
+	return nil
+}
+
 func (o *Bucket) Create(ctx context.Context, meta any) error {
 	pctx := meta.(*config.PluginContext) //nolint:errcheck
 
@@ -162,11 +191,27 @@ func (o *Bucket) Create(ctx context.Context, meta any) error {
 		})
 	}
 
+	if o.RetentionDays.Wanted() > 0 {
+		attrs.RetentionPolicy = o.retentionPolicy()
+	}
+
 	err = b.Create(ctx, o.ProjectID.Wanted(), attrs)
 	if err != nil {
 		return fmt.Errorf("error creating bucket: %w", err)
 	}
 
+	if o.RetentionLocked.Wanted() {
+		created, err := b.Attrs(ctx)
+		if err != nil {
+			return fmt.Errorf("error fetching bucket status: %w", err)
+		}
+
+		err = lockRetentionPolicy(ctx, b, created.MetaGeneration)
+		if err != nil {
+			return err
+		}
+	}
+
 	if !o.Public.Wanted() {
 		return nil
 	}
@@ -240,11 +285,27 @@ func (o *Bucket) Update(ctx context.Context, meta any) error {
 		attrs.Lifecycle = &lifecycle
 	}
 
-	_, err = b.Update(ctx, attrs)
+	if o.RetentionLocked.IsChanged() && !o.RetentionLocked.Wanted() {
+		return fmt.Errorf("retention policy of bucket '%s' is locked and cannot be unlocked", o.Name.Wanted())
+	}
+
+	// Zero retention period removes retention policy.
+	if o.RetentionDays.IsChanged() {
+		attrs.RetentionPolicy = o.retentionPolicy()
+	}
+
+	updated, err := b.Update(ctx, attrs)
 	if err != nil {
 		return fmt.Errorf("error updating bucket: %w", err)
 	}
 
+	if o.RetentionLocked.IsChanged() {
+		err = lockRetentionPolicy(ctx, b, updated.MetaGeneration)
+		if err != nil {
+			return err
+		}
+	}
+
 	if !o.Public.IsChanged() {
 		return nil
 	}
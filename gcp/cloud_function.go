@@ -38,6 +38,11 @@ type CloudFunction struct {
 	TimeoutSeconds fields.IntInputField `default:"300"`
 	EnvVars        fields.MapInputField
 	Ingress        fields.StringInputField `default:"ALLOW_ALL" state:"force_new"` // options: ALLOW_INTERNAL_AND_GCLB, ALLOW_INTERNAL_ONLY
+
+	// Event trigger, function is triggered by HTTPS when event type is empty.
+	EventType     fields.StringInputField `state:"force_new"`
+	EventResource fields.StringInputField
+	EventRetry    fields.BoolInputField
 }
 
 func (o *CloudFunction) ReferenceID() string {
@@ -105,6 +110,16 @@ func (o *CloudFunction) Read(ctx context.Context, meta any) error {
 	o.EnvVars.SetCurrent(envVars)
 	o.Ingress.SetCurrent(cf.IngressSettings)
 
+	if cf.EventTrigger != nil {
+		o.EventType.SetCurrent(cf.EventTrigger.EventType)
+		o.EventResource.SetCurrent(cf.EventTrigger.Resource)
+		o.EventRetry.SetCurrent(cf.EventTrigger.FailurePolicy != nil && cf.EventTrigger.FailurePolicy.Retry != nil)
+	} else {
+		o.EventType.UnsetCurrent()
+		o.EventResource.UnsetCurrent()
+		o.EventRetry.UnsetCurrent()
+	}
+
 	policy, err := cli.Projects.Locations.Functions.GetIamPolicy(cf.Name).Do()
 	if err != nil {
 		return fmt.Errorf("error fetching cloud function policy: %w", err)
@@ -133,7 +148,11 @@ func (o *CloudFunction) setCurrentStatusInfo(cf *cloudfunctions.CloudFunction) {
 		o.StatusMessage.SetCurrent(fmt.Sprintf("Function failed to deploy: %s", cf.Status))
 	}
 
-	o.URL.SetCurrent(cf.HttpsTrigger.Url)
+	if cf.HttpsTrigger != nil {
+		o.URL.SetCurrent(cf.HttpsTrigger.Url)
+	} else {
+		o.URL.SetCurrent("")
+	}
 }
 
 func (o *CloudFunction) wantedAPICloudFunction() *cloudfunctions.CloudFunction {
@@ -144,7 +163,7 @@ func (o *CloudFunction) wantedAPICloudFunction() *cloudfunctions.CloudFunction {
 		envvars[k] = v.(string) //nolint:errcheck
 	}
 
-	return &cloudfunctions.CloudFunction{
+	cf := &cloudfunctions.CloudFunction{
 		Name:                      fmt.Sprintf("projects/%s/locations/%s/functions/%s", o.ProjectID.Wanted(), o.Region.Wanted(), o.Name.Wanted()),
 		AvailableMemoryMb:         int64(o.MemoryLimit.Wanted()),
 		BuildEnvironmentVariables: envvars,
@@ -154,10 +173,28 @@ func (o *CloudFunction) wantedAPICloudFunction() *cloudfunctions.CloudFunction {
 		MinInstances:              int64(o.MinScale.Wanted()),
 		MaxInstances:              int64(o.MaxScale.Wanted()),
 		Runtime:                   o.Runtime.Wanted(),
-		HttpsTrigger:              &cloudfunctions.HttpsTrigger{},
 		Timeout:                   fmt.Sprintf("%ds", o.TimeoutSeconds.Wanted()),
 		SourceArchiveUrl:          fmt.Sprintf("gs://%s/%s", o.SourceBucket.Wanted(), o.SourceObject.Wanted()),
 	}
+
+	if o.EventType.Wanted() == "" {
+		cf.HttpsTrigger = &cloudfunctions.HttpsTrigger{}
+
+		return cf
+	}
+
+	cf.EventTrigger = &cloudfunctions.EventTrigger{
+		EventType: o.EventType.Wanted(),
+		Resource:  o.EventResource.Wanted(),
+	}
+
+	if o.EventRetry.Wanted() {
+		cf.EventTrigger.FailurePolicy = &cloudfunctions.FailurePolicy{
+			Retry: &cloudfunctions.Retry{},
+		}
+	}
+
+	return cf
 }
 
 func (o *CloudFunction) Create(ctx context.Context, meta any) error {
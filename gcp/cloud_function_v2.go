@@ -0,0 +1,581 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/outblocks/cli-plugin-gcp/internal/config"
+	"github.com/outblocks/outblocks-plugin-go/registry"
+	"github.com/outblocks/outblocks-plugin-go/registry/fields"
+	plugin_util "github.com/outblocks/outblocks-plugin-go/util"
+	cloudfunctionsv2 "google.golang.org/api/cloudfunctions/v2"
+	"google.golang.org/api/run/v1"
+)
+
+// CloudFunctionV2 is a 2nd gen cloud function, backed by a Cloud Run service. It shares reference ID with CloudFunction
+// so that existing 1st gen function is picked up and upgraded in place.
+type CloudFunctionV2 struct {
+	registry.ResourceBase
+
+	Name         fields.StringInputField `state:"force_new"`
+	ProjectID    fields.StringInputField `state:"force_new"`
+	Region       fields.StringInputField `state:"force_new"`
+	Entrypoint   fields.StringInputField
+	Runtime      fields.StringInputField
+	SourceBucket fields.StringInputField
+	SourceObject fields.StringInputField
+	IsPublic     fields.BoolInputField
+
+	URL           fields.StringOutputField
+	Ready         fields.BoolOutputField
+	StatusMessage fields.StringOutputField
+
+	Environment        fields.StringInputField `default:"GEN_2"` // GEN_1 functions get upgraded
+	MinScale           fields.IntInputField    `default:"0"`
+	MaxScale           fields.IntInputField    `default:"100"`
+	CPULimit           fields.StringInputField
+	MemoryLimit        fields.IntInputField `default:"256"`
+	Concurrency        fields.IntInputField `default:"1"`
+	TimeoutSeconds     fields.IntInputField `default:"300"`
+	EnvVars            fields.MapInputField
+	Ingress            fields.StringInputField `default:"ALLOW_ALL"` // options: ALLOW_INTERNAL_AND_GCLB, ALLOW_INTERNAL_ONLY
+	ServiceAccountName fields.StringInputField `default:""`
+	VPCConnector       fields.StringInputField `default:""` // Serverless VPC Access connector name
+	EgressMode         fields.StringInputField `default:"private-ranges-only"`
+
+	// Eventarc trigger, function is HTTP triggered if event type is empty.
+	EventType          fields.StringInputField `state:"force_new"`
+	EventFilters       fields.MapInputField
+	EventPathFilters   fields.MapInputField // filters using match-path-pattern operator, e.g. for firestore documents
+	EventPubSubTopic   fields.StringInputField
+	EventTriggerRegion fields.StringInputField
+	EventRetry         fields.BoolInputField `default:"false"`
+}
+
+func (o *CloudFunctionV2) ReferenceID() string {
+	return fields.GenerateID("projects/%s/locations/%s/functions/%s", o.ProjectID, o.Region, o.Name)
+}
+
+func (o *CloudFunctionV2) GetName() string {
+	return fields.VerboseString(o.Name)
+}
+
+func (o *CloudFunctionV2) Read(ctx context.Context, meta any) error {
+	pctx := meta.(*config.PluginContext) //nolint:errcheck
+
+	projectID := o.ProjectID.Any()
+	region := o.Region.Any()
+	name := o.Name.Any()
+
+	cli, err := pctx.GCPCloudFunctionsV2Client(ctx)
+	if err != nil {
+		return err
+	}
+
+	fn, err := getCloudFunctionV2(cli, projectID, region, name)
+	if ErrIs404(err) {
+		o.MarkAsNew()
+
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("error fetching cloud function status: %w", err)
+	}
+
+	o.MarkAsExisting()
+	o.ProjectID.SetCurrent(projectID)
+	o.Name.SetCurrent(name)
+	o.Region.SetCurrent(region)
+	o.Environment.SetCurrent(fn.Environment)
+	o.setCurrentStatusInfo(fn)
+
+	if b := fn.BuildConfig; b != nil {
+		o.Entrypoint.SetCurrent(b.EntryPoint)
+		o.Runtime.SetCurrent(b.Runtime)
+
+		if b.Source != nil && b.Source.StorageSource != nil {
+			o.SourceBucket.SetCurrent(b.Source.StorageSource.Bucket)
+			o.SourceObject.SetCurrent(b.Source.StorageSource.Object)
+		}
+	}
+
+	if t := fn.EventTrigger; t != nil {
+		filters := make(map[string]any, len(t.EventFilters))
+		pathFilters := make(map[string]any)
+
+		for _, f := range t.EventFilters {
+			if f.Operator == CloudFunctionEventFilterPathPattern {
+				pathFilters[f.Attribute] = f.Value
+			} else {
+				filters[f.Attribute] = f.Value
+			}
+		}
+
+		o.EventType.SetCurrent(t.EventType)
+		o.EventFilters.SetCurrent(filters)
+		o.EventPathFilters.SetCurrent(pathFilters)
+		o.EventPubSubTopic.SetCurrent(t.PubsubTopic)
+		o.EventTriggerRegion.SetCurrent(t.TriggerRegion)
+		o.EventRetry.SetCurrent(t.RetryPolicy == CloudFunctionRetryPolicyRetry)
+	} else {
+		o.EventType.UnsetCurrent()
+		o.EventFilters.UnsetCurrent()
+		o.EventPathFilters.UnsetCurrent()
+		o.EventPubSubTopic.UnsetCurrent()
+		o.EventTriggerRegion.UnsetCurrent()
+		o.EventRetry.UnsetCurrent()
+	}
+
+	s := fn.ServiceConfig
+	if s == nil {
+		o.IsPublic.SetCurrent(false)
+
+		return nil
+	}
+
+	envVars := make(map[string]any, len(s.EnvironmentVariables))
+
+	for k, v := range s.EnvironmentVariables {
+		envVars[k] = v
+	}
+
+	o.MinScale.SetCurrent(int(s.MinInstanceCount))
+	o.MaxScale.SetCurrent(int(s.MaxInstanceCount))
+	o.CPULimit.SetCurrent(s.AvailableCpu)
+	o.MemoryLimit.SetCurrent(parseCloudFunctionMemory(s.AvailableMemory))
+	o.Concurrency.SetCurrent(int(s.MaxInstanceRequestConcurrency))
+	o.TimeoutSeconds.SetCurrent(int(s.TimeoutSeconds))
+	o.EnvVars.SetCurrent(envVars)
+	o.Ingress.SetCurrent(s.IngressSettings)
+	o.ServiceAccountName.SetCurrent(s.ServiceAccountEmail)
+	o.VPCConnector.SetCurrent(s.VpcConnector)
+
+	if s.VpcConnector != "" {
+		o.EgressMode.SetCurrent(strings.ReplaceAll(strings.ToLower(s.VpcConnectorEgressSettings), "_", "-"))
+	} else {
+		// Egress mode is irrelevant without connector.
+		o.EgressMode.SetCurrent(o.EgressMode.Wanted())
+	}
+
+	// If service account is default compute service account and user did not specify anything, unset it.
+	if o.ServiceAccountName.Wanted() == "" && s.ServiceAccountEmail == fmt.Sprintf("%d-compute@developer.gserviceaccount.com", pctx.Settings().ProjectNumber) {
+		o.ServiceAccountName.SetCurrent("")
+	}
+
+	// Invoker permission of 2nd gen functions is checked on underlying Cloud Run service.
+	isPublic := false
+
+	if s.Service != "" {
+		runCli, err := pctx.GCPRunClient(ctx, region)
+		if err != nil {
+			return err
+		}
+
+		policy, err := runCli.Projects.Locations.Services.GetIamPolicy(s.Service).Do()
+		if err != nil && !ErrIs404(err) {
+			return fmt.Errorf("error fetching cloud function policy: %w", err)
+		}
+
+		if err == nil {
+			for _, b := range policy.Bindings {
+				if b.Role == "roles/run.invoker" && plugin_util.StringSliceContains(b.Members, ACLAllUsers) {
+					isPublic = true
+				}
+			}
+		}
+	}
+
+	o.IsPublic.SetCurrent(isPublic)
+
+	return nil
+}
+
+func (o *CloudFunctionV2) setCurrentStatusInfo(fn *cloudfunctionsv2.Function) {
+	o.Ready.SetCurrent(fn.State == CloudFunctionReady)
+	o.URL.SetCurrent(fn.Url)
+
+	if fn.State == CloudFunctionReady {
+		o.StatusMessage.SetCurrent("")
+
+		return
+	}
+
+	msgs := make([]string, 0, len(fn.StateMessages))
+
+	for _, m := range fn.StateMessages {
+		msgs = append(msgs, m.Message)
+	}
+
+	if len(msgs) == 0 {
+		msgs = append(msgs, fn.State)
+	}
+
+	o.StatusMessage.SetCurrent(fmt.Sprintf("Function failed to deploy: %s\nhttps://console.cloud.google.com/functions/details/%s/%s?project=%s&tab=logs",
+		strings.Join(msgs, ", "), o.Region.Wanted(), o.Name.Wanted(), o.ProjectID.Wanted()))
+}
+
+func (o *CloudFunctionV2) wantedAPIFunction() *cloudfunctionsv2.Function {
+	envvarsIntf := o.EnvVars.Wanted()
+	envvars := make(map[string]string, len(envvarsIntf))
+
+	for k, v := range envvarsIntf {
+		envvars[k] = v.(string) //nolint:errcheck
+	}
+
+	fn := &cloudfunctionsv2.Function{
+		Name:        fmt.Sprintf("projects/%s/locations/%s/functions/%s", o.ProjectID.Wanted(), o.Region.Wanted(), o.Name.Wanted()),
+		Environment: CloudFunctionGen2,
+		BuildConfig: &cloudfunctionsv2.BuildConfig{
+			EntryPoint:           o.Entrypoint.Wanted(),
+			Runtime:              o.Runtime.Wanted(),
+			EnvironmentVariables: envvars,
+			Source: &cloudfunctionsv2.Source{
+				StorageSource: &cloudfunctionsv2.StorageSource{
+					Bucket: o.SourceBucket.Wanted(),
+					Object: o.SourceObject.Wanted(),
+				},
+			},
+		},
+		ServiceConfig: &cloudfunctionsv2.ServiceConfig{
+			AllTrafficOnLatestRevision:    true,
+			AvailableCpu:                  o.CPULimit.Wanted(),
+			AvailableMemory:               fmt.Sprintf("%dMi", o.MemoryLimit.Wanted()),
+			MaxInstanceRequestConcurrency: int64(o.Concurrency.Wanted()),
+			MinInstanceCount:              int64(o.MinScale.Wanted()),
+			MaxInstanceCount:              int64(o.MaxScale.Wanted()),
+			TimeoutSeconds:                int64(o.TimeoutSeconds.Wanted()),
+			EnvironmentVariables:          envvars,
+			IngressSettings:               o.Ingress.Wanted(),
+			ServiceAccountEmail:           o.ServiceAccountName.Wanted(),
+			ForceSendFields:               []string{"MinInstanceCount"},
+		},
+	}
+
+	if o.VPCConnector.Wanted() != "" {
+		fn.ServiceConfig.VpcConnector = o.VPCConnector.Wanted()
+		fn.ServiceConfig.VpcConnectorEgressSettings = strings.ReplaceAll(strings.ToUpper(o.EgressMode.Wanted()), "-", "_")
+	}
+
+	if o.EventType.Wanted() == "" {
+		return fn
+	}
+
+	var filters []*cloudfunctionsv2.EventFilter
+
+	for k, v := range o.EventFilters.Wanted() {
+		filters = append(filters, &cloudfunctionsv2.EventFilter{
+			Attribute: k,
+			Value:     v.(string), //nolint:errcheck
+		})
+	}
+
+	for k, v := range o.EventPathFilters.Wanted() {
+		filters = append(filters, &cloudfunctionsv2.EventFilter{
+			Attribute: k,
+			Value:     v.(string), //nolint:errcheck
+			Operator:  CloudFunctionEventFilterPathPattern,
+		})
+	}
+
+	retryPolicy := CloudFunctionRetryPolicyDoNotRetry
+	if o.EventRetry.Wanted() {
+		retryPolicy = CloudFunctionRetryPolicyRetry
+	}
+
+	fn.EventTrigger = &cloudfunctionsv2.EventTrigger{
+		EventType:           o.EventType.Wanted(),
+		EventFilters:        filters,
+		PubsubTopic:         o.EventPubSubTopic.Wanted(),
+		TriggerRegion:       o.EventTriggerRegion.Wanted(),
+		RetryPolicy:         retryPolicy,
+		ServiceAccountEmail: o.ServiceAccountName.Wanted(),
+	}
+
+	return fn
+}
+
+func (o *CloudFunctionV2) Create(ctx context.Context, meta any) error {
+	pctx := meta.(*config.PluginContext) //nolint:errcheck
+
+	projectID := o.ProjectID.Wanted()
+	region := o.Region.Wanted()
+	name := o.Name.Wanted()
+
+	cli, err := pctx.GCPCloudFunctionsV2Client(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Function registered as 1st gen before will still exist under the same name, upgrade it instead of creating new one.
+	fn, err := getCloudFunctionV2(cli, projectID, region, name)
+	if err != nil && !ErrIs404(err) {
+		return fmt.Errorf("error fetching cloud function status: %w", err)
+	}
+
+	if err == nil {
+		if fn.Environment == CloudFunctionGen1 {
+			err = upgradeCloudFunctionToGen2(ctx, cli, fn, o.ServiceAccountName.Wanted())
+			if err != nil {
+				return err
+			}
+		}
+
+		return o.update(ctx, pctx, cli)
+	}
+
+	op, err := createCloudFunctionV2(cli, projectID, region, name, o.wantedAPIFunction())
+	if err != nil {
+		return err
+	}
+
+	return o.finalize(ctx, pctx, cli, waitForCloudFunctionsV2Operation(ctx, cli, op))
+}
+
+func (o *CloudFunctionV2) Update(ctx context.Context, meta any) error {
+	pctx := meta.(*config.PluginContext) //nolint:errcheck
+
+	cli, err := pctx.GCPCloudFunctionsV2Client(ctx)
+	if err != nil {
+		return err
+	}
+
+	if o.Environment.Current() == CloudFunctionGen1 {
+		fn, err := getCloudFunctionV2(cli, o.ProjectID.Wanted(), o.Region.Wanted(), o.Name.Wanted())
+		if err != nil {
+			return fmt.Errorf("error fetching cloud function status: %w", err)
+		}
+
+		err = upgradeCloudFunctionToGen2(ctx, cli, fn, o.ServiceAccountName.Wanted())
+		if err != nil {
+			return err
+		}
+	}
+
+	return o.update(ctx, pctx, cli)
+}
+
+func (o *CloudFunctionV2) update(ctx context.Context, pctx *config.PluginContext, cli *cloudfunctionsv2.Service) error {
+	op, err := updateCloudFunctionV2(cli, o.ProjectID.Wanted(), o.Region.Wanted(), o.Name.Wanted(), o.wantedAPIFunction())
+	if err != nil {
+		return err
+	}
+
+	return o.finalize(ctx, pctx, cli, waitForCloudFunctionsV2Operation(ctx, cli, op))
+}
+
+// finalize refreshes status info after deployment operation finished and sets invoker permissions.
+func (o *CloudFunctionV2) finalize(ctx context.Context, pctx *config.PluginContext, cli *cloudfunctionsv2.Service, opErr error) error {
+	projectID := o.ProjectID.Wanted()
+	region := o.Region.Wanted()
+
+	fn, err := getCloudFunctionV2(cli, projectID, region, o.Name.Wanted())
+	if err != nil {
+		if opErr != nil {
+			return opErr
+		}
+
+		return err
+	}
+
+	o.setCurrentStatusInfo(fn)
+
+	if fn.ServiceConfig == nil || fn.ServiceConfig.Service == "" {
+		return nil
+	}
+
+	runCli, err := pctx.GCPRunClient(ctx, region)
+	if err != nil {
+		return err
+	}
+
+	err = setCloudFunctionV2InvokerPolicy(runCli, fn.ServiceConfig.Service, o.IsPublic.Wanted())
+	if err != nil {
+		return fmt.Errorf("error settings cloud function policy: %w", err)
+	}
+
+	return nil
+}
+
+func (o *CloudFunctionV2) Delete(ctx context.Context, meta any) error {
+	pctx := meta.(*config.PluginContext) //nolint:errcheck
+
+	cli, err := pctx.GCPCloudFunctionsV2Client(ctx)
+	if err != nil {
+		return err
+	}
+
+	op, err := deleteCloudFunctionV2(cli, o.ProjectID.Current(), o.Region.Current(), o.Name.Current())
+	if err != nil {
+		return err
+	}
+
+	return waitForCloudFunctionsV2Operation(ctx, cli, op)
+}
+
+// upgradeCloudFunctionToGen2 upgrades 1st gen function in place. Function keeps its name and URL so that
+// serverless NEGs pointing to it keep working. Interrupted upgrades are resumed based on their upgrade state.
+func upgradeCloudFunctionToGen2(ctx context.Context, cli *cloudfunctionsv2.Service, fn *cloudfunctionsv2.Function, triggerServiceAccount string) error {
+	var state string
+
+	if fn.UpgradeInfo != nil {
+		state = fn.UpgradeInfo.UpgradeState
+	}
+
+	if state != CloudFunctionUpgradeSetupSuccessful && state != CloudFunctionUpgradeRedirectSuccessful {
+		op, err := cli.Projects.Locations.Functions.SetupFunctionUpgradeConfig(fn.Name, &cloudfunctionsv2.SetupFunctionUpgradeConfigRequest{
+			TriggerServiceAccount: triggerServiceAccount,
+		}).Do()
+		if err != nil {
+			return fmt.Errorf("error setting up cloud function upgrade: %w", err)
+		}
+
+		err = waitForCloudFunctionsV2Operation(ctx, cli, op)
+		if err != nil {
+			return fmt.Errorf("error setting up cloud function upgrade: %w", err)
+		}
+	}
+
+	if state != CloudFunctionUpgradeRedirectSuccessful {
+		op, err := cli.Projects.Locations.Functions.RedirectFunctionUpgradeTraffic(fn.Name, &cloudfunctionsv2.RedirectFunctionUpgradeTrafficRequest{}).Do()
+		if err != nil {
+			return fmt.Errorf("error redirecting cloud function traffic to 2nd gen: %w", err)
+		}
+
+		err = waitForCloudFunctionsV2Operation(ctx, cli, op)
+		if err != nil {
+			return fmt.Errorf("error redirecting cloud function traffic to 2nd gen: %w", err)
+		}
+	}
+
+	op, err := cli.Projects.Locations.Functions.CommitFunctionUpgrade(fn.Name, &cloudfunctionsv2.CommitFunctionUpgradeRequest{}).Do()
+	if err != nil {
+		return fmt.Errorf("error committing cloud function upgrade: %w", err)
+	}
+
+	err = waitForCloudFunctionsV2Operation(ctx, cli, op)
+	if err != nil {
+		return fmt.Errorf("error committing cloud function upgrade: %w", err)
+	}
+
+	return nil
+}
+
+func setCloudFunctionV2InvokerPolicy(cli *run.APIService, service string, public bool) error {
+	policy, err := cli.Projects.Locations.Services.GetIamPolicy(service).Do()
+	if err != nil {
+		return err
+	}
+
+	var bindings []*run.Binding
+
+	for _, b := range policy.Bindings {
+		if b.Role != "roles/run.invoker" {
+			bindings = append(bindings, b)
+
+			continue
+		}
+
+		var members []string
+
+		for _, m := range b.Members {
+			if m != ACLAllUsers {
+				members = append(members, m)
+			}
+		}
+
+		if len(members) > 0 {
+			b.Members = members
+			bindings = append(bindings, b)
+		}
+	}
+
+	if public {
+		bindings = append(bindings, &run.Binding{
+			Members: []string{ACLAllUsers},
+			Role:    "roles/run.invoker",
+		})
+	}
+
+	policy.Bindings = bindings
+
+	_, err = cli.Projects.Locations.Services.SetIamPolicy(service, &run.SetIamPolicyRequest{
+		Policy: policy,
+	}).Do()
+
+	return err
+}
+
+// parseCloudFunctionMemory converts memory quantity, e.g. "256Mi" or "1G", to megabytes.
+func parseCloudFunctionMemory(s string) int {
+	s = strings.TrimSuffix(s, "i")
+	if s == "" {
+		return 0
+	}
+
+	mult := 1.0
+
+	switch s[len(s)-1] {
+	case 'G':
+		mult = 1024
+	case 'M':
+	case 'k', 'K':
+		mult = 1.0 / 1024
+	default:
+		v, _ := strconv.ParseFloat(s, 64)
+
+		return int(v / 1024 / 1024)
+	}
+
+	v, _ := strconv.ParseFloat(s[:len(s)-1], 64)
+
+	return int(v * mult)
+}
+
+func createCloudFunctionV2(cli *cloudfunctionsv2.Service, project, region, name string, fn *cloudfunctionsv2.Function) (*cloudfunctionsv2.Operation, error) {
+	return cli.Projects.Locations.Functions.Create(fmt.Sprintf("projects/%s/locations/%s", project, region), fn).FunctionId(name).Do()
+}
+
+func updateCloudFunctionV2(cli *cloudfunctionsv2.Service, project, region, name string, fn *cloudfunctionsv2.Function) (*cloudfunctionsv2.Operation, error) {
+	return cli.Projects.Locations.Functions.Patch(fmt.Sprintf("projects/%s/locations/%s/functions/%s", project, region, name), fn).Do()
+}
+
+func getCloudFunctionV2(cli *cloudfunctionsv2.Service, project, region, name string) (*cloudfunctionsv2.Function, error) {
+	return cli.Projects.Locations.Functions.Get(fmt.Sprintf("projects/%s/locations/%s/functions/%s", project, region, name)).Do()
+}
+
+func deleteCloudFunctionV2(cli *cloudfunctionsv2.Service, project, region, name string) (*cloudfunctionsv2.Operation, error) {
+	return cli.Projects.Locations.Functions.Delete(fmt.Sprintf("projects/%s/locations/%s/functions/%s", project, region, name)).Do()
+}
+
+func waitForCloudFunctionsV2Operation(ctx context.Context, cli *cloudfunctionsv2.Service, op *cloudfunctionsv2.Operation) error {
+	t := time.NewTicker(time.Second)
+	defer t.Stop()
+
+	var err error
+
+	for {
+		if op.Done {
+			if op.Error != nil {
+				return errors.New(op.Error.Message)
+			}
+
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+
+		op, err = cli.Projects.Locations.Operations.Get(op.Name).Do()
+		if err != nil {
+			return err
+		}
+	}
+}
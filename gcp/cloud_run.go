@@ -34,6 +34,7 @@ type CloudRun struct {
 	TimeoutSeconds       fields.IntInputField    `default:"300"`
 	Port                 fields.IntInputField    `default:"80"`
 	EnvVars              fields.MapInputField
+	SecretEnvVars        fields.MapInputField    // env var name -> "<secret>:<version>" Secret Manager reference
 	Ingress              fields.StringInputField `default:"all"`  // options: internal-and-cloud-load-balancing
 	ExecutionEnvironment fields.StringInputField `default:"gen1"` // options: gen2
 	CPUThrottling        fields.BoolInputField   `default:"true"`
@@ -59,10 +60,18 @@ type CloudRun struct {
 	StartupProbeTimeoutSeconds      fields.IntInputField `default:"1"`
 	StartupProbeFailureThreshold    fields.IntInputField `default:"3"`
 
+	// TrafficPercent is percent of traffic routed to latest revision, the rest stays on previous one.
+	TrafficPercent fields.IntInputField
+	Rollout        *CloudRunRollout `state:"-"`
+
+	// SecretAccessors are IAM bindings that need to be in place before service can read its secret env vars.
+	SecretAccessors []*SecretIAMMember `state:"-"`
+
 	// Outputs
-	URL           fields.StringOutputField
-	Ready         fields.BoolOutputField
-	StatusMessage fields.StringOutputField
+	URL              fields.StringOutputField
+	Ready            fields.BoolOutputField
+	StatusMessage    fields.StringOutputField
+	PreviousRevision fields.StringOutputField
 }
 
 func (o *CloudRun) ReferenceID() string {
@@ -73,6 +82,16 @@ func (o *CloudRun) GetName() string {
 	return fields.VerboseString(o.Name)
 }
 
+func (o *CloudRun) FieldDependencies() []any {
+	ret := make([]any, 0, len(o.SecretAccessors))
+
+	for _, a := range o.SecretAccessors {
+		ret = append(ret, a.Member)
+	}
+
+	return ret
+}
+
 func (o *CloudRun) Read(ctx context.Context, meta any) error { //nolint:gocyclo
 	pctx := meta.(*config.PluginContext) //nolint:errcheck
 
@@ -113,6 +132,7 @@ func (o *CloudRun) Read(ctx context.Context, meta any) error { //nolint:gocyclo
 		o.MinScale.UnsetCurrent()
 		o.MaxScale.UnsetCurrent()
 		o.EnvVars.UnsetCurrent()
+		o.SecretEnvVars.UnsetCurrent()
 		o.Ingress.UnsetCurrent()
 		o.ExecutionEnvironment.UnsetCurrent()
 		o.CPUThrottling.UnsetCurrent()
@@ -185,13 +205,15 @@ func (o *CloudRun) Read(ctx context.Context, meta any) error { //nolint:gocyclo
 			o.EgressNetwork.SetCurrent(interfaces[0].Network)
 			o.EgressSubnet.SetCurrent(interfaces[0].Subnetwork)
 		}
+
+		o.EgressMode.SetCurrent(svc.Spec.Template.Metadata.Annotations["run.googleapis.com/vpc-access-egress"])
 	} else {
 		o.EgressNetwork.UnsetCurrent()
 		o.EgressSubnet.UnsetCurrent()
+		// Egress mode is irrelevant and not sent without network interfaces.
+		o.EgressMode.SetCurrent(o.EgressMode.Wanted())
 	}
 
-	o.EgressMode.SetCurrent(svc.Spec.Template.Metadata.Annotations["run.googleapis.com/vpc-access-egress"])
-
 	// If service account is default compute service account and user did not specify anything, unset it.
 	if o.ServiceAccountName.Wanted() == "" && strings.HasSuffix(svc.Spec.Template.Spec.ServiceAccountName, "-compute@developer.gserviceaccount.com") {
 		o.ServiceAccountName.SetCurrent("")
@@ -204,12 +226,21 @@ func (o *CloudRun) Read(ctx context.Context, meta any) error { //nolint:gocyclo
 	o.MaxScale.SetCurrent(v)
 
 	envVars := make(map[string]any)
+	secretEnvVars := make(map[string]any)
 
 	for _, e := range svc.Spec.Template.Spec.Containers[0].Env {
+		if e.ValueFrom != nil && e.ValueFrom.SecretKeyRef != nil {
+			secretEnvVars[e.Name] = fmt.Sprintf("%s:%s", e.ValueFrom.SecretKeyRef.Name, e.ValueFrom.SecretKeyRef.Key)
+
+			continue
+		}
+
 		envVars[e.Name] = e.Value
 	}
 
 	o.EnvVars.SetCurrent(envVars)
+	o.SecretEnvVars.SetCurrent(secretEnvVars)
+	o.TrafficPercent.SetCurrent(runServiceLatestTrafficPercent(svc))
 
 	if svc.Spec.Template.Spec.Containers[0].LivenessProbe != nil {
 		if svc.Spec.Template.Spec.Containers[0].LivenessProbe.HttpGet != nil {
@@ -317,6 +348,19 @@ func (o *CloudRun) Update(ctx context.Context, meta any) error {
 		return err
 	}
 
+	if o.isTrafficManaged() {
+		err = o.updateWithTraffic(ctx, pctx, cli)
+		if err != nil {
+			return err
+		}
+
+		if o.IsPublic.IsChanged() {
+			return setRunServiceIAMPolicy(cli, projectID, region, name, o.IsPublic.Wanted())
+		}
+
+		return nil
+	}
+
 	_, err = updateRunService(cli, projectID, name, o.makeRunService())
 	if err != nil {
 		return err
@@ -364,6 +408,21 @@ func (o *CloudRun) makeRunService() *run.Service {
 		envVars = append(envVars, &run.EnvVar{Name: k, Value: v.(string)}) //nolint:errcheck
 	}
 
+	for k, v := range o.SecretEnvVars.Wanted() {
+		ref := v.(string) //nolint:errcheck
+		idx := strings.LastIndex(ref, ":")
+
+		envVars = append(envVars, &run.EnvVar{
+			Name: k,
+			ValueFrom: &run.EnvVarSource{
+				SecretKeyRef: &run.SecretKeySelector{
+					Name: ref[:idx],
+					Key:  ref[idx+1:],
+				},
+			},
+		})
+	}
+
 	command := o.Command.Wanted()
 	commandStr := make([]string, len(command))
 
@@ -0,0 +1,375 @@
+package gcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/outblocks/cli-plugin-gcp/internal/config"
+	"github.com/outblocks/outblocks-plugin-go/registry"
+	"github.com/outblocks/outblocks-plugin-go/registry/fields"
+	"google.golang.org/api/run/v1"
+)
+
+type CloudRunJob struct {
+	registry.ResourceBase
+
+	Name      fields.StringInputField `state:"force_new"`
+	ProjectID fields.StringInputField `state:"force_new"`
+	Region    fields.StringInputField `state:"force_new"`
+	Command   fields.ArrayInputField
+	Args      fields.ArrayInputField
+	Image     fields.StringInputField
+
+	CloudSQLInstances  fields.StringInputField
+	CPULimit           fields.StringInputField `default:"1000m"`
+	MemoryLimit        fields.StringInputField `default:"512Mi"`
+	TimeoutSeconds     fields.IntInputField    `default:"600"`
+	MaxRetries         fields.IntInputField    `default:"3"`
+	TaskCount          fields.IntInputField    `default:"1"`
+	Parallelism        fields.IntInputField    `default:"0"`
+	EnvVars            fields.MapInputField
+	ServiceAccountName fields.StringInputField `default:""`
+	EgressNetwork      fields.StringInputField `default:""` // VPC egress network name
+	EgressSubnet       fields.StringInputField `default:""` // VPC egress subnet name
+	EgressMode         fields.StringInputField `default:"private-ranges-only"`
+
+	// Outputs
+	Ready         fields.BoolOutputField
+	StatusMessage fields.StringOutputField
+}
+
+func (o *CloudRunJob) ReferenceID() string {
+	return fields.GenerateID("locations/%s/namespaces/%s/jobs/%s", o.Region, o.ProjectID, o.Name)
+}
+
+func (o *CloudRunJob) GetName() string {
+	return fields.VerboseString(o.Name)
+}
+
+// RunURL returns URL of Cloud Run Admin API endpoint that starts job execution, e.g. for Cloud Scheduler.
+func (o *CloudRunJob) RunURL() fields.StringInputField {
+	return fields.Sprintf("https://%s-run.googleapis.com/apis/run.googleapis.com/v1/namespaces/%s/jobs/%s:run", o.Region, o.ProjectID, o.Name)
+}
+
+func (o *CloudRunJob) Read(ctx context.Context, meta any) error {
+	pctx := meta.(*config.PluginContext) //nolint:errcheck
+
+	projectID := o.ProjectID.Any()
+	region := o.Region.Any()
+	name := o.Name.Any()
+
+	cli, err := pctx.GCPRunClient(ctx, region)
+	if err != nil {
+		return err
+	}
+
+	job, err := getRunJob(cli, projectID, name)
+	if ErrIs404(err) {
+		o.MarkAsNew()
+
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("error fetching cloud run job: %w", err)
+	}
+
+	o.MarkAsExisting()
+	o.ProjectID.SetCurrent(projectID)
+	o.Name.SetCurrent(name)
+	o.Region.SetCurrent(region)
+
+	if job.Status != nil {
+		for _, cond := range job.Status.Conditions {
+			if cond.Type != CloudRunReady {
+				continue
+			}
+
+			o.Ready.SetCurrent(cond.Status == CloudRunStatusTrue)
+			o.StatusMessage.SetCurrent(cond.Message)
+		}
+	}
+
+	if job.Spec == nil || job.Spec.Template == nil || job.Spec.Template.Spec == nil || job.Spec.Template.Spec.Template == nil ||
+		job.Spec.Template.Spec.Template.Spec == nil || len(job.Spec.Template.Spec.Template.Spec.Containers) != 1 ||
+		job.Spec.Template.Spec.Template.Spec.Containers[0].Resources == nil {
+		o.Command.UnsetCurrent()
+		o.Args.UnsetCurrent()
+		o.Image.UnsetCurrent()
+		o.CloudSQLInstances.UnsetCurrent()
+		o.CPULimit.UnsetCurrent()
+		o.MemoryLimit.UnsetCurrent()
+		o.TimeoutSeconds.UnsetCurrent()
+		o.MaxRetries.UnsetCurrent()
+		o.TaskCount.UnsetCurrent()
+		o.Parallelism.UnsetCurrent()
+		o.EnvVars.UnsetCurrent()
+		o.ServiceAccountName.UnsetCurrent()
+		o.EgressNetwork.UnsetCurrent()
+		o.EgressSubnet.UnsetCurrent()
+		o.EgressMode.UnsetCurrent()
+
+		return nil
+	}
+
+	var annotations map[string]string
+
+	if job.Spec.Template.Metadata != nil {
+		annotations = job.Spec.Template.Metadata.Annotations
+	}
+
+	execSpec := job.Spec.Template.Spec
+	taskSpec := execSpec.Template.Spec
+	container := taskSpec.Containers[0]
+
+	args := make([]any, len(container.Args))
+	for i, v := range container.Args {
+		args[i] = v
+	}
+
+	command := make([]any, len(container.Command))
+	for i, v := range container.Command {
+		command[i] = v
+	}
+
+	o.Command.SetCurrent(command)
+	o.Args.SetCurrent(args)
+	o.Image.SetCurrent(container.Image)
+	o.CloudSQLInstances.SetCurrent(annotations["run.googleapis.com/cloudsql-instances"])
+	o.CPULimit.SetCurrent(container.Resources.Limits["cpu"])
+	o.MemoryLimit.SetCurrent(container.Resources.Limits["memory"])
+	o.TimeoutSeconds.SetCurrent(int(taskSpec.TimeoutSeconds))
+	o.MaxRetries.SetCurrent(int(taskSpec.MaxRetries))
+	o.TaskCount.SetCurrent(int(execSpec.TaskCount))
+	o.Parallelism.SetCurrent(int(execSpec.Parallelism))
+	o.ServiceAccountName.SetCurrent(taskSpec.ServiceAccountName)
+
+	// If service account is default compute service account and user did not specify anything, unset it.
+	if o.ServiceAccountName.Wanted() == "" && strings.HasSuffix(taskSpec.ServiceAccountName, "-compute@developer.gserviceaccount.com") {
+		o.ServiceAccountName.SetCurrent("")
+	}
+
+	existingNetworkInterfaces := annotations["run.googleapis.com/network-interfaces"]
+	if existingNetworkInterfaces != "" {
+		var interfaces []CloudRunNetworkInterface
+
+		err = json.Unmarshal([]byte(existingNetworkInterfaces), &interfaces)
+		if err == nil && len(interfaces) > 0 {
+			o.EgressNetwork.SetCurrent(interfaces[0].Network)
+			o.EgressSubnet.SetCurrent(interfaces[0].Subnetwork)
+		}
+
+		o.EgressMode.SetCurrent(annotations["run.googleapis.com/vpc-access-egress"])
+	} else {
+		o.EgressNetwork.SetCurrent("")
+		o.EgressSubnet.SetCurrent("")
+		o.EgressMode.SetCurrent(o.EgressMode.Wanted())
+	}
+
+	envVars := make(map[string]any, len(container.Env))
+
+	for _, e := range container.Env {
+		envVars[e.Name] = e.Value
+	}
+
+	o.EnvVars.SetCurrent(envVars)
+
+	return nil
+}
+
+func (o *CloudRunJob) Create(ctx context.Context, meta any) error {
+	pctx := meta.(*config.PluginContext) //nolint:errcheck
+
+	projectID := o.ProjectID.Wanted()
+	region := o.Region.Wanted()
+	name := o.Name.Wanted()
+
+	cli, err := pctx.GCPRunClient(ctx, region)
+	if err != nil {
+		return err
+	}
+
+	_, err = cli.Namespaces.Jobs.Create(fmt.Sprintf("namespaces/%s", projectID), o.makeRunJob()).Do()
+	if err != nil {
+		return err
+	}
+
+	ready, msg, err := waitForRunJobReady(ctx, cli, projectID, name)
+	if err != nil {
+		return err
+	}
+
+	o.Ready.SetCurrent(ready)
+	o.StatusMessage.SetCurrent(msg)
+
+	return nil
+}
+
+func (o *CloudRunJob) Update(ctx context.Context, meta any) error {
+	pctx := meta.(*config.PluginContext) //nolint:errcheck
+
+	projectID := o.ProjectID.Wanted()
+	region := o.Region.Wanted()
+	name := o.Name.Wanted()
+
+	cli, err := pctx.GCPRunClient(ctx, region)
+	if err != nil {
+		return err
+	}
+
+	_, err = cli.Namespaces.Jobs.ReplaceJob(fmt.Sprintf("namespaces/%s/jobs/%s", projectID, name), o.makeRunJob()).Do()
+	if err != nil {
+		return err
+	}
+
+	ready, msg, err := waitForRunJobReady(ctx, cli, projectID, name)
+	if err != nil {
+		return err
+	}
+
+	o.Ready.SetCurrent(ready)
+	o.StatusMessage.SetCurrent(msg)
+
+	return nil
+}
+
+func (o *CloudRunJob) Delete(ctx context.Context, meta any) error {
+	pctx := meta.(*config.PluginContext) //nolint:errcheck
+
+	projectID := o.ProjectID.Current()
+	region := o.Region.Current()
+	name := o.Name.Current()
+
+	cli, err := pctx.GCPRunClient(ctx, region)
+	if err != nil {
+		return err
+	}
+
+	_, err = cli.Namespaces.Jobs.Delete(fmt.Sprintf("namespaces/%s/jobs/%s", projectID, name)).Do()
+	if ErrIs404(err) {
+		return nil
+	}
+
+	return err
+}
+
+func (o *CloudRunJob) makeRunJob() *run.Job {
+	var envVars []*run.EnvVar
+	for k, v := range o.EnvVars.Wanted() {
+		envVars = append(envVars, &run.EnvVar{Name: k, Value: v.(string)}) //nolint:errcheck
+	}
+
+	command := o.Command.Wanted()
+	commandStr := make([]string, len(command))
+
+	for i, v := range command {
+		commandStr[i] = v.(string) //nolint:errcheck
+	}
+
+	args := o.Args.Wanted()
+	argsStr := make([]string, len(args))
+
+	for i, v := range args {
+		argsStr[i] = v.(string) //nolint:errcheck
+	}
+
+	annotations := map[string]string{
+		"run.googleapis.com/client-name":        "outblocks",
+		"run.googleapis.com/cloudsql-instances": o.CloudSQLInstances.Wanted(),
+	}
+
+	if o.EgressNetwork.Wanted() != "" && o.EgressSubnet.Wanted() != "" {
+		ifaces := []CloudRunNetworkInterface{{
+			Network:    o.EgressNetwork.Wanted(),
+			Subnetwork: o.EgressSubnet.Wanted(),
+		}}
+
+		ifacesJSON, _ := json.Marshal(ifaces)
+
+		annotations["run.googleapis.com/network-interfaces"] = string(ifacesJSON)
+		annotations["run.googleapis.com/vpc-access-egress"] = o.EgressMode.Wanted()
+	}
+
+	return &run.Job{
+		ApiVersion: "run.googleapis.com/v1",
+		Kind:       "Job",
+		Metadata: &run.ObjectMeta{
+			Name: o.Name.Wanted(),
+			Annotations: map[string]string{
+				"run.googleapis.com/launch-stage": "GA",
+			},
+		},
+		Spec: &run.JobSpec{
+			Template: &run.ExecutionTemplateSpec{
+				Metadata: &run.ObjectMeta{
+					Annotations: annotations,
+				},
+				Spec: &run.ExecutionSpec{
+					Parallelism: int64(o.Parallelism.Wanted()),
+					TaskCount:   int64(o.TaskCount.Wanted()),
+					Template: &run.TaskTemplateSpec{
+						Spec: &run.TaskSpec{
+							ServiceAccountName: o.ServiceAccountName.Wanted(),
+							TimeoutSeconds:     int64(o.TimeoutSeconds.Wanted()),
+							MaxRetries:         int64(o.MaxRetries.Wanted()),
+							ForceSendFields:    []string{"MaxRetries"},
+							Containers: []*run.Container{
+								{
+									Command: commandStr,
+									Args:    argsStr,
+									Image:   o.Image.Wanted(),
+									Env:     envVars,
+									Resources: &run.ResourceRequirements{
+										Limits: map[string]string{
+											"cpu":    o.CPULimit.Wanted(),
+											"memory": o.MemoryLimit.Wanted(),
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func getRunJob(cli *run.APIService, project, name string) (*run.Job, error) {
+	return cli.Namespaces.Jobs.Get(fmt.Sprintf("namespaces/%s/jobs/%s", project, name)).Do()
+}
+
+func waitForRunJobReady(ctx context.Context, cli *run.APIService, project, name string) (ready bool, msg string, err error) {
+	t := time.NewTicker(time.Second * 5)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false, "", ctx.Err()
+		case <-t.C:
+			job, err := getRunJob(cli, project, name)
+			if err != nil {
+				return false, "", fmt.Errorf("failed to query run job for readiness: %w", err)
+			}
+
+			if job.Metadata == nil || job.Status == nil || job.Status.ObservedGeneration != job.Metadata.Generation {
+				continue
+			}
+
+			for _, c := range job.Status.Conditions {
+				if c.Type == CloudRunReady {
+					switch c.Status {
+					case CloudRunStatusTrue:
+						return true, "", nil
+					case CloudRunStatusFalse:
+						return false, c.Message, nil
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/monitoring/apiv3/v2/monitoringpb"
+	"github.com/outblocks/cli-plugin-gcp/internal/config"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/run/v1"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// CloudRunRollout describes progressive rollout of new revision. Traffic of new revision is increased step by step
+// and each step is gated on service readiness and error rate of new revision.
+type CloudRunRollout struct {
+	Steps        []int
+	Interval     time.Duration
+	MaxErrorRate float64
+}
+
+func (o *CloudRun) isTrafficManaged() bool {
+	if o.Rollout != nil && len(o.Rollout.Steps) > 0 {
+		return true
+	}
+
+	percent, ok := o.TrafficPercent.LookupWanted()
+
+	return ok && percent < 100
+}
+
+func (o *CloudRun) trafficSteps() []int {
+	final := 100
+
+	if percent, ok := o.TrafficPercent.LookupWanted(); ok {
+		final = percent
+	}
+
+	var steps []int
+
+	if o.Rollout != nil {
+		for _, s := range o.Rollout.Steps {
+			if s < final {
+				steps = append(steps, s)
+			}
+		}
+	}
+
+	return append(steps, final)
+}
+
+// updateWithTraffic deploys new revision without any traffic and then shifts traffic from previous revision
+// to the new one, optionally step by step. If any of rollout steps fail, traffic is reverted to previous revision.
+func (o *CloudRun) updateWithTraffic(ctx context.Context, pctx *config.PluginContext, cli *run.APIService) error {
+	projectID := o.ProjectID.Wanted()
+	name := o.Name.Wanted()
+
+	cur, err := getRunService(cli, projectID, name)
+	if err != nil {
+		return fmt.Errorf("error fetching cloud run service: %w", err)
+	}
+
+	latestBefore := cur.Status.LatestReadyRevisionName
+
+	// Deploy new revision while keeping traffic on currently serving revisions.
+	svc := o.makeRunService()
+	svc.Spec.Traffic = pinRunServiceTraffic(cur.Spec.Traffic, latestBefore)
+
+	_, err = updateRunService(cli, projectID, name, svc)
+	if err != nil {
+		return err
+	}
+
+	svc, ready, msg, err := waitForRunServiceReady(ctx, cli, projectID, name)
+	if err != nil {
+		return err
+	}
+
+	o.URL.SetCurrent(svc.Status.Url)
+
+	if !ready {
+		o.Ready.SetCurrent(ready)
+		o.StatusMessage.SetCurrent(msg)
+
+		return nil
+	}
+
+	latest := svc.Status.LatestReadyRevisionName
+	previous := latestBefore
+
+	if latest == latestBefore {
+		previous = o.PreviousRevision.Current()
+	}
+
+	if previous == "" || previous == latest {
+		// Nothing to split traffic with.
+		return o.setTraffic(ctx, cli, latest, "", 100)
+	}
+
+	steps := o.trafficSteps()
+
+	for i, percent := range steps {
+		err = o.setTraffic(ctx, cli, latest, previous, percent)
+		if err != nil {
+			return err
+		}
+
+		if o.Rollout == nil || len(steps) == 1 {
+			break
+		}
+
+		err = o.checkRolloutStep(ctx, pctx, cli, latest)
+		if err != nil {
+			revertErr := o.setTraffic(ctx, cli, previous, "", 100)
+			if revertErr != nil {
+				return fmt.Errorf("rollout of revision '%s' failed at %d%%: %w, reverting to revision '%s' failed: %w", latest, percent, err, previous, revertErr)
+			}
+
+			o.Ready.SetCurrent(false)
+			o.StatusMessage.SetCurrent(err.Error())
+
+			return fmt.Errorf("rollout of revision '%s' failed at %d%% (step %d of %d): %w, traffic reverted to revision '%s'", latest, percent, i+1, len(steps), err, previous)
+		}
+	}
+
+	o.PreviousRevision.SetCurrent(previous)
+
+	return nil
+}
+
+// setTraffic routes percent of traffic to revision and rest of it to other revision.
+func (o *CloudRun) setTraffic(ctx context.Context, cli *run.APIService, revision, other string, percent int) error {
+	projectID := o.ProjectID.Wanted()
+	name := o.Name.Wanted()
+
+	svc, err := getRunService(cli, projectID, name)
+	if err != nil {
+		return fmt.Errorf("error fetching cloud run service: %w", err)
+	}
+
+	switch {
+	case percent >= 100 && revision == svc.Status.LatestReadyRevisionName:
+		svc.Spec.Traffic = []*run.TrafficTarget{{Percent: 100, LatestRevision: true}}
+	case percent >= 100 || other == "":
+		svc.Spec.Traffic = []*run.TrafficTarget{{Percent: 100, RevisionName: revision}}
+	default:
+		svc.Spec.Traffic = []*run.TrafficTarget{
+			{Percent: int64(percent), RevisionName: revision},
+			{Percent: int64(100 - percent), RevisionName: other},
+		}
+	}
+
+	_, err = updateRunService(cli, projectID, name, svc)
+	if err != nil {
+		return err
+	}
+
+	svc, ready, msg, err := waitForRunServiceReady(ctx, cli, projectID, name)
+	if err != nil {
+		return err
+	}
+
+	o.Ready.SetCurrent(ready)
+	o.StatusMessage.SetCurrent(msg)
+	o.URL.SetCurrent(svc.Status.Url)
+	o.TrafficPercent.SetCurrent(runServiceLatestTrafficPercent(svc))
+
+	return nil
+}
+
+// checkRolloutStep waits for rollout interval and checks if service is still ready and if new revision error rate
+// is within allowed limit.
+func (o *CloudRun) checkRolloutStep(ctx context.Context, pctx *config.PluginContext, cli *run.APIService, revision string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(o.Rollout.Interval):
+	}
+
+	svc, err := getRunService(cli, o.ProjectID.Wanted(), o.Name.Wanted())
+	if err != nil {
+		return fmt.Errorf("failed to query run service for readiness: %w", err)
+	}
+
+	for _, c := range svc.Status.Conditions {
+		if c.Type == CloudRunReady && c.Status != CloudRunStatusTrue {
+			return fmt.Errorf("service is not ready: %s", c.Message)
+		}
+	}
+
+	if o.Rollout.MaxErrorRate <= 0 {
+		return nil
+	}
+
+	rate, err := o.revisionErrorRate(ctx, pctx, revision, o.Rollout.Interval)
+	if err != nil {
+		return err
+	}
+
+	if rate > o.Rollout.MaxErrorRate {
+		return fmt.Errorf("error rate %.2f%% exceeds allowed %.2f%%", rate*100, o.Rollout.MaxErrorRate*100)
+	}
+
+	return nil
+}
+
+// revisionErrorRate returns ratio of 5xx responses to all requests served by revision in given period.
+func (o *CloudRun) revisionErrorRate(ctx context.Context, pctx *config.PluginContext, revision string, period time.Duration) (float64, error) {
+	cli, err := pctx.GCPMonitoringMetricClient(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if period < time.Minute {
+		period = time.Minute
+	}
+
+	now := time.Now()
+
+	it := cli.ListTimeSeries(ctx, &monitoringpb.ListTimeSeriesRequest{
+		Name: fmt.Sprintf("projects/%s", o.ProjectID.Wanted()),
+		Filter: fmt.Sprintf(`metric.type = "run.googleapis.com/request_count" AND resource.labels.service_name = "%s" AND resource.labels.revision_name = "%s" AND resource.labels.location = "%s"`,
+			o.Name.Wanted(), revision, o.Region.Wanted()),
+		Interval: &monitoringpb.TimeInterval{
+			StartTime: timestamppb.New(now.Add(-period)),
+			EndTime:   timestamppb.New(now),
+		},
+		Aggregation: &monitoringpb.Aggregation{
+			AlignmentPeriod:    durationpb.New(period),
+			PerSeriesAligner:   monitoringpb.Aggregation_ALIGN_DELTA,
+			CrossSeriesReducer: monitoringpb.Aggregation_REDUCE_SUM,
+			GroupByFields:      []string{"metric.labels.response_code_class"},
+		},
+		View: monitoringpb.ListTimeSeriesRequest_FULL,
+	})
+
+	var total, failed int64
+
+	for {
+		ts, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+
+		if err != nil {
+			return 0, fmt.Errorf("error fetching request metrics of revision '%s': %w", revision, err)
+		}
+
+		var count int64
+
+		for _, p := range ts.Points {
+			count += p.GetValue().GetInt64Value()
+		}
+
+		total += count
+
+		if ts.GetMetric().GetLabels()["response_code_class"] == "5xx" {
+			failed += count
+		}
+	}
+
+	if total == 0 {
+		return 0, nil
+	}
+
+	return float64(failed) / float64(total), nil
+}
+
+// pinRunServiceTraffic replaces latest revision traffic targets with specified revision so that newly created revision
+// does not receive any traffic.
+func pinRunServiceTraffic(traffic []*run.TrafficTarget, revision string) []*run.TrafficTarget {
+	if revision == "" {
+		return []*run.TrafficTarget{{Percent: 100, LatestRevision: true}}
+	}
+
+	ret := make([]*run.TrafficTarget, 0, len(traffic))
+
+	for _, t := range traffic {
+		if t.Percent == 0 {
+			continue
+		}
+
+		target := &run.TrafficTarget{Percent: t.Percent, RevisionName: t.RevisionName}
+
+		if t.LatestRevision {
+			target.RevisionName = revision
+		}
+
+		ret = append(ret, target)
+	}
+
+	if len(ret) == 0 {
+		return []*run.TrafficTarget{{Percent: 100, RevisionName: revision}}
+	}
+
+	return ret
+}
+
+func runServiceLatestTrafficPercent(svc *run.Service) int {
+	var percent int64
+
+	latest := svc.Status.LatestReadyRevisionName
+
+	for _, t := range svc.Status.Traffic {
+		if t.LatestRevision || (latest != "" && t.RevisionName == latest) {
+			percent += t.Percent
+		}
+	}
+
+	if len(svc.Status.Traffic) == 0 {
+		return 100
+	}
+
+	return int(percent)
+}
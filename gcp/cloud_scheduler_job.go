@@ -20,6 +20,9 @@ type CloudSchedulerJob struct {
 	HTTPMethod  fields.StringInputField `state:"force_new" default:"GET"`
 	HTTPURL     fields.StringInputField `state:"force_new"`
 	HTTPHeaders fields.MapInputField    `state:"force_new"`
+
+	// Service account used to generate OAuth token, required when targeting Google APIs.
+	HTTPOAuthServiceAccount fields.StringInputField `state:"force_new"`
 }
 
 func (o *CloudSchedulerJob) ReferenceID() string {
@@ -79,6 +82,12 @@ func (o *CloudSchedulerJob) Read(ctx context.Context, meta any) error {
 
 	o.HTTPHeaders.SetCurrent(headers)
 
+	if job.HttpTarget.OauthToken != nil {
+		o.HTTPOAuthServiceAccount.SetCurrent(job.HttpTarget.OauthToken.ServiceAccountEmail)
+	} else {
+		o.HTTPOAuthServiceAccount.UnsetCurrent()
+	}
+
 	return nil
 }
 
@@ -114,14 +123,22 @@ func (o *CloudSchedulerJob) makeJob() *cloudscheduler.Job {
 
 	id := fmt.Sprintf("projects/%s/locations/%s/jobs/%s", o.ProjectID.Wanted(), o.Region.Wanted(), o.Name.Wanted())
 
+	target := &cloudscheduler.HttpTarget{
+		HttpMethod: o.HTTPMethod.Wanted(),
+		Uri:        o.HTTPURL.Wanted(),
+		Headers:    headers,
+	}
+
+	if sa := o.HTTPOAuthServiceAccount.Wanted(); sa != "" {
+		target.OauthToken = &cloudscheduler.OAuthToken{
+			ServiceAccountEmail: sa,
+		}
+	}
+
 	return &cloudscheduler.Job{
-		Name:     id,
-		Schedule: o.Schedule.Wanted(),
-		HttpTarget: &cloudscheduler.HttpTarget{
-			HttpMethod: o.HTTPMethod.Wanted(),
-			Uri:        o.HTTPURL.Wanted(),
-			Headers:    headers,
-		},
+		Name:       id,
+		Schedule:   o.Schedule.Wanted(),
+		HttpTarget: target,
 	}
 }
 
@@ -1,7 +1,7 @@
 package gcp
 
 var (
-	APISRequired = []string{"run.googleapis.com", "artifactregistry.googleapis.com", "compute.googleapis.com", "sqladmin.googleapis.com", "secretmanager.googleapis.com", "cloudresourcemanager.googleapis.com", "cloudfunctions.googleapis.com", "monitoring.googleapis.com", "cloudbuild.googleapis.com", "serviceusage.googleapis.com"}
+	APISRequired = []string{"run.googleapis.com", "artifactregistry.googleapis.com", "compute.googleapis.com", "sqladmin.googleapis.com", "secretmanager.googleapis.com", "cloudresourcemanager.googleapis.com", "cloudfunctions.googleapis.com", "eventarc.googleapis.com", "pubsub.googleapis.com", "redis.googleapis.com", "monitoring.googleapis.com", "cloudbuild.googleapis.com", "serviceusage.googleapis.com"}
 	ValidRegions = []string{"asia-east1", "asia-east2", "asia-northeast1", "asia-northeast2", "asia-northeast3", "asia-south1", "asia-southeast1", "australia-southeast1", "europe-north1", "europe-west1", "europe-west2", "europe-west3", "europe-west4", "europe-west6", "northamerica-northeast1", "southamerica-east1", "us-central1", "us-east1", "us-east4", "us-west1", "us-west2", "us-west3"}
 )
 
@@ -21,6 +21,14 @@ const (
 	CloudFunctionReady   = "ACTIVE"
 	CloudFunctionOffline = "OFFLINE"
 
+	CloudFunctionGen1                      = "GEN_1"
+	CloudFunctionGen2                      = "GEN_2"
+	CloudFunctionRetryPolicyRetry          = "RETRY_POLICY_RETRY"
+	CloudFunctionRetryPolicyDoNotRetry     = "RETRY_POLICY_DO_NOT_RETRY"
+	CloudFunctionUpgradeSetupSuccessful    = "SETUP_FUNCTION_UPGRADE_CONFIG_SUCCESSFUL"
+	CloudFunctionUpgradeRedirectSuccessful = "REDIRECT_FUNCTION_UPGRADE_TRAFFIC_SUCCESSFUL"
+	CloudFunctionEventFilterPathPattern    = "match-path-pattern"
+
 	GCSProxyImageName   = "nginx-gcs-static-proxy"
 	GCSProxyVersion     = "1.21-v5"
 	GCSProxyDockerImage = "docker.io/outblocks/nginx-gcs-static-proxy:" + GCSProxyVersion
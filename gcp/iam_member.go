@@ -0,0 +1,112 @@
+package gcp
+
+import (
+	"sync"
+
+	plugin_util "github.com/outblocks/outblocks-plugin-go/util"
+)
+
+// iamMemberMu serializes read-modify-write of iam policies so that members added concurrently are not lost.
+var iamMemberMu sync.Mutex
+
+// iamBinding is API independent role binding of iam policy.
+type iamBinding struct {
+	Role    string
+	Members []string
+
+	// condition is API specific binding condition, kept as is.
+	condition any
+}
+
+// iamPolicyFuncs reads and writes bindings of iam policy of a single resource.
+type iamPolicyFuncs struct {
+	// get fetches policy bindings.
+	get func() ([]*iamBinding, error)
+	// set replaces bindings of last fetched policy.
+	set func([]*iamBinding) error
+}
+
+// hasIAMMember checks if member is granted role, missing resource is reported as error.
+func hasIAMMember(p iamPolicyFuncs, role, member string) (bool, error) {
+	bindings, err := p.get()
+	if err != nil {
+		return false, err
+	}
+
+	for _, b := range bindings {
+		if b.Role == role && plugin_util.StringSliceContains(b.Members, member) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// addIAMMember grants role to member, leaving other bindings intact.
+func addIAMMember(p iamPolicyFuncs, role, member string) error {
+	iamMemberMu.Lock()
+	defer iamMemberMu.Unlock()
+
+	bindings, err := p.get()
+	if err != nil {
+		return err
+	}
+
+	added := false
+
+	for _, b := range bindings {
+		if b.Role != role {
+			continue
+		}
+
+		if !plugin_util.StringSliceContains(b.Members, member) {
+			b.Members = append(b.Members, member)
+		}
+
+		added = true
+	}
+
+	if !added {
+		bindings = append(bindings, &iamBinding{
+			Role:    role,
+			Members: []string{member},
+		})
+	}
+
+	return p.set(bindings)
+}
+
+// removeIAMMember revokes role from member, dropping bindings left without members.
+func removeIAMMember(p iamPolicyFuncs, role, member string) error {
+	iamMemberMu.Lock()
+	defer iamMemberMu.Unlock()
+
+	bindings, err := p.get()
+	if err != nil {
+		return err
+	}
+
+	ret := make([]*iamBinding, 0, len(bindings))
+
+	for _, b := range bindings {
+		if b.Role == role {
+			members := make([]string, 0, len(b.Members))
+
+			for _, m := range b.Members {
+				if m != member {
+					members = append(members, m)
+				}
+			}
+
+			if len(members) == 0 {
+				continue
+			}
+
+			b.Members = members
+		}
+
+		ret = append(ret, b)
+	}
+
+	return p.set(ret)
+}
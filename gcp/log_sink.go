@@ -0,0 +1,309 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/outblocks/cli-plugin-gcp/internal/config"
+	"github.com/outblocks/outblocks-plugin-go/registry"
+	"github.com/outblocks/outblocks-plugin-go/registry/fields"
+	"google.golang.org/api/bigquery/v2"
+	loggingconfig "google.golang.org/api/logging/v2"
+)
+
+const (
+	LogSinkStorageDestinationPrefix  = "storage.googleapis.com/"
+	LogSinkBigQueryDestinationPrefix = "bigquery.googleapis.com/"
+
+	logSinkStorageWriterRole  = "roles/storage.objectCreator"
+	logSinkBigQueryWriterRole = "WRITER"
+)
+
+// LogSink routes log entries matching filter to GCS bucket or BigQuery dataset.
+// Sink writer identity is granted write access to destination when sink is created and revoked when it is deleted.
+type LogSink struct {
+	registry.ResourceBase
+
+	Name        fields.StringInputField `state:"force_new"`
+	ProjectID   fields.StringInputField `state:"force_new"`
+	Destination fields.StringInputField // e.g. storage.googleapis.com/<bucket> or bigquery.googleapis.com/projects/<project>/datasets/<dataset>
+	Filter      fields.StringInputField
+
+	WriterIdentity fields.StringOutputField
+}
+
+func (o *LogSink) ReferenceID() string {
+	return fields.GenerateID("projects/%s/sinks/%s", o.ProjectID, o.Name)
+}
+
+func (o *LogSink) GetName() string {
+	return fields.VerboseString(o.Name)
+}
+
+func (o *LogSink) Read(ctx context.Context, meta any) error {
+	pctx := meta.(*config.PluginContext) //nolint:errcheck
+
+	projectID := o.ProjectID.Any()
+	name := o.Name.Any()
+
+	cli, err := pctx.GCPLoggingConfigClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	sink, err := cli.Projects.Sinks.Get(LogSinkID(projectID, name)).Do()
+	if ErrIs404(err) {
+		o.MarkAsNew()
+
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("error fetching log sink: %w", err)
+	}
+
+	o.MarkAsExisting()
+	o.ProjectID.SetCurrent(projectID)
+	o.Name.SetCurrent(name)
+	o.Destination.SetCurrent(sink.Destination)
+	o.Filter.SetCurrent(sink.Filter)
+	o.WriterIdentity.SetCurrent(sink.WriterIdentity)
+
+	return nil
+}
+
+func (o *LogSink) makeSink() *loggingconfig.LogSink {
+	sink := &loggingconfig.LogSink{
+		Name:        o.Name.Wanted(),
+		Destination: o.Destination.Wanted(),
+		Filter:      o.Filter.Wanted(),
+	}
+
+	// BigQuery destination gets date-sharded tables, so that whole days of log entries can be expired with tables.
+	if strings.HasPrefix(sink.Destination, LogSinkBigQueryDestinationPrefix) {
+		sink.BigqueryOptions = &loggingconfig.BigQueryOptions{
+			UsePartitionedTables: false,
+			ForceSendFields:      []string{"UsePartitionedTables"},
+		}
+	}
+
+	return sink
+}
+
+func (o *LogSink) Create(ctx context.Context, meta any) error {
+	pctx := meta.(*config.PluginContext) //nolint:errcheck
+
+	cli, err := pctx.GCPLoggingConfigClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	sink, err := cli.Projects.Sinks.Create(fmt.Sprintf("projects/%s", o.ProjectID.Wanted()), o.makeSink()).UniqueWriterIdentity(true).Do()
+	if err != nil {
+		return fmt.Errorf("error creating log sink: %w", err)
+	}
+
+	o.WriterIdentity.SetCurrent(sink.WriterIdentity)
+
+	return grantLogSinkWriter(ctx, pctx, sink.Destination, sink.WriterIdentity)
+}
+
+func (o *LogSink) Update(ctx context.Context, meta any) error {
+	pctx := meta.(*config.PluginContext) //nolint:errcheck
+
+	cli, err := pctx.GCPLoggingConfigClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	sink, err := cli.Projects.Sinks.Patch(LogSinkID(o.ProjectID.Wanted(), o.Name.Wanted()), o.makeSink()).
+		UniqueWriterIdentity(true).UpdateMask("destination,filter").Do()
+	if err != nil {
+		return fmt.Errorf("error updating log sink: %w", err)
+	}
+
+	o.WriterIdentity.SetCurrent(sink.WriterIdentity)
+
+	if cur := o.Destination.Current(); cur != sink.Destination {
+		err = revokeLogSinkWriter(ctx, pctx, cur, sink.WriterIdentity)
+		if err != nil {
+			return err
+		}
+	}
+
+	return grantLogSinkWriter(ctx, pctx, sink.Destination, sink.WriterIdentity)
+}
+
+func (o *LogSink) Delete(ctx context.Context, meta any) error {
+	pctx := meta.(*config.PluginContext) //nolint:errcheck
+
+	cli, err := pctx.GCPLoggingConfigClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = cli.Projects.Sinks.Delete(LogSinkID(o.ProjectID.Current(), o.Name.Current())).Do()
+	if err != nil && !ErrIs404(err) {
+		return fmt.Errorf("error deleting log sink: %w", err)
+	}
+
+	return revokeLogSinkWriter(ctx, pctx, o.Destination.Current(), o.WriterIdentity.Current())
+}
+
+func LogSinkID(projectID, name string) string {
+	return fmt.Sprintf("projects/%s/sinks/%s", projectID, name)
+}
+
+// parseBigQueryDestination returns project and dataset of BigQuery log sink destination.
+func parseBigQueryDestination(destination string) (project, dataset string, err error) {
+	parts := strings.Split(strings.TrimPrefix(destination, LogSinkBigQueryDestinationPrefix), "/")
+
+	if len(parts) != 4 || parts[0] != "projects" || parts[2] != "datasets" {
+		return "", "", fmt.Errorf("invalid bigquery log sink destination: %s", destination)
+	}
+
+	return parts[1], parts[3], nil
+}
+
+func grantLogSinkWriter(ctx context.Context, pctx *config.PluginContext, destination, identity string) error {
+	if identity == "" {
+		return nil
+	}
+
+	switch {
+	case strings.HasPrefix(destination, LogSinkStorageDestinationPrefix):
+		cli, err := pctx.GCPStorageClient(ctx)
+		if err != nil {
+			return err
+		}
+
+		handle := cli.Bucket(strings.TrimPrefix(destination, LogSinkStorageDestinationPrefix)).IAM()
+
+		policy, err := handle.Policy(ctx)
+		if err != nil {
+			return fmt.Errorf("error fetching log sink bucket iam policy: %w", err)
+		}
+
+		if policy.HasRole(identity, logSinkStorageWriterRole) {
+			return nil
+		}
+
+		policy.Add(identity, logSinkStorageWriterRole)
+
+		err = handle.SetPolicy(ctx, policy)
+		if err != nil {
+			return fmt.Errorf("error setting log sink bucket iam policy: %w", err)
+		}
+
+	case strings.HasPrefix(destination, LogSinkBigQueryDestinationPrefix):
+		project, dataset, err := parseBigQueryDestination(destination)
+		if err != nil {
+			return err
+		}
+
+		cli, err := pctx.GCPBigQueryClient(ctx)
+		if err != nil {
+			return err
+		}
+
+		ds, err := cli.Datasets.Get(project, dataset).Do()
+		if err != nil {
+			return fmt.Errorf("error fetching log sink dataset: %w", err)
+		}
+
+		email := strings.TrimPrefix(identity, "serviceAccount:")
+
+		for _, a := range ds.Access {
+			if a.Role == logSinkBigQueryWriterRole && a.UserByEmail == email {
+				return nil
+			}
+		}
+
+		_, err = cli.Datasets.Patch(project, dataset, &bigquery.Dataset{
+			Access: append(ds.Access, &bigquery.DatasetAccess{Role: logSinkBigQueryWriterRole, UserByEmail: email}),
+		}).Do()
+		if err != nil {
+			return fmt.Errorf("error granting log sink access to dataset: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// revokeLogSinkWriter removes write access of sink writer identity, missing destination is ignored.
+func revokeLogSinkWriter(ctx context.Context, pctx *config.PluginContext, destination, identity string) error {
+	if identity == "" {
+		return nil
+	}
+
+	switch {
+	case strings.HasPrefix(destination, LogSinkStorageDestinationPrefix):
+		cli, err := pctx.GCPStorageClient(ctx)
+		if err != nil {
+			return err
+		}
+
+		handle := cli.Bucket(strings.TrimPrefix(destination, LogSinkStorageDestinationPrefix)).IAM()
+
+		policy, err := handle.Policy(ctx)
+		if ErrIs404(err) {
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("error fetching log sink bucket iam policy: %w", err)
+		}
+
+		if !policy.HasRole(identity, logSinkStorageWriterRole) {
+			return nil
+		}
+
+		policy.Remove(identity, logSinkStorageWriterRole)
+
+		err = handle.SetPolicy(ctx, policy)
+		if err != nil && !ErrIs404(err) {
+			return fmt.Errorf("error setting log sink bucket iam policy: %w", err)
+		}
+
+	case strings.HasPrefix(destination, LogSinkBigQueryDestinationPrefix):
+		project, dataset, err := parseBigQueryDestination(destination)
+		if err != nil {
+			return err
+		}
+
+		cli, err := pctx.GCPBigQueryClient(ctx)
+		if err != nil {
+			return err
+		}
+
+		ds, err := cli.Datasets.Get(project, dataset).Do()
+		if ErrIs404(err) {
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("error fetching log sink dataset: %w", err)
+		}
+
+		email := strings.TrimPrefix(identity, "serviceAccount:")
+		access := make([]*bigquery.DatasetAccess, 0, len(ds.Access))
+
+		for _, a := range ds.Access {
+			if a.Role != logSinkBigQueryWriterRole || a.UserByEmail != email {
+				access = append(access, a)
+			}
+		}
+
+		if len(access) == len(ds.Access) {
+			return nil
+		}
+
+		_, err = cli.Datasets.Patch(project, dataset, &bigquery.Dataset{Access: access}).Do()
+		if err != nil && !ErrIs404(err) {
+			return fmt.Errorf("error revoking log sink access to dataset: %w", err)
+		}
+	}
+
+	return nil
+}
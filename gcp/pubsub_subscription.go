@@ -0,0 +1,182 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/outblocks/cli-plugin-gcp/internal/config"
+	"github.com/outblocks/outblocks-plugin-go/registry"
+	"github.com/outblocks/outblocks-plugin-go/registry/fields"
+	"google.golang.org/api/pubsub/v1"
+)
+
+type PubSubSubscription struct {
+	registry.ResourceBase
+
+	Name      fields.StringInputField `state:"force_new"`
+	ProjectID fields.StringInputField `state:"force_new"`
+	Topic     fields.StringInputField `state:"force_new"`
+	Filter    fields.StringInputField `state:"force_new"`
+
+	AckDeadline      fields.IntInputField `default:"10"`
+	MessageRetention fields.IntInputField `default:"604800"`
+
+	DeadLetterTopic     fields.StringInputField
+	MaxDeliveryAttempts fields.IntInputField `default:"5"`
+
+	// Push delivery, pull subscription is created when push endpoint is empty.
+	PushEndpoint       fields.StringInputField
+	PushServiceAccount fields.StringInputField
+}
+
+func (o *PubSubSubscription) ReferenceID() string {
+	return fields.GenerateID("projects/%s/subscriptions/%s", o.ProjectID, o.Name)
+}
+
+func (o *PubSubSubscription) GetName() string {
+	return fields.VerboseString(o.Name)
+}
+
+func (o *PubSubSubscription) Read(ctx context.Context, meta any) error {
+	pctx := meta.(*config.PluginContext) //nolint:errcheck
+
+	projectID := o.ProjectID.Any()
+	name := o.Name.Any()
+
+	cli, err := pctx.GCPPubSubClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	sub, err := cli.Projects.Subscriptions.Get(PubSubSubscriptionID(projectID, name)).Do()
+	if ErrIs404(err) {
+		o.MarkAsNew()
+
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("error fetching pubsub subscription: %w", err)
+	}
+
+	o.MarkAsExisting()
+	o.ProjectID.SetCurrent(projectID)
+	o.Name.SetCurrent(name)
+	o.Topic.SetCurrent(pubSubTopicName(sub.Topic))
+	o.Filter.SetCurrent(sub.Filter)
+	o.AckDeadline.SetCurrent(int(sub.AckDeadlineSeconds))
+	o.MessageRetention.SetCurrent(parseDurationSeconds(sub.MessageRetentionDuration))
+
+	if sub.DeadLetterPolicy != nil {
+		o.DeadLetterTopic.SetCurrent(pubSubTopicName(sub.DeadLetterPolicy.DeadLetterTopic))
+		o.MaxDeliveryAttempts.SetCurrent(int(sub.DeadLetterPolicy.MaxDeliveryAttempts))
+	} else {
+		o.DeadLetterTopic.SetCurrent("")
+		o.MaxDeliveryAttempts.UnsetCurrent()
+	}
+
+	if sub.PushConfig != nil {
+		o.PushEndpoint.SetCurrent(sub.PushConfig.PushEndpoint)
+
+		if sub.PushConfig.OidcToken != nil {
+			o.PushServiceAccount.SetCurrent(sub.PushConfig.OidcToken.ServiceAccountEmail)
+		} else {
+			o.PushServiceAccount.SetCurrent("")
+		}
+	} else {
+		o.PushEndpoint.SetCurrent("")
+		o.PushServiceAccount.SetCurrent("")
+	}
+
+	return nil
+}
+
+func (o *PubSubSubscription) makeSubscription() *pubsub.Subscription {
+	projectID := o.ProjectID.Wanted()
+
+	sub := &pubsub.Subscription{
+		Name:                     PubSubSubscriptionID(projectID, o.Name.Wanted()),
+		Topic:                    PubSubTopicID(projectID, o.Topic.Wanted()),
+		Filter:                   o.Filter.Wanted(),
+		AckDeadlineSeconds:       int64(o.AckDeadline.Wanted()),
+		MessageRetentionDuration: fmt.Sprintf("%ds", o.MessageRetention.Wanted()),
+		PushConfig:               &pubsub.PushConfig{},
+	}
+
+	if topic := o.DeadLetterTopic.Wanted(); topic != "" {
+		sub.DeadLetterPolicy = &pubsub.DeadLetterPolicy{
+			DeadLetterTopic:     PubSubTopicID(projectID, topic),
+			MaxDeliveryAttempts: int64(o.MaxDeliveryAttempts.Wanted()),
+		}
+	}
+
+	if endpoint := o.PushEndpoint.Wanted(); endpoint != "" {
+		sub.PushConfig.PushEndpoint = endpoint
+
+		if sa := o.PushServiceAccount.Wanted(); sa != "" {
+			sub.PushConfig.OidcToken = &pubsub.OidcToken{
+				ServiceAccountEmail: sa,
+			}
+		}
+	}
+
+	return sub
+}
+
+func (o *PubSubSubscription) Create(ctx context.Context, meta any) error {
+	pctx := meta.(*config.PluginContext) //nolint:errcheck
+
+	cli, err := pctx.GCPPubSubClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	sub := o.makeSubscription()
+
+	_, err = cli.Projects.Subscriptions.Create(sub.Name, sub).Do()
+
+	return err
+}
+
+func (o *PubSubSubscription) Update(ctx context.Context, meta any) error {
+	pctx := meta.(*config.PluginContext) //nolint:errcheck
+
+	cli, err := pctx.GCPPubSubClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	sub := o.makeSubscription()
+
+	_, err = cli.Projects.Subscriptions.Patch(sub.Name, &pubsub.UpdateSubscriptionRequest{
+		Subscription: sub,
+		UpdateMask:   "ack_deadline_seconds,message_retention_duration,dead_letter_policy,push_config",
+	}).Do()
+
+	return err
+}
+
+func (o *PubSubSubscription) Delete(ctx context.Context, meta any) error {
+	pctx := meta.(*config.PluginContext) //nolint:errcheck
+
+	cli, err := pctx.GCPPubSubClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = cli.Projects.Subscriptions.Delete(PubSubSubscriptionID(o.ProjectID.Current(), o.Name.Current())).Do()
+	if ErrIs404(err) {
+		return nil
+	}
+
+	return err
+}
+
+func PubSubSubscriptionID(projectID, name string) string {
+	return fmt.Sprintf("projects/%s/subscriptions/%s", projectID, name)
+}
+
+func pubSubTopicName(id string) string {
+	return id[strings.LastIndex(id, "/")+1:]
+}
@@ -0,0 +1,124 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/outblocks/cli-plugin-gcp/internal/config"
+	"github.com/outblocks/outblocks-plugin-go/registry"
+	"github.com/outblocks/outblocks-plugin-go/registry/fields"
+	"google.golang.org/api/pubsub/v1"
+)
+
+type PubSubTopic struct {
+	registry.ResourceBase
+
+	Name      fields.StringInputField `state:"force_new"`
+	ProjectID fields.StringInputField `state:"force_new"`
+
+	// Message retention in seconds, 0 disables topic retention.
+	MessageRetention fields.IntInputField `default:"0"`
+}
+
+func (o *PubSubTopic) ReferenceID() string {
+	return fields.GenerateID("projects/%s/topics/%s", o.ProjectID, o.Name)
+}
+
+func (o *PubSubTopic) GetName() string {
+	return fields.VerboseString(o.Name)
+}
+
+func (o *PubSubTopic) Read(ctx context.Context, meta any) error {
+	pctx := meta.(*config.PluginContext) //nolint:errcheck
+
+	projectID := o.ProjectID.Any()
+	name := o.Name.Any()
+
+	cli, err := pctx.GCPPubSubClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	topic, err := cli.Projects.Topics.Get(PubSubTopicID(projectID, name)).Do()
+	if ErrIs404(err) {
+		o.MarkAsNew()
+
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("error fetching pubsub topic: %w", err)
+	}
+
+	o.MarkAsExisting()
+	o.ProjectID.SetCurrent(projectID)
+	o.Name.SetCurrent(name)
+	o.MessageRetention.SetCurrent(parseDurationSeconds(topic.MessageRetentionDuration))
+
+	return nil
+}
+
+func (o *PubSubTopic) makeTopic() *pubsub.Topic {
+	topic := &pubsub.Topic{
+		Name: PubSubTopicID(o.ProjectID.Wanted(), o.Name.Wanted()),
+	}
+
+	if retention := o.MessageRetention.Wanted(); retention > 0 {
+		topic.MessageRetentionDuration = fmt.Sprintf("%ds", retention)
+	}
+
+	return topic
+}
+
+func (o *PubSubTopic) Create(ctx context.Context, meta any) error {
+	pctx := meta.(*config.PluginContext) //nolint:errcheck
+
+	cli, err := pctx.GCPPubSubClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	topic := o.makeTopic()
+
+	_, err = cli.Projects.Topics.Create(topic.Name, topic).Do()
+
+	return err
+}
+
+func (o *PubSubTopic) Update(ctx context.Context, meta any) error {
+	pctx := meta.(*config.PluginContext) //nolint:errcheck
+
+	cli, err := pctx.GCPPubSubClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	topic := o.makeTopic()
+
+	_, err = cli.Projects.Topics.Patch(topic.Name, &pubsub.UpdateTopicRequest{
+		Topic:      topic,
+		UpdateMask: "message_retention_duration",
+	}).Do()
+
+	return err
+}
+
+func (o *PubSubTopic) Delete(ctx context.Context, meta any) error {
+	pctx := meta.(*config.PluginContext) //nolint:errcheck
+
+	cli, err := pctx.GCPPubSubClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = cli.Projects.Topics.Delete(PubSubTopicID(o.ProjectID.Current(), o.Name.Current())).Do()
+	if ErrIs404(err) {
+		return nil
+	}
+
+	return err
+}
+
+func PubSubTopicID(projectID, name string) string {
+	return fmt.Sprintf("projects/%s/topics/%s", projectID, name)
+}
@@ -0,0 +1,209 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/outblocks/cli-plugin-gcp/internal/config"
+	"github.com/outblocks/outblocks-plugin-go/registry"
+	"github.com/outblocks/outblocks-plugin-go/registry/fields"
+	"google.golang.org/api/redis/v1"
+)
+
+type RedisInstance struct {
+	registry.ResourceBase
+
+	Name              fields.StringInputField `state:"force_new"`
+	ProjectID         fields.StringInputField `state:"force_new"`
+	Region            fields.StringInputField `state:"force_new"`
+	Tier              fields.StringInputField `state:"force_new" default:"BASIC"` // options: BASIC, STANDARD_HA
+	AuthorizedNetwork fields.StringInputField `state:"force_new"`                 // VPC network name
+	MemorySizeGB      fields.IntInputField    `default:"1"`
+	RedisVersion      fields.StringInputField `default:"REDIS_7_2"` // can only be upgraded
+
+	Host fields.StringOutputField
+	Port fields.IntOutputField
+}
+
+func (o *RedisInstance) ReferenceID() string {
+	return fields.GenerateID("projects/%s/locations/%s/instances/%s", o.ProjectID, o.Region, o.Name)
+}
+
+func (o *RedisInstance) GetName() string {
+	return fields.VerboseString(o.Name)
+}
+
+func (o *RedisInstance) Read(ctx context.Context, meta any) error {
+	pctx := meta.(*config.PluginContext) //nolint:errcheck
+
+	projectID := o.ProjectID.Any()
+	region := o.Region.Any()
+	name := o.Name.Any()
+
+	cli, err := pctx.GCPRedisClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	inst, err := cli.Projects.Locations.Instances.Get(RedisInstanceID(projectID, region, name)).Do()
+	if ErrIs404(err) {
+		o.MarkAsNew()
+
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("error fetching redis instance: %w", err)
+	}
+
+	o.MarkAsExisting()
+	o.ProjectID.SetCurrent(projectID)
+	o.Region.SetCurrent(region)
+	o.Name.SetCurrent(name)
+	o.Tier.SetCurrent(inst.Tier)
+	o.AuthorizedNetwork.SetCurrent(path.Base(inst.AuthorizedNetwork))
+	o.MemorySizeGB.SetCurrent(int(inst.MemorySizeGb))
+	o.RedisVersion.SetCurrent(inst.RedisVersion)
+	o.Host.SetCurrent(inst.Host)
+	o.Port.SetCurrent(int(inst.Port))
+
+	return nil
+}
+
+func (o *RedisInstance) Create(ctx context.Context, meta any) error {
+	pctx := meta.(*config.PluginContext) //nolint:errcheck
+
+	projectID := o.ProjectID.Wanted()
+	region := o.Region.Wanted()
+
+	cli, err := pctx.GCPRedisClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	inst := &redis.Instance{
+		Tier:              o.Tier.Wanted(),
+		MemorySizeGb:      int64(o.MemorySizeGB.Wanted()),
+		RedisVersion:      o.RedisVersion.Wanted(),
+		AuthorizedNetwork: fmt.Sprintf("projects/%s/global/networks/%s", projectID, o.AuthorizedNetwork.Wanted()),
+		ConnectMode:       "DIRECT_PEERING",
+	}
+
+	op, err := cli.Projects.Locations.Instances.Create(fmt.Sprintf("projects/%s/locations/%s", projectID, region), inst).InstanceId(o.Name.Wanted()).Do()
+	if err != nil {
+		return err
+	}
+
+	err = waitForRedisOperation(ctx, cli, op)
+	if err != nil {
+		return err
+	}
+
+	return o.refreshConnectionInfo(cli)
+}
+
+func (o *RedisInstance) Update(ctx context.Context, meta any) error {
+	pctx := meta.(*config.PluginContext) //nolint:errcheck
+
+	id := RedisInstanceID(o.ProjectID.Wanted(), o.Region.Wanted(), o.Name.Wanted())
+
+	cli, err := pctx.GCPRedisClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	if o.RedisVersion.IsChanged() {
+		op, err := cli.Projects.Locations.Instances.Upgrade(id, &redis.UpgradeInstanceRequest{
+			RedisVersion: o.RedisVersion.Wanted(),
+		}).Do()
+		if err != nil {
+			return fmt.Errorf("error upgrading redis instance: %w", err)
+		}
+
+		err = waitForRedisOperation(ctx, cli, op)
+		if err != nil {
+			return err
+		}
+	}
+
+	if o.MemorySizeGB.IsChanged() {
+		op, err := cli.Projects.Locations.Instances.Patch(id, &redis.Instance{
+			MemorySizeGb: int64(o.MemorySizeGB.Wanted()),
+		}).UpdateMask("memory_size_gb").Do()
+		if err != nil {
+			return fmt.Errorf("error resizing redis instance: %w", err)
+		}
+
+		err = waitForRedisOperation(ctx, cli, op)
+		if err != nil {
+			return err
+		}
+	}
+
+	return o.refreshConnectionInfo(cli)
+}
+
+func (o *RedisInstance) Delete(ctx context.Context, meta any) error {
+	pctx := meta.(*config.PluginContext) //nolint:errcheck
+
+	cli, err := pctx.GCPRedisClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	op, err := cli.Projects.Locations.Instances.Delete(RedisInstanceID(o.ProjectID.Current(), o.Region.Current(), o.Name.Current())).Do()
+	if ErrIs404(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return waitForRedisOperation(ctx, cli, op)
+}
+
+func (o *RedisInstance) refreshConnectionInfo(cli *redis.Service) error {
+	inst, err := cli.Projects.Locations.Instances.Get(RedisInstanceID(o.ProjectID.Wanted(), o.Region.Wanted(), o.Name.Wanted())).Do()
+	if err != nil {
+		return fmt.Errorf("error fetching redis instance: %w", err)
+	}
+
+	o.Host.SetCurrent(inst.Host)
+	o.Port.SetCurrent(int(inst.Port))
+
+	return nil
+}
+
+func RedisInstanceID(projectID, region, name string) string {
+	return fmt.Sprintf("projects/%s/locations/%s/instances/%s", projectID, region, name)
+}
+
+func waitForRedisOperation(ctx context.Context, cli *redis.Service, op *redis.Operation) error {
+	t := time.NewTicker(5 * time.Second)
+	defer t.Stop()
+
+	var err error
+
+	for !op.Done {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+
+		op, err = cli.Projects.Locations.Operations.Get(op.Name).Do()
+		if err != nil {
+			return err
+		}
+	}
+
+	if op.Error != nil {
+		return errors.New(op.Error.Message)
+	}
+
+	return nil
+}
@@ -30,7 +30,9 @@ var Types = []registry.Resource{
 	(*BucketObject)(nil),
 	(*Bucket)(nil),
 	(*CloudFunction)(nil),
+	(*CloudFunctionV2)(nil),
 	(*CloudRun)(nil),
+	(*CloudRunJob)(nil),
 	(*CloudSQLDatabase)(nil),
 	(*CloudSQLUser)(nil),
 	(*CloudSQL)(nil),
@@ -46,6 +48,13 @@ var Types = []registry.Resource{
 	(*UptimeAlertPolicy)(nil),
 	(*NotificationChannel)(nil),
 	(*CloudSchedulerJob)(nil),
+	(*PubSubTopic)(nil),
+	(*PubSubSubscription)(nil),
+	(*PubSubIAMMember)(nil),
+	(*SecretIAMMember)(nil),
+	(*RedisInstance)(nil),
+	(*LogSink)(nil),
+	(*BigQueryDataset)(nil),
 }
 
 var _ registry.ResourceBeforeDiffHook = (*Image)(nil)
@@ -109,6 +118,16 @@ func GlobalID(e env.Enver, gcpProject, id string) string {
 	return id + ShortShaID(gcpProject)
 }
 
+// SecretNamePrefix returns prefix of Secret Manager secrets holding Outblocks secrets of project.
+func SecretNamePrefix(e env.Enver) string {
+	return fmt.Sprintf("OUTBLOCKS_%s_", util.SanitizeName(e.ProjectID(), false, false))
+}
+
+// SecretName returns name of Secret Manager secret holding Outblocks secret of given key.
+func SecretName(e env.Enver, key string) string {
+	return SecretNamePrefix(e) + key
+}
+
 func RegionToGCR(region string) string {
 	region = strings.SplitN(strings.ToUpper(region), "-", 2)[0]
 
@@ -380,6 +399,16 @@ func SplitURL(url string) (host, path string) {
 	return urlSplit[0], path
 }
 
+// parseDurationSeconds parses duration in protobuf JSON format, e.g. "3.5s", returning full seconds.
+func parseDurationSeconds(v string) int {
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0
+	}
+
+	return int(d.Seconds())
+}
+
 type CloudRunNetworkInterface struct {
 	Network    string `json:"network"`
 	Subnetwork string `json:"subnetwork"`
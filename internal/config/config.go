@@ -11,12 +11,20 @@ import (
 	dockerclient "github.com/docker/docker/client"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/artifactregistry/v1"
+	"google.golang.org/api/bigquery/v2"
+	clouderrorreporting "google.golang.org/api/clouderrorreporting/v1beta1"
 	"google.golang.org/api/cloudfunctions/v1"
+	cloudfunctionsv2 "google.golang.org/api/cloudfunctions/v2"
+	"google.golang.org/api/cloudkms/v1"
 	"google.golang.org/api/cloudresourcemanager/v1"
 	"google.golang.org/api/cloudscheduler/v1"
+	cloudtrace "google.golang.org/api/cloudtrace/v1"
 	"google.golang.org/api/compute/v1"
 	"google.golang.org/api/iam/v1"
+	loggingconfig "google.golang.org/api/logging/v2"
 	"google.golang.org/api/option"
+	"google.golang.org/api/pubsub/v1"
+	"google.golang.org/api/redis/v1"
 	"google.golang.org/api/run/v1"
 	"google.golang.org/api/secretmanager/v1"
 	"google.golang.org/api/serviceusage/v1"
@@ -47,66 +55,110 @@ func GoogleCredentials(ctx context.Context, scopes ...string) (cred *google.Cred
 	return cred, nil
 }
 
-func NewGCPStorageClient(ctx context.Context, cred *google.Credentials) (*storage.Client, error) {
-	return storage.NewClient(ctx, option.WithCredentials(cred))
+func NewGCPStorageClient(ctx context.Context, cred *google.Credentials, opts ...option.ClientOption) (*storage.Client, error) {
+	return storage.NewClient(ctx, clientOptions(cred, opts)...)
 }
 
-func NewDockerClient() (*dockerclient.Client, error) {
-	return dockerclient.NewClientWithOpts(dockerclient.FromEnv, dockerclient.WithAPIVersionNegotiation())
+func NewDockerClient(opts ...dockerclient.Opt) (*dockerclient.Client, error) {
+	return dockerclient.NewClientWithOpts(append([]dockerclient.Opt{dockerclient.FromEnv, dockerclient.WithAPIVersionNegotiation()}, opts...)...)
 }
 
-func NewGCPRunClient(ctx context.Context, cred *google.Credentials, region string) (*run.APIService, error) {
-	return run.NewService(ctx, option.WithCredentials(cred), option.WithEndpoint(fmt.Sprintf("https://%s-run.googleapis.com", region)))
+func NewGCPRunClient(ctx context.Context, cred *google.Credentials, region string, opts ...option.ClientOption) (*run.APIService, error) {
+	opts = append([]option.ClientOption{option.WithEndpoint(fmt.Sprintf("https://%s-run.googleapis.com", region))}, opts...)
+
+	return run.NewService(ctx, clientOptions(cred, opts)...)
+}
+
+func NewGCPComputeClient(ctx context.Context, cred *google.Credentials, opts ...option.ClientOption) (*compute.Service, error) {
+	return compute.NewService(ctx, clientOptions(cred, opts)...)
+}
+
+func NewGCPServiceUsageClient(ctx context.Context, cred *google.Credentials, opts ...option.ClientOption) (*serviceusage.Service, error) {
+	opts = append([]option.ClientOption{option.WithTokenSource(cred.TokenSource)}, opts...)
+
+	return serviceusage.NewService(ctx, opts...)
+}
+
+func NewGCPCloudResourceManagerClient(ctx context.Context, cred *google.Credentials, opts ...option.ClientOption) (*cloudresourcemanager.Service, error) {
+	return cloudresourcemanager.NewService(ctx, clientOptions(cred, opts)...)
+}
+
+func NewGCPSQLAdminClient(ctx context.Context, cred *google.Credentials, opts ...option.ClientOption) (*sqladmin.Service, error) {
+	return sqladmin.NewService(ctx, clientOptions(cred, opts)...)
+}
+
+func NewGCPIAMClient(ctx context.Context, cred *google.Credentials, opts ...option.ClientOption) (*iam.Service, error) {
+	return iam.NewService(ctx, clientOptions(cred, opts)...)
+}
+
+func NewGCPLoggingClient(ctx context.Context, cred *google.Credentials, opts ...option.ClientOption) (*logging.Client, error) {
+	return logging.NewClient(ctx, clientOptions(cred, opts)...)
+}
+
+func NewGCPCloudTraceClient(ctx context.Context, cred *google.Credentials, opts ...option.ClientOption) (*cloudtrace.Service, error) {
+	return cloudtrace.NewService(ctx, clientOptions(cred, opts)...)
+}
+
+func NewGCPErrorReportingClient(ctx context.Context, cred *google.Credentials, opts ...option.ClientOption) (*clouderrorreporting.Service, error) {
+	return clouderrorreporting.NewService(ctx, clientOptions(cred, opts)...)
+}
+
+func NewGCPLoggingConfigClient(ctx context.Context, cred *google.Credentials, opts ...option.ClientOption) (*loggingconfig.Service, error) {
+	return loggingconfig.NewService(ctx, clientOptions(cred, opts)...)
+}
+
+func NewGCPBigQueryClient(ctx context.Context, cred *google.Credentials, opts ...option.ClientOption) (*bigquery.Service, error) {
+	return bigquery.NewService(ctx, clientOptions(cred, opts)...)
 }
 
-func NewGCPComputeClient(ctx context.Context, cred *google.Credentials) (*compute.Service, error) {
-	return compute.NewService(ctx, option.WithCredentials(cred))
+func NewGCPSecretManagerClient(ctx context.Context, cred *google.Credentials, opts ...option.ClientOption) (*secretmanager.Service, error) {
+	return secretmanager.NewService(ctx, clientOptions(cred, opts)...)
 }
 
-func NewGCPServiceUsageClient(ctx context.Context, cred *google.Credentials) (*serviceusage.Service, error) {
-	return serviceusage.NewService(ctx, option.WithTokenSource(cred.TokenSource))
+func NewGCPCloudFunctionsClient(ctx context.Context, cred *google.Credentials, opts ...option.ClientOption) (*cloudfunctions.Service, error) {
+	return cloudfunctions.NewService(ctx, clientOptions(cred, opts)...)
 }
 
-func NewGCPCloudResourceManagerClient(ctx context.Context, cred *google.Credentials) (*cloudresourcemanager.Service, error) {
-	return cloudresourcemanager.NewService(ctx, option.WithCredentials(cred))
+func NewGCPCloudFunctionsV2Client(ctx context.Context, cred *google.Credentials, opts ...option.ClientOption) (*cloudfunctionsv2.Service, error) {
+	return cloudfunctionsv2.NewService(ctx, clientOptions(cred, opts)...)
 }
 
-func NewGCPSQLAdminClient(ctx context.Context, cred *google.Credentials) (*sqladmin.Service, error) {
-	return sqladmin.NewService(ctx, option.WithCredentials(cred))
+func NewGCPPubSubClient(ctx context.Context, cred *google.Credentials, opts ...option.ClientOption) (*pubsub.Service, error) {
+	return pubsub.NewService(ctx, clientOptions(cred, opts)...)
 }
 
-func NewGCPIAMClient(ctx context.Context, cred *google.Credentials) (*iam.Service, error) {
-	return iam.NewService(ctx, option.WithCredentials(cred))
+func NewGCPRedisClient(ctx context.Context, cred *google.Credentials, opts ...option.ClientOption) (*redis.Service, error) {
+	return redis.NewService(ctx, clientOptions(cred, opts)...)
 }
 
-func NewGCPLoggingClient(ctx context.Context, cred *google.Credentials) (*logging.Client, error) {
-	return logging.NewClient(ctx, option.WithCredentials(cred))
+func NewGCPKMSClient(ctx context.Context, cred *google.Credentials, opts ...option.ClientOption) (*cloudkms.Service, error) {
+	return cloudkms.NewService(ctx, clientOptions(cred, opts)...)
 }
 
-func NewGCPSecretManagerClient(ctx context.Context, cred *google.Credentials) (*secretmanager.Service, error) {
-	return secretmanager.NewService(ctx, option.WithCredentials(cred))
+func NewGCPMonitoringUptimeCheckClient(ctx context.Context, cred *google.Credentials, opts ...option.ClientOption) (*monitoring.UptimeCheckClient, error) {
+	return monitoring.NewUptimeCheckClient(ctx, clientOptions(cred, opts)...)
 }
 
-func NewGCPCloudFunctionsClient(ctx context.Context, cred *google.Credentials) (*cloudfunctions.Service, error) {
-	return cloudfunctions.NewService(ctx, option.WithCredentials(cred))
+func NewGCPMonitoringNotificationChannelClient(ctx context.Context, cred *google.Credentials, opts ...option.ClientOption) (*monitoring.NotificationChannelClient, error) {
+	return monitoring.NewNotificationChannelClient(ctx, clientOptions(cred, opts)...)
 }
 
-func NewGCPMonitoringUptimeCheckClient(ctx context.Context, cred *google.Credentials) (*monitoring.UptimeCheckClient, error) {
-	return monitoring.NewUptimeCheckClient(ctx, option.WithCredentials(cred))
+func NewGCPMonitoringAlertPolicyClient(ctx context.Context, cred *google.Credentials, opts ...option.ClientOption) (*monitoring.AlertPolicyClient, error) {
+	return monitoring.NewAlertPolicyClient(ctx, clientOptions(cred, opts)...)
 }
 
-func NewGCPMonitoringNotificationChannelClient(ctx context.Context, cred *google.Credentials) (*monitoring.NotificationChannelClient, error) {
-	return monitoring.NewNotificationChannelClient(ctx, option.WithCredentials(cred))
+func NewGCPMonitoringMetricClient(ctx context.Context, cred *google.Credentials, opts ...option.ClientOption) (*monitoring.MetricClient, error) {
+	return monitoring.NewMetricClient(ctx, clientOptions(cred, opts)...)
 }
 
-func NewGCPMonitoringAlertPolicyClient(ctx context.Context, cred *google.Credentials) (*monitoring.AlertPolicyClient, error) {
-	return monitoring.NewAlertPolicyClient(ctx, option.WithCredentials(cred))
+func NewGCPCloudSchedulerClient(ctx context.Context, cred *google.Credentials, opts ...option.ClientOption) (*cloudscheduler.Service, error) {
+	return cloudscheduler.NewService(ctx, clientOptions(cred, opts)...)
 }
 
-func NewGCPCloudSchedulerClient(ctx context.Context, cred *google.Credentials) (*cloudscheduler.Service, error) {
-	return cloudscheduler.NewService(ctx, option.WithCredentials(cred))
+func NewGCPArtifactRegistryClient(ctx context.Context, cred *google.Credentials, opts ...option.ClientOption) (*artifactregistry.Service, error) {
+	return artifactregistry.NewService(ctx, clientOptions(cred, opts)...)
 }
 
-func NewGCPArtifactRegistryClient(ctx context.Context, cred *google.Credentials) (*artifactregistry.Service, error) {
-	return artifactregistry.NewService(ctx, option.WithCredentials(cred))
+func clientOptions(cred *google.Credentials, opts []option.ClientOption) []option.ClientOption {
+	return append([]option.ClientOption{option.WithCredentials(cred)}, opts...)
 }
@@ -11,14 +11,45 @@ import (
 	"github.com/outblocks/outblocks-plugin-go/env"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/artifactregistry/v1"
+	"google.golang.org/api/bigquery/v2"
+	clouderrorreporting "google.golang.org/api/clouderrorreporting/v1beta1"
 	"google.golang.org/api/cloudfunctions/v1"
+	cloudfunctionsv2 "google.golang.org/api/cloudfunctions/v2"
+	"google.golang.org/api/cloudkms/v1"
 	"google.golang.org/api/cloudscheduler/v1"
+	cloudtrace "google.golang.org/api/cloudtrace/v1"
 	"google.golang.org/api/compute/v1"
+	loggingconfig "google.golang.org/api/logging/v2"
+	"google.golang.org/api/option"
+	"google.golang.org/api/pubsub/v1"
+	"google.golang.org/api/redis/v1"
 	"google.golang.org/api/run/v1"
+	"google.golang.org/api/secretmanager/v1"
 	"google.golang.org/api/serviceusage/v1"
 	sqladmin "google.golang.org/api/sqladmin/v1beta4"
 )
 
+// API names used to look up client option overrides.
+const (
+	APIStorage          = "storage"
+	APIRun              = "run"
+	APICompute          = "compute"
+	APIServiceUsage     = "serviceusage"
+	APISQLAdmin         = "sqladmin"
+	APICloudFunctions   = "cloudfunctions"
+	APIMonitoring       = "monitoring"
+	APICloudScheduler   = "cloudscheduler"
+	APIArtifactRegistry = "artifactregistry"
+	APISecretManager    = "secretmanager"
+	APIPubSub           = "pubsub"
+	APIRedis            = "redis"
+	APIKMS              = "kms"
+	APICloudTrace       = "cloudtrace"
+	APILogging          = "logging"
+	APIBigQuery         = "bigquery"
+	APIErrorReporting   = "clouderrorreporting"
+)
+
 type funcCacheData struct {
 	ret any
 	err error
@@ -36,34 +67,79 @@ type PluginContext struct {
 	serviceusageCli                  *serviceusage.Service
 	sqlAdminCli                      *sqladmin.Service
 	cloudfunctionsCli                *cloudfunctions.Service
+	cloudfunctionsV2Cli              *cloudfunctionsv2.Service
 	monitoringUptimeChecksCli        *monitoring.UptimeCheckClient
 	monitoringNotificationChannelCli *monitoring.NotificationChannelClient
 	monitoringAlertPolicyCli         *monitoring.AlertPolicyClient
+	monitoringMetricCli              *monitoring.MetricClient
 	cloudschedulerCli                *cloudscheduler.Service
 	artifactregistryCli              *artifactregistry.Service
-
-	funcCache map[string]*funcCacheData
+	secretmanagerCli                 *secretmanager.Service
+	pubsubCli                        *pubsub.Service
+	redisCli                         *redis.Service
+	kmsCli                           *cloudkms.Service
+	cloudtraceCli                    *cloudtrace.Service
+	errorReportingCli                *clouderrorreporting.Service
+	loggingConfigCli                 *loggingconfig.Service
+	bigqueryCli                      *bigquery.Service
+
+	clientOpts       map[string]func(region string) []option.ClientOption
+	dockerClientOpts []dockerclient.Opt
+	funcCache        map[string]*funcCacheData
 
 	mu struct {
 		runCli, funcCache sync.Mutex
 	}
 	once struct {
-		storageCli, dockerCli, computeCli, serviceusageCli, sqlAdminCli, cloudfunctionsCli,
-		monitoringUptimeChecksCli, monitoringNotificationChannelCli, monitoringAlertPolicyCli,
-		cloudschedulerCli, artifactregistryCli sync.Once
+		storageCli, dockerCli, computeCli, serviceusageCli, sqlAdminCli, cloudfunctionsCli, cloudfunctionsV2Cli,
+		monitoringUptimeChecksCli, monitoringNotificationChannelCli, monitoringAlertPolicyCli, monitoringMetricCli,
+		cloudschedulerCli, artifactregistryCli, secretmanagerCli, pubsubCli, redisCli, kmsCli, cloudtraceCli, errorReportingCli, loggingConfigCli, bigqueryCli sync.Once
+	}
+}
+
+type PluginContextOption func(*PluginContext)
+
+// WithClientOptions adds client options used when creating clients of specified API, e.g. to point them to a custom endpoint.
+// Region is passed for regional clients and is empty otherwise.
+func WithClientOptions(api string, f func(region string) []option.ClientOption) PluginContextOption {
+	return func(c *PluginContext) {
+		c.clientOpts[api] = f
 	}
 }
 
-func NewPluginContext(e env.Enver, gcred *google.Credentials, settings *Settings) *PluginContext {
-	return &PluginContext{
-		env:       e,
-		gcred:     gcred,
-		settings:  settings,
-		runCliMap: make(map[string]*run.APIService),
-		funcCache: make(map[string]*funcCacheData),
+// WithDockerClientOptions adds options used when creating docker client.
+func WithDockerClientOptions(opts ...dockerclient.Opt) PluginContextOption {
+	return func(c *PluginContext) {
+		c.dockerClientOpts = append(c.dockerClientOpts, opts...)
 	}
 }
 
+func NewPluginContext(e env.Enver, gcred *google.Credentials, settings *Settings, opts ...PluginContextOption) *PluginContext {
+	c := &PluginContext{
+		env:        e,
+		gcred:      gcred,
+		settings:   settings,
+		runCliMap:  make(map[string]*run.APIService),
+		clientOpts: make(map[string]func(string) []option.ClientOption),
+		funcCache:  make(map[string]*funcCacheData),
+	}
+
+	for _, o := range opts {
+		o(c)
+	}
+
+	return c
+}
+
+func (c *PluginContext) clientOptions(api, region string) []option.ClientOption {
+	f, ok := c.clientOpts[api]
+	if !ok {
+		return nil
+	}
+
+	return f(region)
+}
+
 func (c *PluginContext) Settings() *Settings {
 	return c.settings
 }
@@ -80,7 +156,7 @@ func (c *PluginContext) GCPStorageClient(ctx context.Context) (*storage.Client,
 	var err error
 
 	c.once.storageCli.Do(func() {
-		c.storageCli, err = NewGCPStorageClient(ctx, c.GoogleCredentials())
+		c.storageCli, err = NewGCPStorageClient(ctx, c.GoogleCredentials(), c.clientOptions(APIStorage, "")...)
 	})
 
 	if err != nil {
@@ -99,7 +175,7 @@ func (c *PluginContext) GCPRunClient(ctx context.Context, region string) (*run.A
 	if !ok {
 		var err error
 
-		cli, err = NewGCPRunClient(ctx, c.GoogleCredentials(), region)
+		cli, err = NewGCPRunClient(ctx, c.GoogleCredentials(), region, c.clientOptions(APIRun, region)...)
 		if err != nil {
 			return nil, fmt.Errorf("error creating gcp run client: %w", err)
 		}
@@ -114,7 +190,7 @@ func (c *PluginContext) GCPComputeClient(ctx context.Context) (*compute.Service,
 	var err error
 
 	c.once.computeCli.Do(func() {
-		c.computeCli, err = NewGCPComputeClient(ctx, c.GoogleCredentials())
+		c.computeCli, err = NewGCPComputeClient(ctx, c.GoogleCredentials(), c.clientOptions(APICompute, "")...)
 	})
 
 	if err != nil {
@@ -128,7 +204,7 @@ func (c *PluginContext) GCPServiceUsageClient(ctx context.Context) (*serviceusag
 	var err error
 
 	c.once.serviceusageCli.Do(func() {
-		c.serviceusageCli, err = NewGCPServiceUsageClient(ctx, c.GoogleCredentials())
+		c.serviceusageCli, err = NewGCPServiceUsageClient(ctx, c.GoogleCredentials(), c.clientOptions(APIServiceUsage, "")...)
 	})
 
 	if err != nil {
@@ -142,7 +218,7 @@ func (c *PluginContext) GCPSQLAdminClient(ctx context.Context) (*sqladmin.Servic
 	var err error
 
 	c.once.sqlAdminCli.Do(func() {
-		c.sqlAdminCli, err = NewGCPSQLAdminClient(ctx, c.GoogleCredentials())
+		c.sqlAdminCli, err = NewGCPSQLAdminClient(ctx, c.GoogleCredentials(), c.clientOptions(APISQLAdmin, "")...)
 	})
 
 	if err != nil {
@@ -156,7 +232,7 @@ func (c *PluginContext) GCPCloudFunctionsClient(ctx context.Context) (*cloudfunc
 	var err error
 
 	c.once.cloudfunctionsCli.Do(func() {
-		c.cloudfunctionsCli, err = NewGCPCloudFunctionsClient(ctx, c.GoogleCredentials())
+		c.cloudfunctionsCli, err = NewGCPCloudFunctionsClient(ctx, c.GoogleCredentials(), c.clientOptions(APICloudFunctions, "")...)
 	})
 
 	if err != nil {
@@ -166,11 +242,26 @@ func (c *PluginContext) GCPCloudFunctionsClient(ctx context.Context) (*cloudfunc
 	return c.cloudfunctionsCli, err
 }
 
+// GCPCloudFunctionsV2Client returns client of 2nd gen Cloud Functions API. It shares client options with v1 API.
+func (c *PluginContext) GCPCloudFunctionsV2Client(ctx context.Context) (*cloudfunctionsv2.Service, error) {
+	var err error
+
+	c.once.cloudfunctionsV2Cli.Do(func() {
+		c.cloudfunctionsV2Cli, err = NewGCPCloudFunctionsV2Client(ctx, c.GoogleCredentials(), c.clientOptions(APICloudFunctions, "")...)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("error creating gcp cloud functions v2 client: %w", err)
+	}
+
+	return c.cloudfunctionsV2Cli, err
+}
+
 func (c *PluginContext) GCPMonitoringAlertPolicyClient(ctx context.Context) (*monitoring.AlertPolicyClient, error) {
 	var err error
 
 	c.once.monitoringAlertPolicyCli.Do(func() {
-		c.monitoringAlertPolicyCli, err = NewGCPMonitoringAlertPolicyClient(ctx, c.GoogleCredentials())
+		c.monitoringAlertPolicyCli, err = NewGCPMonitoringAlertPolicyClient(ctx, c.GoogleCredentials(), c.clientOptions(APIMonitoring, "")...)
 	})
 
 	if err != nil {
@@ -184,7 +275,7 @@ func (c *PluginContext) GCPMonitoringUptimeCheckClient(ctx context.Context) (*mo
 	var err error
 
 	c.once.monitoringUptimeChecksCli.Do(func() {
-		c.monitoringUptimeChecksCli, err = NewGCPMonitoringUptimeCheckClient(ctx, c.GoogleCredentials())
+		c.monitoringUptimeChecksCli, err = NewGCPMonitoringUptimeCheckClient(ctx, c.GoogleCredentials(), c.clientOptions(APIMonitoring, "")...)
 	})
 
 	if err != nil {
@@ -198,7 +289,7 @@ func (c *PluginContext) GCPMonitoringNotificationChannelClient(ctx context.Conte
 	var err error
 
 	c.once.monitoringNotificationChannelCli.Do(func() {
-		c.monitoringNotificationChannelCli, err = NewGCPMonitoringNotificationChannelClient(ctx, c.GoogleCredentials())
+		c.monitoringNotificationChannelCli, err = NewGCPMonitoringNotificationChannelClient(ctx, c.GoogleCredentials(), c.clientOptions(APIMonitoring, "")...)
 	})
 
 	if err != nil {
@@ -208,11 +299,25 @@ func (c *PluginContext) GCPMonitoringNotificationChannelClient(ctx context.Conte
 	return c.monitoringNotificationChannelCli, err
 }
 
+func (c *PluginContext) GCPMonitoringMetricClient(ctx context.Context) (*monitoring.MetricClient, error) {
+	var err error
+
+	c.once.monitoringMetricCli.Do(func() {
+		c.monitoringMetricCli, err = NewGCPMonitoringMetricClient(ctx, c.GoogleCredentials(), c.clientOptions(APIMonitoring, "")...)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("error creating gcp monitoring metric client: %w", err)
+	}
+
+	return c.monitoringMetricCli, err
+}
+
 func (c *PluginContext) GCPCloudSchedulerClient(ctx context.Context) (*cloudscheduler.Service, error) {
 	var err error
 
 	c.once.cloudschedulerCli.Do(func() {
-		c.cloudschedulerCli, err = NewGCPCloudSchedulerClient(ctx, c.GoogleCredentials())
+		c.cloudschedulerCli, err = NewGCPCloudSchedulerClient(ctx, c.GoogleCredentials(), c.clientOptions(APICloudScheduler, "")...)
 	})
 
 	if err != nil {
@@ -226,7 +331,7 @@ func (c *PluginContext) GCPArtifactRegistryClient(ctx context.Context) (*artifac
 	var err error
 
 	c.once.artifactregistryCli.Do(func() {
-		c.artifactregistryCli, err = NewGCPArtifactRegistryClient(ctx, c.GoogleCredentials())
+		c.artifactregistryCli, err = NewGCPArtifactRegistryClient(ctx, c.GoogleCredentials(), c.clientOptions(APIArtifactRegistry, "")...)
 	})
 
 	if err != nil {
@@ -236,11 +341,123 @@ func (c *PluginContext) GCPArtifactRegistryClient(ctx context.Context) (*artifac
 	return c.artifactregistryCli, err
 }
 
+func (c *PluginContext) GCPSecretManagerClient(ctx context.Context) (*secretmanager.Service, error) {
+	var err error
+
+	c.once.secretmanagerCli.Do(func() {
+		c.secretmanagerCli, err = NewGCPSecretManagerClient(ctx, c.GoogleCredentials(), c.clientOptions(APISecretManager, "")...)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("error creating gcp secret manager client: %w", err)
+	}
+
+	return c.secretmanagerCli, err
+}
+
+func (c *PluginContext) GCPPubSubClient(ctx context.Context) (*pubsub.Service, error) {
+	var err error
+
+	c.once.pubsubCli.Do(func() {
+		c.pubsubCli, err = NewGCPPubSubClient(ctx, c.GoogleCredentials(), c.clientOptions(APIPubSub, "")...)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("error creating gcp pubsub client: %w", err)
+	}
+
+	return c.pubsubCli, err
+}
+
+func (c *PluginContext) GCPRedisClient(ctx context.Context) (*redis.Service, error) {
+	var err error
+
+	c.once.redisCli.Do(func() {
+		c.redisCli, err = NewGCPRedisClient(ctx, c.GoogleCredentials(), c.clientOptions(APIRedis, "")...)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("error creating gcp redis client: %w", err)
+	}
+
+	return c.redisCli, err
+}
+
+func (c *PluginContext) GCPKMSClient(ctx context.Context) (*cloudkms.Service, error) {
+	var err error
+
+	c.once.kmsCli.Do(func() {
+		c.kmsCli, err = NewGCPKMSClient(ctx, c.GoogleCredentials(), c.clientOptions(APIKMS, "")...)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("error creating gcp kms client: %w", err)
+	}
+
+	return c.kmsCli, err
+}
+
+func (c *PluginContext) GCPCloudTraceClient(ctx context.Context) (*cloudtrace.Service, error) {
+	var err error
+
+	c.once.cloudtraceCli.Do(func() {
+		c.cloudtraceCli, err = NewGCPCloudTraceClient(ctx, c.GoogleCredentials(), c.clientOptions(APICloudTrace, "")...)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("error creating gcp cloud trace client: %w", err)
+	}
+
+	return c.cloudtraceCli, err
+}
+
+func (c *PluginContext) GCPErrorReportingClient(ctx context.Context) (*clouderrorreporting.Service, error) {
+	var err error
+
+	c.once.errorReportingCli.Do(func() {
+		c.errorReportingCli, err = NewGCPErrorReportingClient(ctx, c.GoogleCredentials(), c.clientOptions(APIErrorReporting, "")...)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("error creating gcp error reporting client: %w", err)
+	}
+
+	return c.errorReportingCli, err
+}
+
+func (c *PluginContext) GCPLoggingConfigClient(ctx context.Context) (*loggingconfig.Service, error) {
+	var err error
+
+	c.once.loggingConfigCli.Do(func() {
+		c.loggingConfigCli, err = NewGCPLoggingConfigClient(ctx, c.GoogleCredentials(), c.clientOptions(APILogging, "")...)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("error creating gcp logging config client: %w", err)
+	}
+
+	return c.loggingConfigCli, err
+}
+
+func (c *PluginContext) GCPBigQueryClient(ctx context.Context) (*bigquery.Service, error) {
+	var err error
+
+	c.once.bigqueryCli.Do(func() {
+		c.bigqueryCli, err = NewGCPBigQueryClient(ctx, c.GoogleCredentials(), c.clientOptions(APIBigQuery, "")...)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("error creating gcp bigquery client: %w", err)
+	}
+
+	return c.bigqueryCli, err
+}
+
 func (c *PluginContext) DockerClient() (*dockerclient.Client, error) {
 	var err error
 
 	c.once.dockerCli.Do(func() {
-		c.dockerCli, err = NewDockerClient()
+		c.dockerCli, err = NewDockerClient(c.dockerClientOpts...)
 	})
 
 	if err != nil {
@@ -4,4 +4,7 @@ type Settings struct {
 	ProjectID     string
 	ProjectNumber int64
 	Region        string
+
+	// LogExport holds raw log export plugin properties, nil if log export is not configured.
+	LogExport map[string]any
 }
@@ -0,0 +1,212 @@
+package fakegcp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+var (
+	dockerAPIVersionRegex = regexp.MustCompile(`^/v[0-9.]+/`)
+	artifactRegistryRegex = regexp.MustCompile(`^([a-z0-9-]+)-docker\.pkg\.dev/([^/]+)/([^/]+)/([^:]+):(.+)$`)
+)
+
+const dockerAPIVersion = "1.47"
+
+func (s *Server) handleArtifactRegistry(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/artifactregistry/v1/")
+
+	// Creating repositories and deleting anything but tags is a long running operation.
+	api := &resourceAPI{
+		name:        "artifactregistry",
+		longRunning: (r.Method == http.MethodPost || r.Method == http.MethodDelete) && !strings.Contains(path, "/tags/"),
+	}
+
+	s.handleResource(w, r, api, path)
+}
+
+// normalizeImageName converts image name to a form with tag, skipping default docker hub registry.
+func normalizeImageName(name string) string {
+	name = strings.TrimPrefix(name, "docker.io/")
+	name = strings.TrimPrefix(name, "library/")
+
+	if strings.Contains(name, "@") {
+		return name
+	}
+
+	if !strings.Contains(name[strings.LastIndex(name, "/")+1:], ":") {
+		name += ":latest"
+	}
+
+	return name
+}
+
+// AddLocalImage registers image in fake docker daemon as if it was built locally. Returns image ID.
+func (s *Server) AddLocalImage(name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.addLocalImage(name)
+}
+
+func (s *Server) addLocalImage(name string) string {
+	name = normalizeImageName(name)
+	id := "sha256:" + fullHash(name)
+
+	s.images[name] = id
+
+	return id
+}
+
+func fullHash(v string) string {
+	h := sha256.Sum256([]byte(v))
+
+	return hex.EncodeToString(h[:])
+}
+
+func (s *Server) pushImage(name string) error {
+	id, ok := s.images[name]
+	if !ok {
+		return fmt.Errorf("image '%s' does not exist locally", name)
+	}
+
+	m := artifactRegistryRegex.FindStringSubmatch(name)
+	if m == nil {
+		return fmt.Errorf("unsupported registry for image '%s'", name)
+	}
+
+	region, project, repo, pkg, tag := m[1], m[2], m[3], m[4], m[5]
+	digest := "sha256:" + fullHash(id+name)
+	pkgName := fmt.Sprintf("projects/%s/locations/%s/repositories/%s/packages/%s", project, region, repo, pkg)
+
+	s.put("artifactregistry/"+pkgName, map[string]any{
+		"name":       pkgName,
+		"createTime": timestamp(),
+		"updateTime": timestamp(),
+	})
+	s.put(fmt.Sprintf("artifactregistry/%s/versions/%s", pkgName, digest), map[string]any{
+		"name":       fmt.Sprintf("%s/versions/%s", pkgName, digest),
+		"createTime": timestamp(),
+		"updateTime": timestamp(),
+	})
+	s.put(fmt.Sprintf("artifactregistry/%s/tags/%s", pkgName, tag), map[string]any{
+		"name":    fmt.Sprintf("%s/tags/%s", pkgName, tag),
+		"version": fmt.Sprintf("%s/versions/%s", pkgName, digest),
+	})
+
+	s.digests[id] = fmt.Sprintf("%s-docker.pkg.dev/%s/%s/%s@%s", region, project, repo, pkg, digest)
+
+	return nil
+}
+
+func (s *Server) handleDocker(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := dockerAPIVersionRegex.ReplaceAllString(r.URL.Path, "/")
+	q := r.URL.Query()
+
+	w.Header().Set("Api-Version", dockerAPIVersion)
+	w.Header().Set("Ostype", "linux")
+
+	switch {
+	case path == "/_ping":
+		w.WriteHeader(http.StatusOK)
+
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte("OK"))
+		}
+
+	case path == "/images/create" && r.Method == http.MethodPost:
+		name := q.Get("fromImage")
+		if tag := q.Get("tag"); tag != "" {
+			name += ":" + tag
+		}
+
+		s.addLocalImage(name)
+		writeJSON(w, http.StatusOK, map[string]any{"status": "Downloaded newer image for " + name})
+
+	case strings.HasPrefix(path, "/images/") && strings.HasSuffix(path, "/tag") && r.Method == http.MethodPost:
+		source := normalizeImageName(strings.TrimSuffix(strings.TrimPrefix(path, "/images/"), "/tag"))
+
+		id, ok := s.images[source]
+		if !ok {
+			writeDockerError(w, http.StatusNotFound, "No such image: "+source)
+
+			return
+		}
+
+		tag := q.Get("tag")
+		if tag == "" {
+			tag = "latest"
+		}
+
+		s.images[normalizeImageName(q.Get("repo")+":"+tag)] = id
+		w.WriteHeader(http.StatusCreated)
+
+	case strings.HasPrefix(path, "/images/") && strings.HasSuffix(path, "/push") && r.Method == http.MethodPost:
+		name := strings.TrimSuffix(strings.TrimPrefix(path, "/images/"), "/push")
+		if tag := q.Get("tag"); tag != "" {
+			name += ":" + tag
+		}
+
+		name = normalizeImageName(name)
+
+		if err := s.pushImage(name); err != nil {
+			writeJSON(w, http.StatusOK, map[string]any{"errorDetail": map[string]any{"message": err.Error()}, "error": err.Error()})
+
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{"status": "Pushed " + name})
+
+	case strings.HasPrefix(path, "/images/") && strings.HasSuffix(path, "/json") && r.Method == http.MethodGet:
+		name := normalizeImageName(strings.TrimSuffix(strings.TrimPrefix(path, "/images/"), "/json"))
+
+		id, ok := s.images[name]
+		if !ok {
+			writeDockerError(w, http.StatusNotFound, "No such image: "+name)
+
+			return
+		}
+
+		var (
+			repoTags    []string
+			repoDigests []string
+		)
+
+		for n, v := range s.images {
+			if v == id {
+				repoTags = append(repoTags, n)
+			}
+		}
+
+		if d, ok := s.digests[id]; ok {
+			repoDigests = append(repoDigests, d)
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{
+			"Id":           id,
+			"RepoTags":     repoTags,
+			"RepoDigests":  repoDigests,
+			"Architecture": "amd64",
+			"Os":           "linux",
+			"Config": map[string]any{
+				"Cmd": []string{"/app"},
+			},
+		})
+
+	default:
+		writeDockerError(w, http.StatusNotFound, "page not found")
+	}
+}
+
+func writeDockerError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]any{"message": msg})
+}
@@ -0,0 +1,98 @@
+package fakegcp
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// handleBigQuery implements BigQuery datasets, which are identified by datasetReference instead of name.
+func (s *Server) handleBigQuery(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/bigquery/v2/")
+	parts := strings.Split(path, "/")
+
+	if len(parts) < 3 || parts[0] != "projects" || parts[2] != "datasets" || len(parts) > 4 {
+		writeNotFound(w, path)
+
+		return
+	}
+
+	if len(parts) == 3 {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+
+			return
+		}
+
+		obj, err := readJSON(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+
+			return
+		}
+
+		ref, _ := obj["datasetReference"].(map[string]any)
+		id, _ := ref["datasetId"].(string)
+
+		if id == "" {
+			writeError(w, http.StatusBadRequest, "dataset id is required")
+
+			return
+		}
+
+		name := path + "/" + id
+		key := "bigquery/" + name
+
+		if _, ok := s.get(key); ok {
+			writeConflict(w, name)
+
+			return
+		}
+
+		obj["id"] = parts[1] + ":" + id
+		obj["creationTime"] = fmt.Sprint(time.Now().UnixMilli())
+
+		s.put(key, obj)
+		writeJSON(w, http.StatusOK, obj)
+
+		return
+	}
+
+	key := "bigquery/" + path
+
+	cur, ok := s.get(key)
+	if !ok {
+		writeNotFound(w, path)
+
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, cur)
+
+	case http.MethodPatch, http.MethodPut:
+		obj, err := readJSON(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+
+			return
+		}
+
+		merge(cur, obj)
+
+		s.put(key, cur)
+		writeJSON(w, http.StatusOK, cur)
+
+	case http.MethodDelete:
+		s.delete(key)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
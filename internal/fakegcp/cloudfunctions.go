@@ -0,0 +1,193 @@
+package fakegcp
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+func (s *Server) cloudFunctionURL(name string) string {
+	parts := strings.Split(name, "/")
+
+	return fmt.Sprintf("https://%s-%s.cloudfunctions.net/%s", parts[3], parts[1], parts[len(parts)-1])
+}
+
+func (s *Server) cloudFunctionsAPI() *resourceAPI {
+	return &resourceAPI{
+		name:        "cloudfunctions",
+		longRunning: true,
+		init: func(name string, obj map[string]any) {
+			obj["status"] = "ACTIVE"
+			obj["environment"] = "GEN_1"
+			obj["versionId"] = fmt.Sprint(s.nextID())
+
+			if _, ok := obj["eventTrigger"]; !ok {
+				obj["httpsTrigger"] = map[string]any{
+					"url":           s.cloudFunctionURL(name),
+					"securityLevel": "SECURE_OPTIONAL",
+				}
+			}
+		},
+	}
+}
+
+// cloudFunctionsV2API shares resources with v1 API, same as real API does.
+func (s *Server) cloudFunctionsV2API() *resourceAPI {
+	return &resourceAPI{
+		name:        "cloudfunctions",
+		longRunning: true,
+		init: func(name string, obj map[string]any) {
+			parts := strings.Split(name, "/")
+
+			obj["state"] = "ACTIVE"
+			obj["environment"] = "GEN_2"
+			obj["url"] = s.cloudFunctionURL(name)
+
+			svc, _ := obj["serviceConfig"].(map[string]any)
+			if svc == nil {
+				svc = make(map[string]any)
+				obj["serviceConfig"] = svc
+			}
+
+			svc["service"] = fmt.Sprintf("projects/%s/locations/%s/services/%s", parts[1], parts[3], parts[len(parts)-1])
+			svc["uri"] = s.CloudRunURL(parts[len(parts)-1], parts[3])
+
+			if trigger, ok := obj["eventTrigger"].(map[string]any); ok {
+				trigger["trigger"] = fmt.Sprintf("projects/%s/locations/%s/triggers/%s-%s", parts[1], parts[3], parts[len(parts)-1], hash(name, 6))
+			}
+		},
+	}
+}
+
+// cloudFunctionV2View returns 1st gen function as seen through v2 API.
+func cloudFunctionV2View(obj map[string]any) map[string]any {
+	if obj["environment"] != "GEN_1" {
+		return obj
+	}
+
+	ret := map[string]any{
+		"name":        obj["name"],
+		"environment": "GEN_1",
+		"state":       obj["status"],
+		"buildConfig": map[string]any{
+			"entryPoint": obj["entryPoint"],
+			"runtime":    obj["runtime"],
+		},
+		"serviceConfig": map[string]any{
+			"availableMemory":      fmt.Sprintf("%vM", obj["availableMemoryMb"]),
+			"environmentVariables": obj["environmentVariables"],
+			"ingressSettings":      obj["ingressSettings"],
+		},
+	}
+
+	if trigger, ok := obj["httpsTrigger"].(map[string]any); ok {
+		ret["url"] = trigger["url"]
+	}
+
+	if info, ok := obj["upgradeInfo"]; ok {
+		ret["upgradeInfo"] = info
+	}
+
+	return ret
+}
+
+func (s *Server) handleCloudFunctions(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if strings.HasPrefix(r.URL.Path, "/cloudfunctions/v2/") {
+		s.handleCloudFunctionsV2(w, r, strings.TrimPrefix(r.URL.Path, "/cloudfunctions/v2/"))
+
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/cloudfunctions/v1/")
+	base, verb := splitVerb(path)
+
+	switch verb {
+	case "":
+		s.handleResource(w, r, s.cloudFunctionsAPI(), path)
+	case "getIamPolicy", "setIamPolicy":
+		if _, ok := s.get("cloudfunctions/" + base); !ok {
+			writeNotFound(w, base)
+
+			return
+		}
+
+		s.iamPolicy(w, r, "cloudfunctions/"+base)
+	default:
+		writeNotFound(w, path)
+	}
+}
+
+func (s *Server) handleCloudFunctionsV2(w http.ResponseWriter, r *http.Request, path string) {
+	api := s.cloudFunctionsV2API()
+	base, verb := splitVerb(path)
+	key := "cloudfunctions/" + base
+
+	if verb == "" {
+		obj, ok := s.get(key)
+
+		switch {
+		case ok && r.Method == http.MethodGet:
+			writeJSON(w, http.StatusOK, cloudFunctionV2View(obj))
+		case ok && obj["environment"] == "GEN_1" && r.Method == http.MethodPatch:
+			writeError(w, http.StatusBadRequest, "function %s is 1st gen, upgrade it first", base)
+		default:
+			s.handleResource(w, r, api, path)
+
+			if r.Method == http.MethodDelete {
+				parts := strings.Split(base, "/")
+				s.delete(fmt.Sprintf("run/projects/%s/locations/%s/services/%s:iam", parts[1], parts[3], parts[len(parts)-1]))
+			}
+		}
+
+		return
+	}
+
+	obj, ok := s.get(key)
+	if !ok {
+		writeNotFound(w, base)
+
+		return
+	}
+
+	info, _ := obj["upgradeInfo"].(map[string]any)
+	state, _ := info["upgradeState"].(string)
+
+	switch verb {
+	case "setupFunctionUpgradeConfig":
+		if obj["environment"] != "GEN_1" {
+			writeError(w, http.StatusBadRequest, "function %s is not eligible for upgrade", base)
+
+			return
+		}
+
+		obj["upgradeInfo"] = map[string]any{"upgradeState": "SETUP_FUNCTION_UPGRADE_CONFIG_SUCCESSFUL"}
+	case "redirectFunctionUpgradeTraffic":
+		if state != "SETUP_FUNCTION_UPGRADE_CONFIG_SUCCESSFUL" {
+			writeError(w, http.StatusBadRequest, "function %s upgrade is in invalid state: %s", base, state)
+
+			return
+		}
+
+		info["upgradeState"] = "REDIRECT_FUNCTION_UPGRADE_TRAFFIC_SUCCESSFUL"
+	case "commitFunctionUpgrade":
+		if state != "REDIRECT_FUNCTION_UPGRADE_TRAFFIC_SUCCESSFUL" {
+			writeError(w, http.StatusBadRequest, "function %s upgrade is in invalid state: %s", base, state)
+
+			return
+		}
+
+		obj = cloudFunctionV2View(obj)
+		delete(obj, "upgradeInfo")
+		api.init(base, obj)
+		s.put(key, obj)
+	default:
+		writeNotFound(w, path)
+
+		return
+	}
+
+	s.writeLongRunning(w, api.name, obj)
+}
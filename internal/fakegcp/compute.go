@@ -0,0 +1,203 @@
+package fakegcp
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+func (s *Server) handleCompute(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/compute/v1/")
+	parts := strings.Split(path, "/")
+
+	// projects/{project}/global/{collection}[/{name}[/{action}]]
+	// projects/{project}/regions/{region}/{collection}[/{name}[/{action}]]
+	if len(parts) < 4 || parts[0] != "projects" {
+		writeNotFound(w, path)
+
+		return
+	}
+
+	scope := parts[:3]
+	rest := parts[3:]
+
+	if parts[2] == "regions" {
+		if len(parts) < 5 {
+			writeNotFound(w, path)
+
+			return
+		}
+
+		scope = parts[:4]
+		rest = parts[4:]
+	}
+
+	scopePath := strings.Join(scope, "/")
+	collection := rest[0]
+	collectionKey := fmt.Sprintf("compute/%s/%s", scopePath, collection)
+
+	if collection == "operations" {
+		if len(rest) < 2 {
+			writeNotFound(w, path)
+
+			return
+		}
+
+		op, ok := s.operations[collectionKey+"/"+rest[1]]
+		if !ok {
+			writeNotFound(w, path)
+
+			return
+		}
+
+		writeJSON(w, http.StatusOK, op)
+
+		return
+	}
+
+	switch {
+	case len(rest) == 1 && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]any{"items": s.list(collectionKey + "/")})
+
+	case len(rest) == 1 && r.Method == http.MethodPost:
+		obj, err := readJSON(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+
+			return
+		}
+
+		name, _ := obj["name"].(string)
+		key := collectionKey + "/" + name
+
+		if _, ok := s.get(key); ok {
+			writeConflict(w, name)
+
+			return
+		}
+
+		selfLink := fmt.Sprintf("%s/compute/v1/%s/%s/%s", s.srv.URL, scopePath, collection, name)
+
+		obj["id"] = fmt.Sprint(s.nextID())
+		obj["selfLink"] = selfLink
+		obj["creationTimestamp"] = timestamp()
+		obj["fingerprint"] = hash(selfLink+timestamp(), 12)
+
+		initComputeResource(collection, obj)
+		s.put(key, obj)
+		s.writeComputeOperation(w, scopePath, selfLink, "insert")
+
+	case len(rest) == 2:
+		key := collectionKey + "/" + rest[1]
+
+		cur, ok := s.get(key)
+		if !ok {
+			writeNotFound(w, path)
+
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, cur)
+
+		case http.MethodPut, http.MethodPatch:
+			obj, err := readJSON(r)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "%s", err)
+
+				return
+			}
+
+			if r.Method == http.MethodPut {
+				for _, k := range []string{"id", "selfLink", "creationTimestamp", "address", "managed"} {
+					if _, ok := obj[k]; !ok && cur[k] != nil {
+						obj[k] = cur[k]
+					}
+				}
+
+				cur = obj
+			} else {
+				merge(cur, obj)
+			}
+
+			cur["fingerprint"] = hash(fmt.Sprint(cur["selfLink"], s.nextID()), 12)
+
+			s.put(key, cur)
+			s.writeComputeOperation(w, scopePath, fmt.Sprint(cur["selfLink"]), "update")
+
+		case http.MethodDelete:
+			s.delete(key)
+			s.writeComputeOperation(w, scopePath, fmt.Sprint(cur["selfLink"]), "delete")
+
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+
+	case len(rest) == 3 && r.Method == http.MethodPost:
+		// Custom actions, e.g. urlMaps/{name}/invalidateCache or operations/{name}/wait.
+		cur, ok := s.get(collectionKey + "/" + rest[1])
+		if !ok {
+			writeNotFound(w, path)
+
+			return
+		}
+
+		s.writeComputeOperation(w, scopePath, fmt.Sprint(cur["selfLink"]), rest[2])
+
+	default:
+		writeNotFound(w, path)
+	}
+}
+
+func initComputeResource(collection string, obj map[string]any) {
+	switch collection {
+	case "addresses":
+		if _, ok := obj["address"]; !ok {
+			obj["address"] = ip("203.0.113", fmt.Sprint(obj["name"]))
+		}
+
+		obj["status"] = "RESERVED"
+
+	case "sslCertificates":
+		managed, _ := obj["managed"].(map[string]any)
+		if managed == nil {
+			break
+		}
+
+		domainStatus := make(map[string]any)
+
+		if domains, ok := managed["domains"].([]any); ok {
+			for _, d := range domains {
+				domainStatus[fmt.Sprint(d)] = "ACTIVE"
+			}
+		}
+
+		managed["status"] = "ACTIVE"
+		managed["domainStatus"] = domainStatus
+	}
+}
+
+func (s *Server) writeComputeOperation(w http.ResponseWriter, scopePath, targetLink, opType string) {
+	name := fmt.Sprintf("operation-%d", s.nextID())
+	selfLink := fmt.Sprintf("%s/compute/v1/%s/operations/%s", s.srv.URL, scopePath, name)
+
+	op := map[string]any{
+		"kind":          "compute#operation",
+		"id":            fmt.Sprint(s.nextID()),
+		"name":          name,
+		"operationType": opType,
+		"targetLink":    targetLink,
+		"status":        "DONE",
+		"progress":      100,
+		"selfLink":      selfLink,
+		"insertTime":    timestamp(),
+		"endTime":       timestamp(),
+	}
+
+	s.operations[fmt.Sprintf("compute/%s/operations/%s", scopePath, name)] = op
+	writeJSON(w, http.StatusOK, op)
+}
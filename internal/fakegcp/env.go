@@ -0,0 +1,111 @@
+package fakegcp
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/outblocks/outblocks-plugin-go/env"
+	"github.com/outblocks/outblocks-plugin-go/log"
+)
+
+// Env is a static env.Enver implementation.
+type Env struct {
+	Name            string
+	ID              string
+	Dir             string
+	Environment     string
+	PluginPath      string
+	PluginCachePath string
+}
+
+func NewEnv(dir string) *Env {
+	return &Env{
+		Name:        "test-project",
+		ID:          "test-project-id",
+		Dir:         dir,
+		Environment: "dev",
+	}
+}
+
+func (e *Env) PluginDir() string {
+	return e.PluginPath
+}
+
+func (e *Env) PluginProjectCacheDir() string {
+	return e.PluginCachePath
+}
+
+func (e *Env) ProjectDir() string {
+	return e.Dir
+}
+
+func (e *Env) ProjectName() string {
+	return e.Name
+}
+
+func (e *Env) ProjectID() string {
+	return e.ID
+}
+
+func (e *Env) Env() string {
+	return e.Environment
+}
+
+// Logger is a log.Logger implementation that records all logged messages.
+type Logger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (l *Logger) log(level string, msg string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.messages = append(l.messages, fmt.Sprintf("%s: %s", level, msg))
+}
+
+// Messages returns all logged messages prefixed with their level.
+func (l *Logger) Messages() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([]string(nil), l.messages...)
+}
+
+func (l *Logger) Fatal(a ...any) {
+	panic(fmt.Sprint(a...))
+}
+
+func (l *Logger) Fatalln(a ...any) {
+	panic(fmt.Sprintln(a...))
+}
+
+func (l *Logger) Fatalf(format string, a ...any) {
+	panic(fmt.Sprintf(format, a...))
+}
+
+func (l *Logger) Error(a ...any)                 { l.log("error", fmt.Sprint(a...)) }
+func (l *Logger) Errorln(a ...any)               { l.log("error", fmt.Sprintln(a...)) }
+func (l *Logger) Errorf(format string, a ...any) { l.log("error", fmt.Sprintf(format, a...)) }
+func (l *Logger) Warn(a ...any)                  { l.log("warn", fmt.Sprint(a...)) }
+func (l *Logger) Warnln(a ...any)                { l.log("warn", fmt.Sprintln(a...)) }
+func (l *Logger) Warnf(format string, a ...any)  { l.log("warn", fmt.Sprintf(format, a...)) }
+func (l *Logger) Info(a ...any)                  { l.log("info", fmt.Sprint(a...)) }
+func (l *Logger) Infoln(a ...any)                { l.log("info", fmt.Sprintln(a...)) }
+func (l *Logger) Infof(format string, a ...any)  { l.log("info", fmt.Sprintf(format, a...)) }
+func (l *Logger) Debug(a ...any)                 { l.log("debug", fmt.Sprint(a...)) }
+func (l *Logger) Debugln(a ...any)               { l.log("debug", fmt.Sprintln(a...)) }
+func (l *Logger) Debugf(format string, a ...any) { l.log("debug", fmt.Sprintf(format, a...)) }
+func (l *Logger) Success(a ...any)               { l.log("success", fmt.Sprint(a...)) }
+func (l *Logger) Successln(a ...any)             { l.log("success", fmt.Sprintln(a...)) }
+func (l *Logger) Successf(format string, a ...any) {
+	l.log("success", fmt.Sprintf(format, a...))
+}
+func (l *Logger) Print(a ...any)                 { l.log("print", fmt.Sprint(a...)) }
+func (l *Logger) Println(a ...any)               { l.log("print", fmt.Sprintln(a...)) }
+func (l *Logger) Printf(format string, a ...any) { l.log("print", fmt.Sprintf(format, a...)) }
+
+var (
+	_ env.Enver  = (*Env)(nil)
+	_ log.Logger = (*Logger)(nil)
+)
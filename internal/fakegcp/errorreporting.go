@@ -0,0 +1,73 @@
+package fakegcp
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AddErrorGroupStats adds Error Reporting error group of given service, stats are returned as is together with affected service.
+func (s *Server) AddErrorGroupStats(service string, stats map[string]any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats["affectedServices"] = []any{map[string]any{"service": service}}
+	stats["numAffectedServices"] = 1
+
+	s.put(fmt.Sprintf("clouderrorreporting/projects/%s/groupStats/%d", s.ProjectID, s.nextID()), stats)
+}
+
+// handleErrorReporting fakes listing of error group stats filtered by service, ordered by count.
+func (s *Server) handleErrorReporting(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/clouderrorreporting/v1beta1/")
+
+	if r.Method != http.MethodGet || !strings.HasSuffix(path, "/groupStats") {
+		writeNotFound(w, path)
+
+		return
+	}
+
+	q := r.URL.Query()
+
+	if q.Get("timeRange.period") == "" {
+		writeError(w, http.StatusBadRequest, "timeRange.period is required")
+
+		return
+	}
+
+	service := q.Get("serviceFilter.service")
+
+	var stats []map[string]any
+
+	for _, k := range s.keysWithPrefix("clouderrorreporting/" + path + "/") {
+		v := s.resources[k]
+
+		affected, _ := v["affectedServices"].([]any)
+
+		if service != "" && (len(affected) == 0 || affected[0].(map[string]any)["service"] != service) { //nolint:errcheck
+			continue
+		}
+
+		stats = append(stats, v)
+	}
+
+	count := func(v map[string]any) int64 {
+		n, _ := strconv.ParseInt(fmt.Sprint(v["count"]), 10, 64)
+		return n
+	}
+
+	sort.SliceStable(stats, func(i, j int) bool {
+		return count(stats[i]) > count(stats[j])
+	})
+
+	if size, _ := strconv.Atoi(q.Get("pageSize")); size > 0 && len(stats) > size {
+		stats = stats[:size]
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"errorGroupStats": stats})
+}
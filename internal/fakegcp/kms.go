@@ -0,0 +1,57 @@
+package fakegcp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/http"
+	"strings"
+)
+
+// handleKMS fakes symmetric encrypt and decrypt, ciphertext is plaintext prefixed with key name so it only decrypts with same key.
+func (s *Server) handleKMS(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/kms/v1/")
+	name, verb := splitVerb(path)
+
+	if r.Method != http.MethodPost || !strings.Contains(name, "/cryptoKeys/") {
+		writeNotFound(w, path)
+
+		return
+	}
+
+	req, err := readJSON(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+
+		return
+	}
+
+	prefix := []byte("kms:" + name + ":")
+
+	switch verb {
+	case "encrypt":
+		plaintext, err := base64.StdEncoding.DecodeString(req["plaintext"].(string))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{
+			"name":       name + "/cryptoKeyVersions/1",
+			"ciphertext": base64.StdEncoding.EncodeToString(append(prefix, plaintext...)),
+		})
+	case "decrypt":
+		ciphertext, err := base64.StdEncoding.DecodeString(req["ciphertext"].(string))
+		if err != nil || !bytes.HasPrefix(ciphertext, prefix) {
+			writeError(w, http.StatusBadRequest, "Decryption failed: the ciphertext is invalid.")
+
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{
+			"plaintext": base64.StdEncoding.EncodeToString(ciphertext[len(prefix):]),
+		})
+	default:
+		writeNotFound(w, path)
+	}
+}
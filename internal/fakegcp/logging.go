@@ -0,0 +1,25 @@
+package fakegcp
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var loggingAPI = &resourceAPI{
+	name: "logging",
+	init: func(name string, obj map[string]any) {
+		if _, ok := obj["writerIdentity"]; !ok {
+			obj["writerIdentity"] = fmt.Sprintf("serviceAccount:service-%s@gcp-sa-logging.iam.gserviceaccount.com", hash(name, 12))
+		}
+	},
+}
+
+func (s *Server) handleLogging(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/logging/v2/")
+
+	s.handleResource(w, r, loggingAPI, path)
+}
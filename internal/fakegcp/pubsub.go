@@ -0,0 +1,138 @@
+package fakegcp
+
+import (
+	"net/http"
+	"strings"
+)
+
+// handlePubSub implements Pub/Sub topics and subscriptions. Unlike other resource APIs, they are created with PUT
+// and patched with update requests wrapping the resource.
+func (s *Server) handlePubSub(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/pubsub/v1/")
+	base, verb := splitVerb(path)
+	key := "pubsub/" + base
+
+	if verb != "" {
+		switch verb {
+		case "getIamPolicy", "setIamPolicy":
+			if _, ok := s.get(key); !ok {
+				writeNotFound(w, base)
+
+				return
+			}
+
+			s.iamPolicy(w, r, key)
+		default:
+			writeNotFound(w, path)
+		}
+
+		return
+	}
+
+	cur, ok := s.get(key)
+
+	switch r.Method {
+	case http.MethodGet:
+		if !ok {
+			writeNotFound(w, path)
+
+			return
+		}
+
+		writeJSON(w, http.StatusOK, cur)
+
+	case http.MethodPut:
+		if ok {
+			writeConflict(w, path)
+
+			return
+		}
+
+		obj, err := readJSON(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+
+			return
+		}
+
+		if topic, ok := obj["topic"].(string); ok {
+			if _, exists := s.get("pubsub/" + topic); !exists {
+				writeNotFound(w, topic)
+
+				return
+			}
+		}
+
+		obj["name"] = path
+
+		s.put(key, obj)
+		writeJSON(w, http.StatusOK, obj)
+
+	case http.MethodPatch:
+		if !ok {
+			writeNotFound(w, path)
+
+			return
+		}
+
+		req, err := readJSON(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+
+			return
+		}
+
+		var obj map[string]any
+
+		for _, k := range []string{"topic", "subscription"} {
+			if v, ok := req[k].(map[string]any); ok {
+				obj = v
+			}
+		}
+
+		mask, _ := req["updateMask"].(string)
+
+		for _, field := range strings.Split(mask, ",") {
+			field = pubSubFieldName(field)
+
+			if v, ok := obj[field]; ok {
+				cur[field] = v
+			} else {
+				delete(cur, field)
+			}
+		}
+
+		s.put(key, cur)
+		writeJSON(w, http.StatusOK, cur)
+
+	case http.MethodDelete:
+		if !ok {
+			writeNotFound(w, path)
+
+			return
+		}
+
+		s.delete(key)
+		s.delete(key + ":iam")
+		writeJSON(w, http.StatusOK, map[string]any{})
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// pubSubFieldName converts update mask path to JSON field name, e.g. "message_retention_duration" to "messageRetentionDuration".
+func pubSubFieldName(field string) string {
+	parts := strings.Split(strings.TrimSpace(field), "_")
+
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+
+	return strings.Join(parts, "")
+}
@@ -0,0 +1,52 @@
+package fakegcp
+
+import (
+	"net/http"
+	"strings"
+)
+
+var redisAPI = &resourceAPI{
+	name:        "redis",
+	longRunning: true,
+	init: func(name string, obj map[string]any) {
+		parts := strings.Split(name, "/")
+
+		obj["state"] = "READY"
+		obj["host"] = ip("10.10.0", name)
+		obj["port"] = 6379
+		obj["locationId"] = parts[3] + "-b"
+		obj["currentLocationId"] = parts[3] + "-b"
+	},
+}
+
+func (s *Server) handleRedis(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/redis/v1/")
+	base, verb := splitVerb(path)
+
+	switch verb {
+	case "":
+		s.handleResource(w, r, redisAPI, path)
+	case "upgrade":
+		obj, ok := s.get("redis/" + base)
+		if !ok {
+			writeNotFound(w, base)
+
+			return
+		}
+
+		req, err := readJSON(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+
+			return
+		}
+
+		obj["redisVersion"] = req["redisVersion"]
+		s.writeLongRunning(w, redisAPI.name, obj)
+	default:
+		writeNotFound(w, path)
+	}
+}
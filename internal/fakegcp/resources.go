@@ -0,0 +1,239 @@
+package fakegcp
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// resourceAPI describes an API that addresses resources by their full names, e.g. "projects/p/locations/l/jobs/j".
+type resourceAPI struct {
+	name        string
+	longRunning bool
+	init        func(name string, obj map[string]any)
+}
+
+func isCollectionPath(path string) bool {
+	return strings.Count(path, "/")%2 == 0
+}
+
+func (s *Server) writeLongRunning(w http.ResponseWriter, api string, resp map[string]any) {
+	name := fmt.Sprintf("operations/operation-%d", s.nextID())
+
+	op := map[string]any{
+		"name":     name,
+		"done":     true,
+		"response": resp,
+	}
+
+	s.operations[api+"/"+name] = op
+	writeJSON(w, http.StatusOK, op)
+}
+
+// handleResource implements generic list/get/create/patch/delete semantics for resourceAPI.
+func (s *Server) handleResource(w http.ResponseWriter, r *http.Request, api *resourceAPI, path string) {
+	respond := func(obj map[string]any) {
+		if api.longRunning {
+			s.writeLongRunning(w, api.name, obj)
+
+			return
+		}
+
+		writeJSON(w, http.StatusOK, obj)
+	}
+
+	if strings.HasPrefix(path, "operations/") || strings.Contains(path, "/operations/") {
+		op, ok := s.operations[api.name+"/"+path]
+		if !ok {
+			op = map[string]any{"name": path, "done": true}
+		}
+
+		writeJSON(w, http.StatusOK, op)
+
+		return
+	}
+
+	if isCollectionPath(path) {
+		collection := path[strings.LastIndex(path, "/")+1:]
+
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, map[string]any{collection: s.list(api.name + "/" + path + "/")})
+
+		case http.MethodPost:
+			obj, err := readJSON(r)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "%s", err)
+
+				return
+			}
+
+			var id string
+
+			for k, v := range r.URL.Query() {
+				if strings.HasSuffix(k, "Id") && len(v) > 0 {
+					id = v[0]
+				}
+			}
+
+			if id == "" {
+				name, _ := obj["name"].(string)
+				id = name[strings.LastIndex(name, "/")+1:]
+			}
+
+			name := path + "/" + id
+			key := api.name + "/" + name
+
+			if _, ok := s.get(key); ok || id == "" {
+				if id == "" {
+					writeError(w, http.StatusBadRequest, "resource id is required")
+				} else {
+					writeConflict(w, name)
+				}
+
+				return
+			}
+
+			obj["name"] = name
+			obj["createTime"] = timestamp()
+			obj["updateTime"] = timestamp()
+
+			if api.init != nil {
+				api.init(name, obj)
+			}
+
+			s.put(key, obj)
+			respond(obj)
+
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+
+		return
+	}
+
+	key := api.name + "/" + path
+
+	cur, ok := s.get(key)
+	if !ok {
+		writeNotFound(w, path)
+
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, cur)
+
+	case http.MethodPatch, http.MethodPut:
+		obj, err := readJSON(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+
+			return
+		}
+
+		merge(cur, obj)
+
+		cur["name"] = path
+		cur["updateTime"] = timestamp()
+
+		if api.init != nil {
+			api.init(path, cur)
+		}
+
+		s.put(key, cur)
+		respond(cur)
+
+	case http.MethodDelete:
+		s.delete(key)
+		s.delete(key + ":iam")
+
+		for _, k := range s.keysWithPrefix(key + "/") {
+			s.delete(k)
+		}
+
+		respond(map[string]any{})
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+var (
+	cloudSchedulerAPI = &resourceAPI{
+		name: "cloudscheduler",
+		init: func(_ string, obj map[string]any) {
+			obj["state"] = "ENABLED"
+		},
+	}
+	serviceUsageAPI = &resourceAPI{name: "serviceusage"}
+)
+
+func (s *Server) handleCloudScheduler(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/cloudscheduler/v1/")
+	base, verb := splitVerb(path)
+
+	switch verb {
+	case "":
+		s.handleResource(w, r, cloudSchedulerAPI, path)
+	case "run", "pause", "resume":
+		job, ok := s.get("cloudscheduler/" + base)
+		if !ok {
+			writeNotFound(w, base)
+
+			return
+		}
+
+		switch verb {
+		case "pause":
+			job["state"] = "PAUSED"
+		case "resume":
+			job["state"] = "ENABLED"
+		case "run":
+			job["lastAttemptTime"] = timestamp()
+		}
+
+		writeJSON(w, http.StatusOK, job)
+	default:
+		writeNotFound(w, path)
+	}
+}
+
+func (s *Server) handleServiceUsage(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/serviceusage/v1/")
+	base, verb := splitVerb(path)
+
+	switch verb {
+	case "":
+		if r.Method == http.MethodGet && !isCollectionPath(path) {
+			svc, ok := s.get("serviceusage/" + path)
+			if !ok {
+				svc = map[string]any{"name": path, "state": "DISABLED"}
+			}
+
+			writeJSON(w, http.StatusOK, svc)
+
+			return
+		}
+
+		s.handleResource(w, r, serviceUsageAPI, path)
+	case "enable", "disable":
+		state := "ENABLED"
+		if verb == "disable" {
+			state = "DISABLED"
+		}
+
+		svc := map[string]any{"name": base, "state": state}
+		s.put("serviceusage/"+base, svc)
+		s.writeLongRunning(w, serviceUsageAPI.name, map[string]any{"service": svc})
+	default:
+		writeNotFound(w, path)
+	}
+}
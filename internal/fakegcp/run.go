@@ -0,0 +1,299 @@
+package fakegcp
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	knativePrefix = "apis/serving.knative.dev/v1/"
+	runJobsPrefix = "apis/run.googleapis.com/v1/"
+)
+
+// CloudRunURL returns URL that fake Cloud Run assigns to a service.
+func (s *Server) CloudRunURL(name, region string) string {
+	return fmt.Sprintf("https://%s-%s-%s.a.run.app", name, hash(s.ProjectID, 10), regionCode(region))
+}
+
+func regionCode(region string) string {
+	var code string
+
+	for _, part := range strings.Split(region, "-") {
+		if part != "" {
+			code += part[:1]
+		}
+	}
+
+	return code
+}
+
+func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	region, path, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/run/"), "/")
+
+	switch {
+	case strings.HasPrefix(path, knativePrefix):
+		s.handleRunService(w, r, region, strings.TrimPrefix(path, knativePrefix))
+	case strings.HasPrefix(path, runJobsPrefix):
+		s.handleRunJob(w, r, region, strings.TrimPrefix(path, runJobsPrefix))
+	case strings.HasPrefix(path, "v1/projects/"):
+		base, _ := splitVerb(strings.TrimPrefix(path, "v1/"))
+		s.iamPolicy(w, r, "run/"+base)
+	default:
+		writeNotFound(w, path)
+	}
+}
+
+func (s *Server) handleRunService(w http.ResponseWriter, r *http.Request, region, path string) {
+	key := fmt.Sprintf("run/%s/%s", region, path)
+
+	switch r.Method {
+	case http.MethodGet:
+		if strings.HasSuffix(path, "/services") {
+			writeJSON(w, http.StatusOK, map[string]any{"items": s.list(key + "/")})
+
+			return
+		}
+
+		svc, ok := s.get(key)
+		if !ok {
+			writeNotFound(w, path)
+
+			return
+		}
+
+		writeJSON(w, http.StatusOK, svc)
+
+	case http.MethodPost, http.MethodPut:
+		svc, err := readJSON(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+
+			return
+		}
+
+		meta, _ := svc["metadata"].(map[string]any)
+		if meta == nil {
+			writeError(w, http.StatusBadRequest, "metadata is required")
+
+			return
+		}
+
+		name, _ := meta["name"].(string)
+		generation := 1.0
+		revision := ""
+
+		if r.Method == http.MethodPost {
+			key += "/" + name
+
+			if _, ok := s.get(key); ok {
+				writeConflict(w, name)
+
+				return
+			}
+		} else {
+			cur, ok := s.get(key)
+			if !ok {
+				writeNotFound(w, path)
+
+				return
+			}
+
+			curMeta, _ := cur["metadata"].(map[string]any)
+			generation, _ = curMeta["generation"].(float64)
+			generation++
+
+			// New revision is only created when revision template changes.
+			if curSpec, _ := cur["spec"].(map[string]any); curSpec != nil {
+				spec, _ := svc["spec"].(map[string]any)
+
+				if sameJSON(curSpec["template"], spec["template"]) {
+					curStatus, _ := cur["status"].(map[string]any)
+					revision, _ = curStatus["latestCreatedRevisionName"].(string)
+				}
+			}
+		}
+
+		if revision == "" {
+			revision = fmt.Sprintf("%s-%05d-%s", name, int(generation), hash(fmt.Sprint(s.nextID()), 3))
+		}
+
+		meta["generation"] = generation
+		meta["uid"] = hash(key, 32)
+		meta["creationTimestamp"] = timestamp()
+
+		svc["status"] = map[string]any{
+			"observedGeneration":        generation,
+			"url":                       s.CloudRunURL(name, region),
+			"address":                   map[string]any{"url": s.CloudRunURL(name, region)},
+			"latestCreatedRevisionName": revision,
+			"latestReadyRevisionName":   revision,
+			"conditions": []any{
+				map[string]any{"type": "Ready", "status": "True", "lastTransitionTime": timestamp()},
+				map[string]any{"type": "ConfigurationsReady", "status": "True", "lastTransitionTime": timestamp()},
+				map[string]any{"type": "RoutesReady", "status": "True", "lastTransitionTime": timestamp()},
+			},
+			"traffic": runServiceTrafficStatus(svc, revision),
+		}
+
+		s.put(key, svc)
+		writeJSON(w, http.StatusOK, svc)
+
+	case http.MethodDelete:
+		if !s.delete(key) {
+			writeNotFound(w, path)
+
+			return
+		}
+
+		// IAM policies are managed through v1 API which addresses services by location.
+		parts := strings.Split(path, "/")
+		s.delete(fmt.Sprintf("run/projects/%s/locations/%s/services/%s:iam", parts[1], region, parts[len(parts)-1]))
+
+		writeJSON(w, http.StatusOK, map[string]any{"status": "Success"})
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) handleRunJob(w http.ResponseWriter, r *http.Request, region, path string) {
+	path, verb := splitVerb(path)
+	key := fmt.Sprintf("run/%s/%s", region, path)
+
+	if verb == "run" {
+		job, ok := s.get(key)
+		if !ok {
+			writeNotFound(w, path)
+
+			return
+		}
+
+		status, _ := job["status"].(map[string]any)
+		count, _ := status["executionCount"].(float64)
+		status["executionCount"] = count + 1
+
+		s.put(key, job)
+		writeJSON(w, http.StatusOK, map[string]any{
+			"apiVersion": "run.googleapis.com/v1",
+			"kind":       "Execution",
+			"metadata":   map[string]any{"name": fmt.Sprintf("%s-%s", path[strings.LastIndex(path, "/")+1:], hash(fmt.Sprint(s.nextID()), 5))},
+		})
+
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if strings.HasSuffix(path, "/jobs") {
+			writeJSON(w, http.StatusOK, map[string]any{"items": s.list(key + "/")})
+
+			return
+		}
+
+		job, ok := s.get(key)
+		if !ok {
+			writeNotFound(w, path)
+
+			return
+		}
+
+		writeJSON(w, http.StatusOK, job)
+
+	case http.MethodPost, http.MethodPut:
+		job, err := readJSON(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+
+			return
+		}
+
+		meta, _ := job["metadata"].(map[string]any)
+		if meta == nil {
+			writeError(w, http.StatusBadRequest, "metadata is required")
+
+			return
+		}
+
+		name, _ := meta["name"].(string)
+		generation := 1.0
+
+		if r.Method == http.MethodPost {
+			key += "/" + name
+
+			if _, ok := s.get(key); ok {
+				writeConflict(w, name)
+
+				return
+			}
+		} else {
+			cur, ok := s.get(key)
+			if !ok {
+				writeNotFound(w, path)
+
+				return
+			}
+
+			curMeta, _ := cur["metadata"].(map[string]any)
+			generation, _ = curMeta["generation"].(float64)
+			generation++
+		}
+
+		meta["generation"] = generation
+		meta["uid"] = hash(key, 32)
+		meta["creationTimestamp"] = timestamp()
+
+		job["status"] = map[string]any{
+			"observedGeneration": generation,
+			"conditions": []any{
+				map[string]any{"type": "Ready", "status": "True", "lastTransitionTime": timestamp()},
+			},
+		}
+
+		s.put(key, job)
+		writeJSON(w, http.StatusOK, job)
+
+	case http.MethodDelete:
+		if !s.delete(key) {
+			writeNotFound(w, path)
+
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{"status": "Success"})
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// runServiceTrafficStatus resolves traffic targets of service spec, latest revision targets point to given revision.
+func runServiceTrafficStatus(svc map[string]any, revision string) []any {
+	spec, _ := svc["spec"].(map[string]any)
+	traffic, _ := spec["traffic"].([]any)
+
+	if len(traffic) == 0 {
+		return []any{
+			map[string]any{"revisionName": revision, "percent": 100, "latestRevision": true},
+		}
+	}
+
+	ret := make([]any, 0, len(traffic))
+
+	for _, t := range traffic {
+		target, _ := t.(map[string]any)
+		status := map[string]any{"percent": target["percent"], "revisionName": target["revisionName"]}
+
+		if latest, _ := target["latestRevision"].(bool); latest {
+			status["revisionName"] = revision
+			status["latestRevision"] = true
+		}
+
+		ret = append(ret, status)
+	}
+
+	return ret
+}
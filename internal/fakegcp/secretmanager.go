@@ -0,0 +1,193 @@
+package fakegcp
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var secretManagerAPI = &resourceAPI{
+	name: "secretmanager",
+	init: func(_ string, obj map[string]any) {
+		// Like the real API, ttl is converted to expireTime.
+		if ttl, ok := obj["ttl"].(string); ok {
+			if d, err := time.ParseDuration(ttl); err == nil {
+				obj["expireTime"] = time.Now().Add(d).UTC().Format(time.RFC3339)
+			}
+
+			delete(obj, "ttl")
+		}
+	},
+}
+
+func (s *Server) handleSecretManager(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/secretmanager/v1/")
+	base, verb := splitVerb(path)
+
+	switch verb {
+	case "":
+		if strings.HasSuffix(path, "/versions/latest") {
+			ver := s.latestSecretVersion(strings.TrimSuffix(path, "/versions/latest"))
+			if ver == nil {
+				writeNotFound(w, path)
+
+				return
+			}
+
+			writeJSON(w, http.StatusOK, secretVersionInfo(ver))
+
+			return
+		}
+
+		s.handleResource(w, r, secretManagerAPI, path)
+
+	case "addVersion":
+		s.addSecretVersion(w, r, base)
+
+	case "access":
+		var ver map[string]any
+
+		if strings.HasSuffix(base, "/versions/latest") {
+			ver = s.latestSecretVersion(strings.TrimSuffix(base, "/versions/latest"))
+		} else {
+			ver, _ = s.get("secretmanager/" + base)
+		}
+
+		if ver == nil {
+			writeNotFound(w, base)
+
+			return
+		}
+
+		if ver["state"] != "ENABLED" {
+			writeError(w, http.StatusBadRequest, "secret version '%s' is in %s state", ver["name"], ver["state"])
+
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{
+			"name":    ver["name"],
+			"payload": ver["payload"],
+		})
+
+	case "destroy", "disable", "enable":
+		ver, ok := s.get("secretmanager/" + base)
+		if !ok {
+			writeNotFound(w, base)
+
+			return
+		}
+
+		state := map[string]string{"destroy": "DESTROYED", "disable": "DISABLED", "enable": "ENABLED"}[verb]
+
+		ver["state"] = state
+		if state == "DESTROYED" {
+			delete(ver, "payload")
+			ver["destroyTime"] = timestamp()
+		}
+
+		writeJSON(w, http.StatusOK, secretVersionInfo(ver))
+
+	case "getIamPolicy", "setIamPolicy":
+		s.iamPolicy(w, r, "secretmanager/"+base)
+
+	default:
+		writeNotFound(w, path)
+	}
+}
+
+func (s *Server) addSecretVersion(w http.ResponseWriter, r *http.Request, secret string) {
+	if _, ok := s.get("secretmanager/" + secret); !ok {
+		writeNotFound(w, secret)
+
+		return
+	}
+
+	req, err := readJSON(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+
+		return
+	}
+
+	num := len(s.keysWithPrefix("secretmanager/"+secret+"/versions/")) + 1
+	name := fmt.Sprintf("%s/versions/%d", secret, num)
+
+	ver := map[string]any{
+		"name":       name,
+		"state":      "ENABLED",
+		"createTime": timestamp(),
+		"etag":       hash(name, 8),
+		"payload":    req["payload"],
+	}
+
+	s.put("secretmanager/"+name, ver)
+	writeJSON(w, http.StatusOK, secretVersionInfo(ver))
+}
+
+func (s *Server) latestSecretVersion(secret string) map[string]any {
+	var (
+		latest    map[string]any
+		latestNum int
+	)
+
+	for _, k := range s.keysWithPrefix("secretmanager/" + secret + "/versions/") {
+		ver := s.resources[k]
+		if ver["state"] != "ENABLED" {
+			continue
+		}
+
+		num, _ := strconv.Atoi(k[strings.LastIndex(k, "/")+1:])
+		if num > latestNum {
+			latest, latestNum = ver, num
+		}
+	}
+
+	return latest
+}
+
+// secretVersionInfo returns secret version without its payload.
+func secretVersionInfo(ver map[string]any) map[string]any {
+	ret := make(map[string]any, len(ver))
+
+	for k, v := range ver {
+		if k != "payload" {
+			ret[k] = v
+		}
+	}
+
+	return ret
+}
+
+// SecretVersions returns base64 encoded payload data of all enabled versions of a secret sorted by version number.
+func (s *Server) SecretVersions(name string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := s.keysWithPrefix(fmt.Sprintf("secretmanager/projects/%s/secrets/%s/versions/", s.ProjectID, name))
+
+	sort.Slice(keys, func(i, j int) bool {
+		a, _ := strconv.Atoi(keys[i][strings.LastIndex(keys[i], "/")+1:])
+		b, _ := strconv.Atoi(keys[j][strings.LastIndex(keys[j], "/")+1:])
+
+		return a < b
+	})
+
+	var ret []string
+
+	for _, k := range keys {
+		ver := s.resources[k]
+
+		if payload, ok := ver["payload"].(map[string]any); ok && ver["state"] == "ENABLED" {
+			ret = append(ret, fmt.Sprint(payload["data"]))
+		}
+	}
+
+	return ret
+}
@@ -0,0 +1,420 @@
+// Package fakegcp implements an in-memory fake of GCP APIs (and docker daemon) used by the plugin.
+// It allows running plan and apply end to end without network access, e.g. in go test.
+package fakegcp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/storage"
+	dockerclient "github.com/docker/docker/client"
+	"github.com/outblocks/cli-plugin-gcp/internal/config"
+	"github.com/outblocks/outblocks-plugin-go/env"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/option"
+)
+
+const (
+	DefaultProjectID     = "fake-project"
+	DefaultProjectNumber = 123456789
+	DefaultRegion        = "europe-west1"
+)
+
+type Server struct {
+	ProjectID     string
+	ProjectNumber int64
+	Region        string
+
+	srv    *httptest.Server
+	docker *httptest.Server
+
+	mu         sync.Mutex
+	counter    int64
+	resources  map[string]map[string]any
+	operations map[string]map[string]any
+	objects    map[string][]*object
+	images     map[string]string
+	digests    map[string]string
+	requests   []string
+	failures   []*failure
+}
+
+type failure struct {
+	code  int
+	match func(r *http.Request) bool
+}
+
+// New starts fake GCP server. It has to be closed after use.
+func New() *Server {
+	s := &Server{
+		ProjectID:     DefaultProjectID,
+		ProjectNumber: DefaultProjectNumber,
+		Region:        DefaultRegion,
+
+		resources:  make(map[string]map[string]any),
+		operations: make(map[string]map[string]any),
+		objects:    make(map[string][]*object),
+		images:     make(map[string]string),
+		digests:    make(map[string]string),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/run/", s.handleRun)
+	mux.HandleFunc("/compute/v1/", s.handleCompute)
+	mux.HandleFunc("/sqladmin/", s.handleSQLAdmin)
+	mux.HandleFunc("/storage/v1/", s.handleStorage)
+	mux.HandleFunc("/upload/storage/v1/", s.handleStorageUpload)
+	mux.HandleFunc("/artifactregistry/", s.handleArtifactRegistry)
+	mux.HandleFunc("/cloudscheduler/", s.handleCloudScheduler)
+	mux.HandleFunc("/secretmanager/", s.handleSecretManager)
+	mux.HandleFunc("/serviceusage/", s.handleServiceUsage)
+	mux.HandleFunc("/cloudfunctions/", s.handleCloudFunctions)
+	mux.HandleFunc("/pubsub/", s.handlePubSub)
+	mux.HandleFunc("/redis/", s.handleRedis)
+	mux.HandleFunc("/kms/", s.handleKMS)
+	mux.HandleFunc("/logging/", s.handleLogging)
+	mux.HandleFunc("/bigquery/", s.handleBigQuery)
+	mux.HandleFunc("/clouderrorreporting/", s.handleErrorReporting)
+
+	s.srv = httptest.NewServer(s.logRequests(mux))
+	s.docker = httptest.NewServer(s.logRequests(http.HandlerFunc(s.handleDocker)))
+
+	return s
+}
+
+func (s *Server) Close() {
+	s.srv.Close()
+	s.docker.Close()
+}
+
+func (s *Server) URL() string {
+	return s.srv.URL
+}
+
+// Settings returns plugin settings matching fake project.
+func (s *Server) Settings() *config.Settings {
+	return &config.Settings{
+		ProjectID:     s.ProjectID,
+		ProjectNumber: s.ProjectNumber,
+		Region:        s.Region,
+	}
+}
+
+// Options returns plugin context options that redirect all clients to fake server.
+func (s *Server) Options() []config.PluginContextOption {
+	endpoint := func(prefix string, extra ...option.ClientOption) func(string) []option.ClientOption {
+		return func(string) []option.ClientOption {
+			return append([]option.ClientOption{
+				option.WithEndpoint(s.srv.URL + prefix),
+				option.WithHTTPClient(s.srv.Client()),
+			}, extra...)
+		}
+	}
+
+	return []config.PluginContextOption{
+		config.WithClientOptions(config.APIRun, func(region string) []option.ClientOption {
+			return []option.ClientOption{
+				option.WithEndpoint(fmt.Sprintf("%s/run/%s/", s.srv.URL, region)),
+				option.WithHTTPClient(s.srv.Client()),
+			}
+		}),
+		config.WithClientOptions(config.APICompute, endpoint("/compute/v1/")),
+		config.WithClientOptions(config.APISQLAdmin, endpoint("/sqladmin/")),
+		config.WithClientOptions(config.APIStorage, endpoint("/storage/v1/", storage.WithJSONReads())),
+		config.WithClientOptions(config.APIArtifactRegistry, endpoint("/artifactregistry/")),
+		config.WithClientOptions(config.APICloudScheduler, endpoint("/cloudscheduler/")),
+		config.WithClientOptions(config.APISecretManager, endpoint("/secretmanager/")),
+		config.WithClientOptions(config.APIServiceUsage, endpoint("/serviceusage/")),
+		config.WithClientOptions(config.APICloudFunctions, endpoint("/cloudfunctions/")),
+		config.WithClientOptions(config.APIPubSub, endpoint("/pubsub/")),
+		config.WithClientOptions(config.APIRedis, endpoint("/redis/")),
+		config.WithClientOptions(config.APIKMS, endpoint("/kms/")),
+		config.WithClientOptions(config.APILogging, endpoint("/logging/")),
+		config.WithClientOptions(config.APIBigQuery, endpoint("/bigquery/v2/")),
+		config.WithClientOptions(config.APIErrorReporting, endpoint("/clouderrorreporting/")),
+		config.WithDockerClientOptions(
+			dockerclient.WithHost("tcp://"+strings.TrimPrefix(s.docker.URL, "http://")),
+			dockerclient.WithHTTPClient(s.docker.Client()),
+		),
+	}
+}
+
+// PluginContext creates plugin context using fake server for all supported APIs.
+func (s *Server) PluginContext(e env.Enver) *config.PluginContext {
+	return config.NewPluginContext(e, Credentials(), s.Settings(), s.Options()...)
+}
+
+// Credentials returns static credentials that are accepted by fake server.
+func Credentials() *google.Credentials {
+	return &google.Credentials{
+		ProjectID:   DefaultProjectID,
+		TokenSource: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "fake-token", Expiry: time.Now().Add(24 * time.Hour)}),
+	}
+}
+
+// FailRequests makes server respond with given status code to all requests matching f, e.g. to simulate API errors.
+// Retried status codes (429 and 5xx) should be avoided as clients would retry them until timeout.
+func (s *Server) FailRequests(code int, f func(r *http.Request) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures = append(s.failures, &failure{code: code, match: f})
+}
+
+// Requests returns list of all requests handled so far in "METHOD path" format.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.requests...)
+}
+
+// Resource returns a copy of stored resource, key is a resource path prefixed with API name, e.g. "run/namespaces/project/services/name".
+func (s *Server) Resource(key string) (map[string]any, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.resources[key]
+	if !ok {
+		return nil, false
+	}
+
+	return copyMap(v), true
+}
+
+// UpdateResource modifies stored resource in place, e.g. to simulate changes made outside of plugin.
+func (s *Server) UpdateResource(key string, f func(map[string]any)) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.get(key)
+	if !ok {
+		return false
+	}
+
+	f(v)
+
+	return true
+}
+
+// DeleteResource removes stored resource.
+func (s *Server) DeleteResource(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.delete(key)
+}
+
+// ResourceKeys returns sorted keys of all stored resources.
+func (s *Server) ResourceKeys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.resources))
+	for k := range s.resources {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func (s *Server) logRequests(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, fmt.Sprintf("%s %s", r.Method, r.URL.Path))
+
+		for _, f := range s.failures {
+			if f.match(r) {
+				s.mu.Unlock()
+				writeError(w, f.code, "injected failure of %s %s", r.Method, r.URL.Path)
+
+				return
+			}
+		}
+
+		s.mu.Unlock()
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) nextID() int64 {
+	s.counter++
+
+	return s.counter
+}
+
+func (s *Server) get(key string) (map[string]any, bool) {
+	v, ok := s.resources[key]
+
+	return v, ok
+}
+
+func (s *Server) put(key string, v map[string]any) {
+	s.resources[key] = v
+}
+
+func (s *Server) delete(key string) bool {
+	_, ok := s.resources[key]
+	delete(s.resources, key)
+
+	return ok
+}
+
+func (s *Server) list(prefix string) []map[string]any {
+	var keys []string
+
+	for k := range s.resources {
+		if strings.HasPrefix(k, prefix) && !strings.Contains(k[len(prefix):], "/") && !strings.Contains(k[len(prefix):], ":") {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+
+	ret := make([]map[string]any, len(keys))
+	for i, k := range keys {
+		ret[i] = s.resources[k]
+	}
+
+	return ret
+}
+
+func hash(v string, n int) string {
+	h := sha256.Sum256([]byte(v))
+
+	return hex.EncodeToString(h[:])[:n]
+}
+
+// ip returns an address from given network prefix that is stable for the same name.
+func ip(prefix, name string) string {
+	h := sha256.Sum256([]byte(name))
+
+	return fmt.Sprintf("%s.%d", prefix, int(h[0])%250+1)
+}
+
+func timestamp() string {
+	return time.Now().UTC().Format(time.RFC3339Nano)
+}
+
+func copyMap(in map[string]any) map[string]any {
+	data, _ := json.Marshal(in)
+
+	var out map[string]any
+
+	_ = json.Unmarshal(data, &out)
+
+	return out
+}
+
+func sameJSON(a, b any) bool {
+	da, _ := json.Marshal(a)
+	db, _ := json.Marshal(b)
+
+	return bytes.Equal(da, db)
+}
+
+func merge(dst, src map[string]any) {
+	for k, v := range src {
+		dst[k] = v
+	}
+}
+
+func readJSON(r *http.Request) (map[string]any, error) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string]any)
+
+	if len(data) == 0 {
+		return ret, nil
+	}
+
+	err = json.Unmarshal(data, &ret)
+
+	return ret, err
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, format string, a ...any) {
+	writeJSON(w, code, map[string]any{
+		"error": map[string]any{
+			"code":    code,
+			"message": fmt.Sprintf(format, a...),
+			"status":  http.StatusText(code),
+		},
+	})
+}
+
+func writeNotFound(w http.ResponseWriter, key string) {
+	writeError(w, http.StatusNotFound, "resource '%s' not found", key)
+}
+
+func writeConflict(w http.ResponseWriter, key string) {
+	writeError(w, http.StatusConflict, "resource '%s' already exists", key)
+}
+
+// splitVerb splits custom method from path, e.g. "a/b:enable" into "a/b" and "enable".
+func splitVerb(path string) (base, verb string) {
+	idx := strings.LastIndex(path, ":")
+	if idx == -1 || strings.Contains(path[idx:], "/") {
+		return path, ""
+	}
+
+	return path[:idx], path[idx+1:]
+}
+
+func (s *Server) iamPolicy(w http.ResponseWriter, r *http.Request, key string) {
+	switch r.Method {
+	case http.MethodGet, http.MethodPost:
+		if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, ":setIamPolicy") {
+			req, err := readJSON(r)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "%s", err)
+
+				return
+			}
+
+			policy, _ := req["policy"].(map[string]any)
+			if policy == nil {
+				policy = make(map[string]any)
+			}
+
+			policy["etag"] = hash(fmt.Sprint(s.nextID()), 8)
+			s.put(key+":iam", policy)
+			writeJSON(w, http.StatusOK, policy)
+
+			return
+		}
+
+		policy, ok := s.get(key + ":iam")
+		if !ok {
+			policy = map[string]any{"etag": "BwAAAAAAAAA="}
+		}
+
+		writeJSON(w, http.StatusOK, policy)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
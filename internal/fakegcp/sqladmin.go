@@ -0,0 +1,342 @@
+package fakegcp
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+func (s *Server) handleSQLAdmin(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/sqladmin/sql/v1beta4/")
+	parts := strings.Split(path, "/")
+
+	if len(parts) < 3 || parts[0] != "projects" {
+		writeNotFound(w, path)
+
+		return
+	}
+
+	project := parts[1]
+
+	switch {
+	case parts[2] == "operations" && len(parts) == 4:
+		op, ok := s.operations["sqladmin/"+path]
+		if !ok {
+			writeNotFound(w, path)
+
+			return
+		}
+
+		writeJSON(w, http.StatusOK, op)
+
+	case parts[2] == "instances" && len(parts) <= 4:
+		s.handleSQLInstance(w, r, project, parts[3:])
+
+	case parts[2] == "instances" && len(parts) >= 5 && parts[4] == "users":
+		s.handleSQLUser(w, r, project, parts[3])
+
+	case parts[2] == "instances" && len(parts) >= 5 && parts[4] == "databases":
+		s.handleSQLDatabase(w, r, project, parts[3], parts[5:])
+
+	default:
+		writeNotFound(w, path)
+	}
+}
+
+func (s *Server) handleSQLInstance(w http.ResponseWriter, r *http.Request, project string, rest []string) {
+	collectionKey := fmt.Sprintf("sqladmin/projects/%s/instances", project)
+
+	if len(rest) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, map[string]any{"items": s.list(collectionKey + "/")})
+
+		case http.MethodPost:
+			inst, err := readJSON(r)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "%s", err)
+
+				return
+			}
+
+			name, _ := inst["name"].(string)
+			key := collectionKey + "/" + name
+
+			if _, ok := s.get(key); ok {
+				writeConflict(w, name)
+
+				return
+			}
+
+			region, _ := inst["region"].(string)
+
+			delete(inst, "rootPassword")
+
+			inst["project"] = project
+			inst["state"] = "RUNNABLE"
+			inst["connectionName"] = fmt.Sprintf("%s:%s:%s", project, region, name)
+			inst["ipAddresses"] = []any{
+				map[string]any{"type": "PRIMARY", "ipAddress": ip("198.51.100", inst["connectionName"].(string))},
+			}
+			inst["serviceAccountEmailAddress"] = fmt.Sprintf("p%d-fake@gcp-sa-cloud-sql.iam.gserviceaccount.com", s.ProjectNumber)
+			setSQLSettingsVersion(inst, 1)
+
+			s.put(key, inst)
+			s.writeSQLOperation(w, project, name, "CREATE")
+
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+
+		return
+	}
+
+	name := rest[0]
+	key := collectionKey + "/" + name
+
+	cur, ok := s.get(key)
+	if !ok {
+		writeNotFound(w, name)
+
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, cur)
+
+	case http.MethodPut, http.MethodPatch:
+		inst, err := readJSON(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+
+			return
+		}
+
+		delete(inst, "rootPassword")
+
+		version := sqlSettingsVersion(cur)
+
+		for _, k := range []string{"project", "state", "connectionName", "ipAddresses", "serviceAccountEmailAddress"} {
+			inst[k] = cur[k]
+		}
+
+		if r.Method == http.MethodPatch {
+			merge(cur, inst)
+			inst = cur
+		}
+
+		setSQLSettingsVersion(inst, version+1)
+
+		s.put(key, inst)
+		s.writeSQLOperation(w, project, name, "UPDATE")
+
+	case http.MethodDelete:
+		s.delete(key)
+
+		for _, k := range s.keysWithPrefix(key + "/") {
+			s.delete(k)
+		}
+
+		s.writeSQLOperation(w, project, name, "DELETE")
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) handleSQLDatabase(w http.ResponseWriter, r *http.Request, project, instance string, rest []string) {
+	collectionKey := fmt.Sprintf("sqladmin/projects/%s/instances/%s/databases", project, instance)
+
+	if _, ok := s.get(fmt.Sprintf("sqladmin/projects/%s/instances/%s", project, instance)); !ok {
+		writeNotFound(w, instance)
+
+		return
+	}
+
+	if len(rest) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, map[string]any{"items": s.list(collectionKey + "/")})
+
+		case http.MethodPost:
+			db, err := readJSON(r)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "%s", err)
+
+				return
+			}
+
+			name, _ := db["name"].(string)
+			key := collectionKey + "/" + name
+
+			if _, ok := s.get(key); ok {
+				writeConflict(w, name)
+
+				return
+			}
+
+			db["project"] = project
+			db["instance"] = instance
+
+			s.put(key, db)
+			s.writeSQLOperation(w, project, instance, "CREATE_DATABASE")
+
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+
+		return
+	}
+
+	key := collectionKey + "/" + rest[0]
+
+	cur, ok := s.get(key)
+	if !ok {
+		writeNotFound(w, rest[0])
+
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, cur)
+
+	case http.MethodPut, http.MethodPatch:
+		db, err := readJSON(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+
+			return
+		}
+
+		merge(cur, db)
+		s.put(key, cur)
+		s.writeSQLOperation(w, project, instance, "UPDATE_DATABASE")
+
+	case http.MethodDelete:
+		s.delete(key)
+		s.writeSQLOperation(w, project, instance, "DELETE_DATABASE")
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) handleSQLUser(w http.ResponseWriter, r *http.Request, project, instance string) {
+	collectionKey := fmt.Sprintf("sqladmin/projects/%s/instances/%s/users", project, instance)
+
+	if _, ok := s.get(fmt.Sprintf("sqladmin/projects/%s/instances/%s", project, instance)); !ok {
+		writeNotFound(w, instance)
+
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]any{"items": s.list(collectionKey + "/")})
+
+	case http.MethodPost, http.MethodPut:
+		user, err := readJSON(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+
+			return
+		}
+
+		name, _ := user["name"].(string)
+		if q := r.URL.Query().Get("name"); q != "" {
+			name = q
+		}
+
+		key := collectionKey + "/" + name
+
+		if _, ok := s.get(key); ok == (r.Method == http.MethodPost) {
+			if ok {
+				writeConflict(w, name)
+			} else {
+				writeNotFound(w, name)
+			}
+
+			return
+		}
+
+		delete(user, "password")
+
+		user["name"] = name
+		user["project"] = project
+		user["instance"] = instance
+
+		s.put(key, user)
+		s.writeSQLOperation(w, project, instance, "CREATE_USER")
+
+	case http.MethodDelete:
+		name := r.URL.Query().Get("name")
+
+		if !s.delete(collectionKey + "/" + name) {
+			writeNotFound(w, name)
+
+			return
+		}
+
+		s.writeSQLOperation(w, project, instance, "DELETE_USER")
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) writeSQLOperation(w http.ResponseWriter, project, instance, opType string) {
+	name := fmt.Sprintf("op-%d", s.nextID())
+
+	op := map[string]any{
+		"kind":          "sql#operation",
+		"name":          name,
+		"operationType": opType,
+		"status":        "DONE",
+		"targetId":      instance,
+		"targetProject": project,
+		"insertTime":    timestamp(),
+		"endTime":       timestamp(),
+	}
+
+	s.operations[fmt.Sprintf("sqladmin/projects/%s/operations/%s", project, name)] = op
+	writeJSON(w, http.StatusOK, op)
+}
+
+func (s *Server) keysWithPrefix(prefix string) []string {
+	var keys []string
+
+	for k := range s.resources {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+
+	return keys
+}
+
+func sqlSettingsVersion(inst map[string]any) int64 {
+	settings, _ := inst["settings"].(map[string]any)
+	if settings == nil {
+		return 0
+	}
+
+	v, _ := strconv.ParseInt(fmt.Sprint(settings["settingsVersion"]), 10, 64)
+
+	return v
+}
+
+func setSQLSettingsVersion(inst map[string]any, v int64) {
+	settings, _ := inst["settings"].(map[string]any)
+	if settings == nil {
+		settings = make(map[string]any)
+		inst["settings"] = settings
+	}
+
+	settings["settingsVersion"] = strconv.FormatInt(v, 10)
+}
@@ -0,0 +1,686 @@
+package fakegcp
+
+import (
+	"crypto/md5" //nolint:gosec
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type object struct {
+	attrs map[string]any
+	data  []byte
+	live  bool
+}
+
+func (o *object) generation() int64 {
+	v, _ := strconv.ParseInt(fmt.Sprint(o.attrs["generation"]), 10, 64)
+
+	return v
+}
+
+// Object returns content of live object stored in fake GCS.
+func (s *Server) Object(bucket, name string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	obj := s.liveObject(bucket, name)
+	if obj == nil {
+		return nil, false
+	}
+
+	return append([]byte(nil), obj.data...), true
+}
+
+func (s *Server) liveObject(bucket, name string) *object {
+	for _, o := range s.objects[bucket] {
+		if o.live && o.attrs["name"] == name {
+			return o
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) findObject(bucket, name, generation string) *object {
+	if generation == "" {
+		return s.liveObject(bucket, name)
+	}
+
+	for _, o := range s.objects[bucket] {
+		if o.attrs["name"] == name && fmt.Sprint(o.attrs["generation"]) == generation {
+			return o
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) bucketVersioning(bucket string) bool {
+	b, ok := s.get("storage/b/" + bucket)
+	if !ok {
+		return false
+	}
+
+	v, _ := b["versioning"].(map[string]any)
+
+	return v != nil && v["enabled"] == true
+}
+
+// archiveObject makes live object noncurrent (if bucket is versioned) or removes it.
+func (s *Server) archiveObject(bucket string, obj *object) {
+	if s.bucketVersioning(bucket) {
+		obj.live = false
+		obj.attrs["timeDeleted"] = timestamp()
+
+		return
+	}
+
+	s.removeObject(bucket, obj)
+}
+
+func (s *Server) removeObject(bucket string, obj *object) {
+	objs := s.objects[bucket]
+
+	for i, o := range objs {
+		if o == obj {
+			s.objects[bucket] = append(objs[:i], objs[i+1:]...)
+
+			return
+		}
+	}
+}
+
+func checkGenerationPreconditions(q url.Values, obj *object) bool {
+	gen := int64(0)
+	if obj != nil {
+		gen = obj.generation()
+	}
+
+	if v := q.Get("ifGenerationMatch"); v != "" && v != strconv.FormatInt(gen, 10) {
+		return false
+	}
+
+	if v := q.Get("ifGenerationNotMatch"); v != "" && v == strconv.FormatInt(gen, 10) {
+		return false
+	}
+
+	if v := q.Get("ifMetagenerationMatch"); v != "" && (obj == nil || v != fmt.Sprint(obj.attrs["metageneration"])) {
+		return false
+	}
+
+	return true
+}
+
+func storagePathParts(r *http.Request, prefix string) []string {
+	parts := strings.Split(strings.TrimPrefix(r.URL.EscapedPath(), prefix), "/")
+
+	for i, p := range parts {
+		if v, err := url.PathUnescape(p); err == nil {
+			parts[i] = v
+		}
+	}
+
+	return parts
+}
+
+func (s *Server) handleStorage(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	parts := storagePathParts(r, "/storage/v1/")
+
+	if len(parts) == 0 || parts[0] != "b" {
+		writeNotFound(w, r.URL.Path)
+
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		s.handleBuckets(w, r)
+	case len(parts) == 2:
+		s.handleBucket(w, r, parts[1])
+	case len(parts) == 3 && parts[2] == "iam":
+		s.handleBucketIAM(w, r, parts[1])
+	case len(parts) == 3 && parts[2] == "lockRetentionPolicy":
+		s.handleLockRetentionPolicy(w, r, parts[1])
+	case len(parts) == 3 && parts[2] == "o":
+		s.handleListObjects(w, r, parts[1])
+	case len(parts) == 4 && parts[2] == "o":
+		s.handleObject(w, r, parts[1], parts[3])
+	case len(parts) == 9 && parts[2] == "o" && (parts[4] == "rewriteTo" || parts[4] == "copyTo"):
+		s.handleRewriteObject(w, r, parts[1], parts[3], parts[6], parts[8])
+	default:
+		writeNotFound(w, r.URL.Path)
+	}
+}
+
+func (s *Server) handleBuckets(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		project := r.URL.Query().Get("project")
+
+		var items []map[string]any
+
+		for _, b := range s.list("storage/b/") {
+			if project == "" || b["projectNumber"] == fmt.Sprint(s.ProjectNumber) {
+				items = append(items, b)
+			}
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{"kind": "storage#buckets", "items": items})
+
+	case http.MethodPost:
+		b, err := readJSON(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+
+			return
+		}
+
+		name, _ := b["name"].(string)
+		key := "storage/b/" + name
+
+		if _, ok := s.get(key); ok {
+			writeConflict(w, name)
+
+			return
+		}
+
+		b["kind"] = "storage#bucket"
+		b["id"] = name
+		b["projectNumber"] = fmt.Sprint(s.ProjectNumber)
+		b["metageneration"] = "1"
+		b["timeCreated"] = timestamp()
+		b["updated"] = timestamp()
+		b["etag"] = hash(name, 8)
+
+		if b["location"] == nil {
+			b["location"] = "US"
+		}
+
+		b["location"] = strings.ToUpper(fmt.Sprint(b["location"]))
+
+		if b["storageClass"] == nil {
+			b["storageClass"] = "STANDARD"
+		}
+
+		setRetentionPolicy(b, nil)
+
+		s.put(key, b)
+		writeJSON(w, http.StatusOK, b)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) handleBucket(w http.ResponseWriter, r *http.Request, bucket string) {
+	key := "storage/b/" + bucket
+
+	cur, ok := s.get(key)
+	if !ok {
+		writeNotFound(w, bucket)
+
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, cur)
+
+	case http.MethodPatch, http.MethodPut:
+		b, err := readJSON(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+
+			return
+		}
+
+		if locked := retentionPolicyLocked(cur); locked != nil {
+			if rp, ok := b["retentionPolicy"]; ok && !retentionPolicyExtends(rp, locked) {
+				writeError(w, http.StatusForbidden, "retention policy of bucket '%s' is locked", bucket)
+
+				return
+			}
+		}
+
+		setRetentionPolicy(b, cur)
+		merge(cur, b)
+
+		mg, _ := strconv.Atoi(fmt.Sprint(cur["metageneration"]))
+		cur["metageneration"] = strconv.Itoa(mg + 1)
+		cur["updated"] = timestamp()
+
+		s.put(key, cur)
+		writeJSON(w, http.StatusOK, cur)
+
+	case http.MethodDelete:
+		if len(s.objects[bucket]) != 0 {
+			writeError(w, http.StatusConflict, "bucket '%s' is not empty", bucket)
+
+			return
+		}
+
+		s.delete(key)
+		s.delete(key + ":iam")
+		delete(s.objects, bucket)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// setRetentionPolicy fills in retention policy fields set by server, keeping lock of current bucket.
+func setRetentionPolicy(b, cur map[string]any) {
+	rp, ok := b["retentionPolicy"].(map[string]any)
+	if !ok {
+		return
+	}
+
+	rp["effectiveTime"] = timestamp()
+	rp["isLocked"] = retentionPolicyLocked(cur) != nil
+}
+
+// retentionPolicyLocked returns locked retention policy of bucket, nil if it is missing or not locked.
+func retentionPolicyLocked(b map[string]any) map[string]any {
+	rp, _ := b["retentionPolicy"].(map[string]any)
+	if rp == nil || rp["isLocked"] != true {
+		return nil
+	}
+
+	return rp
+}
+
+func retentionPolicyExtends(rp any, locked map[string]any) bool {
+	m, _ := rp.(map[string]any)
+	if m == nil {
+		return false
+	}
+
+	period, _ := strconv.ParseInt(fmt.Sprint(m["retentionPeriod"]), 10, 64)
+	lockedPeriod, _ := strconv.ParseInt(fmt.Sprint(locked["retentionPeriod"]), 10, 64)
+
+	return period >= lockedPeriod
+}
+
+func (s *Server) handleLockRetentionPolicy(w http.ResponseWriter, r *http.Request, bucket string) {
+	key := "storage/b/" + bucket
+
+	cur, ok := s.get(key)
+	if !ok {
+		writeNotFound(w, bucket)
+
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+
+		return
+	}
+
+	if v := r.URL.Query().Get("ifMetagenerationMatch"); v == "" || v != fmt.Sprint(cur["metageneration"]) {
+		writeError(w, http.StatusPreconditionFailed, "metageneration of bucket '%s' does not match", bucket)
+
+		return
+	}
+
+	rp, ok := cur["retentionPolicy"].(map[string]any)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "bucket '%s' has no retention policy", bucket)
+
+		return
+	}
+
+	rp["isLocked"] = true
+
+	mg, _ := strconv.Atoi(fmt.Sprint(cur["metageneration"]))
+	cur["metageneration"] = strconv.Itoa(mg + 1)
+
+	s.put(key, cur)
+	writeJSON(w, http.StatusOK, cur)
+}
+
+func (s *Server) handleBucketIAM(w http.ResponseWriter, r *http.Request, bucket string) {
+	key := "storage/b/" + bucket
+
+	if _, ok := s.get(key); !ok {
+		writeNotFound(w, bucket)
+
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		policy, ok := s.get(key + ":iam")
+		if !ok {
+			policy = map[string]any{"kind": "storage#policy", "resourceId": "projects/_/buckets/" + bucket, "etag": "CAE="}
+		}
+
+		writeJSON(w, http.StatusOK, policy)
+
+	case http.MethodPut:
+		policy, err := readJSON(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+
+			return
+		}
+
+		policy["kind"] = "storage#policy"
+		policy["etag"] = base64.StdEncoding.EncodeToString([]byte(hash(fmt.Sprint(s.nextID()), 4)))
+
+		s.put(key+":iam", policy)
+		writeJSON(w, http.StatusOK, policy)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) handleListObjects(w http.ResponseWriter, r *http.Request, bucket string) {
+	if _, ok := s.get("storage/b/" + bucket); !ok {
+		writeNotFound(w, bucket)
+
+		return
+	}
+
+	q := r.URL.Query()
+	prefix := q.Get("prefix")
+	delimiter := q.Get("delimiter")
+	versions := q.Get("versions") == "true"
+
+	items := []map[string]any{}
+	prefixes := map[string]struct{}{}
+
+	for _, o := range s.objects[bucket] {
+		name := fmt.Sprint(o.attrs["name"])
+
+		if (!o.live && !versions) || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		if delimiter != "" {
+			if idx := strings.Index(name[len(prefix):], delimiter); idx != -1 {
+				prefixes[name[:len(prefix)+idx+len(delimiter)]] = struct{}{}
+
+				continue
+			}
+		}
+
+		items = append(items, o.attrs)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return fmt.Sprint(items[i]["name"]) < fmt.Sprint(items[j]["name"])
+	})
+
+	prefixList := make([]string, 0, len(prefixes))
+	for p := range prefixes {
+		prefixList = append(prefixList, p)
+	}
+
+	sort.Strings(prefixList)
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"kind":     "storage#objects",
+		"items":    items,
+		"prefixes": prefixList,
+	})
+}
+
+func (s *Server) handleObject(w http.ResponseWriter, r *http.Request, bucket, name string) {
+	if _, ok := s.get("storage/b/" + bucket); !ok {
+		writeNotFound(w, bucket)
+
+		return
+	}
+
+	q := r.URL.Query()
+	obj := s.findObject(bucket, name, q.Get("generation"))
+
+	if !checkGenerationPreconditions(q, obj) {
+		writeError(w, http.StatusPreconditionFailed, "precondition failed for object '%s'", name)
+
+		return
+	}
+
+	if obj == nil {
+		writeNotFound(w, name)
+
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if q.Get("alt") != "media" {
+			writeJSON(w, http.StatusOK, obj.attrs)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", fmt.Sprint(obj.attrs["contentType"]))
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+		w.Header().Set("X-Goog-Generation", fmt.Sprint(obj.attrs["generation"]))
+		w.Header().Set("X-Goog-Metageneration", fmt.Sprint(obj.attrs["metageneration"]))
+		w.Header().Set("X-Goog-Hash", fmt.Sprintf("crc32c=%s,md5=%s", obj.attrs["crc32c"], obj.attrs["md5Hash"]))
+		w.WriteHeader(http.StatusOK)
+
+		_, _ = w.Write(obj.data)
+
+	case http.MethodPatch, http.MethodPut:
+		attrs, err := readJSON(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+
+			return
+		}
+
+		for _, k := range []string{"contentType", "cacheControl", "metadata", "acl", "contentDisposition", "contentEncoding"} {
+			if v, ok := attrs[k]; ok {
+				obj.attrs[k] = v
+			}
+		}
+
+		mg, _ := strconv.Atoi(fmt.Sprint(obj.attrs["metageneration"]))
+		obj.attrs["metageneration"] = strconv.Itoa(mg + 1)
+		obj.attrs["updated"] = timestamp()
+
+		writeJSON(w, http.StatusOK, obj.attrs)
+
+	case http.MethodDelete:
+		if q.Get("generation") != "" || !obj.live {
+			s.removeObject(bucket, obj)
+		} else {
+			s.archiveObject(bucket, obj)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) handleRewriteObject(w http.ResponseWriter, r *http.Request, srcBucket, srcName, dstBucket, dstName string) {
+	src := s.findObject(srcBucket, srcName, r.URL.Query().Get("sourceGeneration"))
+	if src == nil {
+		writeNotFound(w, srcName)
+
+		return
+	}
+
+	if _, ok := s.get("storage/b/" + dstBucket); !ok {
+		writeNotFound(w, dstBucket)
+
+		return
+	}
+
+	attrs, err := readJSON(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+
+		return
+	}
+
+	dst := s.liveObject(dstBucket, dstName)
+	if !checkGenerationPreconditions(r.URL.Query(), dst) {
+		writeError(w, http.StatusPreconditionFailed, "precondition failed for object '%s'", dstName)
+
+		return
+	}
+
+	if attrs["contentType"] == nil {
+		attrs["contentType"] = src.attrs["contentType"]
+	}
+
+	obj := s.storeObject(dstBucket, dstName, attrs, src.data)
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"kind":                "storage#rewriteResponse",
+		"done":                true,
+		"objectSize":          obj.attrs["size"],
+		"totalBytesRewritten": obj.attrs["size"],
+		"resource":            obj.attrs,
+	})
+}
+
+func (s *Server) storeObject(bucket, name string, attrs map[string]any, data []byte) *object {
+	if cur := s.liveObject(bucket, name); cur != nil {
+		s.archiveObject(bucket, cur)
+	}
+
+	md5sum := md5.Sum(data) //nolint:gosec
+	crc := make([]byte, 4)
+	binary.BigEndian.PutUint32(crc, crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli)))
+
+	gen := strconv.FormatInt(time.Now().UnixMicro()+s.nextID(), 10)
+
+	if attrs["contentType"] == nil || attrs["contentType"] == "" {
+		attrs["contentType"] = "application/octet-stream"
+	}
+
+	attrs["kind"] = "storage#object"
+	attrs["bucket"] = bucket
+	attrs["name"] = name
+	attrs["id"] = fmt.Sprintf("%s/%s/%s", bucket, name, gen)
+	attrs["generation"] = gen
+	attrs["metageneration"] = "1"
+	attrs["size"] = strconv.Itoa(len(data))
+	attrs["md5Hash"] = base64.StdEncoding.EncodeToString(md5sum[:])
+	attrs["crc32c"] = base64.StdEncoding.EncodeToString(crc)
+	attrs["storageClass"] = "STANDARD"
+	attrs["timeCreated"] = timestamp()
+	attrs["updated"] = timestamp()
+	attrs["etag"] = hash(gen, 8)
+	attrs["selfLink"] = fmt.Sprintf("%s/storage/v1/b/%s/o/%s", s.srv.URL, bucket, url.PathEscape(name))
+	attrs["mediaLink"] = fmt.Sprintf("%s/storage/v1/b/%s/o/%s?alt=media&generation=%s", s.srv.URL, bucket, url.PathEscape(name), gen)
+
+	obj := &object{
+		attrs: attrs,
+		data:  data,
+		live:  true,
+	}
+
+	s.objects[bucket] = append(s.objects[bucket], obj)
+
+	return obj
+}
+
+func (s *Server) handleStorageUpload(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	parts := storagePathParts(r, "/upload/storage/v1/")
+
+	if len(parts) != 3 || parts[0] != "b" || parts[2] != "o" || r.Method != http.MethodPost {
+		writeNotFound(w, r.URL.Path)
+
+		return
+	}
+
+	bucket := parts[1]
+
+	if _, ok := s.get("storage/b/" + bucket); !ok {
+		writeNotFound(w, bucket)
+
+		return
+	}
+
+	q := r.URL.Query()
+
+	if q.Get("uploadType") != "multipart" {
+		writeError(w, http.StatusNotImplemented, "upload type '%s' is not supported", q.Get("uploadType"))
+
+		return
+	}
+
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		writeError(w, http.StatusBadRequest, "invalid content type: %s", r.Header.Get("Content-Type"))
+
+		return
+	}
+
+	mr := multipart.NewReader(r.Body, params["boundary"])
+
+	metaPart, err := mr.NextPart()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+
+		return
+	}
+
+	attrs, err := readJSON(&http.Request{Body: metaPart})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+
+		return
+	}
+
+	dataPart, err := mr.NextPart()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+
+		return
+	}
+
+	data, err := io.ReadAll(dataPart)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+
+		return
+	}
+
+	if attrs["contentType"] == nil {
+		attrs["contentType"] = dataPart.Header.Get("Content-Type")
+	}
+
+	name := fmt.Sprint(attrs["name"])
+	if q.Get("name") != "" {
+		name = q.Get("name")
+	}
+
+	if !checkGenerationPreconditions(q, s.liveObject(bucket, name)) {
+		writeError(w, http.StatusPreconditionFailed, "precondition failed for object '%s'", name)
+
+		return
+	}
+
+	obj := s.storeObject(bucket, name, attrs, data)
+
+	writeJSON(w, http.StatusOK, obj.attrs)
+}
@@ -19,6 +19,42 @@ func (p *Plugin) Command(ctx context.Context, req *apiv1.CommandRequest) (*apiv1
 		err = p.DBDump(ctx, req)
 	case "dbrestore":
 		err = p.DBRestore(ctx, req)
+	case "dbexec":
+		err = p.DBExec(ctx, req)
+	case "dbmigrate":
+		err = p.DBMigrate(ctx, req)
+	case "state-encrypt":
+		err = p.StateEncrypt(ctx, req)
+	case "state-history":
+		err = p.StateHistory(ctx, req)
+	case "state-diff":
+		err = p.StateDiff(ctx, req)
+	case "state-restore":
+		err = p.StateRestore(ctx, req)
+	case "state-migrate":
+		err = p.StateMigrate(ctx, req)
+	case "import":
+		err = p.Import(ctx, req)
+	case "drift":
+		err = p.Drift(ctx, req)
+	case "force-unlock":
+		err = p.ForceUnlock(ctx, req)
+	case "secret-history":
+		err = p.SecretHistory(ctx, req)
+	case "secret-show":
+		err = p.SecretShow(ctx, req)
+	case "secret-diff":
+		err = p.SecretDiff(ctx, req)
+	case "secret-rollback":
+		err = p.SecretRollback(ctx, req)
+	case "secrets-import":
+		err = p.SecretsImport(ctx, req)
+	case "secrets-export":
+		err = p.SecretsExport(ctx, req)
+	case "trace":
+		err = p.Trace(ctx, req)
+	case "errors":
+		err = p.Errors(ctx, req)
 	default:
 		return nil, fmt.Errorf("unknown command: %s", req.Command)
 	}
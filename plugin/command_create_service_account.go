@@ -68,6 +68,57 @@ func addServiceAccountRoles(crmCli *cloudresourcemanager.Service, projectID, ser
 	return nil
 }
 
+// secretIAMRoleID is custom role allowing to grant access to single secrets, e.g. to Cloud Run service accounts,
+// without project wide Secret Manager admin role.
+const secretIAMRoleID = "outblocksSecretIamAdmin"
+
+var secretIAMRolePermissions = []string{
+	"secretmanager.secrets.getIamPolicy",
+	"secretmanager.secrets.setIamPolicy",
+}
+
+// ensureSecretIAMRole creates or updates custom role managing secret IAM policies and returns its name.
+func ensureSecretIAMRole(iamCli *iam.Service, projectID string) (string, error) {
+	name := fmt.Sprintf("projects/%s/roles/%s", projectID, secretIAMRoleID)
+	role := &iam.Role{
+		Title:               "Outblocks Secret IAM Admin",
+		Description:         "Manage access to secrets. Created by Outblocks.",
+		IncludedPermissions: secretIAMRolePermissions,
+		Stage:               "GA",
+	}
+
+	cur, err := iamCli.Projects.Roles.Get(name).Do()
+	if gcp.ErrIs404(err) {
+		_, err = iamCli.Projects.Roles.Create(fmt.Sprintf("projects/%s", projectID), &iam.CreateRoleRequest{
+			RoleId: secretIAMRoleID,
+			Role:   role,
+		}).Do()
+		if err != nil {
+			return "", fmt.Errorf("error creating secret iam role: %w", err)
+		}
+
+		return name, nil
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("error getting secret iam role: %w", err)
+	}
+
+	if cur.Deleted {
+		_, err = iamCli.Projects.Roles.Undelete(name, &iam.UndeleteRoleRequest{Etag: cur.Etag}).Do()
+		if err != nil {
+			return "", fmt.Errorf("error undeleting secret iam role: %w", err)
+		}
+	}
+
+	_, err = iamCli.Projects.Roles.Patch(name, role).UpdateMask("title,description,includedPermissions,stage").Do()
+	if err != nil {
+		return "", fmt.Errorf("error updating secret iam role: %w", err)
+	}
+
+	return name, nil
+}
+
 func (p *Plugin) setupOutblocksServiceAccountPermissions(ctx context.Context, name string, fresh bool) error {
 	crmCli, err := config.NewGCPCloudResourceManagerClient(ctx, p.gcred)
 	if err != nil {
@@ -91,16 +142,25 @@ func (p *Plugin) setupOutblocksServiceAccountPermissions(ctx context.Context, na
 		}
 	}
 
+	iamCli, err := config.NewGCPIAMClient(ctx, p.gcred)
+	if err != nil {
+		return fmt.Errorf("error creating gcp iam client: %w", err)
+	}
+
 	err = p.runAndEnsureAPI(ctx, func() error {
-		err = addServiceAccountRoles(crmCli, p.settings.ProjectID, name,
+		secretIAMRole, err := ensureSecretIAMRole(iamCli, p.settings.ProjectID)
+		if err != nil {
+			return err
+		}
+
+		return addServiceAccountRoles(crmCli, p.settings.ProjectID, name,
 			"roles/editor",
 			"roles/secretmanager.secretAccessor",
+			secretIAMRole,
 			"roles/cloudfunctions.admin",
 			"roles/run.admin",
-			"roles/cloudkms.cryptoKeyDecrypter",
+			"roles/cloudkms.cryptoKeyEncrypterDecrypter",
 		)
-
-		return err
 	})
 	if err != nil {
 		return err
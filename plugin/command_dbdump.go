@@ -2,6 +2,7 @@ package plugin
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -100,6 +101,124 @@ func databaseDockerImage(version string) string {
 	return ""
 }
 
+type dbCommandTarget struct {
+	dep          *apiv1.DependencyState
+	cloudsqluser *gcp.CloudSQLUser
+	dbVersion    string
+	dockerImage  string
+}
+
+// prepareDBCommandTarget finds database dependency with its user and docker image of database client.
+func (p *Plugin) prepareDBCommandTarget(req *apiv1.CommandRequest, name, user string) (*dbCommandTarget, error) {
+	dep, err := filterDepByName(name, req.DependencyStates)
+	if err != nil {
+		return nil, err
+	}
+
+	cloudsqluser, err := p.extractCloudSQLUser(req.PluginState.Registry, dep.Dependency, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if cloudsqluser == nil {
+		return nil, fmt.Errorf("user '%s' not found in database", user)
+	}
+
+	opts, err := deploy.NewDatabaseDepOptions(dep.Dependency.Properties.AsMap(), dep.Dependency.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	dockerImage := databaseDockerImage(opts.DatabaseVersion)
+	if dockerImage == "" {
+		return nil, fmt.Errorf("unsupported database version")
+	}
+
+	return &dbCommandTarget{
+		dep:          dep,
+		cloudsqluser: cloudsqluser,
+		dbVersion:    opts.DatabaseVersion,
+		dockerImage:  dockerImage,
+	}, nil
+}
+
+// runDatabaseDockerCommand runs dockerized database client connecting to proxy on host, feeding it stdin if set.
+// If stdout is nil, output is logged.
+func (p *Plugin) runDatabaseDockerCommand(dockerImage string, args, env []string, stdin io.Reader, stdout io.Writer) error {
+	runArgs := []string{"run"}
+
+	if stdin != nil {
+		runArgs = append(runArgs, "--interactive")
+	}
+
+	runArgs = append(runArgs,
+		"--rm",
+		"--add-host=host.docker.internal:host-gateway",
+	)
+
+	p.log.Debugf("Running command: %s\n", strings.Join(args, " "))
+
+	for _, e := range env {
+		runArgs = append(runArgs, fmt.Sprintf("--env=%s", e))
+	}
+
+	runArgs = append(runArgs, dockerImage)
+
+	cmd, err := command.New(
+		exec.Command("docker", append(runArgs, args...)...), //nolint:gosec,noctx
+	)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+
+	wg.Add(2)
+
+	if stdin != nil {
+		cmd.SetStdin(stdin)
+	}
+
+	go func() {
+		if stdout != nil {
+			_, _ = io.Copy(stdout, cmd.Stdout())
+		} else {
+			s := bufio.NewScanner(cmd.Stdout())
+
+			for s.Scan() {
+				p.log.Printf("%s\n", plugin_util.StripAnsiControl(s.Text()))
+			}
+		}
+
+		wg.Done()
+	}()
+
+	go func() {
+		s := bufio.NewScanner(cmd.Stderr())
+
+		for s.Scan() {
+			p.log.Printf("%s\n", plugin_util.StripAnsiControl(s.Text()))
+		}
+
+		wg.Done()
+	}()
+
+	err = cmd.Run()
+	if err != nil {
+		return err
+	}
+
+	err = cmd.Wait()
+
+	wg.Wait()
+
+	if err != nil {
+		return fmt.Errorf("error running command: %s: %w", strings.Join(args, " "), err)
+	}
+
+	return nil
+}
+
 func databaseDockerPostgresDumpArgs(port int, database, user, password string, tables, excludeTables []any, verbose, override bool, additionalArgs []string) (args, env []string) {
 	env = []string{
 		"PGHOST=host.docker.internal",
@@ -203,30 +322,11 @@ func (p *Plugin) DBDump(ctx context.Context, req *apiv1.CommandRequest) error {
 	excludeTables := flags["exclude-tables"].([]any) //nolint:errcheck
 	isHelp := hasHelpParam(req.Args.Positional)
 
-	dep, err := filterDepByName(name, req.DependencyStates)
+	target, err := p.prepareDBCommandTarget(req, name, user)
 	if err != nil {
 		return err
 	}
 
-	cloudsqluser, err := p.extractCloudSQLUser(req.PluginState.Registry, dep.Dependency, user)
-	if err != nil {
-		return err
-	}
-
-	if cloudsqluser == nil {
-		return fmt.Errorf("user '%s' not found in database", user)
-	}
-
-	opts, err := deploy.NewDatabaseDepOptions(dep.Dependency.Properties.AsMap(), dep.Dependency.Type)
-	if err != nil {
-		return err
-	}
-
-	dockerImage := databaseDockerImage(opts.DatabaseVersion)
-	if dockerImage == "" {
-		return fmt.Errorf("unsupported database version")
-	}
-
 	f, err := os.Create(file)
 	if err != nil {
 		return fmt.Errorf("cannot create output file '%s': %w", file, err)
@@ -234,78 +334,25 @@ func (p *Plugin) DBDump(ctx context.Context, req *apiv1.CommandRequest) error {
 
 	defer f.Close()
 
-	return p.runFuncOnDBConnection(ctx, dep, cloudsqluser, func(port int) error {
+	return p.runFuncOnDBConnection(ctx, target.dep, target.cloudsqluser, func(port int) error {
 		p.log.Infoln("Creating database dump...")
 
-		args, env := databaseDockerDumpArgs(opts.DatabaseVersion, port, database, cloudsqluser.Name.Any(), cloudsqluser.Password.Any(), tables, excludeTables, verbose, override, req.Args.Positional)
-		runArgs := []string{
-			"run",
-			"--rm",
-			"--add-host=host.docker.internal:host-gateway",
-		}
+		args, env := databaseDockerDumpArgs(target.dbVersion, port, database, target.cloudsqluser.Name.Any(), target.cloudsqluser.Password.Any(), tables, excludeTables, verbose, override, req.Args.Positional)
 
 		if isHelp {
-			args = []string{args[0], "--help"}
-		}
-
-		p.log.Debugf("Running command: %s\n", strings.Join(args, " "))
-
-		for _, e := range env {
-			runArgs = append(runArgs, fmt.Sprintf("--env=%s", e))
-		}
-
-		runArgs = append(runArgs, dockerImage)
-
-		cmd, err := command.New(
-			exec.Command("docker", append(runArgs, args...)...), //nolint:gosec,noctx
-		)
-		if err != nil {
-			return err
-		}
-
-		var stdout []byte
-
-		var wg sync.WaitGroup
-
-		wg.Add(2)
-
-		go func() {
-			if isHelp {
-				stdout, _ = io.ReadAll(cmd.Stdout())
-			} else {
-				_, _ = io.Copy(f, cmd.Stdout())
-			}
+			var stdout bytes.Buffer
 
-			wg.Done()
-		}()
-
-		go func() {
-			s := bufio.NewScanner(cmd.Stderr())
-
-			for s.Scan() {
-				p.log.Printf("%s\n", plugin_util.StripAnsiControl(s.Text()))
-			}
-
-			wg.Done()
-		}()
-
-		err = cmd.Run()
-		if err != nil {
-			return err
-		}
-
-		err = cmd.Wait()
-
-		wg.Wait()
+			args = []string{args[0], "--help"}
+			_ = p.runDatabaseDockerCommand(target.dockerImage, args, env, nil, &stdout)
 
-		if isHelp {
-			p.log.Print(string(stdout))
+			p.log.Print(stdout.String())
 
 			return nil
 		}
 
+		err := p.runDatabaseDockerCommand(target.dockerImage, args, env, nil, f)
 		if err != nil {
-			p.log.Errorf("Error running command: %s\n%s\n", strings.Join(args, " "), err)
+			p.log.Errorf("%s\n", err)
 
 			return nil
 		}
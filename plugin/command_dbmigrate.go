@@ -0,0 +1,298 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
+)
+
+var migrationTableRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*(\.[a-zA-Z_][a-zA-Z0-9_]*)?$`)
+
+type dbMigration struct {
+	version string
+	path    string
+}
+
+// listDBMigrations returns ordered migrations from given dir. Migration version is its filename without
+// ".sql" (or ".up.sql") extension, ".down.sql" files are skipped and versions have to be unique.
+func listDBMigrations(dir string) ([]*dbMigration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read migrations dir '%s': %w", dir, err)
+	}
+
+	var ret []*dbMigration
+
+	seen := make(map[string]string)
+
+	for _, e := range entries {
+		name := e.Name()
+
+		if e.IsDir() || !strings.HasSuffix(name, ".sql") || strings.HasSuffix(name, ".down.sql") {
+			continue
+		}
+
+		version := strings.TrimSuffix(strings.TrimSuffix(name, ".sql"), ".up")
+
+		if prev, ok := seen[version]; ok {
+			return nil, fmt.Errorf("duplicate migration version '%s' in files '%s' and '%s'", version, prev, name)
+		}
+
+		seen[version] = name
+
+		ret = append(ret, &dbMigration{
+			version: version,
+			path:    filepath.Join(dir, name),
+		})
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].version < ret[j].version
+	})
+
+	return ret, nil
+}
+
+func databaseDockerPostgresExecArgs(port int, database, user, password string, query, verbose bool) (args, env []string) {
+	env = []string{
+		"PGHOST=host.docker.internal",
+		fmt.Sprintf("PGPORT=%d", port),
+		fmt.Sprintf("PGDATABASE=%s", database),
+		fmt.Sprintf("PGUSER=%s", user),
+		fmt.Sprintf("PGPASSWORD=%s", password),
+	}
+
+	args = []string{"psql", "--no-psqlrc", "--set=ON_ERROR_STOP=1"}
+
+	if query {
+		args = append(args, "--tuples-only", "--no-align", "--quiet")
+	} else {
+		args = append(args, "--single-transaction")
+	}
+
+	if verbose {
+		args = append(args, "--echo-queries")
+	}
+
+	return args, env
+}
+
+func databaseDockerMySQLExecArgs(port int, database, user, password string, query, verbose bool) (args, env []string) {
+	env = []string{
+		"MYSQL_HOST=host.docker.internal",
+		fmt.Sprintf("MYSQL_TCP_PORT=%d", port),
+		fmt.Sprintf("MYSQL_PWD=%s", password),
+	}
+
+	args = []string{"mysql", fmt.Sprintf("--user=%s", user), "--compress"}
+
+	if query {
+		args = append(args, "--batch", "--skip-column-names")
+	}
+
+	if verbose {
+		args = append(args, "--verbose")
+	}
+
+	args = append(args, database)
+
+	return args, env
+}
+
+func databaseDockerExecArgs(dbVersion string, port int, database, user, password string, query, verbose bool) (args, env []string) {
+	switch {
+	case strings.HasPrefix(dbVersion, "POSTGRES_"):
+		return databaseDockerPostgresExecArgs(port, database, user, password, query, verbose)
+	case strings.HasPrefix(dbVersion, "MYSQL_"):
+		return databaseDockerMySQLExecArgs(port, database, user, password, query, verbose)
+	}
+
+	panic("unsupported database version")
+}
+
+func migrationTableSQL(dbVersion, table string) string {
+	if strings.HasPrefix(dbVersion, "MYSQL_") {
+		return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (version VARCHAR(255) NOT NULL PRIMARY KEY, applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP);\n", table)
+	}
+
+	return fmt.Sprintf("SET client_min_messages = warning;\nCREATE TABLE IF NOT EXISTS %s (version VARCHAR(255) NOT NULL PRIMARY KEY, applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW());\n", table)
+}
+
+// stripLineComment returns line without trailing "--" or "#" comment that is outside of quotes.
+func stripLineComment(line string) string {
+	var quote rune
+
+	for i, c := range line {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '#' || (c == '-' && strings.HasPrefix(line[i:], "--")):
+			return line[:i]
+		}
+	}
+
+	return line
+}
+
+// sqlTerminated returns true if last statement of sql ends with ";", ignoring trailing comments.
+func sqlTerminated(sql string) bool {
+	lines := strings.Split(sql, "\n")
+
+	for i := len(lines) - 1; i >= 0; i-- {
+		if l := strings.TrimSpace(stripLineComment(lines[i])); l != "" {
+			return strings.HasSuffix(l, ";")
+		}
+	}
+
+	return true
+}
+
+// migrationSQL returns migration contents followed by statement recording it as applied.
+func migrationSQL(data []byte, table, version string) string {
+	sql := strings.TrimSpace(string(data))
+
+	// Terminate on a separate line, so that it doesn't end up in a trailing comment.
+	if !sqlTerminated(sql) {
+		sql += "\n;"
+	}
+
+	return fmt.Sprintf("%s\nINSERT INTO %s (version) VALUES ('%s');\n", sql, table, strings.ReplaceAll(version, "'", "''"))
+}
+
+func (p *Plugin) DBExec(ctx context.Context, req *apiv1.CommandRequest) error {
+	flags := req.Args.Flags.AsMap()
+	name := flags["name"].(string)         //nolint:errcheck
+	user := flags["user"].(string)         //nolint:errcheck
+	file := flags["file"].(string)         //nolint:errcheck
+	database := flags["database"].(string) //nolint:errcheck
+	verbose := flags["verbose"].(bool)     //nolint:errcheck
+
+	target, err := p.prepareDBCommandTarget(req, name, user)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("cannot open sql file '%s': %w", file, err)
+	}
+
+	defer f.Close()
+
+	return p.runFuncOnDBConnection(ctx, target.dep, target.cloudsqluser, func(port int) error {
+		p.log.Infof("Executing '%s'...\n", file)
+
+		args, env := databaseDockerExecArgs(target.dbVersion, port, database, target.cloudsqluser.Name.Any(), target.cloudsqluser.Password.Any(), false, verbose)
+
+		err := p.runDatabaseDockerCommand(target.dockerImage, args, env, f, nil)
+		if err != nil {
+			return err
+		}
+
+		p.log.Successln("All done.")
+
+		return nil
+	})
+}
+
+func (p *Plugin) DBMigrate(ctx context.Context, req *apiv1.CommandRequest) error {
+	flags := req.Args.Flags.AsMap()
+	name := flags["name"].(string)         //nolint:errcheck
+	user := flags["user"].(string)         //nolint:errcheck
+	dir := flags["dir"].(string)           //nolint:errcheck
+	database := flags["database"].(string) //nolint:errcheck
+	table := flags["table"].(string)       //nolint:errcheck
+	dryRun := flags["dry-run"].(bool)      //nolint:errcheck
+	verbose := flags["verbose"].(bool)     //nolint:errcheck
+
+	if !migrationTableRegex.MatchString(table) {
+		return fmt.Errorf("invalid migrations table name: %s", table)
+	}
+
+	migrations, err := listDBMigrations(dir)
+	if err != nil {
+		return err
+	}
+
+	target, err := p.prepareDBCommandTarget(req, name, user)
+	if err != nil {
+		return err
+	}
+
+	return p.runFuncOnDBConnection(ctx, target.dep, target.cloudsqluser, func(port int) error {
+		dbUser := target.cloudsqluser.Name.Any()
+		dbPassword := target.cloudsqluser.Password.Any()
+
+		// Fetch already applied migrations.
+		args, env := databaseDockerExecArgs(target.dbVersion, port, database, dbUser, dbPassword, true, false)
+
+		var out bytes.Buffer
+
+		err := p.runDatabaseDockerCommand(target.dockerImage, args, env,
+			strings.NewReader(migrationTableSQL(target.dbVersion, table)+fmt.Sprintf("SELECT version FROM %s;\n", table)), &out)
+		if err != nil {
+			return fmt.Errorf("error reading applied migrations: %w", err)
+		}
+
+		applied := make(map[string]bool)
+
+		for _, l := range strings.Split(out.String(), "\n") {
+			if l = strings.TrimSpace(l); l != "" {
+				applied[l] = true
+			}
+		}
+
+		var pending []*dbMigration
+
+		for _, m := range migrations {
+			if !applied[m.version] {
+				pending = append(pending, m)
+			}
+		}
+
+		if len(pending) == 0 {
+			p.log.Successln("Database is up to date.")
+
+			return nil
+		}
+
+		if dryRun {
+			for _, m := range pending {
+				p.log.Printf("Pending migration: %s\n", m.version)
+			}
+
+			return nil
+		}
+
+		args, env = databaseDockerExecArgs(target.dbVersion, port, database, dbUser, dbPassword, false, verbose)
+
+		for _, m := range pending {
+			p.log.Infof("Applying migration '%s'...\n", m.version)
+
+			data, err := os.ReadFile(m.path)
+			if err != nil {
+				return fmt.Errorf("cannot read migration file '%s': %w", m.path, err)
+			}
+
+			err = p.runDatabaseDockerCommand(target.dockerImage, args, env, strings.NewReader(migrationSQL(data, table, m.version)), nil)
+			if err != nil {
+				return fmt.Errorf("error applying migration '%s': %w", m.version, err)
+			}
+		}
+
+		p.log.Successf("All done. Applied %d migration(s).\n", len(pending))
+
+		return nil
+	})
+}
@@ -0,0 +1,141 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestListDBMigrations(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		files []string
+		want  []string
+		err   string
+	}{
+		{
+			name:  "ordered by filename",
+			files: []string{"002_users.sql", "010_orders.sql", "001_init.sql"},
+			want:  []string{"001_init", "002_users", "010_orders"},
+		},
+		{
+			name:  "up and down files",
+			files: []string{"002_users.up.sql", "002_users.down.sql", "001_init.up.sql", "001_init.down.sql"},
+			want:  []string{"001_init", "002_users"},
+		},
+		{
+			name:  "other files and dirs are skipped",
+			files: []string{"001_init.sql", "README.md", "seeds/001_seed.sql"},
+			want:  []string{"001_init"},
+		},
+		{
+			name:  "duplicate version",
+			files: []string{"001_init.sql", "001_init.up.sql"},
+			err:   "duplicate migration version '001_init' in files '001_init.sql' and '001_init.up.sql'",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			for _, f := range tc.files {
+				path := filepath.Join(dir, f)
+
+				err := os.MkdirAll(filepath.Dir(path), 0o755)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				err = os.WriteFile(path, []byte("SELECT 1;"), 0o600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			migrations, err := listDBMigrations(dir)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error containing %q, got: %v", tc.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+
+			for _, m := range migrations {
+				got = append(got, m.version)
+
+				if m.path != filepath.Join(dir, filepath.Base(m.path)) {
+					t.Fatalf("unexpected migration path: %s", m.path)
+				}
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestMigrationSQL(t *testing.T) {
+	const insert = "INSERT INTO schema_migrations (version) VALUES ('001');\n"
+
+	for _, tc := range []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "terminated",
+			in:   "CREATE TABLE a (id INT);\n",
+			want: "CREATE TABLE a (id INT);\n" + insert,
+		},
+		{
+			name: "unterminated",
+			in:   "CREATE TABLE a (id INT)",
+			want: "CREATE TABLE a (id INT)\n;\n" + insert,
+		},
+		{
+			name: "unterminated with trailing comment",
+			in:   "CREATE TABLE a (id INT) -- table a\n",
+			want: "CREATE TABLE a (id INT) -- table a\n;\n" + insert,
+		},
+		{
+			name: "unterminated with trailing hash comment",
+			in:   "CREATE TABLE a (id INT) # table a",
+			want: "CREATE TABLE a (id INT) # table a\n;\n" + insert,
+		},
+		{
+			name: "terminated with trailing comments",
+			in:   "CREATE TABLE a (id INT); -- table a\n-- done\n",
+			want: "CREATE TABLE a (id INT); -- table a\n-- done\n" + insert,
+		},
+		{
+			name: "comment markers in strings",
+			in:   "INSERT INTO a VALUES ('--', \"#\");",
+			want: "INSERT INTO a VALUES ('--', \"#\");\n" + insert,
+		},
+		{
+			name: "empty",
+			in:   "\n",
+			want: "\n" + insert,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := migrationSQL([]byte(tc.in), "schema_migrations", "001")
+			if got != tc.want {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+
+	if got := migrationSQL(nil, "schema_migrations", "it's"); !strings.Contains(got, "VALUES ('it''s')") {
+		t.Fatalf("expected version to be escaped, got: %q", got)
+	}
+}
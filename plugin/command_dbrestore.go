@@ -1,18 +1,12 @@
 package plugin
 
 import (
-	"bufio"
 	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"strings"
-	"sync"
 
-	"github.com/outblocks/cli-plugin-gcp/deploy"
 	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
-	plugin_util "github.com/outblocks/outblocks-plugin-go/util"
-	"github.com/outblocks/outblocks-plugin-go/util/command"
 )
 
 func databaseDockerPostgresRestoreArgs(port int, database, user, password string, tables, excludeTables []any, usePsql, verbose, override bool, additionalArgs []string) (args, env []string) {
@@ -99,30 +93,11 @@ func (p *Plugin) DBRestore(ctx context.Context, req *apiv1.CommandRequest) error
 	excludeTables := flags["exclude-tables"].([]any) //nolint:errcheck
 	isHelp := hasHelpParam(req.Args.Positional)
 
-	dep, err := filterDepByName(name, req.DependencyStates)
+	target, err := p.prepareDBCommandTarget(req, name, user)
 	if err != nil {
 		return err
 	}
 
-	cloudsqluser, err := p.extractCloudSQLUser(req.PluginState.Registry, dep.Dependency, user)
-	if err != nil {
-		return err
-	}
-
-	if cloudsqluser == nil {
-		return fmt.Errorf("user '%s' not found in database", user)
-	}
-
-	opts, err := deploy.NewDatabaseDepOptions(dep.Dependency.Properties.AsMap(), dep.Dependency.Type)
-	if err != nil {
-		return err
-	}
-
-	dockerImage := databaseDockerImage(opts.DatabaseVersion)
-	if dockerImage == "" {
-		return fmt.Errorf("unsupported database version")
-	}
-
 	f, err := os.Open(file)
 	if err != nil {
 		return fmt.Errorf("cannot open backup file '%s': %w", file, err)
@@ -130,73 +105,18 @@ func (p *Plugin) DBRestore(ctx context.Context, req *apiv1.CommandRequest) error
 
 	defer f.Close()
 
-	return p.runFuncOnDBConnection(ctx, dep, cloudsqluser, func(port int) error {
+	return p.runFuncOnDBConnection(ctx, target.dep, target.cloudsqluser, func(port int) error {
 		p.log.Infoln("Restoring database dump...")
 
-		args, env := databaseDockerRestoreArgs(opts.DatabaseVersion, port, database, cloudsqluser.Name.Any(), cloudsqluser.Password.Any(), tables, excludeTables, usePsql, verbose, override, req.Args.Positional)
-		runArgs := []string{
-			"run",
-			"--interactive",
-			"--rm",
-			"--add-host=host.docker.internal:host-gateway",
-		}
+		args, env := databaseDockerRestoreArgs(target.dbVersion, port, database, target.cloudsqluser.Name.Any(), target.cloudsqluser.Password.Any(), tables, excludeTables, usePsql, verbose, override, req.Args.Positional)
 
 		if isHelp {
 			args = []string{args[0], "--help"}
 		}
 
-		p.log.Debugf("Running command: %s\n", strings.Join(args, " "))
-
-		for _, e := range env {
-			runArgs = append(runArgs, fmt.Sprintf("--env=%s", e))
-		}
-
-		runArgs = append(runArgs, dockerImage)
-
-		cmd, err := command.New(
-			exec.Command("docker", append(runArgs, args...)...), //nolint:gosec,noctx
-		)
-		if err != nil {
-			return err
-		}
-
-		var wg sync.WaitGroup
-
-		wg.Add(2)
-
-		cmd.SetStdin(f)
-
-		go func() {
-			s := bufio.NewScanner(cmd.Stdout())
-
-			for s.Scan() {
-				p.log.Printf("%s\n", plugin_util.StripAnsiControl(s.Text()))
-			}
-
-			wg.Done()
-		}()
-
-		go func() {
-			s := bufio.NewScanner(cmd.Stderr())
-
-			for s.Scan() {
-				p.log.Printf("%s\n", plugin_util.StripAnsiControl(s.Text()))
-			}
-
-			wg.Done()
-		}()
-
-		err = cmd.Run()
-		if err != nil {
-			return err
-		}
-
-		err = cmd.Wait()
-
-		wg.Wait()
-
+		err := p.runDatabaseDockerCommand(target.dockerImage, args, env, f, nil)
 		if err != nil {
-			p.log.Errorf("Error running command: %s\n%s\n", strings.Join(args, " "), err)
+			p.log.Errorf("%s\n", err)
 
 			return nil
 		}
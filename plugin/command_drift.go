@@ -0,0 +1,51 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/outblocks/cli-plugin-gcp/actions"
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
+	"github.com/outblocks/outblocks-plugin-go/registry"
+)
+
+// Drift reads all resources back from GCP and prints JSON report of fields changed outside of Outblocks since last deploy.
+func (p *Plugin) Drift(ctx context.Context, req *apiv1.CommandRequest) error {
+	flags := req.Args.Flags.AsMap()
+
+	fail := flags["fail"].(bool) //nolint:errcheck
+
+	appPlans := make([]*apiv1.AppPlan, 0, len(req.AppStates))
+	for _, s := range req.AppStates {
+		appPlans = append(appPlans, &apiv1.AppPlan{State: s})
+	}
+
+	depPlans := make([]*apiv1.DependencyPlan, 0, len(req.DependencyStates))
+	for _, s := range req.DependencyStates {
+		depPlans = append(depPlans, &apiv1.DependencyPlan{State: s})
+	}
+
+	a, err := actions.NewPlan(p.PluginContext(), p.log, req.PluginState, nil, registry.NewRegistry(&registry.Options{Read: true}), false, true)
+	if err != nil {
+		return err
+	}
+
+	report, err := a.Drift(ctx, appPlans, depPlans)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	p.log.Println(string(data))
+
+	if fail && report.Drifted {
+		return fmt.Errorf("drift detected")
+	}
+
+	return nil
+}
@@ -0,0 +1,194 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/outblocks/cli-plugin-gcp/deploy"
+	"github.com/outblocks/cli-plugin-gcp/gcp"
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
+	"google.golang.org/api/clouderrorreporting/v1beta1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const errorsStackTraceLines = 10
+
+// errorReportingPeriods maps period flag values to Error Reporting time range periods.
+var errorReportingPeriods = map[string]struct {
+	period   string
+	duration time.Duration
+}{
+	"1h":  {"PERIOD_1_HOUR", time.Hour},
+	"6h":  {"PERIOD_6_HOURS", 6 * time.Hour},
+	"1d":  {"PERIOD_1_DAY", 24 * time.Hour},
+	"1w":  {"PERIOD_1_WEEK", 7 * 24 * time.Hour},
+	"30d": {"PERIOD_30_DAYS", 30 * 24 * time.Hour},
+}
+
+// openBrowser opens URL in default web browser.
+func openBrowser(u string) error {
+	var cmd *exec.Cmd
+
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", u)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", u)
+	default:
+		cmd = exec.Command("xdg-open", u)
+	}
+
+	return cmd.Start()
+}
+
+// formatErrorTime returns error reporting timestamp in local time.
+func formatErrorTime(s string) string {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return s
+	}
+
+	return t.Local().Format("2006-01-02 15:04:05")
+}
+
+// stackTraceSample returns first lines of error message, which holds stack trace if one was reported.
+func stackTraceSample(msg string) string {
+	lines := strings.Split(strings.TrimSpace(msg), "\n")
+
+	if len(lines) > errorsStackTraceLines {
+		lines = append(lines[:errorsStackTraceLines], fmt.Sprintf("... (%d more lines)", len(lines)-errorsStackTraceLines))
+	}
+
+	return "    " + strings.Join(lines, "\n    ")
+}
+
+// Errors prints Error Reporting error groups of Cloud Run services and functions of current environment.
+func (p *Plugin) Errors(ctx context.Context, req *apiv1.CommandRequest) error {
+	flags := req.Args.Flags.AsMap()
+
+	periodFlag := flags["period"].(string)   //nolint:errcheck
+	limit := int64(flags["limit"].(float64)) //nolint:errcheck
+	open := flags["open"].(bool)             //nolint:errcheck
+
+	period, ok := errorReportingPeriods[periodFlag]
+	if !ok {
+		return fmt.Errorf("invalid period: %s, supported periods: 1h, 6h, 1d, 1w, 30d", periodFlag)
+	}
+
+	if limit <= 0 {
+		return fmt.Errorf("invalid limit: %d", limit)
+	}
+
+	var apps []*apiv1.App
+
+	for _, s := range req.AppStates {
+		switch s.App.Type {
+		case deploy.AppTypeService, deploy.AppTypeFunction:
+			apps = append(apps, s.App)
+		}
+	}
+
+	if len(apps) == 0 {
+		return fmt.Errorf("no service or function apps found")
+	}
+
+	sort.Slice(apps, func(i, j int) bool {
+		return apps[i].Id < apps[j].Id
+	})
+
+	cli, err := p.PluginContext().GCPErrorReportingClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	total := 0
+
+	for _, app := range apps {
+		// Error Reporting service name is the name of Cloud Run service or function.
+		service := gcp.ID(p.env, app.Id)
+
+		var stats []*clouderrorreporting.ErrorGroupStats
+
+		err = p.runAndEnsureAPI(ctx, func() error {
+			resp, err := cli.Projects.GroupStats.List("projects/" + p.settings.ProjectID).
+				ServiceFilterService(service).TimeRangePeriod(period.period).Order("COUNT_DESC").PageSize(limit).Context(ctx).Do()
+			if err != nil {
+				return err
+			}
+
+			stats = resp.ErrorGroupStats
+
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("error listing error groups of app '%s': %w", app.Name, err)
+		}
+
+		if len(stats) == 0 {
+			continue
+		}
+
+		total += len(stats)
+
+		p.log.Printf("%s %s (%s): %d error groups\n", app.Type, app.Name, service, len(stats))
+
+		for _, s := range stats {
+			status := ""
+			if s.Group != nil && s.Group.ResolutionStatus != "" {
+				status = " " + s.Group.ResolutionStatus
+			}
+
+			p.log.Printf("  count=%d first_seen=%s last_seen=%s%s\n", s.Count, formatErrorTime(s.FirstSeenTime), formatErrorTime(s.LastSeenTime), status)
+
+			if s.Group != nil {
+				p.log.Printf("  https://console.cloud.google.com/errors/detail/%s?project=%s\n", s.Group.GroupId, p.settings.ProjectID)
+			}
+
+			if s.Representative != nil {
+				p.log.Printf("%s\n", stackTraceSample(s.Representative.Message))
+			}
+
+			p.log.Println()
+		}
+	}
+
+	if total == 0 {
+		p.log.Infof("No errors reported in last %s.\n", periodFlag)
+	}
+
+	// Show error logs of the same apps.
+	state := req.PluginState
+	if state == nil {
+		state = &apiv1.PluginState{}
+	}
+
+	filter, _, err := p.createLogFilter(ctx, &apiv1.LogsRequest{
+		Apps:     apps,
+		State:    state,
+		Severity: apiv1.LogSeverity_LOG_SEVERITY_ERROR,
+		Start:    timestamppb.New(time.Now().Add(-period.duration)),
+	})
+	if err != nil {
+		return err
+	}
+
+	loggingURL := logsExplorerURL(p.settings.ProjectID, filter)
+
+	p.log.Infof("Logs Explorer Web UI: %s\n", loggingURL)
+
+	if !open {
+		return nil
+	}
+
+	err = openBrowser(loggingURL)
+	if err != nil {
+		return fmt.Errorf("error opening web browser: %w", err)
+	}
+
+	return nil
+}
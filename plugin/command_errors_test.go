@@ -0,0 +1,152 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/outblocks/cli-plugin-gcp/deploy"
+	"github.com/outblocks/cli-plugin-gcp/gcp"
+	"github.com/outblocks/cli-plugin-gcp/internal/fakegcp"
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
+)
+
+func TestErrors(t *testing.T) {
+	ctx := context.Background()
+	p, srv := newTestPlugin(t)
+
+	appStates := make(map[string]*apiv1.AppState)
+
+	for _, app := range []*apiv1.App{
+		{Id: "app_api", Name: "api", Type: deploy.AppTypeService},
+		{Id: "app_fn", Name: "fn", Type: deploy.AppTypeFunction},
+		{Id: "app_web", Name: "web", Type: deploy.AppTypeStatic},
+		{Id: "app_worker", Name: "worker", Type: deploy.AppTypeJob},
+	} {
+		appStates[app.Id] = &apiv1.AppState{App: app}
+	}
+
+	apiID, fnID := gcp.ID(p.env, "app_api"), gcp.ID(p.env, "app_fn")
+
+	var stackTrace []string
+
+	for i := range 15 {
+		stackTrace = append(stackTrace, fmt.Sprintf("line %d", i+1))
+	}
+
+	srv.AddErrorGroupStats(apiID, map[string]any{
+		"group":          map[string]any{"groupId": "group-rare"},
+		"count":          "2",
+		"firstSeenTime":  "2026-01-01T10:00:00Z",
+		"lastSeenTime":   "2026-01-02T10:00:00Z",
+		"representative": map[string]any{"message": "rare error"},
+	})
+	srv.AddErrorGroupStats(apiID, map[string]any{
+		"group":          map[string]any{"groupId": "group-panic", "resolutionStatus": "ACKNOWLEDGED"},
+		"count":          "42",
+		"firstSeenTime":  "2026-01-01T10:00:00Z",
+		"lastSeenTime":   "2026-01-02T10:00:00Z",
+		"representative": map[string]any{"message": strings.Join(stackTrace, "\n")},
+	})
+	srv.AddErrorGroupStats(fnID, map[string]any{
+		"group":          map[string]any{"groupId": "group-fn"},
+		"count":          "5",
+		"representative": map[string]any{"message": "function error"},
+	})
+	srv.AddErrorGroupStats(gcp.ID(p.env, "app_other"), map[string]any{
+		"group": map[string]any{"groupId": "group-other"},
+		"count": "100",
+	})
+
+	listErrors := func(states map[string]*apiv1.AppState, period string, limit int) ([]string, error) {
+		p.log = &fakegcp.Logger{}
+
+		err := p.Errors(ctx, &apiv1.CommandRequest{
+			Args: &apiv1.CommandArgs{Flags: mustStruct(t, map[string]any{
+				"period": period,
+				"limit":  limit,
+				"open":   false,
+			})},
+			AppStates: states,
+		})
+
+		return p.log.(*fakegcp.Logger).Messages(), err
+	}
+
+	msgs, err := listErrors(appStates, "1d", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := strings.Join(msgs, "")
+
+	for _, want := range []string{
+		fmt.Sprintf("print: service api (%s): 2 error groups\n", apiID),
+		fmt.Sprintf("print: function fn (%s): 1 error groups\n", fnID),
+		"errors/detail/group-fn?project=" + srv.ProjectID,
+		"ACKNOWLEDGED\n",
+		"https://console.cloud.google.com/errors/detail/group-panic?project=" + srv.ProjectID,
+		"    line 10\n    ... (5 more lines)\n",
+		"    rare error\n",
+		"info: Logs Explorer Web UI: https://console.cloud.google.com/logs/query;query=",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+
+	// Groups are listed by count, only for service and function apps.
+	if strings.Index(out, "group-panic") > strings.Index(out, "group-rare") {
+		t.Fatalf("expected error groups to be ordered by count, got:\n%s", out)
+	}
+
+	for _, notWant := range []string{"group-other", "line 11", "web", "worker"} {
+		if strings.Contains(out, notWant) {
+			t.Fatalf("expected output not to contain %q, got:\n%s", notWant, out)
+		}
+	}
+
+	// Logs Explorer shows errors of the same apps.
+	logs := msgs[len(msgs)-1]
+	if !strings.Contains(logs, apiID) || !strings.Contains(logs, fnID) || !strings.Contains(logs, "ERROR") {
+		t.Fatalf("expected logs explorer url to filter error logs of apps, got: %s", logs)
+	}
+
+	msgs, err = listErrors(appStates, "1d", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out = strings.Join(msgs, "")
+	if !strings.Contains(out, "api ("+apiID+"): 1 error groups") || strings.Contains(out, "group-rare") {
+		t.Fatalf("expected limit to be applied, got:\n%s", out)
+	}
+
+	admin := &apiv1.App{Id: "app_admin", Name: "admin", Type: deploy.AppTypeService}
+
+	msgs, err = listErrors(map[string]*apiv1.AppState{admin.Id: {App: admin}}, "1h", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(strings.Join(msgs, ""), "info: No errors reported in last 1h.\n") {
+		t.Fatalf("expected no errors to be reported, got: %q", msgs)
+	}
+
+	for _, tc := range []struct {
+		states map[string]*apiv1.AppState
+		period string
+		limit  int
+		err    string
+	}{
+		{appStates, "2d", 10, "invalid period: 2d"},
+		{appStates, "1d", 0, "invalid limit: 0"},
+		{map[string]*apiv1.AppState{"app_web": appStates["app_web"]}, "1d", 10, "no service or function apps found"},
+	} {
+		_, err := listErrors(tc.states, tc.period, tc.limit)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Fatalf("expected error containing %q, got: %v", tc.err, err)
+		}
+	}
+}
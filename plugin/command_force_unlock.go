@@ -0,0 +1,89 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/storage"
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
+)
+
+// ForceUnlock shows owner of state lock or named lock and deletes it, regardless of its lease.
+func (p *Plugin) ForceUnlock(ctx context.Context, req *apiv1.CommandRequest) error {
+	flags := req.Args.Flags.AsMap()
+
+	name := flags["name"].(string)      //nolint:errcheck
+	lockID := flags["lock-id"].(string) //nolint:errcheck
+	dryRun := flags["dry-run"].(bool)   //nolint:errcheck
+
+	lockfile := p.stateLockfile()
+	desc := "state lock"
+
+	if name != "" {
+		lockfile = p.lockfile(name)
+		desc = fmt.Sprintf("lock '%s'", name)
+	}
+
+	cli, err := p.PluginContext().GCPStorageClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	o := cli.Bucket(p.locksBucket(flags)).Object(lockfile)
+
+	info, owner, createdAt, err := checkLock(ctx, o)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		p.log.Infof("No %s is held.\n", desc)
+
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("error checking %s: %w", desc, err)
+	}
+
+	if lockID != "" && lockID != info {
+		return fmt.Errorf("%s is held with lock id '%s', not '%s'", desc, info, lockID)
+	}
+
+	lease := "none"
+
+	attrs, err := o.Attrs(ctx)
+	if err == nil {
+		if expiry, ok := lockLeaseExpiry(attrs); ok {
+			if time.Now().After(expiry) {
+				lease = fmt.Sprintf("expired %s ago", time.Since(expiry).Round(time.Second))
+			} else {
+				lease = fmt.Sprintf("expires in %s", time.Until(expiry).Round(time.Second))
+			}
+		}
+	}
+
+	p.log.Printf("Lock ID:  %s\n", info)
+	p.log.Printf("Owner:    %s\n", owner)
+	p.log.Printf("Acquired: %s (%s ago)\n", createdAt.Local().Format("2006-01-02 15:04:05"), time.Since(createdAt).Round(time.Second))
+	p.log.Printf("Lease:    %s\n", lease)
+
+	if dryRun {
+		return nil
+	}
+
+	err = releaseLock(ctx, o, info)
+
+	switch {
+	case errors.Is(err, errReleaseLockFailed):
+		p.log.Infof("%s was already released.\n", desc)
+
+		return nil
+	case errors.Is(err, errReleaseLockMismatch):
+		return fmt.Errorf("%s was acquired again in the meantime, check it again", desc)
+	case err != nil:
+		return err
+	}
+
+	p.log.Successf("Released %s held by %s.\n", desc, owner)
+
+	return nil
+}
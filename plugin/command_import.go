@@ -0,0 +1,413 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/outblocks/cli-plugin-gcp/deploy"
+	"github.com/outblocks/cli-plugin-gcp/gcp"
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
+	"github.com/outblocks/outblocks-plugin-go/registry"
+	"github.com/outblocks/outblocks-plugin-go/registry/fields"
+)
+
+// defaultRegistryPath is where plugin registry is kept in state file when it isn't there yet.
+const defaultRegistryPath = "plugins_state.gcp"
+
+const (
+	ImportTypeCloudRun = "cloud_run"
+	ImportTypeCloudSQL = "cloud_sql"
+	ImportTypeBucket   = "bucket"
+)
+
+// importTarget describes registry namespace that imported resource is added to.
+type importTarget struct {
+	source    string
+	namespace string
+	name      string
+	// bucket is name of bucket configured for storage dependency, empty if dependency is not in state.
+	bucket string
+}
+
+func findImportTarget(flags map[string]any, appStates map[string]*apiv1.AppState, depStates map[string]*apiv1.DependencyState) (*importTarget, error) {
+	appName := flags["app"].(string)         //nolint:errcheck
+	depName := flags["dependency"].(string)  //nolint:errcheck
+	namespace := flags["namespace"].(string) //nolint:errcheck
+
+	if (appName == "") == (depName == "") {
+		return nil, fmt.Errorf("exactly one of app or dependency flag is required")
+	}
+
+	if appName != "" {
+		for _, a := range appStates {
+			if a.App != nil && a.App.Name == appName {
+				return &importTarget{source: registry.SourceApp, namespace: a.App.Id, name: appName}, nil
+			}
+		}
+
+		if namespace == "" {
+			return nil, fmt.Errorf("app '%s' not found in state, specify its id with --namespace", appName)
+		}
+
+		return &importTarget{source: registry.SourceApp, namespace: namespace, name: appName}, nil
+	}
+
+	for _, d := range depStates {
+		if d.Dependency == nil || d.Dependency.Name != depName {
+			continue
+		}
+
+		target := &importTarget{source: registry.SourceDependency, namespace: d.Dependency.Id, name: depName}
+
+		if d.Dependency.Type == deploy.DepTypeStorage {
+			dep, err := deploy.NewStorageDep(d.Dependency)
+			if err != nil {
+				return nil, fmt.Errorf("error decoding dependency '%s' properties: %w", depName, err)
+			}
+
+			target.bucket = dep.Opts.Name
+		}
+
+		return target, nil
+	}
+
+	if namespace == "" {
+		return nil, fmt.Errorf("dependency '%s' not found in state, specify its id with --namespace", depName)
+	}
+
+	return &importTarget{source: registry.SourceDependency, namespace: namespace, name: depName}, nil
+}
+
+// parseImportID returns last path segment of GCP object ID together with project and location found in it.
+func parseImportID(id string) (project, location, name string) {
+	id = strings.TrimPrefix(id, "gs://")
+	parts := strings.Split(strings.Trim(id, "/"), "/")
+
+	for i := 0; i+1 < len(parts); i += 2 {
+		switch parts[i] {
+		case "projects", "namespaces":
+			project = parts[i+1]
+		case "locations", "regions":
+			location = parts[i+1]
+		}
+	}
+
+	return project, location, parts[len(parts)-1]
+}
+
+// importResource reads existing GCP object and returns it serialized as registry entry marked as existing.
+func (p *Plugin) importResource(ctx context.Context, target *importTarget, typ, id string) (*registry.ResourceSerialized, error) {
+	project, location, name := parseImportID(id)
+	if project == "" {
+		project = p.settings.ProjectID
+	}
+
+	if location == "" {
+		location = p.settings.Region
+	}
+
+	var (
+		res registry.Resource
+		key string
+	)
+
+	switch typ {
+	case ImportTypeCloudRun:
+		if target.source != registry.SourceApp {
+			return nil, fmt.Errorf("cloud run service can only be imported to an app")
+		}
+
+		key = "cloud_run"
+		res = &gcp.CloudRun{
+			Name:      fields.String(name),
+			ProjectID: fields.String(project),
+			Region:    fields.String(location),
+		}
+
+		// Service name is derived from app id and cannot be changed, differently named service would be replaced on next deploy.
+		if expected := gcp.ID(p.env, target.namespace); name != expected {
+			return nil, fmt.Errorf("cloud run service name '%s' differs from '%s' expected for app '%s', only service named as expected can be imported", name, expected, target.name)
+		}
+	case ImportTypeCloudSQL:
+		if target.source != registry.SourceDependency {
+			return nil, fmt.Errorf("cloud sql instance can only be imported to a dependency")
+		}
+
+		key = "cloud_sql"
+		res = &gcp.CloudSQL{
+			Name:      fields.String(name),
+			ProjectID: fields.String(project),
+		}
+	case ImportTypeBucket:
+		key = "bucket"
+		res = &gcp.Bucket{
+			Name:      fields.String(name),
+			ProjectID: fields.String(project),
+		}
+
+		// Bucket name cannot be changed, differently named bucket would be replaced on next deploy.
+		if target.source == registry.SourceApp {
+			if expected := gcp.GlobalID(p.env, project, target.namespace); name != expected {
+				return nil, fmt.Errorf("bucket name '%s' differs from '%s' expected for app '%s', only bucket named as expected can be imported", name, expected, target.name)
+			}
+		} else {
+			if target.bucket == "" {
+				return nil, fmt.Errorf("bucket can only be imported to a storage dependency present in state")
+			}
+
+			if name != target.bucket {
+				return nil, fmt.Errorf("bucket name '%s' differs from '%s' configured for dependency '%s', only bucket named as configured can be imported", name, target.bucket, target.name)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported import type '%s', supported: %s, %s, %s", typ, ImportTypeCloudRun, ImportTypeCloudSQL, ImportTypeBucket)
+	}
+
+	reg := registry.NewRegistry(&registry.Options{})
+
+	var err error
+
+	if target.source == registry.SourceApp {
+		_, err = reg.RegisterAppResource(&apiv1.App{Id: target.namespace}, key, res)
+	} else {
+		_, err = reg.RegisterDependencyResource(&apiv1.Dependency{Id: target.namespace}, key, res)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = res.(registry.ResourceReader).Read(ctx, p.PluginContext())
+	if err != nil {
+		return nil, err
+	}
+
+	if res.IsNew() {
+		return nil, fmt.Errorf("%s '%s' not found in project '%s'", typ, name, project)
+	}
+
+	dump, err := reg.Dump()
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []*registry.ResourceSerialized
+
+	err = json.Unmarshal(dump, &resources)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resources) != 1 {
+		return nil, fmt.Errorf("unexpected number of imported resources: %d", len(resources))
+	}
+
+	return resources[0], nil
+}
+
+// findRegistryPath returns path of gcp plugin registry within state.
+func findRegistryPath(state []byte) (string, error) {
+	regs, err := stateRegistries(state)
+	if err != nil {
+		return "", err
+	}
+
+	for path := range regs {
+		if path == defaultRegistryPath || strings.HasSuffix(path, ".gcp") {
+			return path, nil
+		}
+	}
+
+	return defaultRegistryPath, nil
+}
+
+// addToStateRegistry adds resource to plugin registry stored in state, replacing existing entry only if force is set.
+func addToStateRegistry(state []byte, res *registry.ResourceSerialized, force bool) ([]byte, error) {
+	path, err := findRegistryPath(state)
+	if err != nil {
+		return nil, err
+	}
+
+	var data map[string]any
+
+	dec := json.NewDecoder(bytes.NewReader(state))
+	dec.UseNumber()
+
+	err = dec.Decode(&data)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding state: %w", err)
+	}
+
+	if data == nil {
+		data = make(map[string]any)
+	}
+
+	// Walk to plugin state, creating missing levels.
+	pluginState := data
+
+	for _, k := range strings.Split(path, ".") {
+		next, ok := pluginState[k].(map[string]any)
+		if !ok {
+			next = make(map[string]any)
+			pluginState[k] = next
+		}
+
+		pluginState = next
+	}
+
+	var resources []*registry.ResourceSerialized
+
+	if reg, ok := pluginState["registry"].(string); ok && reg != "" {
+		raw, err := base64.StdEncoding.DecodeString(reg)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding plugin registry: %w", err)
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+
+		err = dec.Decode(&resources)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding plugin registry: %w", err)
+		}
+	}
+
+	replaced := false
+
+	for i, r := range resources {
+		if r.ResourceID != res.ResourceID {
+			continue
+		}
+
+		if !force {
+			return nil, fmt.Errorf("resource %s is already in state, use --force to overwrite it", resourceKey(r))
+		}
+
+		resources[i] = res
+		replaced = true
+	}
+
+	if !replaced {
+		resources = append(resources, res)
+	}
+
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].Less(&resources[j].ResourceID)
+	})
+
+	raw, err := json.Marshal(resources)
+	if err != nil {
+		return nil, err
+	}
+
+	pluginState["registry"] = base64.StdEncoding.EncodeToString(raw)
+
+	return json.Marshal(data)
+}
+
+func (p *Plugin) Import(ctx context.Context, req *apiv1.CommandRequest) error {
+	flags := req.Args.Flags.AsMap()
+
+	typ := flags["type"].(string)  //nolint:errcheck
+	id := flags["id"].(string)     //nolint:errcheck
+	force := flags["force"].(bool) //nolint:errcheck
+
+	target, err := findImportTarget(flags, req.AppStates, req.DependencyStates)
+	if err != nil {
+		return err
+	}
+
+	if typ == ImportTypeCloudSQL && target.source == registry.SourceDependency {
+		for _, d := range req.DependencyStates {
+			if d.Dependency != nil && d.Dependency.Id == target.namespace && d.Dependency.Type != deploy.DepTypePostgreSQL && d.Dependency.Type != deploy.DepTypeMySQL {
+				return fmt.Errorf("dependency '%s' is not a database", target.name)
+			}
+		}
+	}
+
+	local, err := newStateEncryption("", flags["key"].(string)) //nolint:errcheck
+	if err != nil {
+		return err
+	}
+
+	res, err := p.importResource(ctx, target, typ, id)
+	if err != nil {
+		return err
+	}
+
+	cli, err := p.PluginContext().GCPStorageClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	o := cli.Bucket(p.stateBucket(flags)).Object(p.statefile())
+
+	return p.withStateLock(ctx, cli, p.locksBucket(flags), func() error {
+		var (
+			data  []byte
+			state = []byte("{}")
+		)
+
+		attrs, err := o.Attrs(ctx)
+
+		switch {
+		case errors.Is(err, storage.ErrObjectNotExist):
+			attrs = nil
+		case err != nil:
+			return err
+		default:
+			data, err = readObject(ctx, o.Generation(attrs.Generation))
+			if err != nil {
+				return err
+			}
+
+			state, err = p.decryptState(ctx, local, data)
+			if err != nil {
+				return err
+			}
+		}
+
+		enc, err := stateEncryptionOf(data, local)
+		if err != nil {
+			return err
+		}
+
+		state, err = addToStateRegistry(state, res, force)
+		if err != nil {
+			return err
+		}
+
+		data, err = p.encryptState(ctx, enc, state)
+		if err != nil {
+			return err
+		}
+
+		cond := storage.Conditions{DoesNotExist: true}
+		if attrs != nil {
+			cond = storage.Conditions{GenerationMatch: attrs.Generation}
+		}
+
+		w := o.If(cond).NewWriter(ctx)
+
+		_, err = w.Write(data)
+		if err != nil {
+			return err
+		}
+
+		err = w.Close()
+		if err != nil {
+			return fmt.Errorf("error writing state: %w", err)
+		}
+
+		p.log.Successf("Imported %s '%s' into %s '%s'.\n", typ, res.ReferenceID, strings.TrimSuffix(target.source, "s"), target.name)
+
+		return nil
+	})
+}
@@ -0,0 +1,165 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/outblocks/cli-plugin-gcp/actions"
+	"github.com/outblocks/cli-plugin-gcp/deploy"
+	"github.com/outblocks/cli-plugin-gcp/gcp"
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
+	"github.com/outblocks/outblocks-plugin-go/registry"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func mustStruct(t *testing.T, m map[string]any) *structpb.Struct {
+	t.Helper()
+
+	s, err := structpb.NewStruct(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func TestImportedResourcesAreAdopted(t *testing.T) {
+	ctx := context.Background()
+	p, srv := newTestPlugin(t)
+
+	imageHash := srv.AddLocalImage("test/api:latest")
+
+	api := &apiv1.App{
+		Id:         "app_api",
+		Name:       "api",
+		Type:       deploy.AppTypeService,
+		Dir:        "api",
+		Properties: mustStruct(t, map[string]any{"container": map[string]any{"port": 8080}}),
+	}
+
+	files := &apiv1.Dependency{
+		Id:         "dep_files",
+		Name:       "files",
+		Type:       deploy.DepTypeStorage,
+		Properties: mustStruct(t, map[string]any{"name": "test-files"}),
+	}
+
+	db := &apiv1.Dependency{
+		Id:         "dep_db",
+		Name:       "db",
+		Type:       deploy.DepTypePostgreSQL,
+		Properties: mustStruct(t, map[string]any{}),
+	}
+
+	apps := []*apiv1.AppPlan{
+		{
+			State: &apiv1.AppState{App: api},
+			Build: &apiv1.AppBuild{LocalDockerImage: "test/api:latest", LocalDockerHash: imageHash},
+		},
+	}
+	deps := []*apiv1.DependencyPlan{
+		{State: &apiv1.DependencyState{Dependency: files}},
+		{State: &apiv1.DependencyState{Dependency: db}},
+	}
+
+	newPlan := func(state *apiv1.PluginState) *actions.PlanAction {
+		a, err := actions.NewPlan(p.PluginContext(), p.log, state, nil, registry.NewRegistry(&registry.Options{}), false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return a
+	}
+
+	// Create resources outside of state that is later imported to.
+	err := newPlan(nil).Apply(ctx, apps, deps, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var instance string
+
+	prefix := "sqladmin/projects/" + srv.ProjectID + "/instances/"
+
+	for _, k := range srv.ResourceKeys() {
+		if strings.HasPrefix(k, prefix) && !strings.Contains(strings.TrimPrefix(k, prefix), "/") {
+			instance = strings.TrimPrefix(k, prefix)
+		}
+	}
+
+	if instance == "" {
+		t.Fatal("expected cloud sql instance to be created")
+	}
+
+	// Cloud Run service has to be named as the one planned for app.
+	_, err = p.importResource(ctx, &importTarget{source: registry.SourceApp, namespace: api.Id, name: api.Name}, ImportTypeCloudRun, "other-service")
+	if err == nil || !strings.Contains(err.Error(), "differs") {
+		t.Fatalf("expected differently named cloud run service to be refused, got: %v", err)
+	}
+
+	// Bucket has to be named as the one configured for dependency.
+	filesTarget, err := findImportTarget(map[string]any{"app": "", "dependency": files.Name, "namespace": ""}, nil, map[string]*apiv1.DependencyState{files.Id: {Dependency: files}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = p.importResource(ctx, filesTarget, ImportTypeBucket, "gs://other-files")
+	if err == nil || !strings.Contains(err.Error(), "differs") {
+		t.Fatalf("expected differently named bucket to be refused, got: %v", err)
+	}
+
+	state := []byte("{}")
+
+	for _, imp := range []struct {
+		target *importTarget
+		typ    string
+		id     string
+	}{
+		{&importTarget{source: registry.SourceApp, namespace: api.Id, name: api.Name}, ImportTypeCloudRun, gcp.ID(p.env, api.Id)},
+		{&importTarget{source: registry.SourceDependency, namespace: db.Id, name: db.Name}, ImportTypeCloudSQL, "projects/" + srv.ProjectID + "/instances/" + instance},
+		{filesTarget, ImportTypeBucket, "gs://test-files"},
+	} {
+		res, err := p.importResource(ctx, imp.target, imp.typ, imp.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		state, err = addToStateRegistry(state, res, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	regs, err := stateRegistries(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reg, err := json.Marshal(regs[defaultRegistryPath])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := newPlan(&apiv1.PluginState{Registry: reg}).Plan(ctx, apps, deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imported := map[string]string{
+		"CloudRun": api.Id,
+		"CloudSQL": db.Id,
+		"Bucket":   files.Id,
+	}
+
+	for _, act := range plan.Actions {
+		if imported[act.ObjectType] != act.Namespace {
+			continue
+		}
+
+		if act.Type != apiv1.PlanType_PLAN_TYPE_UPDATE {
+			t.Errorf("expected imported %s to be adopted, got: %v", act.ObjectType, act)
+		}
+	}
+}
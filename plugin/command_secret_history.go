@@ -0,0 +1,313 @@
+package plugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/outblocks/cli-plugin-gcp/gcp"
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
+	"google.golang.org/api/secretmanager/v1"
+)
+
+func parseSecretVersion(v string) (string, error) {
+	if v == "" || v == "latest" {
+		return "latest", nil
+	}
+
+	if num, err := strconv.Atoi(v); err != nil || num <= 0 {
+		return "", fmt.Errorf("invalid secret version: %s", v)
+	}
+
+	return v, nil
+}
+
+// accessSecretVersion returns value of given version of a secret.
+// Disabled version is refused unless enableDisabled is set, in which case it is enabled only for the time of reading it.
+func accessSecretVersion(cli *secretmanager.Service, project, name, version string, enableDisabled bool) (val string, err error) {
+	verPath := fmt.Sprintf("projects/%s/secrets/%s/versions/%s", project, name, version)
+
+	ver, err := cli.Projects.Secrets.Versions.Get(verPath).Do()
+	if gcp.ErrIs404(err) {
+		return "", fmt.Errorf("version %s of secret '%s' not found", version, name)
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("error fetching secret '%s' version: %w", name, err)
+	}
+
+	switch ver.State {
+	case "DESTROYED":
+		return "", fmt.Errorf("version %s of secret '%s' was destroyed and its value can't be recovered", version, name)
+	case "DISABLED":
+		if !enableDisabled {
+			return "", fmt.Errorf("version %s of secret '%s' is disabled, use --enable-disabled to temporarily enable it for reading", version, name)
+		}
+
+		_, err = cli.Projects.Secrets.Versions.Enable(ver.Name, &secretmanager.EnableSecretVersionRequest{}).Do()
+		if err != nil {
+			return "", fmt.Errorf("error enabling secret '%s' version: %w", name, err)
+		}
+
+		// Version must not be left enabled, failing to disable it again fails the whole read.
+		defer func() {
+			_, disableErr := cli.Projects.Secrets.Versions.Disable(ver.Name, &secretmanager.DisableSecretVersionRequest{}).Do()
+			if disableErr != nil {
+				val = ""
+				err = errors.Join(err, fmt.Errorf("error disabling secret '%s' version %s again, disable it manually: %w", name, version, disableErr))
+			}
+		}()
+	}
+
+	ret, err := cli.Projects.Secrets.Versions.Access(ver.Name).Do()
+	if err != nil {
+		return "", fmt.Errorf("error accessing secret '%s' value: %w", name, err)
+	}
+
+	data, _ := base64.StdEncoding.DecodeString(ret.Payload.Data)
+
+	return string(data), nil
+}
+
+// SecretHistory lists versions of a secret with their creation time, state and creator, newest first.
+func (p *Plugin) SecretHistory(ctx context.Context, req *apiv1.CommandRequest) error {
+	flags := req.Args.Flags.AsMap()
+
+	cli, err := p.initSecrets(ctx)
+	if err != nil {
+		return err
+	}
+
+	key, err := p.secretKey(flags["key"].(string)) //nolint:errcheck
+	if err != nil {
+		return err
+	}
+
+	secret, err := cli.Projects.Secrets.Get(fmt.Sprintf("projects/%s/secrets/%s", p.settings.ProjectID, key)).Do()
+	if gcp.ErrIs404(err) {
+		return fmt.Errorf("secret '%s' not found", flags["key"])
+	}
+
+	if err != nil {
+		return fmt.Errorf("error fetching secret '%s': %w", key, err)
+	}
+
+	versions, err := listSecretVersions(cli, p.settings.ProjectID, key)
+	if err != nil {
+		return err
+	}
+
+	if len(versions) == 0 {
+		p.log.Infoln("No secret versions found.")
+
+		return nil
+	}
+
+	for _, v := range versions {
+		num := secretVersionNumber(v.Name)
+
+		creator := secret.Annotations[secretVersionAnnotation(num)]
+		if creator == "" {
+			creator = "unknown"
+		}
+
+		created, _ := time.Parse(time.RFC3339, v.CreateTime)
+
+		p.log.Printf("%d\t%s\t%s\t%s\n", num, created.Local().Format("2006-01-02 15:04:05"), strings.ToLower(v.State), creator)
+	}
+
+	return nil
+}
+
+// SecretShow prints value of chosen version of a secret.
+func (p *Plugin) SecretShow(ctx context.Context, req *apiv1.CommandRequest) error {
+	flags := req.Args.Flags.AsMap()
+
+	version, err := parseSecretVersion(flags["version"].(string)) //nolint:errcheck
+	if err != nil {
+		return err
+	}
+
+	enableDisabled := flags["enable-disabled"].(bool) //nolint:errcheck
+
+	cli, err := p.initSecrets(ctx)
+	if err != nil {
+		return err
+	}
+
+	key, err := p.secretKey(flags["key"].(string)) //nolint:errcheck
+	if err != nil {
+		return err
+	}
+
+	val, err := accessSecretVersion(cli, p.settings.ProjectID, key, version, enableDisabled)
+	if err != nil {
+		return err
+	}
+
+	p.log.Println(val)
+
+	return nil
+}
+
+// maskSecretLine hides line of secret value, leaving its length and fingerprint to tell lines apart.
+func maskSecretLine(line string) string {
+	h := sha256.Sum256([]byte(line))
+
+	return fmt.Sprintf("<%d chars, sha256:%s>", len(line), hex.EncodeToString(h[:])[:8])
+}
+
+// diffLines returns line diff of two values, unchanged lines are prefixed with space, removed with '-' and added with '+'.
+func diffLines(from, to string) []string {
+	a := strings.Split(from, "\n")
+	b := strings.Split(to, "\n")
+
+	// Longest common subsequence lengths of suffixes.
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ret []string
+
+	i, j := 0, 0
+
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ret = append(ret, "  "+a[i])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			ret = append(ret, "- "+a[i])
+			i++
+		default:
+			ret = append(ret, "+ "+b[j])
+			j++
+		}
+	}
+
+	return ret
+}
+
+// SecretDiff compares two versions of a secret line by line, values are masked unless requested otherwise.
+func (p *Plugin) SecretDiff(ctx context.Context, req *apiv1.CommandRequest) error {
+	flags := req.Args.Flags.AsMap()
+
+	fromVer, err := parseSecretVersion(flags["from"].(string)) //nolint:errcheck
+	if err != nil {
+		return err
+	}
+
+	toVer, err := parseSecretVersion(flags["to"].(string)) //nolint:errcheck
+	if err != nil {
+		return err
+	}
+
+	showValues := flags["show-values"].(bool)         //nolint:errcheck
+	enableDisabled := flags["enable-disabled"].(bool) //nolint:errcheck
+
+	cli, err := p.initSecrets(ctx)
+	if err != nil {
+		return err
+	}
+
+	key, err := p.secretKey(flags["key"].(string)) //nolint:errcheck
+	if err != nil {
+		return err
+	}
+
+	from, err := accessSecretVersion(cli, p.settings.ProjectID, key, fromVer, enableDisabled)
+	if err != nil {
+		return err
+	}
+
+	to, err := accessSecretVersion(cli, p.settings.ProjectID, key, toVer, enableDisabled)
+	if err != nil {
+		return err
+	}
+
+	if from == to {
+		p.log.Infof("Secret versions %s and %s have the same value.\n", fromVer, toVer)
+
+		return nil
+	}
+
+	for _, l := range diffLines(from, to) {
+		if !showValues {
+			l = l[:2] + maskSecretLine(l[2:])
+		}
+
+		p.log.Println(l)
+	}
+
+	return nil
+}
+
+// SecretRollback restores value of an older version of a secret by adding it as a new version.
+func (p *Plugin) SecretRollback(ctx context.Context, req *apiv1.CommandRequest) error {
+	flags := req.Args.Flags.AsMap()
+
+	version, err := parseSecretVersion(flags["version"].(string)) //nolint:errcheck
+	if err != nil {
+		return err
+	}
+
+	enableDisabled := flags["enable-disabled"].(bool) //nolint:errcheck
+
+	if version == "latest" {
+		return fmt.Errorf("version to roll back to is required")
+	}
+
+	cli, err := p.initSecrets(ctx)
+	if err != nil {
+		return err
+	}
+
+	key, err := p.secretKey(flags["key"].(string)) //nolint:errcheck
+	if err != nil {
+		return err
+	}
+
+	val, err := accessSecretVersion(cli, p.settings.ProjectID, key, version, enableDisabled)
+	if err != nil {
+		return err
+	}
+
+	cur, _, err := accessSecretValue(cli, p.settings.ProjectID, key)
+	if err != nil {
+		return err
+	}
+
+	if cur == val {
+		p.log.Infof("Secret '%s' already has value of version %s.\n", flags["key"], version)
+
+		return nil
+	}
+
+	err = setSecretValue(cli, p.settings.ProjectID, key, val, fmt.Sprintf("%s (rollback to %s)", lockdata(), version), true)
+	if err != nil {
+		return err
+	}
+
+	p.log.Successf("Secret '%s' rolled back to value of version %s.\n", flags["key"], version)
+	p.log.Infoln("Apps with secret env vars not pinned to a version pick up the new version on next deploy.")
+
+	return nil
+}
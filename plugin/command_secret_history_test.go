@@ -0,0 +1,198 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/outblocks/cli-plugin-gcp/gcp"
+	"github.com/outblocks/cli-plugin-gcp/internal/fakegcp"
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
+	"google.golang.org/api/secretmanager/v1"
+)
+
+// newTestSecretVersions creates secret with one version per value and returns its name.
+func newTestSecretVersions(t *testing.T, p *Plugin, cli *secretmanager.Service, key string, values ...string) string {
+	t.Helper()
+
+	name := gcp.SecretName(p.env, key)
+
+	err := createSecret(cli, p.env, &secretsOptions{}, p.settings.ProjectID, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range values {
+		err = setSecretValue(cli, p.settings.ProjectID, name, v, "test", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return name
+}
+
+func secretVersionState(t *testing.T, srv *fakegcp.Server, name, version string) string {
+	t.Helper()
+
+	ver, ok := srv.Resource("secretmanager/projects/" + srv.ProjectID + "/secrets/" + name + "/versions/" + version)
+	if !ok {
+		t.Fatalf("secret version %s not found", version)
+	}
+
+	return ver["state"].(string) //nolint:errcheck
+}
+
+func TestParseSecretVersion(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want string
+		err  bool
+	}{
+		{"", "latest", false},
+		{"latest", "latest", false},
+		{"3", "3", false},
+		{"0", "", true},
+		{"-1", "", true},
+		{"v1", "", true},
+	} {
+		got, err := parseSecretVersion(tc.in)
+		if (err != nil) != tc.err || got != tc.want {
+			t.Fatalf("parseSecretVersion(%q): expected %q (error: %t), got: %q, %v", tc.in, tc.want, tc.err, got, err)
+		}
+	}
+}
+
+func TestAccessSecretVersion(t *testing.T) {
+	ctx := context.Background()
+	p, srv := newTestPlugin(t)
+
+	cli, err := p.initSecrets(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name := newTestSecretVersions(t, p, cli, "DB_PASSWORD", "first", "second", "third")
+
+	verPath := fmt.Sprintf("projects/%s/secrets/%s/versions/", srv.ProjectID, name)
+
+	_, err = cli.Projects.Secrets.Versions.Disable(verPath+"1", &secretmanager.DisableSecretVersionRequest{}).Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = cli.Projects.Secrets.Versions.Destroy(verPath+"2", &secretmanager.DestroySecretVersionRequest{}).Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name           string
+		version        string
+		enableDisabled bool
+		want           string
+		err            string
+	}{
+		{name: "latest", version: "latest", want: "third"},
+		{name: "enabled version", version: "3", want: "third"},
+		{name: "disabled version", version: "1", err: "version 1 of secret '" + name + "' is disabled, use --enable-disabled"},
+		{name: "disabled version enabled for reading", version: "1", enableDisabled: true, want: "first"},
+		{name: "destroyed version", version: "2", enableDisabled: true, err: "was destroyed and its value can't be recovered"},
+		{name: "missing version", version: "5", err: "version 5 of secret '" + name + "' not found"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := accessSecretVersion(cli, srv.ProjectID, name, tc.version, tc.enableDisabled)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error containing %q, got: %q, %v", tc.err, got, err)
+				}
+			} else if err != nil || got != tc.want {
+				t.Fatalf("expected %q, got: %q, %v", tc.want, got, err)
+			}
+
+			if state := secretVersionState(t, srv, name, "1"); state != "DISABLED" {
+				t.Fatalf("expected disabled version to stay disabled, got: %s", state)
+			}
+		})
+	}
+
+	// Failing to disable version again fails the read.
+	srv.FailRequests(http.StatusForbidden, func(r *http.Request) bool {
+		return strings.HasSuffix(r.URL.Path, "/versions/1:disable")
+	})
+
+	got, err := accessSecretVersion(cli, srv.ProjectID, name, "1", true)
+	if err == nil || got != "" || !strings.Contains(err.Error(), "error disabling secret '"+name+"' version 1 again, disable it manually") {
+		t.Fatalf("expected failure to disable version again to be returned, got: %q, %v", got, err)
+	}
+}
+
+func TestSecretRollback(t *testing.T) {
+	ctx := context.Background()
+	p, srv := newTestPlugin(t)
+
+	cli, err := p.initSecrets(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name := newTestSecretVersions(t, p, cli, "API_KEY", "first", "second")
+
+	_, err = cli.Projects.Secrets.Versions.Disable(fmt.Sprintf("projects/%s/secrets/%s/versions/1", srv.ProjectID, name), &secretmanager.DisableSecretVersionRequest{}).Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rollback := func(version string, enableDisabled bool) error {
+		return p.SecretRollback(ctx, &apiv1.CommandRequest{Args: &apiv1.CommandArgs{Flags: mustStruct(t, map[string]any{
+			"key":             "API_KEY",
+			"version":         version,
+			"enable-disabled": enableDisabled,
+		})}})
+	}
+
+	err = rollback("latest", false)
+	if err == nil || !strings.Contains(err.Error(), "version to roll back to is required") {
+		t.Fatalf("expected rollback without version to be refused, got: %v", err)
+	}
+
+	err = rollback("1", false)
+	if err == nil || !strings.Contains(err.Error(), "is disabled") {
+		t.Fatalf("expected rollback to disabled version to be refused, got: %v", err)
+	}
+
+	err = rollback("1", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	val, _, err := accessSecretValue(cli, srv.ProjectID, name)
+	if err != nil || val != "first" {
+		t.Fatalf("expected secret to be rolled back to first value, got: %q, %v", val, err)
+	}
+
+	if state := secretVersionState(t, srv, name, "1"); state != "DISABLED" {
+		t.Fatalf("expected rolled back version to stay disabled, got: %s", state)
+	}
+
+	secret, err := getSecret(cli, srv.ProjectID, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if creator := secret.Annotations[secretVersionAnnotation(3)]; !strings.HasSuffix(creator, "(rollback to 1)") {
+		t.Fatalf("expected new version to be annotated as rollback, got: %q", creator)
+	}
+
+	// Rolling back to current value doesn't add a version.
+	err = rollback("3", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(srv.SecretVersions(name)) != 2 {
+		t.Fatalf("expected no version to be added, got: %v", srv.SecretVersions(name))
+	}
+}
@@ -0,0 +1,244 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/outblocks/cli-plugin-gcp/gcp"
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
+	"github.com/outblocks/outblocks-plugin-go/util/errgroup"
+	"google.golang.org/api/secretmanager/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (p *Plugin) currentSecrets(ctx context.Context, cli *secretmanager.Service) (map[string]*secretmanager.Secret, map[string]string, error) {
+	var (
+		secrets map[string]*secretmanager.Secret
+		err     error
+	)
+
+	err = p.runAndEnsureAPI(ctx, func() error {
+		secrets, err = listSecrets(cli, p.settings.ProjectID, p.secretKeyPrefix())
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	values, err := p.secretValues(ctx, cli, secrets)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return secrets, values, nil
+}
+
+// diffSecrets returns sorted keys of secrets added, changed and removed (only when pruning) between current and new values.
+func diffSecrets(cur, values map[string]string, prune bool) (added, changed, removed []string) {
+	for k, v := range values {
+		old, ok := cur[k]
+
+		switch {
+		case !ok:
+			added = append(added, k)
+		case old != v:
+			changed = append(changed, k)
+		}
+	}
+
+	if prune {
+		for k := range cur {
+			if _, ok := values[k]; !ok {
+				removed = append(removed, k)
+			}
+		}
+	}
+
+	sort.Strings(added)
+	sort.Strings(changed)
+	sort.Strings(removed)
+
+	return added, changed, removed
+}
+
+// secretsImportOptions decodes secrets properties given as JSON that new secrets are created with.
+// Secrets properties are not available to commands, without them secrets use automatic replication.
+func secretsImportOptions(props string) (*secretsOptions, error) {
+	in := make(map[string]any)
+
+	if props != "" {
+		err := json.Unmarshal([]byte(props), &in)
+		if err != nil {
+			return nil, fmt.Errorf("secrets properties must be a JSON object: %w", err)
+		}
+	}
+
+	return newSecretsOptions(in)
+}
+
+// SecretsImport sets secrets from dotenv, json or yaml file, optionally encrypted with sops.
+func (p *Plugin) SecretsImport(ctx context.Context, req *apiv1.CommandRequest) error {
+	flags := req.Args.Flags.AsMap()
+
+	file := flags["file"].(string)               //nolint:errcheck
+	ageKeyFile := flags["age-key-file"].(string) //nolint:errcheck
+	prune := flags["prune"].(bool)               //nolint:errcheck
+	dryRun := flags["dry-run"].(bool)            //nolint:errcheck
+	yes := flags["yes"].(bool)                   //nolint:errcheck
+
+	format, err := secretsFileFormat(file, flags["format"].(string)) //nolint:errcheck
+	if err != nil {
+		return err
+	}
+
+	opts, err := secretsImportOptions(flags["properties"].(string)) //nolint:errcheck
+	if err != nil {
+		return err
+	}
+
+	values, err := readSecretsFile(ctx, file, format, ageKeyFile)
+	if err != nil {
+		return err
+	}
+
+	for k := range values {
+		if _, err := p.secretKey(k); err != nil {
+			return fmt.Errorf("invalid secret key '%s': %w", k, err)
+		}
+	}
+
+	cli, err := p.initSecrets(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, cur, err := p.currentSecrets(ctx, cli)
+	if err != nil {
+		return err
+	}
+
+	added, changed, removed := diffSecrets(cur, values, prune)
+
+	if len(added)+len(changed)+len(removed) == 0 {
+		p.log.Infoln("Secrets are up to date.")
+
+		return nil
+	}
+
+	for _, k := range added {
+		p.log.Printf("+ %s\n", k)
+	}
+
+	for _, k := range changed {
+		p.log.Printf("~ %s\n", k)
+	}
+
+	for _, k := range removed {
+		p.log.Printf("- %s\n", k)
+	}
+
+	p.log.Printf("%d to add, %d to change, %d to remove.\n", len(added), len(changed), len(removed))
+
+	if dryRun {
+		return nil
+	}
+
+	if !yes {
+		res, err := p.hostCli.PromptConfirmation(ctx, &apiv1.PromptConfirmationRequest{
+			Message: "Do you want to apply these secret changes?",
+		})
+		if err != nil {
+			if s, ok := status.FromError(err); ok && s.Code() == codes.Aborted {
+				return nil
+			}
+
+			return err
+		}
+
+		if !res.Confirmed {
+			return nil
+		}
+	}
+
+	g, _ := errgroup.WithConcurrency(ctx, gcp.DefaultConcurrency)
+
+	for _, k := range added {
+		g.Go(func() error {
+			key, _ := p.secretKey(k)
+
+			err := createSecret(cli, p.env, opts, p.settings.ProjectID, key)
+			if err != nil {
+				return err
+			}
+
+			return setSecretValue(cli, p.settings.ProjectID, key, values[k], lockdata(), false)
+		})
+	}
+
+	for _, k := range changed {
+		g.Go(func() error {
+			key, _ := p.secretKey(k)
+
+			return setSecretValue(cli, p.settings.ProjectID, key, values[k], lockdata(), true)
+		})
+	}
+
+	for _, k := range removed {
+		g.Go(func() error {
+			key, _ := p.secretKey(k)
+
+			_, err := deleteSecret(cli, p.settings.ProjectID, key)
+
+			return err
+		})
+	}
+
+	err = g.Wait()
+	if err != nil {
+		return err
+	}
+
+	p.log.Successf("Imported secrets from '%s'.\n", file)
+
+	return nil
+}
+
+// SecretsExport writes current secrets to dotenv, json or yaml file, optionally encrypted with sops.
+func (p *Plugin) SecretsExport(ctx context.Context, req *apiv1.CommandRequest) error {
+	flags := req.Args.Flags.AsMap()
+
+	file := flags["file"].(string) //nolint:errcheck
+	age := flags["age"].(string)   //nolint:errcheck
+	pgp := flags["pgp"].(string)   //nolint:errcheck
+
+	format, err := secretsFileFormat(file, flags["format"].(string)) //nolint:errcheck
+	if err != nil {
+		return err
+	}
+
+	cli, err := p.initSecrets(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, values, err := p.currentSecrets(ctx, cli)
+	if err != nil {
+		return err
+	}
+
+	err = writeSecretsFile(ctx, file, format, values, age, pgp)
+	if err != nil {
+		return err
+	}
+
+	if age == "" && pgp == "" {
+		p.log.Warnf("Secrets were written unencrypted, do not commit '%s' to version control.\n", file)
+	}
+
+	p.log.Successf("Exported %d secrets to '%s'.\n", len(values), file)
+
+	return nil
+}
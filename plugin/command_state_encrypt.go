@@ -0,0 +1,140 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cloud.google.com/go/storage"
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
+	"google.golang.org/api/iterator"
+)
+
+// StateEncrypt re-encrypts existing state object in place with a new key, decrypting it first with previous key if needed.
+func (p *Plugin) StateEncrypt(ctx context.Context, req *apiv1.CommandRequest) error {
+	flags := req.Args.Flags.AsMap()
+
+	bucket := p.stateBucket(flags)
+	kmsKey := flags["kms-key"].(string)           //nolint:errcheck
+	key := flags["key"].(string)                  //nolint:errcheck
+	decryptKey := flags["decrypt-key"].(string)   //nolint:errcheck
+	purgeHistory := flags["purge-history"].(bool) //nolint:errcheck
+
+	if kmsKey == "" && key == "" {
+		return fmt.Errorf("either kms-key or key flag is required")
+	}
+
+	enc, err := newStateEncryption(kmsKey, key)
+	if err != nil {
+		return err
+	}
+
+	dec := enc
+
+	if decryptKey != "" {
+		dec, err = newStateEncryption("", decryptKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	cli, err := p.PluginContext().GCPStorageClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	b := cli.Bucket(bucket)
+	o := b.Object(p.statefile())
+
+	attrs, err := o.Attrs(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return fmt.Errorf("state '%s' not found in bucket '%s'", p.statefile(), bucket)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	data, err := readObject(ctx, o.Generation(attrs.Generation))
+	if err != nil {
+		return err
+	}
+
+	state, err := p.decryptState(ctx, dec, data)
+	if err != nil {
+		return err
+	}
+
+	data, err = p.encryptState(ctx, enc, state)
+	if err != nil {
+		return err
+	}
+
+	// Only replace state that wasn't modified in the meantime.
+	w := o.If(storage.Conditions{GenerationMatch: attrs.Generation}).NewWriter(ctx)
+
+	_, err = w.Write(data)
+	if err != nil {
+		return err
+	}
+
+	err = w.Close()
+	if err != nil {
+		return fmt.Errorf("error writing encrypted state: %w", err)
+	}
+
+	p.log.Successf("State '%s' encrypted.\n", p.statefile())
+
+	if !purgeHistory {
+		return nil
+	}
+
+	return p.purgePlaintextStateVersions(ctx, b, w.Attrs().Generation)
+}
+
+// purgePlaintextStateVersions deletes noncurrent versions of state that were stored without encryption.
+func (p *Plugin) purgePlaintextStateVersions(ctx context.Context, b *storage.BucketHandle, liveGeneration int64) error {
+	it := b.Objects(ctx, &storage.Query{
+		Prefix:   p.statefile(),
+		Versions: true,
+	})
+
+	purged := 0
+
+	for {
+		attrs, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+
+		if err != nil {
+			return fmt.Errorf("error listing state versions: %w", err)
+		}
+
+		if attrs.Name != p.statefile() || attrs.Generation == liveGeneration {
+			continue
+		}
+
+		o := b.Object(attrs.Name).Generation(attrs.Generation)
+
+		data, err := readObject(ctx, o)
+		if err != nil {
+			return err
+		}
+
+		if isEncryptedState(data) {
+			continue
+		}
+
+		err = o.Delete(ctx)
+		if err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+			return fmt.Errorf("error deleting plaintext state version: %w", err)
+		}
+
+		purged++
+	}
+
+	p.log.Infof("Purged %d plaintext state version(s).\n", purged)
+
+	return nil
+}
@@ -0,0 +1,358 @@
+package plugin
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
+	"github.com/outblocks/outblocks-plugin-go/registry"
+	"google.golang.org/api/iterator"
+)
+
+func (p *Plugin) stateBucket(flags map[string]any) string {
+	bucket := flags["bucket"].(string) //nolint:errcheck
+	if bucket == "" {
+		bucket = p.defaultStateBucket(p.settings.ProjectID)
+	}
+
+	return bucket
+}
+
+func (p *Plugin) locksBucket(flags map[string]any) string {
+	bucket := flags["locks-bucket"].(string) //nolint:errcheck
+	if bucket == "" {
+		bucket = p.defaultLocksBucket(p.settings.ProjectID)
+	}
+
+	return bucket
+}
+
+func parseGeneration(v string) (int64, error) {
+	gen, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid state generation: %s", v)
+	}
+
+	return gen, nil
+}
+
+// stateVersions returns all generations of state object, newest first.
+func (p *Plugin) stateVersions(ctx context.Context, b *storage.BucketHandle) ([]*storage.ObjectAttrs, error) {
+	it := b.Objects(ctx, &storage.Query{
+		Prefix:   p.statefile(),
+		Versions: true,
+	})
+
+	var versions []*storage.ObjectAttrs
+
+	for {
+		attrs, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("error listing state versions: %w", err)
+		}
+
+		if attrs.Name == p.statefile() {
+			versions = append(versions, attrs)
+		}
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Generation > versions[j].Generation
+	})
+
+	return versions, nil
+}
+
+// readStateGeneration reads and decrypts given generation of state, 0 means live version.
+func (p *Plugin) readStateGeneration(ctx context.Context, b *storage.BucketHandle, enc *stateEncryption, gen int64) ([]byte, error) {
+	o := b.Object(p.statefile())
+	if gen != 0 {
+		o = o.Generation(gen)
+	}
+
+	data, err := readObject(ctx, o)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, fmt.Errorf("state generation %d not found", gen)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return p.decryptState(ctx, enc, data)
+}
+
+func (p *Plugin) StateHistory(ctx context.Context, req *apiv1.CommandRequest) error {
+	flags := req.Args.Flags.AsMap()
+
+	cli, err := p.PluginContext().GCPStorageClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	versions, err := p.stateVersions(ctx, cli.Bucket(p.stateBucket(flags)))
+	if err != nil {
+		return err
+	}
+
+	if len(versions) == 0 {
+		p.log.Infoln("No state versions found.")
+
+		return nil
+	}
+
+	for _, v := range versions {
+		status := "noncurrent"
+		if v.Deleted.IsZero() {
+			status = "current"
+		}
+
+		p.log.Printf("%d\t%s\t%d bytes\t%s\n", v.Generation, v.Updated.Local().Format("2006-01-02 15:04:05"), v.Size, status)
+	}
+
+	return nil
+}
+
+// stateRegistries extracts plugin registries from state, keyed by path of plugin state within state file.
+func stateRegistries(state []byte) (map[string][]*registry.ResourceSerialized, error) {
+	var data any
+
+	err := json.Unmarshal(state, &data)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding state: %w", err)
+	}
+
+	ret := make(map[string][]*registry.ResourceSerialized)
+
+	var walk func(path string, v any)
+
+	walk = func(path string, v any) {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return
+		}
+
+		if reg, ok := m["registry"].(string); ok {
+			raw, err := base64.StdEncoding.DecodeString(reg)
+			if err == nil {
+				var resources []*registry.ResourceSerialized
+
+				if json.Unmarshal(raw, &resources) == nil {
+					ret[path] = resources
+
+					return
+				}
+			}
+		}
+
+		for k, child := range m {
+			walk(strings.TrimPrefix(path+"."+k, "."), child)
+		}
+	}
+
+	walk("", data)
+
+	return ret, nil
+}
+
+func resourceKey(r *registry.ResourceSerialized) string {
+	return fmt.Sprintf("%s %s/%s %s", r.Type, r.Source, r.Namespace, r.ID)
+}
+
+// diffStateRegistries returns human readable, sorted list of resources added, removed and changed between states.
+// Only names of changed properties are listed to avoid printing secrets.
+func diffStateRegistries(from, to map[string][]*registry.ResourceSerialized) []string {
+	var ret []string
+
+	paths := make(map[string]struct{})
+
+	for k := range from {
+		paths[k] = struct{}{}
+	}
+
+	for k := range to {
+		paths[k] = struct{}{}
+	}
+
+	for _, path := range sortedKeys(paths) {
+		fromMap := make(map[string]*registry.ResourceSerialized)
+		toMap := make(map[string]*registry.ResourceSerialized)
+		keys := make(map[string]struct{})
+
+		for _, r := range from[path] {
+			fromMap[resourceKey(r)] = r
+			keys[resourceKey(r)] = struct{}{}
+		}
+
+		for _, r := range to[path] {
+			toMap[resourceKey(r)] = r
+			keys[resourceKey(r)] = struct{}{}
+		}
+
+		for _, key := range sortedKeys(keys) {
+			f, t := fromMap[key], toMap[key]
+
+			switch {
+			case f == nil:
+				ret = append(ret, fmt.Sprintf("+ [%s] %s", path, key))
+			case t == nil:
+				ret = append(ret, fmt.Sprintf("- [%s] %s", path, key))
+			default:
+				props := make(map[string]struct{})
+
+				for k, v := range f.Properties {
+					if !reflect.DeepEqual(v, t.Properties[k]) {
+						props[k] = struct{}{}
+					}
+				}
+
+				for k := range t.Properties {
+					if _, ok := f.Properties[k]; !ok {
+						props[k] = struct{}{}
+					}
+				}
+
+				if len(props) != 0 {
+					ret = append(ret, fmt.Sprintf("~ [%s] %s: %s", path, key, strings.Join(sortedKeys(props), ", ")))
+				}
+			}
+		}
+	}
+
+	return ret
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	ret := make([]string, 0, len(m))
+
+	for k := range m {
+		ret = append(ret, k)
+	}
+
+	sort.Strings(ret)
+
+	return ret
+}
+
+func (p *Plugin) StateDiff(ctx context.Context, req *apiv1.CommandRequest) error {
+	flags := req.Args.Flags.AsMap()
+
+	fromGen, err := parseGeneration(flags["from"].(string)) //nolint:errcheck
+	if err != nil {
+		return err
+	}
+
+	var toGen int64
+
+	if v := flags["to"].(string); v != "" { //nolint:errcheck
+		toGen, err = parseGeneration(v)
+		if err != nil {
+			return err
+		}
+	}
+
+	enc, err := newStateEncryption("", flags["key"].(string)) //nolint:errcheck
+	if err != nil {
+		return err
+	}
+
+	cli, err := p.PluginContext().GCPStorageClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	b := cli.Bucket(p.stateBucket(flags))
+
+	from, err := p.readStateGeneration(ctx, b, enc, fromGen)
+	if err != nil {
+		return err
+	}
+
+	to, err := p.readStateGeneration(ctx, b, enc, toGen)
+	if err != nil {
+		return err
+	}
+
+	fromRegs, err := stateRegistries(from)
+	if err != nil {
+		return err
+	}
+
+	toRegs, err := stateRegistries(to)
+	if err != nil {
+		return err
+	}
+
+	changes := diffStateRegistries(fromRegs, toRegs)
+	if len(changes) == 0 {
+		p.log.Infoln("No resource changes between state versions.")
+
+		return nil
+	}
+
+	for _, c := range changes {
+		p.log.Println(c)
+	}
+
+	return nil
+}
+
+func (p *Plugin) StateRestore(ctx context.Context, req *apiv1.CommandRequest) error {
+	flags := req.Args.Flags.AsMap()
+
+	gen, err := parseGeneration(flags["generation"].(string)) //nolint:errcheck
+	if err != nil {
+		return err
+	}
+
+	cli, err := p.PluginContext().GCPStorageClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	b := cli.Bucket(p.stateBucket(flags))
+	o := b.Object(p.statefile())
+
+	_, err = o.Generation(gen).Attrs(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return fmt.Errorf("state generation %d not found", gen)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// Take state lock so that restore doesn't race with apply.
+	return p.withStateLock(ctx, cli, p.locksBucket(flags), func() error {
+		cur, err := o.Attrs(ctx)
+		if err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+			return err
+		}
+
+		dst := o.If(storage.Conditions{DoesNotExist: true})
+		if cur != nil {
+			dst = o.If(storage.Conditions{GenerationMatch: cur.Generation})
+		}
+
+		attrs, err := dst.CopierFrom(o.Generation(gen)).Run(ctx)
+		if err != nil {
+			return fmt.Errorf("error restoring state: %w", err)
+		}
+
+		p.log.Successf("State restored from generation %d as generation %d.\n", gen, attrs.Generation)
+
+		return nil
+	})
+}
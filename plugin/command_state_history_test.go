@@ -0,0 +1,235 @@
+package plugin
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/storage"
+	"github.com/outblocks/cli-plugin-gcp/internal/fakegcp"
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
+	"github.com/outblocks/outblocks-plugin-go/registry"
+)
+
+// newTestStateBuckets returns plugin with versioned state bucket and locks bucket created.
+func newTestStateBuckets(t *testing.T) (*Plugin, *fakegcp.Server, *storage.BucketHandle) {
+	t.Helper()
+
+	ctx := context.Background()
+	p, srv := newTestPlugin(t)
+
+	cli, err := p.PluginContext().GCPStorageClient(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = getBucket(ctx, cli.Bucket("test-locks"), srv.ProjectID, locksBucketAttrs(srv.Region), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := cli.Bucket("test-state")
+
+	_, _, err = getBucket(ctx, b, srv.ProjectID, stateBucketAttrs(srv.Region), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p, srv, b
+}
+
+// writeStateVersion writes new live version of state and returns its generation.
+func writeStateVersion(t *testing.T, p *Plugin, b *storage.BucketHandle, state []byte) int64 {
+	t.Helper()
+
+	w := b.Object(p.statefile()).NewWriter(context.Background())
+
+	_, err := w.Write(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return w.Attrs().Generation
+}
+
+func testStateWith(t *testing.T, resources ...*registry.ResourceSerialized) []byte {
+	t.Helper()
+
+	state := []byte("{}")
+
+	for _, r := range resources {
+		var err error
+
+		state, err = addToStateRegistry(state, r, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return state
+}
+
+func testResource(typ, ns, id string, props map[string]any) *registry.ResourceSerialized {
+	return &registry.ResourceSerialized{
+		ResourceID: registry.ResourceID{ID: id, Namespace: ns, Type: typ, Source: registry.SourceApp},
+		Properties: props,
+	}
+}
+
+func stateFlags(t *testing.T, m map[string]any) *apiv1.CommandArgs {
+	t.Helper()
+
+	m["bucket"] = "test-state"
+	m["locks-bucket"] = "test-locks"
+
+	return &apiv1.CommandArgs{Flags: mustStruct(t, m)}
+}
+
+func TestDiffStateRegistries(t *testing.T) {
+	run := testResource("CloudRun", "app_api", "cloud_run", map[string]any{"name": "api", "image": "api:1", "env": "secret"})
+	bucket := testResource("Bucket", "app_web", "bucket", map[string]any{"name": "web"})
+	sql := testResource("CloudSQL", "dep_db", "cloud_sql", map[string]any{"name": "db"})
+
+	changedRun := testResource("CloudRun", "app_api", "cloud_run", map[string]any{"name": "api", "image": "api:2", "env": "other-secret", "cpu": "1"})
+
+	for _, tc := range []struct {
+		name     string
+		from, to map[string][]*registry.ResourceSerialized
+		want     []string
+	}{
+		{
+			name: "no changes",
+			from: map[string][]*registry.ResourceSerialized{"plugins_state.gcp": {run, bucket}},
+			to:   map[string][]*registry.ResourceSerialized{"plugins_state.gcp": {bucket, run}},
+		},
+		{
+			name: "added and removed",
+			from: map[string][]*registry.ResourceSerialized{"plugins_state.gcp": {run, bucket}},
+			to:   map[string][]*registry.ResourceSerialized{"plugins_state.gcp": {run, sql}},
+			want: []string{
+				"- [plugins_state.gcp] Bucket app/app_web bucket",
+				"+ [plugins_state.gcp] CloudSQL app/dep_db cloud_sql",
+			},
+		},
+		{
+			name: "changed properties are listed by name only",
+			from: map[string][]*registry.ResourceSerialized{"plugins_state.gcp": {run}},
+			to:   map[string][]*registry.ResourceSerialized{"plugins_state.gcp": {changedRun}},
+			want: []string{"~ [plugins_state.gcp] CloudRun app/app_api cloud_run: cpu, env, image"},
+		},
+		{
+			name: "registries at different paths",
+			from: map[string][]*registry.ResourceSerialized{"a.gcp": {bucket}},
+			to:   map[string][]*registry.ResourceSerialized{"b.gcp": {bucket}},
+			want: []string{
+				"- [a.gcp] Bucket app/app_web bucket",
+				"+ [b.gcp] Bucket app/app_web bucket",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := diffStateRegistries(tc.from, tc.to)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+
+			for _, c := range got {
+				if strings.Contains(c, "secret") {
+					t.Fatalf("expected property values not to be printed, got: %s", c)
+				}
+			}
+		})
+	}
+}
+
+func TestStateDiffBetweenGenerations(t *testing.T) {
+	ctx := context.Background()
+	p, _, b := newTestStateBuckets(t)
+
+	bucket := testResource("Bucket", "app_web", "bucket", map[string]any{"name": "web"})
+
+	gen1 := writeStateVersion(t, p, b, testStateWith(t))
+	gen2 := writeStateVersion(t, p, b, testStateWith(t, bucket))
+
+	err := p.StateDiff(ctx, &apiv1.CommandRequest{Args: stateFlags(t, map[string]any{
+		"from": strconv.FormatInt(gen1, 10),
+		"to":   strconv.FormatInt(gen2, 10),
+		"key":  "",
+	})})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs := p.log.(*fakegcp.Logger).Messages()
+	if !reflect.DeepEqual(msgs, []string{"print: + [plugins_state.gcp] Bucket app/app_web bucket\n"}) {
+		t.Fatalf("expected added bucket to be printed, got: %q", msgs)
+	}
+
+	err = p.StateDiff(ctx, &apiv1.CommandRequest{Args: stateFlags(t, map[string]any{"from": "1", "to": "", "key": ""})})
+	if err == nil || !strings.Contains(err.Error(), "state generation 1 not found") {
+		t.Fatalf("expected unknown generation to be refused, got: %v", err)
+	}
+}
+
+func TestStateRestore(t *testing.T) {
+	ctx := context.Background()
+	p, srv, b := newTestStateBuckets(t)
+
+	v1 := testStateWith(t, testResource("Bucket", "app_web", "bucket", map[string]any{"name": "web"}))
+	v2 := testStateWith(t)
+
+	gen1 := writeStateVersion(t, p, b, v1)
+	gen2 := writeStateVersion(t, p, b, v2)
+
+	err := p.StateRestore(ctx, &apiv1.CommandRequest{Args: stateFlags(t, map[string]any{"generation": "1"})})
+	if err == nil || !strings.Contains(err.Error(), "state generation 1 not found") {
+		t.Fatalf("expected unknown generation to be refused, got: %v", err)
+	}
+
+	err = p.StateRestore(ctx, &apiv1.CommandRequest{Args: stateFlags(t, map[string]any{"generation": strconv.FormatInt(gen1, 10)})})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, ok := srv.Object("test-state", p.statefile())
+	if !ok || string(data) != string(v1) {
+		t.Fatalf("expected restored generation to be live, got: %s", data)
+	}
+
+	versions, err := p.stateVersions(ctx, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(versions) != 3 || versions[0].Generation <= gen2 || versions[1].Generation != gen2 || versions[2].Generation != gen1 {
+		t.Fatalf("expected restore to add new generation and keep previous ones, got: %d versions", len(versions))
+	}
+
+	// Restore is refused while state is locked.
+	cli, err := p.PluginContext().GCPStorageClient(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, _, err = acquireLock(ctx, cli.Bucket("test-locks").Object(p.stateLockfile()), defaultLockTTL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = p.StateRestore(ctx, &apiv1.CommandRequest{Args: stateFlags(t, map[string]any{"generation": strconv.FormatInt(gen2, 10)})})
+	if err == nil {
+		t.Fatal("expected restore of locked state to fail")
+	}
+
+	data, _ = srv.Object("test-state", p.statefile())
+	if string(data) != string(v1) {
+		t.Fatalf("expected state to stay unchanged while locked, got: %s", data)
+	}
+}
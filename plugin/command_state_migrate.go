@@ -0,0 +1,205 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/storage"
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
+)
+
+// stateMovedHeader prefixes marker left in place of state that was migrated to another bucket.
+const stateMovedHeader = "outblocks-gcp-state-moved:v1\n"
+
+type stateMove struct {
+	Project     string    `json:"project"`
+	Bucket      string    `json:"bucket"`
+	LocksBucket string    `json:"locks_bucket"`
+	MovedAt     time.Time `json:"moved_at"`
+}
+
+func (m *stateMove) Error() error {
+	return fmt.Errorf("state was migrated to bucket '%s' in project '%s' on %s, set state properties to: project: %s, bucket: %s, locks_bucket: %s",
+		m.Bucket, m.Project, m.MovedAt.Local().Format("2006-01-02 15:04:05"), m.Project, m.Bucket, m.LocksBucket)
+}
+
+func stateMovedTo(state []byte) (*stateMove, bool) {
+	if !bytes.HasPrefix(state, []byte(stateMovedHeader)) {
+		return nil, false
+	}
+
+	var m stateMove
+
+	_ = json.Unmarshal(state[len(stateMovedHeader):], &m)
+
+	return &m, true
+}
+
+// StateMigrate copies state to another bucket, possibly in another project or region, and leaves a marker pointing to it in old location.
+func (p *Plugin) StateMigrate(ctx context.Context, req *apiv1.CommandRequest) error {
+	flags := req.Args.Flags.AsMap()
+
+	srcBucket := p.stateBucket(flags)
+	srcLocksBucket := p.locksBucket(flags)
+	force := flags["force"].(bool) //nolint:errcheck
+
+	dst := &stateMove{
+		Project:     flags["to-project"].(string),      //nolint:errcheck
+		Bucket:      flags["to-bucket"].(string),       //nolint:errcheck
+		LocksBucket: flags["to-locks-bucket"].(string), //nolint:errcheck
+	}
+
+	region := flags["to-region"].(string) //nolint:errcheck
+
+	if dst.Project == "" {
+		dst.Project = p.settings.ProjectID
+	}
+
+	if region == "" {
+		region = p.settings.Region
+	}
+
+	if dst.Bucket == "" {
+		dst.Bucket = p.defaultStateBucket(dst.Project)
+	}
+
+	if dst.LocksBucket == "" {
+		dst.LocksBucket = p.defaultLocksBucket(dst.Project)
+	}
+
+	if dst.Bucket == srcBucket {
+		return fmt.Errorf("state is already stored in bucket '%s'", srcBucket)
+	}
+
+	cli, err := p.PluginContext().GCPStorageClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = cli.Bucket(srcBucket).Object(p.statefile()).Attrs(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return fmt.Errorf("state '%s' not found in bucket '%s'", p.statefile(), srcBucket)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	_, _, err = getBucket(ctx, cli.Bucket(srcLocksBucket), p.settings.ProjectID, locksBucketAttrs(p.settings.Region), true)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = getBucket(ctx, cli.Bucket(dst.Bucket), dst.Project, stateBucketAttrs(region), true)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = getBucket(ctx, cli.Bucket(dst.LocksBucket), dst.Project, locksBucketAttrs(region), true)
+	if err != nil {
+		return err
+	}
+
+	migrate := func() error {
+		return p.migrateState(ctx, cli, srcBucket, dst, force)
+	}
+
+	// Hold both locks so that nothing is applied against either location during migration.
+	return p.withStateLock(ctx, cli, srcLocksBucket, func() error {
+		if dst.LocksBucket == srcLocksBucket {
+			return migrate()
+		}
+
+		return p.withStateLock(ctx, cli, dst.LocksBucket, migrate)
+	})
+}
+
+func (p *Plugin) migrateState(ctx context.Context, cli *storage.Client, srcBucket string, dst *stateMove, force bool) error {
+	src := cli.Bucket(srcBucket).Object(p.statefile())
+
+	srcAttrs, err := src.Attrs(ctx)
+	if err != nil {
+		return err
+	}
+
+	data, err := readObject(ctx, src.Generation(srcAttrs.Generation))
+	if err != nil {
+		return err
+	}
+
+	if moved, ok := stateMovedTo(data); ok {
+		return moved.Error()
+	}
+
+	// Copy state.
+	o := cli.Bucket(dst.Bucket).Object(p.statefile())
+	cond := storage.Conditions{DoesNotExist: true}
+
+	dstAttrs, err := o.Attrs(ctx)
+
+	switch {
+	case errors.Is(err, storage.ErrObjectNotExist):
+	case err != nil:
+		return err
+	case !force:
+		return fmt.Errorf("state already exists in bucket '%s', use --force to overwrite it", dst.Bucket)
+	default:
+		cond = storage.Conditions{GenerationMatch: dstAttrs.Generation}
+	}
+
+	w := o.If(cond).NewWriter(ctx)
+	w.ContentType = srcAttrs.ContentType
+
+	_, err = w.Write(data)
+	if err != nil {
+		return err
+	}
+
+	err = w.Close()
+	if err != nil {
+		return fmt.Errorf("error writing state copy: %w", err)
+	}
+
+	// Verify copy.
+	copied := o.Generation(w.Attrs().Generation)
+
+	check, err := readObject(ctx, copied)
+	if err != nil {
+		return fmt.Errorf("error reading state copy: %w", err)
+	}
+
+	if !bytes.Equal(check, data) || (srcAttrs.CRC32C != 0 && w.Attrs().CRC32C != srcAttrs.CRC32C) {
+		_ = copied.Delete(ctx)
+
+		return fmt.Errorf("state copy in bucket '%s' doesn't match original, migration aborted", dst.Bucket)
+	}
+
+	// Switch over by replacing old state with marker, unless it was modified in the meantime.
+	dst.MovedAt = time.Now()
+
+	marker, err := json.Marshal(dst)
+	if err != nil {
+		return err
+	}
+
+	mw := src.If(storage.Conditions{GenerationMatch: srcAttrs.Generation}).NewWriter(ctx)
+
+	_, err = mw.Write(append([]byte(stateMovedHeader), marker...))
+	if err != nil {
+		return err
+	}
+
+	err = mw.Close()
+	if err != nil {
+		return fmt.Errorf("error marking old state as migrated, copy is left in bucket '%s': %w", dst.Bucket, err)
+	}
+
+	p.log.Successf("State migrated from bucket '%s' to bucket '%s' in project '%s'.\n", srcBucket, dst.Bucket, dst.Project)
+	p.log.Infof("Update state properties to: project: %s, bucket: %s, locks_bucket: %s\n", dst.Project, dst.Bucket, dst.LocksBucket)
+
+	return nil
+}
@@ -0,0 +1,163 @@
+package plugin
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
+	"google.golang.org/grpc"
+)
+
+type testGetStateStream struct {
+	grpc.ServerStream
+
+	ctx       context.Context
+	responses []*apiv1.GetStateResponse
+}
+
+func (s *testGetStateStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *testGetStateStream) Send(r *apiv1.GetStateResponse) error {
+	s.responses = append(s.responses, r)
+
+	return nil
+}
+
+func migrateFlags(t *testing.T, force bool) *apiv1.CommandArgs {
+	t.Helper()
+
+	return stateFlags(t, map[string]any{
+		"force":           force,
+		"to-project":      "",
+		"to-bucket":       "test-state-new",
+		"to-locks-bucket": "test-locks-new",
+		"to-region":       "",
+	})
+}
+
+func getTestState(t *testing.T, p *Plugin, bucket, locksBucket string) ([]byte, error) {
+	t.Helper()
+
+	stream := &testGetStateStream{ctx: context.Background()}
+
+	err := p.GetState(&apiv1.GetStateRequest{
+		Properties: mustStruct(t, map[string]any{"bucket": bucket, "locks_bucket": locksBucket}),
+		SkipCreate: true,
+	}, stream)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(stream.responses) != 1 {
+		t.Fatalf("expected single state response, got: %v", stream.responses)
+	}
+
+	return stream.responses[0].GetState().State, nil
+}
+
+func TestStateMigrate(t *testing.T) {
+	ctx := context.Background()
+	p, srv, b := newTestStateBuckets(t)
+
+	state := testStateWith(t, testResource("Bucket", "app_web", "bucket", map[string]any{"name": "web"}))
+	writeStateVersion(t, p, b, state)
+
+	err := p.StateMigrate(ctx, &apiv1.CommandRequest{Args: migrateFlags(t, false)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	copied, ok := srv.Object("test-state-new", p.statefile())
+	if !ok || string(copied) != string(state) {
+		t.Fatalf("expected state to be copied, got: %s", copied)
+	}
+
+	marker, _ := srv.Object("test-state", p.statefile())
+
+	moved, ok := stateMovedTo(marker)
+	if !ok || moved.Bucket != "test-state-new" || moved.LocksBucket != "test-locks-new" || moved.Project != srv.ProjectID || moved.MovedAt.IsZero() {
+		t.Fatalf("expected old state to be replaced with marker, got: %s", marker)
+	}
+
+	// Reading old location points to the new one.
+	_, err = getTestState(t, p, "test-state", "test-locks")
+	if err == nil || !strings.Contains(err.Error(), "state was migrated to bucket 'test-state-new'") || !strings.Contains(err.Error(), "locks_bucket: test-locks-new") {
+		t.Fatalf("expected reading migrated state to point to new location, got: %v", err)
+	}
+
+	got, err := getTestState(t, p, "test-state-new", "test-locks-new")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != string(state) {
+		t.Fatalf("expected migrated state to be read from new location, got: %s", got)
+	}
+
+	// Already migrated state cannot be migrated again, even with force.
+	err = p.StateMigrate(ctx, &apiv1.CommandRequest{Args: migrateFlags(t, true)})
+	if err == nil || !strings.Contains(err.Error(), "state was migrated to bucket 'test-state-new'") {
+		t.Fatalf("expected migration of already migrated state to be refused, got: %v", err)
+	}
+
+	copied, _ = srv.Object("test-state-new", p.statefile())
+	if string(copied) != string(state) {
+		t.Fatalf("expected migrated state to stay unchanged, got: %s", copied)
+	}
+}
+
+func TestStateMigrateExistingDestination(t *testing.T) {
+	ctx := context.Background()
+	p, srv, b := newTestStateBuckets(t)
+
+	cli, err := p.PluginContext().GCPStorageClient(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := cli.Bucket("test-state-new")
+
+	_, _, err = getBucket(ctx, dst, srv.ProjectID, stateBucketAttrs(srv.Region), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state := testStateWith(t, testResource("Bucket", "app_web", "bucket", map[string]any{"name": "web"}))
+	other := testStateWith(t)
+
+	writeStateVersion(t, p, b, state)
+	writeStateVersion(t, p, dst, other)
+
+	err = p.StateMigrate(ctx, &apiv1.CommandRequest{Args: migrateFlags(t, false)})
+	if err == nil || !strings.Contains(err.Error(), "use --force to overwrite it") {
+		t.Fatalf("expected existing destination state to be refused, got: %v", err)
+	}
+
+	data, _ := srv.Object("test-state", p.statefile())
+	if string(data) != string(state) {
+		t.Fatalf("expected source state to stay unchanged, got: %s", data)
+	}
+
+	data, _ = srv.Object("test-state-new", p.statefile())
+	if string(data) != string(other) {
+		t.Fatalf("expected destination state to stay unchanged, got: %s", data)
+	}
+
+	err = p.StateMigrate(ctx, &apiv1.CommandRequest{Args: migrateFlags(t, true)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ = srv.Object("test-state-new", p.statefile())
+	if string(data) != string(state) {
+		t.Fatalf("expected destination state to be overwritten with force, got: %s", data)
+	}
+
+	data, _ = srv.Object("test-state", p.statefile())
+	if _, ok := stateMovedTo(data); !ok {
+		t.Fatalf("expected old state to be replaced with marker, got: %s", data)
+	}
+}
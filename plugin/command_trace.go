@@ -0,0 +1,352 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	logging "cloud.google.com/go/logging/apiv2"
+	"cloud.google.com/go/logging/apiv2/loggingpb"
+	"github.com/outblocks/cli-plugin-gcp/gcp"
+	"github.com/outblocks/cli-plugin-gcp/internal/config"
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
+	cloudtrace "google.golang.org/api/cloudtrace/v1"
+	"google.golang.org/api/iterator"
+	"google.golang.org/genproto/googleapis/cloud/audit"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const traceMaxLogEntries = 5000
+
+var traceIDRegex = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// parseTraceID accepts bare trace ID, trace resource name or X-Cloud-Trace-Context header value.
+func parseTraceID(s string) (string, error) {
+	id := strings.TrimSpace(s)
+
+	if i := strings.LastIndex(id, "/traces/"); i != -1 {
+		id = id[i+len("/traces/"):]
+	}
+
+	id, _, _ = strings.Cut(id, "/")
+	id, _, _ = strings.Cut(id, ";")
+	id = strings.ToLower(id)
+
+	if !traceIDRegex.MatchString(id) {
+		return "", fmt.Errorf("invalid trace ID: %s", s)
+	}
+
+	return id, nil
+}
+
+// parseSpanID parses hex span ID of log entry to numeric span ID used by Cloud Trace.
+func parseSpanID(s string) (uint64, error) {
+	return strconv.ParseUint(s, 16, 64)
+}
+
+// traceEvent is either a span start or a log entry on trace timeline.
+type traceEvent struct {
+	time  time.Time
+	depth int
+	text  string
+}
+
+// logEntryMessage returns one line summary of log entry.
+func logEntryMessage(e *loggingpb.LogEntry) string {
+	var parts []string
+
+	if h := e.HttpRequest; h != nil {
+		req := fmt.Sprintf("%s %s %d", h.RequestMethod, h.RequestUrl, h.Status)
+
+		if h.Latency != nil {
+			req += " " + h.Latency.AsDuration().String()
+		}
+
+		parts = append(parts, req)
+	}
+
+	switch p := e.Payload.(type) {
+	case *loggingpb.LogEntry_TextPayload:
+		parts = append(parts, strings.TrimSpace(p.TextPayload))
+	case *loggingpb.LogEntry_JsonPayload:
+		if msg, ok := p.JsonPayload.Fields["message"]; ok {
+			parts = append(parts, msg.GetStringValue())
+		} else if data, err := protojson.Marshal(p.JsonPayload); err == nil {
+			parts = append(parts, string(data))
+		}
+	case *loggingpb.LogEntry_ProtoPayload:
+		var msg audit.AuditLog
+
+		if p.ProtoPayload.UnmarshalTo(&msg) != nil {
+			parts = append(parts, p.ProtoPayload.TypeUrl)
+		} else {
+			_, text := auditLogPayload(&msg)
+			parts = append(parts, text)
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func listLogEntries(ctx context.Context, cli *logging.Client, project, filter string, limit int) ([]*loggingpb.LogEntry, error) {
+	var ret []*loggingpb.LogEntry
+
+	iter := cli.ListLogEntries(ctx, &loggingpb.ListLogEntriesRequest{
+		ResourceNames: []string{
+			"projects/" + project,
+		},
+		Filter:   filter,
+		OrderBy:  "timestamp asc",
+		PageSize: 1000,
+	})
+
+	for len(ret) < limit {
+		entry, err := iter.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("getting logs error: %w", err)
+		}
+
+		ret = append(ret, entry)
+	}
+
+	return ret, nil
+}
+
+// findRequestTrace returns trace ID of request to given URL that is closest to given time.
+func (p *Plugin) findRequestTrace(ctx context.Context, cli *logging.Client, url string, at time.Time, window time.Duration) (string, error) {
+	filter := fmt.Sprintf(`httpRequest.requestUrl : %q trace:* timestamp >= "%s" timestamp <= "%s"`,
+		url, at.Add(-window).Format(time.RFC3339), at.Add(window).Format(time.RFC3339))
+
+	entries, err := listLogEntries(ctx, cli, p.settings.ProjectID, filter, traceMaxLogEntries)
+	if err != nil {
+		return "", err
+	}
+
+	if len(entries) == 0 {
+		return "", fmt.Errorf("no request to '%s' found within %s of %s", url, window, at.Local().Format(time.RFC3339))
+	}
+
+	var (
+		closest *loggingpb.LogEntry
+		diff    time.Duration
+	)
+
+	traces := make(map[string]struct{})
+
+	for _, e := range entries {
+		traces[e.Trace] = struct{}{}
+
+		d := e.Timestamp.AsTime().Sub(at).Abs()
+		if closest == nil || d < diff {
+			closest, diff = e, d
+		}
+	}
+
+	traceID := logTraceID(closest.Trace)
+
+	if len(traces) > 1 {
+		p.log.Warnf("Found %d requests to '%s', showing the one closest to %s with trace %s.\n", len(traces), url, at.Local().Format(time.RFC3339), traceID)
+	}
+
+	return traceID, nil
+}
+
+// traceSpanEvents returns timeline events of trace spans along with nesting depth of each span by its ID.
+func traceSpanEvents(spans []*cloudtrace.TraceSpan) ([]*traceEvent, map[uint64]int) {
+	parents := make(map[uint64]uint64, len(spans))
+
+	for _, s := range spans {
+		parents[s.SpanId] = s.ParentSpanId
+	}
+
+	depths := make(map[uint64]int, len(spans))
+
+	for _, s := range spans {
+		depth := 0
+
+		for parent := s.ParentSpanId; parent != 0 && depth < len(spans); parent = parents[parent] {
+			if _, ok := parents[parent]; !ok {
+				break
+			}
+
+			depth++
+		}
+
+		depths[s.SpanId] = depth
+	}
+
+	events := make([]*traceEvent, 0, len(spans))
+
+	for _, s := range spans {
+		start, _ := time.Parse(time.RFC3339Nano, s.StartTime)
+		end, _ := time.Parse(time.RFC3339Nano, s.EndTime)
+
+		text := fmt.Sprintf("SPAN    [span %016x] %s (%s)", s.SpanId, s.Name, end.Sub(start).Round(time.Microsecond))
+
+		if status := s.Labels["/http/status_code"]; status != "" {
+			text += " status=" + status
+		}
+
+		events = append(events, &traceEvent{
+			time:  start,
+			depth: depths[s.SpanId],
+			text:  text,
+		})
+	}
+
+	return events, depths
+}
+
+// Trace prints log entries of all services and Cloud Trace spans of a single request as a timeline.
+func (p *Plugin) Trace(ctx context.Context, req *apiv1.CommandRequest) error {
+	flags := req.Args.Flags.AsMap()
+
+	traceFlag := flags["trace"].(string) //nolint:errcheck
+	urlFlag := flags["url"].(string)     //nolint:errcheck
+	timeFlag := flags["time"].(string)   //nolint:errcheck
+
+	if (traceFlag == "") == (urlFlag == "") {
+		return fmt.Errorf("either trace ID or request URL is required")
+	}
+
+	window, err := time.ParseDuration(flags["window"].(string)) //nolint:errcheck
+	if err != nil || window <= 0 {
+		return fmt.Errorf("invalid window: %s", flags["window"])
+	}
+
+	at := time.Now()
+
+	if timeFlag != "" {
+		at, err = time.Parse(time.RFC3339, timeFlag)
+		if err != nil {
+			return fmt.Errorf("invalid time, expected RFC3339 format, e.g. 2006-01-02T15:04:05Z: %w", err)
+		}
+	}
+
+	loggingCli, err := config.NewGCPLoggingClient(ctx, p.gcred)
+	if err != nil {
+		return fmt.Errorf("error creating gcp logging client: %w", err)
+	}
+
+	defer loggingCli.Close() //nolint:errcheck
+
+	var traceID string
+
+	if urlFlag != "" {
+		traceID, err = p.findRequestTrace(ctx, loggingCli, urlFlag, at, window)
+	} else {
+		traceID, err = parseTraceID(traceFlag)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// Map resource names to app and dependency IDs the same way logs do.
+	state := req.PluginState
+	if state == nil {
+		state = &apiv1.PluginState{}
+	}
+
+	logsReq := &apiv1.LogsRequest{
+		State:    state,
+		Contains: []string{"source=apps,databases,load_balancer,scheduler"},
+	}
+
+	for _, s := range req.AppStates {
+		logsReq.Apps = append(logsReq.Apps, s.App)
+	}
+
+	for _, s := range req.DependencyStates {
+		logsReq.Dependencies = append(logsReq.Dependencies, s.Dependency)
+	}
+
+	_, idMap, _ := p.createLogFilter(ctx, logsReq)
+
+	filter := fmt.Sprintf(`trace = "projects/%s/traces/%s" timestamp >= "%s" timestamp <= "%s"`,
+		p.settings.ProjectID, traceID, at.Add(-window).Format(time.RFC3339), at.Add(window).Format(time.RFC3339))
+
+	entries, err := listLogEntries(ctx, loggingCli, p.settings.ProjectID, filter, traceMaxLogEntries)
+	if err != nil {
+		return err
+	}
+
+	traceCli, err := p.PluginContext().GCPCloudTraceClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	var trace *cloudtrace.Trace
+
+	err = p.runAndEnsureAPI(ctx, func() error {
+		trace, err = traceCli.Projects.Traces.Get(p.settings.ProjectID, traceID).Do()
+		if gcp.ErrIs404(err) {
+			trace, err = &cloudtrace.Trace{}, nil
+		}
+
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("error fetching trace: %w", err)
+	}
+
+	events, depths := traceSpanEvents(trace.Spans)
+
+	for _, e := range entries {
+		src := logEntryToProto(e, idMap).Source
+		if src == "" {
+			src = e.Resource.Type
+		}
+
+		depth := 0
+		text := fmt.Sprintf("%-7s [%s] %s", e.Severity.String(), src, logEntryMessage(e))
+
+		if spanID, err := parseSpanID(e.SpanId); err == nil {
+			if d, ok := depths[spanID]; ok {
+				depth = d + 1
+			}
+
+			text = fmt.Sprintf("%-7s [%s] [span %016x] %s", e.Severity.String(), src, spanID, logEntryMessage(e))
+		}
+
+		events = append(events, &traceEvent{
+			time:  e.Timestamp.AsTime(),
+			depth: depth,
+			text:  text,
+		})
+	}
+
+	p.log.Infof("Trace %s: %d spans, %d log entries.\n", traceID, len(trace.Spans), len(entries))
+	p.log.Infof("Trace Explorer Web UI: https://console.cloud.google.com/traces/list?tid=%s&project=%s\n", traceID, p.settings.ProjectID)
+
+	if len(trace.Spans) == 0 {
+		p.log.Infoln("No spans found, request may not have been sampled by Cloud Trace.")
+	}
+
+	if len(events) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].time.Before(events[j].time)
+	})
+
+	start := events[0].time
+
+	p.log.Printf("%s\n", start.Local().Format("2006-01-02 15:04:05.000"))
+
+	for _, e := range events {
+		p.log.Printf("+%10.3fms  %s%s\n", float64(e.time.Sub(start).Microseconds())/1000, strings.Repeat("  ", e.depth), e.text)
+	}
+
+	return nil
+}
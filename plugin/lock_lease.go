@@ -0,0 +1,175 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/outblocks/outblocks-plugin-go/log"
+	"github.com/outblocks/outblocks-plugin-go/validate"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	defaultLockTTL = 5 * time.Minute
+	minLockTTL     = 30 * time.Second
+
+	// lockExpiresMetadata is metadata key of lock object holding lease expiry in RFC3339 format.
+	lockExpiresMetadata = "lease-expires"
+)
+
+func lockTTLFromProperties(props map[string]*structpb.Value) (time.Duration, error) {
+	v, err := validate.OptionalString("", props, "lock_ttl", "lock ttl must be a string")
+	if err != nil {
+		return 0, err
+	}
+
+	if v == "" {
+		return defaultLockTTL, nil
+	}
+
+	ttl, err := time.ParseDuration(v)
+	if err != nil || ttl < minLockTTL {
+		return 0, fmt.Errorf("lock ttl must be a duration of at least %s, e.g. '5m'", minLockTTL)
+	}
+
+	return ttl, nil
+}
+
+func lockLeaseMetadata(ttl time.Duration) map[string]string {
+	return map[string]string{
+		lockExpiresMetadata: time.Now().Add(ttl).UTC().Format(time.RFC3339),
+	}
+}
+
+// lockLeaseExpiry returns lease expiry of lock object, locks without lease never expire.
+func lockLeaseExpiry(attrs *storage.ObjectAttrs) (time.Time, bool) {
+	t, err := time.Parse(time.RFC3339, attrs.Metadata[lockExpiresMetadata])
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return t, true
+}
+
+func lockLeaseExpired(attrs *storage.ObjectAttrs) bool {
+	t, ok := lockLeaseExpiry(attrs)
+
+	return ok && time.Now().After(t)
+}
+
+// takeOverExpiredLock deletes lock if its lease has expired and wasn't renewed in the meantime.
+func takeOverExpiredLock(ctx context.Context, o *storage.ObjectHandle) (bool, error) {
+	attrs, err := o.Attrs(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return true, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	if !lockLeaseExpired(attrs) {
+		return false, nil
+	}
+
+	err = o.If(storage.Conditions{GenerationMatch: attrs.Generation, MetagenerationMatch: attrs.Metageneration}).Delete(ctx)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return true, nil
+		}
+
+		if e, ok := err.(*googleapi.Error); ok && e.Code == http.StatusPreconditionFailed {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+// lockLeases keeps leases of held locks alive by renewing them in background until lock gets released.
+type lockLeases struct {
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+}
+
+func lockLeaseKey(o *storage.ObjectHandle, lockID string) string {
+	return fmt.Sprintf("%s/%s#%s", o.BucketName(), o.ObjectName(), lockID)
+}
+
+func (l *lockLeases) start(logger log.Logger, o *storage.ObjectHandle, lockID string, ttl time.Duration) {
+	gen, err := strconv.ParseInt(lockID, 36, 64)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	key := lockLeaseKey(o, lockID)
+
+	l.mu.Lock()
+
+	if l.cancels == nil {
+		l.cancels = make(map[string]context.CancelFunc)
+	}
+
+	if prev, ok := l.cancels[key]; ok {
+		prev()
+	}
+
+	l.cancels[key] = cancel
+
+	l.mu.Unlock()
+
+	go func() {
+		t := time.NewTicker(ttl / 3)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+
+			_, err := o.If(storage.Conditions{GenerationMatch: gen}).Update(ctx, storage.ObjectAttrsToUpdate{
+				Metadata: lockLeaseMetadata(ttl),
+			})
+
+			switch {
+			case err == nil, ctx.Err() != nil:
+			case errors.Is(err, storage.ErrObjectNotExist):
+				logger.Warnf("Lock '%s' was removed by someone else, stopping lease renewal.\n", o.ObjectName())
+
+				return
+			default:
+				if e, ok := err.(*googleapi.Error); ok && e.Code == http.StatusPreconditionFailed {
+					logger.Warnf("Lock '%s' was taken over by someone else, stopping lease renewal.\n", o.ObjectName())
+
+					return
+				}
+
+				logger.Debugf("Error renewing lease of lock '%s': %s\n", o.ObjectName(), err)
+			}
+		}
+	}()
+}
+
+func (l *lockLeases) stop(o *storage.ObjectHandle, lockID string) {
+	key := lockLeaseKey(o, lockID)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if cancel, ok := l.cancels[key]; ok {
+		cancel()
+		delete(l.cancels, key)
+	}
+}
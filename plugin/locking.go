@@ -30,28 +30,42 @@ func (p *Plugin) lockfile(name string) string {
 	return fmt.Sprintf("%s/%s/%s", p.env.Env(), p.env.ProjectName(), name)
 }
 
-func (p *Plugin) acquireLocks(ctx context.Context, lockfiles []string, lockNamesMap map[string]string, lockWait time.Duration, b *storage.BucketHandle, waitCb func() error) (locksAcquired map[string]string, lockInfoFailed []*apiv1.LockError, err error) {
+func (p *Plugin) acquireLocks(ctx context.Context, lockfiles []string, lockNamesMap map[string]string, lockWait, lockTTL time.Duration, b *storage.BucketHandle, waitCb func() error) (locksAcquired map[string]string, lockInfoFailed []*apiv1.LockError, err error) {
 	var mu sync.Mutex
 
 	t := time.NewTicker(time.Second)
 	start := time.Now()
 	locksAcquired = make(map[string]string)
 
+	defer t.Stop()
+
+	// Locks acquired so far have to be released on failure, otherwise their leases would keep them alive.
+	fail := func(err error) (map[string]string, []*apiv1.LockError, error) {
+		releaseErr := p.releaseLockObjects(context.Background(), b, locksAcquired)
+		if releaseErr != nil {
+			return nil, nil, errors.Join(err, fmt.Errorf("error releasing acquired locks: %w", releaseErr))
+		}
+
+		return nil, nil, err
+	}
+
 	for i, lockfile := range lockfiles {
 		lockObject := b.Object(lockfile)
 		name := lockNamesMap[lockfile]
 
 		for {
-			lockInfo, owner, createdAt, err := acquireLock(ctx, lockObject)
+			lockInfo, owner, createdAt, err := acquireLock(ctx, lockObject, lockTTL)
 			if err == nil {
 				locksAcquired[name] = lockInfo
 
+				p.lockLeases.start(p.log, lockObject, lockInfo, lockTTL)
+
 				break
 			}
 
 			if lockWait == 0 || time.Since(start) > lockWait {
 				if !errors.Is(err, errAcquireLockFailed) {
-					return nil, nil, err
+					return fail(err)
 				}
 
 				lockInfoFailed = append(lockInfoFailed, types.NewLockError(name, lockInfo, owner, createdAt))
@@ -89,13 +103,13 @@ func (p *Plugin) acquireLocks(ctx context.Context, lockfiles []string, lockNames
 			if waitCb != nil {
 				err = waitCb()
 				if err != nil {
-					return nil, nil, err
+					return fail(err)
 				}
 			}
 
 			select {
 			case <-ctx.Done():
-				return nil, nil, ctx.Err()
+				return fail(ctx.Err())
 			case <-t.C:
 			}
 		}
@@ -117,6 +131,11 @@ func (p *Plugin) AcquireLocks(r *apiv1.AcquireLocksRequest, stream apiv1.Locking
 		return err
 	}
 
+	lockTTL, err := lockTTLFromProperties(r.Properties.Fields)
+	if err != nil {
+		return err
+	}
+
 	pctx := p.PluginContext()
 
 	cli, err := pctx.GCPStorageClient(ctx)
@@ -126,9 +145,7 @@ func (p *Plugin) AcquireLocks(r *apiv1.AcquireLocksRequest, stream apiv1.Locking
 
 	b := cli.Bucket(bucket)
 
-	_, _, err = getBucket(ctx, b, project, &storage.BucketAttrs{
-		Location: p.settings.Region,
-	}, true)
+	_, _, err = getBucket(ctx, b, project, locksBucketAttrs(p.settings.Region), true)
 	if err != nil {
 		return err
 	}
@@ -151,7 +168,7 @@ func (p *Plugin) AcquireLocks(r *apiv1.AcquireLocksRequest, stream apiv1.Locking
 
 	first := true
 
-	locksAcquired, lockInfoFailed, err := p.acquireLocks(ctx, lockfiles, lockNamesMap, lockWait, b, func() error {
+	locksAcquired, lockInfoFailed, err := p.acquireLocks(ctx, lockfiles, lockNamesMap, lockWait, lockTTL, b, func() error {
 		if first {
 			err = stream.Send(&apiv1.AcquireLocksResponse{
 				Waiting: true,
@@ -204,14 +221,22 @@ func (p *Plugin) ReleaseLocks(ctx context.Context, r *apiv1.ReleaseLocksRequest)
 		return nil, err
 	}
 
-	b := cli.Bucket(bucket)
+	return &apiv1.ReleaseLocksResponse{}, p.releaseLockObjects(ctx, cli.Bucket(bucket), r.Locks)
+}
+
+// releaseLockObjects stops lease renewal of locks mapped by name to lock IDs and releases them.
+func (p *Plugin) releaseLockObjects(ctx context.Context, b *storage.BucketHandle, locks map[string]string) error {
 	g, _ := errgroup.WithConcurrency(ctx, gcp.DefaultConcurrency)
 
-	for name, info := range r.Locks {
+	for name, info := range locks {
 		g.Go(func() error {
-			return releaseLock(ctx, b.Object(p.lockfile(name)), info)
+			lock := b.Object(p.lockfile(name))
+
+			p.lockLeases.stop(lock, info)
+
+			return releaseLock(ctx, lock, info)
 		})
 	}
 
-	return &apiv1.ReleaseLocksResponse{}, g.Wait()
+	return g.Wait()
 }
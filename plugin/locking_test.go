@@ -0,0 +1,194 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/outblocks/cli-plugin-gcp/internal/fakegcp"
+)
+
+func newTestLocks(t *testing.T, names ...string) (*Plugin, *fakegcp.Server, *storage.BucketHandle, []string, map[string]string) {
+	t.Helper()
+
+	ctx := context.Background()
+	p, srv := newTestPlugin(t)
+
+	cli, err := p.PluginContext().GCPStorageClient(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := cli.Bucket("test-locks")
+
+	_, _, err = getBucket(ctx, b, srv.ProjectID, locksBucketAttrs(srv.Region), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lockNamesMap := make(map[string]string, len(names))
+	lockfiles := make([]string, len(names))
+
+	for i, n := range names {
+		lockfiles[i] = p.lockfile(n)
+		lockNamesMap[lockfiles[i]] = n
+	}
+
+	sort.Strings(lockfiles)
+
+	return p, srv, b, lockfiles, lockNamesMap
+}
+
+func TestAcquireLocksReleasesPartiallyAcquiredLocksOnError(t *testing.T) {
+	ctx := context.Background()
+	p, srv, b, lockfiles, lockNamesMap := newTestLocks(t, "a", "b")
+
+	// Let first lock be acquired and fail creating the second one.
+	uploads := 0
+
+	srv.FailRequests(http.StatusForbidden, func(r *http.Request) bool {
+		if r.Method != http.MethodPost || !strings.HasPrefix(r.URL.Path, "/upload/storage/v1/") {
+			return false
+		}
+
+		uploads++
+
+		return uploads > 1
+	})
+
+	locks, lockErrs, err := p.acquireLocks(ctx, lockfiles, lockNamesMap, 0, time.Minute, b, nil)
+	if err == nil {
+		t.Fatalf("expected error, got locks: %v", locks)
+	}
+
+	if len(locks) != 0 || len(lockErrs) != 0 {
+		t.Fatalf("expected no locks to be returned, got: %v %v", locks, lockErrs)
+	}
+
+	if uploads != 2 {
+		t.Fatalf("expected first lock to be acquired before failure, got %d uploads", uploads)
+	}
+
+	for _, f := range lockfiles {
+		if _, ok := srv.Object("test-locks", f); ok {
+			t.Fatalf("expected lock %s to be released", f)
+		}
+	}
+
+	p.lockLeases.mu.Lock()
+	defer p.lockLeases.mu.Unlock()
+
+	if len(p.lockLeases.cancels) != 0 {
+		t.Fatalf("expected lease renewal to be stopped, got: %v", p.lockLeases.cancels)
+	}
+}
+
+func TestAcquireLockTakesOverOnlyExpiredLease(t *testing.T) {
+	ctx := context.Background()
+	_, _, b, lockfiles, _ := newTestLocks(t, "a")
+	o := b.Object(lockfiles[0])
+
+	lockID, _, _, err := acquireLock(ctx, o, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Live lease is refused.
+	info, _, _, err := acquireLock(ctx, o, time.Minute)
+	if !errors.Is(err, errAcquireLockFailed) || info != lockID {
+		t.Fatalf("expected live lock %s to be refused, got: %s, %v", lockID, info, err)
+	}
+
+	// Lock without lease never expires.
+	_, err = o.Update(ctx, storage.ObjectAttrsToUpdate{Metadata: map[string]string{lockExpiresMetadata: ""}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, _, err = acquireLock(ctx, o, time.Minute)
+	if !errors.Is(err, errAcquireLockFailed) {
+		t.Fatalf("expected lock without lease to be refused, got: %v", err)
+	}
+
+	// Expired lease is taken over.
+	_, err = o.Update(ctx, storage.ObjectAttrsToUpdate{Metadata: lockLeaseMetadata(-time.Minute)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newLockID, _, _, err := acquireLock(ctx, o, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if newLockID == lockID {
+		t.Fatal("expected expired lock to be replaced")
+	}
+
+	if err := releaseLock(ctx, o, lockID); !errors.Is(err, errReleaseLockMismatch) {
+		t.Fatalf("expected previous owner to lose lock, got: %v", err)
+	}
+}
+
+func TestLockLeaseRenewal(t *testing.T) {
+	ctx := context.Background()
+	p, _, b, lockfiles, _ := newTestLocks(t, "a")
+	o := b.Object(lockfiles[0])
+	expired := lockLeaseMetadata(-time.Minute)
+
+	lockID, _, _, err := acquireLock(ctx, o, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = o.Update(ctx, storage.ObjectAttrsToUpdate{Metadata: expired})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leaseExpiry := func() string {
+		attrs, err := o.Attrs(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return attrs.Metadata[lockExpiresMetadata]
+	}
+
+	// Renewal moves lease expiry forward.
+	p.lockLeases.start(p.log, o, lockID, 30*time.Millisecond)
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for leaseExpiry() == expired[lockExpiresMetadata] {
+		if time.Now().After(deadline) {
+			t.Fatal("expected lease to be renewed")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Once stopped, lease is no longer renewed.
+	p.lockLeases.stop(o, lockID)
+
+	_, err = o.Update(ctx, storage.ObjectAttrsToUpdate{Metadata: expired})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if leaseExpiry() != expired[lockExpiresMetadata] {
+		t.Fatal("expected lease renewal to be stopped")
+	}
+
+	expiredLock, err := takeOverExpiredLock(ctx, o)
+	if err != nil || !expiredLock {
+		t.Fatalf("expected expired lock to be taken over, got: %v", err)
+	}
+}
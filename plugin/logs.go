@@ -1,6 +1,7 @@
 package plugin
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -14,9 +15,12 @@ import (
 	"github.com/outblocks/cli-plugin-gcp/internal/config"
 	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
 	"github.com/outblocks/outblocks-plugin-go/registry"
+	cloudfunctionsv2 "google.golang.org/api/cloudfunctions/v2"
 	"google.golang.org/api/iterator"
 	"google.golang.org/genproto/googleapis/cloud/audit"
 	loggingtype "google.golang.org/genproto/googleapis/logging/type"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 func logEntryToProto(e *loggingpb.LogEntry, idMap map[string]string) *apiv1.LogsResponse {
@@ -69,8 +73,19 @@ func logEntryToProto(e *loggingpb.LogEntry, idMap map[string]string) *apiv1.Logs
 	switch e.Resource.Type {
 	case "cloud_run_revision":
 		src = idMap[e.Resource.Labels["service_name"]]
+	case "cloud_run_job":
+		src = idMap[e.Resource.Labels["job_name"]]
 	case "cloudsql_database":
 		src = idMap[e.Resource.Labels["database_id"]]
+	case "http_load_balancer":
+		src = idMap[e.Resource.Labels["backend_service_name"]]
+		if src == "" {
+			src = idMap[e.Resource.Labels["url_map_name"]]
+		}
+	case "cloud_scheduler_job":
+		src = idMap[e.Resource.Labels["job_id"]]
+	case "build":
+		src = idMap[e.Resource.Labels["build_id"]]
 	}
 
 	ret := &apiv1.LogsResponse{
@@ -87,16 +102,22 @@ func logEntryToProto(e *loggingpb.LogEntry, idMap map[string]string) *apiv1.Logs
 
 		err := p.ProtoPayload.UnmarshalTo(&msg)
 		if err != nil {
-			panic(err)
+			ret.Payload = &apiv1.LogsResponse_Text{
+				Text: p.ProtoPayload.TypeUrl,
+			}
+
+			break
 		}
 
-		if msg.Status != nil {
-			ret.Payload = &apiv1.LogsResponse_Text{
-				Text: msg.Status.Message,
+		payload, text := auditLogPayload(&msg)
+
+		if payload != nil {
+			ret.Payload = &apiv1.LogsResponse_Json{
+				Json: payload,
 			}
 		} else {
 			ret.Payload = &apiv1.LogsResponse_Text{
-				Text: msg.MethodName,
+				Text: text,
 			}
 		}
 
@@ -105,71 +126,186 @@ func logEntryToProto(e *loggingpb.LogEntry, idMap map[string]string) *apiv1.Logs
 			Text: p.TextPayload,
 		}
 
+		// Pass JSON written as plain text, e.g. by loggers not recognized by Cloud Logging, as structured data.
+		if text := strings.TrimSpace(p.TextPayload); strings.HasPrefix(text, "{") {
+			var payload structpb.Struct
+
+			if protojson.Unmarshal([]byte(text), &payload) == nil {
+				ret.Payload = &apiv1.LogsResponse_Json{
+					Json: &payload,
+				}
+			}
+		}
+
 	case *loggingpb.LogEntry_JsonPayload:
 		ret.Payload = &apiv1.LogsResponse_Json{
 			Json: p.JsonPayload,
 		}
 	}
 
+	if e.Trace != "" {
+		addLogTrace(ret, logTraceID(e.Trace), e.SpanId)
+	}
+
 	return ret
 }
 
-func (p *Plugin) createLogFilter(r *apiv1.LogsRequest) (filter string, idMap map[string]string, err error) {
-	var (
-		filterOrs          []string
-		filterAnds         []string
-		cloudRunNames      []string
-		cloudFunctionNames []string
-		cloudSQLNames      []string
-	)
+const (
+	// Reserved payload fields carrying trace and span ID of log entry to host. Cloud Logging strips fields with
+	// these names from JSON payloads when entries are written, so they never collide with logged fields.
+	logTracePayloadField = "logging.googleapis.com/trace"
+	logSpanPayloadField  = "logging.googleapis.com/spanId"
+)
 
-	idMap = make(map[string]string)
-	reg := registry.NewRegistry(nil)
+// addLogTrace passes trace and span ID of log entry to host as reserved payload fields, other payload fields are kept as is.
+// Text payload becomes structured one with text as its message.
+func addLogTrace(ret *apiv1.LogsResponse, traceID, spanID string) {
+	var payload *structpb.Struct
+
+	switch p := ret.Payload.(type) {
+	case *apiv1.LogsResponse_Json:
+		payload = p.Json
+	case *apiv1.LogsResponse_Text:
+		payload = &structpb.Struct{
+			Fields: map[string]*structpb.Value{
+				"message": structpb.NewStringValue(p.Text),
+			},
+		}
+	default:
+		payload = &structpb.Struct{}
+	}
 
-	gcp.RegisterTypes(reg)
-	_ = reg.Load(r.State.Registry)
+	if payload.Fields == nil {
+		payload.Fields = make(map[string]*structpb.Value)
+	}
 
-	for _, app := range r.Apps {
-		gcpID := gcp.ID(p.env, app.Id)
-		idMap[gcpID] = app.Id
+	payload.Fields[logTracePayloadField] = structpb.NewStringValue(traceID)
 
-		if app.Type == deploy.AppTypeFunction {
-			cloudFunctionNames = append(cloudFunctionNames, gcpID)
-		} else {
-			cloudRunNames = append(cloudRunNames, gcpID)
-		}
+	if spanID != "" {
+		payload.Fields[logSpanPayloadField] = structpb.NewStringValue(spanID)
+	}
+
+	ret.Payload = &apiv1.LogsResponse_Json{
+		Json: payload,
+	}
+}
+
+// logTraceID returns trace ID from trace resource name, e.g. `projects/my-project/traces/06796866738c859f2f19b7cfb3214824`.
+func logTraceID(trace string) string {
+	return trace[strings.LastIndex(trace, "/")+1:]
+}
+
+// auditLogPayload returns audit log as structured data, with its status message or method name as message.
+func auditLogPayload(msg *audit.AuditLog) (payload *structpb.Struct, text string) {
+	text = msg.MethodName
+	if msg.Status != nil {
+		text = msg.Status.Message
+	}
+
+	data, err := protojson.Marshal(msg)
+	if err != nil {
+		return nil, text
 	}
 
-	for _, dep := range r.Dependencies {
-		switch dep.Type {
-		case deploy.DepTypePostgreSQL, deploy.DepTypeMySQL:
-			db := &gcp.CloudSQL{}
+	payload = &structpb.Struct{}
 
-			if reg.GetDependencyResource(dep, "cloud_sql", db) {
-				gcpID := fmt.Sprintf("%s:%s", p.settings.ProjectID, db.Name.Any())
-				cloudSQLNames = append(cloudSQLNames, gcpID)
-				idMap[gcpID] = dep.Id
+	if protojson.Unmarshal(data, payload) != nil {
+		return nil, text
+	}
+
+	if payload.Fields == nil {
+		payload.Fields = make(map[string]*structpb.Value)
+	}
+
+	payload.Fields["message"] = structpb.NewStringValue(text)
+
+	return payload, text
+}
+
+// functionBuildIDs returns IDs of latest Cloud Build builds of function apps mapped to app IDs.
+func (p *Plugin) functionBuildIDs(ctx context.Context, reg *registry.Registry, apps []*apiv1.App) (map[string]string, error) {
+	ret := make(map[string]string)
+
+	var cli *cloudfunctionsv2.Service
+
+	for _, app := range apps {
+		if app.Type != deploy.AppTypeFunction {
+			continue
+		}
+
+		var region string
+
+		fnV2 := &gcp.CloudFunctionV2{}
+		fnV1 := &gcp.CloudFunction{}
+
+		switch {
+		case reg.GetAppResource(app, "cloud_function_v2", fnV2):
+			region = fnV2.Region.Any()
+		case reg.GetAppResource(app, "cloud_function", fnV1):
+			region = fnV1.Region.Any()
+		default:
+			continue
+		}
+
+		if cli == nil {
+			var err error
+
+			cli, err = p.PluginContext().GCPCloudFunctionsV2Client(ctx)
+			if err != nil {
+				return nil, err
 			}
 		}
-	}
 
-	if len(cloudRunNames) > 0 {
-		filterOrs = append(filterOrs, fmt.Sprintf(`(resource.type = "cloud_run_revision" resource.labels.service_name = ("%s"))`, strings.Join(cloudRunNames, `" OR "`))) //nolint:gocritic
+		// Functions API v2 returns both 1st and 2nd gen functions.
+		fn, err := cli.Projects.Locations.Functions.Get(fmt.Sprintf("projects/%s/locations/%s/functions/%s", p.settings.ProjectID, region, gcp.ID(p.env, app.Id))).Do()
+		if gcp.ErrIs404(err) {
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("error fetching function '%s': %w", app.Id, err)
+		}
+
+		if fn.BuildConfig == nil || fn.BuildConfig.Build == "" {
+			continue
+		}
+
+		build := fn.BuildConfig.Build
+		ret[build[strings.LastIndex(build, "/")+1:]] = app.Id
 	}
 
-	if len(cloudFunctionNames) > 0 {
-		filterOrs = append(filterOrs, fmt.Sprintf(`(resource.type = "cloud_function" resource.labels.function_name = ("%s"))`, strings.Join(cloudFunctionNames, `" OR "`))) //nolint:gocritic
+	return ret, nil
+}
+
+func (p *Plugin) createLogFilter(ctx context.Context, r *apiv1.LogsRequest) (filter string, idMap map[string]string, err error) {
+	var filterAnds []string
+
+	sources, contains, err := logSources(r.Contains)
+	if err != nil {
+		return "", nil, err
 	}
 
-	if len(cloudSQLNames) > 0 {
-		filterOrs = append(filterOrs, fmt.Sprintf(`(resource.type = "cloudsql_database" resource.labels.database_id = ("%s"))`, strings.Join(cloudSQLNames, `" OR "`))) //nolint:gocritic
+	reg := registry.NewRegistry(nil)
+
+	gcp.RegisterTypes(reg)
+	_ = reg.Load(r.State.Registry)
+
+	resourceFilter := deploy.NewLogResourceFilter(p.env, p.settings.ProjectID, reg, r.Apps, r.Dependencies, sources)
+
+	if sources[deploy.LogSourceBuild] {
+		builds, err := p.functionBuildIDs(ctx, reg, r.Apps)
+		if err != nil {
+			return "", nil, err
+		}
+
+		resourceFilter.AddResources("build", "build_id", builds)
 	}
 
-	if len(filterOrs) == 0 {
-		return "", nil, fmt.Errorf("no valid apps and/or dependencies defined")
+	if resourceFilter.IsEmpty() {
+		return "", nil, fmt.Errorf("no valid apps and/or dependencies defined for selected log sources")
 	}
 
-	filterAnds = append(filterAnds, fmt.Sprintf("(%s)", strings.Join(filterOrs, " OR ")))
+	filterAnds = append(filterAnds, resourceFilter.Field().Any())
 
 	if r.Severity > apiv1.LogSeverity_LOG_SEVERITY_UNSPECIFIED {
 		filterAnds = append(filterAnds, fmt.Sprintf(`severity >= "%s"`, r.Severity.String()[len("LOG_SEVERITY_"):])) //nolint:gocritic
@@ -183,19 +319,43 @@ func (p *Plugin) createLogFilter(r *apiv1.LogsRequest) (filter string, idMap map
 		filterAnds = append(filterAnds, fmt.Sprintf(`timestamp <= "%s"`, r.End.AsTime().Format(time.RFC3339))) //nolint:gocritic
 	}
 
-	for _, c := range r.Contains {
-		filterAnds = append(filterAnds, fmt.Sprintf(`"%s"`, c)) //nolint:gocritic
+	for _, c := range contains {
+		pred, ok, err := logFieldPredicate(c)
+		if err != nil {
+			return "", nil, err
+		}
+
+		if ok {
+			filterAnds = append(filterAnds, pred)
+		} else {
+			filterAnds = append(filterAnds, fmt.Sprintf(`"%s"`, c)) //nolint:gocritic
+		}
 	}
 
 	for _, c := range r.NotContains {
-		filterAnds = append(filterAnds, fmt.Sprintf(`NOT "%s"`, c)) //nolint:gocritic
+		pred, ok, err := logFieldPredicate(c)
+		if err != nil {
+			return "", nil, err
+		}
+
+		if ok {
+			filterAnds = append(filterAnds, fmt.Sprintf("NOT (%s)", pred))
+		} else {
+			filterAnds = append(filterAnds, fmt.Sprintf(`NOT "%s"`, c)) //nolint:gocritic
+		}
 	}
 
 	if r.Filter != "" {
 		filterAnds = append(filterAnds, r.Filter)
 	}
 
-	return strings.Join(filterAnds, " "), idMap, nil
+	return strings.Join(filterAnds, " "), resourceFilter.IDMap, nil
+}
+
+// logsExplorerURL returns Logs Explorer Web UI URL showing log entries matching filter.
+func logsExplorerURL(projectID, filter string) string {
+	return fmt.Sprintf("https://console.cloud.google.com/logs/query;query=%s?project=%s",
+		strings.ReplaceAll(url.PathEscape(filter), "=", "%3D"), projectID)
 }
 
 func (p *Plugin) Logs(r *apiv1.LogsRequest, srv apiv1.LogsPluginService_LogsServer) error {
@@ -206,15 +366,12 @@ func (p *Plugin) Logs(r *apiv1.LogsRequest, srv apiv1.LogsPluginService_LogsServ
 		return fmt.Errorf("error creating gcp logging client: %w", err)
 	}
 
-	filter, idMap, err := p.createLogFilter(r)
+	filter, idMap, err := p.createLogFilter(ctx, r)
 	if err != nil {
 		return err
 	}
 
-	loggingURL := fmt.Sprintf("https://console.cloud.google.com/logs/query;query=%s?project=%s",
-		strings.ReplaceAll(url.PathEscape(filter), "=", "%3D"), p.settings.ProjectID)
-
-	p.log.Infof("Logs Explorer Web UI: %s\n", loggingURL)
+	p.log.Infof("Logs Explorer Web UI: %s\n", logsExplorerURL(p.settings.ProjectID, filter))
 
 	iter := loggingCli.ListLogEntries(ctx, &loggingpb.ListLogEntriesRequest{
 		ResourceNames: []string{
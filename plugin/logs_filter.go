@@ -0,0 +1,105 @@
+package plugin
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/outblocks/cli-plugin-gcp/deploy"
+)
+
+// logFieldPredicateRegex matches predicates on log entry fields, e.g. `payload.user_id=123` or `http.status>=500`.
+var logFieldPredicateRegex = regexp.MustCompile(`^(payload|http|labels)\.([A-Za-z0-9_.\-]+)\s*(!=|>=|<=|=~|!~|=|>|<|:)\s*(.*)$`)
+
+// logHTTPFields maps http request fields, accepted in snake or camel case, to their names in log entry.
+var logHTTPFields = map[string]string{
+	"method":        "requestMethod",
+	"requestmethod": "requestMethod",
+	"url":           "requestUrl",
+	"requesturl":    "requestUrl",
+	"requestsize":   "requestSize",
+	"status":        "status",
+	"responsesize":  "responseSize",
+	"useragent":     "userAgent",
+	"remoteip":      "remoteIp",
+	"serverip":      "serverIp",
+	"referer":       "referer",
+	"latency":       "latency",
+	"protocol":      "protocol",
+}
+
+// logFieldPredicate translates predicate on log entry field to Cloud Logging filter expression.
+// Returns false if expression is not a field predicate and should be treated as text search instead.
+func logFieldPredicate(expr string) (string, bool, error) {
+	m := logFieldPredicateRegex.FindStringSubmatch(strings.TrimSpace(expr))
+	if m == nil {
+		return "", false, nil
+	}
+
+	root, field, op, value := m[1], m[2], m[3], strings.TrimSpace(m[4])
+
+	switch root {
+	case "payload":
+		field = "jsonPayload." + field
+	case "labels":
+		field = fmt.Sprintf(`labels.%q`, field)
+	case "http":
+		name, ok := logHTTPFields[strings.ToLower(strings.ReplaceAll(field, "_", ""))]
+		if !ok {
+			return "", false, fmt.Errorf("unknown http log field '%s' in '%s'", field, expr)
+		}
+
+		field = "httpRequest." + name
+	}
+
+	// Value starting with operator character means operator is not supported, e.g. `==` or `=>`.
+	if value != "" && strings.ContainsRune("=<>!~", rune(value[0])) {
+		return "", false, fmt.Errorf("unknown operator '%s%c' in '%s'", op, value[0], expr)
+	}
+
+	quoted := len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"'
+
+	if quoted {
+		v, err := strconv.Unquote(value)
+		if err != nil {
+			return "", false, fmt.Errorf("invalid value in '%s': %w", expr, err)
+		}
+
+		value = v
+	}
+
+	// Unquoted numbers are compared numerically.
+	if _, err := strconv.ParseFloat(value, 64); err == nil && !quoted && op != "=~" && op != "!~" {
+		return fmt.Sprintf("%s %s %s", field, op, value), true, nil
+	}
+
+	return fmt.Sprintf("%s %s %q", field, op, value), true, nil
+}
+
+// logSourcePredicateRegex matches selection of log sources, e.g. `source=apps,load_balancer`.
+var logSourcePredicateRegex = regexp.MustCompile(`^source\s*=\s*(.*)$`)
+
+// logSources extracts selected log sources from contains expressions, returning remaining expressions.
+// Apps and databases are selected by default.
+func logSources(contains []string) (sources map[string]bool, rest []string, err error) {
+	var selected []string
+
+	for _, c := range contains {
+		m := logSourcePredicateRegex.FindStringSubmatch(strings.TrimSpace(c))
+		if m == nil {
+			rest = append(rest, c)
+
+			continue
+		}
+
+		selected = append(selected, strings.Split(m[1], ",")...)
+	}
+
+	sources, err = deploy.ParseLogSources(selected)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return sources, rest, nil
+}
@@ -0,0 +1,289 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/outblocks/cli-plugin-gcp/deploy"
+	"github.com/outblocks/cli-plugin-gcp/gcp"
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
+	"github.com/outblocks/outblocks-plugin-go/registry"
+	"github.com/outblocks/outblocks-plugin-go/registry/fields"
+	cloudfunctionsv2 "google.golang.org/api/cloudfunctions/v2"
+)
+
+func TestLogFieldPredicate(t *testing.T) {
+	for _, tc := range []struct {
+		expr string
+		want string
+		err  string
+	}{
+		// Numbers are compared numerically unless quoted or matched with regexp.
+		{expr: "payload.user_id=123", want: `jsonPayload.user_id = 123`},
+		{expr: "payload.user_id = -1.5", want: `jsonPayload.user_id = -1.5`},
+		{expr: `payload.user_id="123"`, want: `jsonPayload.user_id = "123"`},
+		{expr: "payload.code=~123", want: `jsonPayload.code =~ "123"`},
+		{expr: "http.status>=500", want: `httpRequest.status >= 500`},
+
+		// Quoting and escaping.
+		{expr: "payload.msg:connection timeout", want: `jsonPayload.msg : "connection timeout"`},
+		{expr: `payload.msg="say \"hi\""`, want: `jsonPayload.msg = "say \"hi\""`},
+		{expr: `payload.msg=say "hi"`, want: `jsonPayload.msg = "say \"hi\""`},
+		{expr: `payload.path=C:\tmp`, want: `jsonPayload.path = "C:\\tmp"`},
+		{expr: `payload.msg="tab\there"`, want: `jsonPayload.msg = "tab\there"`},
+		{expr: `payload.msg="`, want: `jsonPayload.msg = "\""`},
+		{expr: `payload.msg="\q"`, err: "invalid value"},
+
+		// Fields and operators.
+		{expr: "payload.nested.field!=x", want: `jsonPayload.nested.field != "x"`},
+		{expr: "payload.path!~^/api/", want: `jsonPayload.path !~ "^/api/"`},
+		{expr: "payload.latency<0.5", want: `jsonPayload.latency < 0.5`},
+		{expr: "labels.app-name=web", want: `labels."app-name" = "web"`},
+		{expr: "http.request_method=GET", want: `httpRequest.requestMethod = "GET"`},
+		{expr: "http.userAgent:curl", want: `httpRequest.userAgent : "curl"`},
+		{expr: "http.remote_ip=10.0.0.1", want: `httpRequest.remoteIp = "10.0.0.1"`},
+		{expr: "http.cookie=abc", err: "unknown http log field 'cookie'"},
+		{expr: "payload.user_id==123", err: "unknown operator '=='"},
+		{expr: "payload.user_id => 1", err: "unknown operator '=>'"},
+		{expr: "http.status<>200", err: "unknown operator '<>'"},
+
+		// Anything else is searched as text.
+		{expr: "connection timeout"},
+		{expr: "resource.type=cloud_run_revision"},
+		{expr: "payload.user_id"},
+		{expr: "payload.user id=1"},
+		{expr: "payload.user_id ~ 1"},
+	} {
+		t.Run(tc.expr, func(t *testing.T) {
+			got, ok, err := logFieldPredicate(tc.expr)
+
+			switch {
+			case tc.err != "":
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error containing %q, got: %q, %v", tc.err, got, err)
+				}
+			case err != nil:
+				t.Fatal(err)
+			case ok != (tc.want != ""):
+				t.Fatalf("expected field predicate %v, got %v", tc.want != "", ok)
+			case got != tc.want:
+				t.Fatalf("expected %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestLogSources(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		contains []string
+		want     []string
+		rest     []string
+		err      string
+	}{
+		{
+			name:     "default sources",
+			contains: []string{"timeout"},
+			want:     []string{deploy.LogSourceApps, deploy.LogSourceDatabases},
+			rest:     []string{"timeout"},
+		},
+		{
+			name:     "selected sources",
+			contains: []string{"source=load_balancer,scheduler", "timeout", "source = Build"},
+			want:     []string{deploy.LogSourceBuild, deploy.LogSourceLoadBalancer, deploy.LogSourceScheduler},
+			rest:     []string{"timeout"},
+		},
+		{
+			name:     "all sources",
+			contains: []string{"source=all"},
+			want:     []string{deploy.LogSourceApps, deploy.LogSourceBuild, deploy.LogSourceDatabases, deploy.LogSourceLoadBalancer, deploy.LogSourceScheduler},
+		},
+		{
+			name:     "unknown source",
+			contains: []string{"source=apps,cdn"},
+			err:      "unknown log source 'cdn'",
+		},
+		{
+			name:     "empty source",
+			contains: []string{"source="},
+			err:      "unknown log source ''",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sources, rest, err := logSources(tc.contains)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error containing %q, got: %v", tc.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make([]string, 0, len(sources))
+
+			for s, ok := range sources {
+				if ok {
+					got = append(got, s)
+				}
+			}
+
+			sort.Strings(got)
+
+			if !reflect.DeepEqual(got, tc.want) || !reflect.DeepEqual(rest, tc.rest) {
+				t.Fatalf("expected sources %q and rest %q, got %q and %q", tc.want, tc.rest, got, rest)
+			}
+		})
+	}
+}
+
+func TestCreateLogFilterSources(t *testing.T) {
+	ctx := context.Background()
+	p, srv := newTestPlugin(t)
+
+	api := &apiv1.App{Id: "app_api", Name: "api", Type: deploy.AppTypeService}
+	worker := &apiv1.App{Id: "app_worker", Name: "worker", Type: deploy.AppTypeJob}
+	fn := &apiv1.App{Id: "app_fn", Name: "fn", Type: deploy.AppTypeFunction}
+	db := &apiv1.Dependency{Id: "dep_db", Name: "db", Type: deploy.DepTypePostgreSQL}
+
+	existing := func(v string) fields.StringInputField {
+		f := fields.String(v)
+		f.SetCurrent(v)
+
+		return f
+	}
+
+	reg := registry.NewRegistry(nil)
+	gcp.RegisterTypes(reg)
+
+	mustRegister := func(_ bool, err error) {
+		t.Helper()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	urlMap := &gcp.URLMap{Name: existing("test-lb-https")}
+	job := &gcp.CloudSchedulerJob{Name: existing("test-api-nightly")}
+	function := &gcp.CloudFunctionV2{Name: existing(gcp.ID(p.env, fn.Id)), Region: existing(srv.Region)}
+	sql := &gcp.CloudSQL{Name: existing("test-db")}
+
+	mustRegister(reg.RegisterPluginResource(deploy.LoadBalancerName, "load_balancer-https-0", urlMap))
+	mustRegister(reg.RegisterAppResource(api, "cloud_scheduler_job_1", job))
+	mustRegister(reg.RegisterAppResource(fn, "cloud_function_v2", function))
+	mustRegister(reg.RegisterDependencyResource(db, "cloud_sql", sql))
+
+	for _, r := range []registry.Resource{urlMap, job, function, sql} {
+		r.MarkAsExisting()
+	}
+
+	state, err := reg.Dump()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Build ID of function is read from API.
+	cli, err := p.PluginContext().GCPCloudFunctionsV2Client(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = cli.Projects.Locations.Functions.Create(fmt.Sprintf("projects/%s/locations/%s", srv.ProjectID, srv.Region), &cloudfunctionsv2.Function{
+		BuildConfig: &cloudfunctionsv2.BuildConfig{Build: "projects/123/locations/" + srv.Region + "/builds/build-1"},
+	}).FunctionId(gcp.ID(p.env, fn.Id)).Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filter := func(contains ...string) (string, map[string]string, error) {
+		return p.createLogFilter(ctx, &apiv1.LogsRequest{
+			Apps:         []*apiv1.App{api, worker, fn},
+			Dependencies: []*apiv1.Dependency{db},
+			State:        &apiv1.PluginState{Registry: state},
+			Contains:     contains,
+		})
+	}
+
+	apiID, fnID := gcp.ID(p.env, api.Id), gcp.ID(p.env, fn.Id)
+
+	for _, tc := range []struct {
+		name     string
+		contains []string
+		want     []string
+		notWant  []string
+		ids      map[string]string
+	}{
+		{
+			name: "default sources",
+			want: []string{
+				`(resource.type = "cloud_run_revision" resource.labels.service_name = ("` + apiID + `" OR "` + fnID + `"))`,
+				`(resource.type = "cloud_run_job" resource.labels.job_name = ("` + gcp.ID(p.env, worker.Id) + `"))`,
+				`(resource.type = "cloudsql_database" resource.labels.database_id = ("` + srv.ProjectID + `:test-db"))`,
+			},
+			notWant: []string{"http_load_balancer", "cloud_scheduler_job", `"build"`},
+			ids:     map[string]string{apiID: api.Id, srv.ProjectID + ":test-db": db.Id},
+		},
+		{
+			name:     "load balancer",
+			contains: []string{"source=load_balancer"},
+			want: []string{
+				`(resource.type = "http_load_balancer" (resource.labels.backend_service_name = ("` + apiID + `" OR "` + fnID +
+					`") OR (resource.labels.url_map_name = ("test-lb-https") resource.labels.backend_service_name = "")))`,
+			},
+			notWant: []string{"cloud_run_revision", "cloudsql_database", "cloud_scheduler_job", `"build"`},
+			ids:     map[string]string{"test-lb-https": deploy.LoadBalancerName},
+		},
+		{
+			name:     "scheduler",
+			contains: []string{"source=scheduler"},
+			want:     []string{`(resource.type = "cloud_scheduler_job" resource.labels.job_id = ("test-api-nightly"))`},
+			notWant:  []string{"cloud_run_revision", "http_load_balancer", `"build"`},
+			ids:      map[string]string{"test-api-nightly": api.Id},
+		},
+		{
+			name:     "build",
+			contains: []string{"source=build", "timeout"},
+			want:     []string{`(resource.type = "build" resource.labels.build_id = ("build-1"))`, `"timeout"`},
+			notWant:  []string{"cloud_run_revision", "http_load_balancer", "cloud_scheduler_job", "source="},
+			ids:      map[string]string{"build-1": fn.Id},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ids, err := filter(tc.contains...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, w := range tc.want {
+				if !strings.Contains(got, w) {
+					t.Fatalf("expected filter to contain %s, got: %s", w, got)
+				}
+			}
+
+			for _, w := range tc.notWant {
+				if strings.Contains(got, w) {
+					t.Fatalf("expected filter not to contain %s, got: %s", w, got)
+				}
+			}
+
+			for k, v := range tc.ids {
+				if ids[k] != v {
+					t.Fatalf("expected %s to be mapped to %s, got: %v", k, v, ids)
+				}
+			}
+		})
+	}
+
+	_, _, err = filter("source=cdn")
+	if err == nil || !strings.Contains(err.Error(), "unknown log source 'cdn'") {
+		t.Fatalf("expected unknown log source to be refused, got: %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package plugin
+
+import (
+	"testing"
+
+	"cloud.google.com/go/logging/apiv2/loggingpb"
+	"google.golang.org/genproto/googleapis/api/monitoredres"
+	loggingtype "google.golang.org/genproto/googleapis/logging/type"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestLogEntryToProtoTrace(t *testing.T) {
+	const trace = "projects/test/traces/06796866738c859f2f19b7cfb3214824"
+
+	resource := &monitoredres.MonitoredResource{
+		Type:   "cloud_run_revision",
+		Labels: map[string]string{"service_name": "test-api"},
+	}
+
+	jsonPayload, err := structpb.NewStruct(map[string]any{"message": "hello", "trace": "app-trace"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		entry *loggingpb.LogEntry
+		want  map[string]any
+		text  string
+	}{
+		{
+			name:  "json payload keeps its fields",
+			entry: &loggingpb.LogEntry{Resource: resource, Trace: trace, SpanId: "000000000000004a", Payload: &loggingpb.LogEntry_JsonPayload{JsonPayload: jsonPayload}},
+			want: map[string]any{
+				"message":            "hello",
+				"trace":              "app-trace",
+				logTracePayloadField: "06796866738c859f2f19b7cfb3214824",
+				logSpanPayloadField:  "000000000000004a",
+			},
+		},
+		{
+			name:  "text payload becomes message",
+			entry: &loggingpb.LogEntry{Resource: resource, Trace: trace, Payload: &loggingpb.LogEntry_TextPayload{TextPayload: "hello"}},
+			want: map[string]any{
+				"message":            "hello",
+				logTracePayloadField: "06796866738c859f2f19b7cfb3214824",
+			},
+		},
+		{
+			name:  "request log without payload",
+			entry: &loggingpb.LogEntry{Resource: resource, Trace: trace, SpanId: "000000000000004a", HttpRequest: &loggingtype.HttpRequest{Status: 200}},
+			want: map[string]any{
+				logTracePayloadField: "06796866738c859f2f19b7cfb3214824",
+				logSpanPayloadField:  "000000000000004a",
+			},
+		},
+		{
+			name:  "entry without trace is unchanged",
+			entry: &loggingpb.LogEntry{Resource: resource, Payload: &loggingpb.LogEntry_TextPayload{TextPayload: "hello"}},
+			text:  "hello",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ret := logEntryToProto(tc.entry, map[string]string{"test-api": "app_api"})
+
+			if ret.Source != "app_api" {
+				t.Fatalf("expected source to be mapped, got: %s", ret.Source)
+			}
+
+			if tc.want == nil {
+				if ret.GetText() != tc.text {
+					t.Fatalf("expected text payload %q, got: %v", tc.text, ret.Payload)
+				}
+
+				return
+			}
+
+			got := ret.GetJson().AsMap()
+
+			if len(got) != len(tc.want) {
+				t.Fatalf("expected payload %v, got: %v", tc.want, got)
+			}
+
+			for k, v := range tc.want {
+				if got[k] != v {
+					t.Fatalf("expected payload %v, got: %v", tc.want, got)
+				}
+			}
+		})
+	}
+}
@@ -23,17 +23,20 @@ type Plugin struct {
 	settings      config.Settings
 	apisEnabled   map[string]struct{}
 	pluginContext *config.PluginContext
+	contextOpts   []config.PluginContextOption
+	lockLeases    lockLeases
 }
 
-func NewPlugin() *Plugin {
+func NewPlugin(opts ...config.PluginContextOption) *Plugin {
 	return &Plugin{
 		apisEnabled: make(map[string]struct{}),
+		contextOpts: opts,
 	}
 }
 
 func (p *Plugin) PluginContext() *config.PluginContext {
 	if p.pluginContext == nil {
-		p.pluginContext = config.NewPluginContext(p.env, p.gcred, &p.settings)
+		p.pluginContext = config.NewPluginContext(p.env, p.gcred, &p.settings, p.contextOpts...)
 	}
 
 	return p.pluginContext
@@ -0,0 +1,22 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/outblocks/cli-plugin-gcp/internal/fakegcp"
+)
+
+func newTestPlugin(t *testing.T) (*Plugin, *fakegcp.Server) {
+	t.Helper()
+
+	srv := fakegcp.New()
+	t.Cleanup(srv.Close)
+
+	p := NewPlugin(srv.Options()...)
+	p.env = fakegcp.NewEnv(t.TempDir())
+	p.log = &fakegcp.Logger{}
+	p.gcred = fakegcp.Credentials()
+	p.settings = *srv.Settings()
+
+	return p, srv
+}
@@ -4,12 +4,12 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
-	"time"
 
 	"github.com/outblocks/cli-plugin-gcp/gcp"
-	"github.com/outblocks/cli-plugin-gcp/internal/config"
 	"github.com/outblocks/outblocks-plugin-go/env"
 	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
 	"github.com/outblocks/outblocks-plugin-go/util"
@@ -18,38 +18,34 @@ import (
 )
 
 func (p *Plugin) initSecrets(ctx context.Context) (*secretmanager.Service, error) {
-	cli, err := config.NewGCPSecretManagerClient(ctx, p.gcred)
-	if err != nil {
-		return nil, fmt.Errorf("error creating gcp secret manager client: %w", err)
-	}
-
-	return cli, nil
+	return p.PluginContext().GCPSecretManagerClient(ctx)
 }
 
-func getSecret(cli *secretmanager.Service, project, name string) (ok bool, err error) {
-	_, err = cli.Projects.Secrets.Get(fmt.Sprintf("projects/%s/secrets/%s", project, name)).Do()
+func getSecret(cli *secretmanager.Service, project, name string) (*secretmanager.Secret, error) {
+	s, err := cli.Projects.Secrets.Get(fmt.Sprintf("projects/%s/secrets/%s", project, name)).Do()
 	if gcp.ErrIs404(err) {
-		return false, nil
+		return nil, nil
 	}
 
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
-	return true, nil
+	return s, nil
 }
 
-func createSecret(cli *secretmanager.Service, e env.Enver, project, key string) error {
-	_, err := cli.Projects.Secrets.Create(fmt.Sprintf("projects/%s", project), &secretmanager.Secret{
+func createSecret(cli *secretmanager.Service, e env.Enver, opts *secretsOptions, project, key string) error {
+	s := &secretmanager.Secret{
 		Labels: map[string]string{
 			"creator": "outblocks",
 			"env":     e.Env(),
 			"project": e.ProjectName(),
 		},
-		Replication: &secretmanager.Replication{
-			Automatic: &secretmanager.Automatic{},
-		},
-	}).SecretId(key).Do()
+	}
+
+	opts.apply(s)
+
+	_, err := cli.Projects.Secrets.Create(fmt.Sprintf("projects/%s", project), s).SecretId(key).Do()
 	if err != nil {
 		return fmt.Errorf("error creating secret '%s': %w", key, err)
 	}
@@ -76,7 +72,8 @@ func accessSecretValue(cli *secretmanager.Service, project, name string) (val st
 	return string(data), true, nil
 }
 
-func listSecrets(cli *secretmanager.Service, project, prefix string) ([]string, error) {
+// listSecrets returns secrets with given prefix keyed by their name.
+func listSecrets(cli *secretmanager.Service, project, prefix string) (map[string]*secretmanager.Secret, error) {
 	ret, err := cli.Projects.Secrets.List(fmt.Sprintf("projects/%s", project)).
 		PageSize(25000).
 		Filter(prefix).
@@ -85,7 +82,7 @@ func listSecrets(cli *secretmanager.Service, project, prefix string) ([]string,
 		return nil, fmt.Errorf("error listing secrets: %w", err)
 	}
 
-	var secrets []string
+	secrets := make(map[string]*secretmanager.Secret)
 
 	for _, s := range ret.Secrets {
 		parts := strings.SplitN(s.Name, "/", 4)
@@ -95,16 +92,57 @@ func listSecrets(cli *secretmanager.Service, project, prefix string) ([]string,
 			continue
 		}
 
-		secrets = append(secrets, name)
+		secrets[name] = s
 	}
 
 	return secrets, nil
 }
 
-func setSecretValue(cli *secretmanager.Service, project, name, value string, cleanup bool) error {
+// secretVersionsKept is number of most recent secret versions kept on update so that they can be rolled back to.
+const secretVersionsKept = 10
+
+func secretVersionAnnotation(num int) string {
+	return fmt.Sprintf("version-%d-creator", num)
+}
+
+func secretVersionNumber(name string) int {
+	num, _ := strconv.Atoi(name[strings.LastIndex(name, "/")+1:])
+
+	return num
+}
+
+// listSecretVersions returns all versions of a secret, newest first.
+func listSecretVersions(cli *secretmanager.Service, project, name string) ([]*secretmanager.SecretVersion, error) {
+	ret, err := cli.Projects.Secrets.Versions.List(fmt.Sprintf("projects/%s/secrets/%s", project, name)).
+		PageSize(25000).
+		Do()
+	if err != nil {
+		return nil, fmt.Errorf("error listing secret '%s' versions: %w", name, err)
+	}
+
+	versions := ret.Versions
+
+	sort.Slice(versions, func(i, j int) bool {
+		return secretVersionNumber(versions[i].Name) > secretVersionNumber(versions[j].Name)
+	})
+
+	return versions, nil
+}
+
+// setSecretValue adds new version of a secret recording its creator in secret annotations.
+// With cleanup, versions older than last secretVersionsKept are destroyed.
+func setSecretValue(cli *secretmanager.Service, project, name, value, creator string, cleanup bool) error {
 	secretPath := fmt.Sprintf("projects/%s/secrets/%s", project, name)
 
-	now := time.Now()
+	secret, err := cli.Projects.Secrets.Get(secretPath).Do()
+	if err != nil {
+		return fmt.Errorf("error fetching secret '%s': %w", name, err)
+	}
+
+	annotations := secret.Annotations
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
 
 	if value != "" {
 		newVer, err := cli.Projects.Secrets.AddVersion(secretPath, &secretmanager.AddSecretVersionRequest{
@@ -116,31 +154,40 @@ func setSecretValue(cli *secretmanager.Service, project, name, value string, cle
 			return fmt.Errorf("error setting secret '%s' value: %w", name, err)
 		}
 
-		now, _ = time.Parse(time.RFC3339, newVer.CreateTime)
+		annotations[secretVersionAnnotation(secretVersionNumber(newVer.Name))] = creator
 	}
 
-	if !cleanup {
-		return nil
-	}
+	if cleanup {
+		versions, err := listSecretVersions(cli, project, name)
+		if err != nil {
+			return err
+		}
 
-	versions, err := cli.Projects.Secrets.Versions.List(secretPath).Do()
-	if err != nil {
-		return fmt.Errorf("error listing secret '%s' versions: %w", name, err)
-	}
+		for i, v := range versions {
+			if i < secretVersionsKept {
+				continue
+			}
 
-	for _, v := range versions.Versions {
-		verTime, _ := time.Parse(time.RFC3339, v.CreateTime)
+			delete(annotations, secretVersionAnnotation(secretVersionNumber(v.Name)))
 
-		if !verTime.Before(now) || v.State != "ENABLED" {
-			continue
-		}
+			if v.State == "DESTROYED" {
+				continue
+			}
 
-		_, err = cli.Projects.Secrets.Versions.Destroy(v.Name, &secretmanager.DestroySecretVersionRequest{}).Do()
-		if err != nil {
-			return fmt.Errorf("error deleting old secret '%s' version: %w", name, err)
+			_, err = cli.Projects.Secrets.Versions.Destroy(v.Name, &secretmanager.DestroySecretVersionRequest{}).Do()
+			if err != nil {
+				return fmt.Errorf("error deleting old secret '%s' version: %w", name, err)
+			}
 		}
 	}
 
+	_, err = cli.Projects.Secrets.Patch(secretPath, &secretmanager.Secret{
+		Annotations: annotations,
+	}).UpdateMask("annotations").Do()
+	if err != nil {
+		return fmt.Errorf("error updating secret '%s' annotations: %w", name, err)
+	}
+
 	return nil
 }
 
@@ -161,7 +208,16 @@ func deleteSecret(cli *secretmanager.Service, project, name string) (ok bool, er
 }
 
 func (p *Plugin) secretKeyPrefix() string {
-	return fmt.Sprintf("OUTBLOCKS_%s_", util.SanitizeName(p.env.ProjectID(), false, false))
+	return gcp.SecretNamePrefix(p.env)
+}
+
+// warnSecretMismatch warns about existing secret not matching secrets configuration, e.g. created before it was changed.
+func (p *Plugin) warnSecretMismatch(opts *secretsOptions, s *secretmanager.Secret) {
+	name := s.Name[strings.LastIndex(s.Name, "/")+1:]
+
+	for _, m := range opts.mismatches(s) {
+		p.log.Warnf("Secret '%s' doesn't match secrets configuration: %s.\n", strings.TrimPrefix(name, p.secretKeyPrefix()), m)
+	}
 }
 
 func (p *Plugin) secretKey(key string) (string, error) {
@@ -170,7 +226,7 @@ func (p *Plugin) secretKey(key string) (string, error) {
 		return "", fmt.Errorf("secret names can only contain English letters (A-Z), numbers (0-9), dashes (-), and underscores (_)")
 	}
 
-	return fmt.Sprintf("%s%s", p.secretKeyPrefix(), sanitized), nil
+	return gcp.SecretName(p.env, sanitized), nil
 }
 
 func (p *Plugin) GetSecret(ctx context.Context, req *apiv1.GetSecretRequest) (*apiv1.GetSecretResponse, error) {
@@ -214,10 +270,15 @@ func (p *Plugin) SetSecret(ctx context.Context, req *apiv1.SetSecretRequest) (*a
 		return nil, err
 	}
 
-	var ok bool
+	opts, err := newSecretsOptions(req.Properties.AsMap())
+	if err != nil {
+		return nil, err
+	}
+
+	var secret *secretmanager.Secret
 
 	err = p.runAndEnsureAPI(ctx, func() error {
-		ok, err = getSecret(cli, p.settings.ProjectID, key)
+		secret, err = getSecret(cli, p.settings.ProjectID, key)
 		return err
 	})
 	if err != nil {
@@ -226,14 +287,16 @@ func (p *Plugin) SetSecret(ctx context.Context, req *apiv1.SetSecretRequest) (*a
 
 	cleanup := true
 
-	if !ok {
-		err = createSecret(cli, p.env, p.settings.ProjectID, key)
+	if secret == nil {
+		err = createSecret(cli, p.env, opts, p.settings.ProjectID, key)
 		if err != nil {
 			return nil, err
 		}
 
 		cleanup = false
 	} else {
+		p.warnSecretMismatch(opts, secret)
+
 		existingVal, _, err := accessSecretValue(cli, p.settings.ProjectID, key)
 		if err != nil {
 			return nil, err
@@ -246,7 +309,7 @@ func (p *Plugin) SetSecret(ctx context.Context, req *apiv1.SetSecretRequest) (*a
 		}
 	}
 
-	err = setSecretValue(cli, p.settings.ProjectID, key, req.Value, cleanup)
+	err = setSecretValue(cli, p.settings.ProjectID, key, req.Value, lockdata(), cleanup)
 	if err != nil {
 		return nil, err
 	}
@@ -282,15 +345,20 @@ func (p *Plugin) DeleteSecret(ctx context.Context, req *apiv1.DeleteSecretReques
 	}, nil
 }
 
-func (p *Plugin) GetSecrets(ctx context.Context, _ *apiv1.GetSecretsRequest) (*apiv1.GetSecretsResponse, error) {
+func (p *Plugin) GetSecrets(ctx context.Context, req *apiv1.GetSecretsRequest) (*apiv1.GetSecretsResponse, error) {
 	cli, err := p.initSecrets(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	opts, err := newSecretsOptions(req.Properties.AsMap())
+	if err != nil {
+		return nil, err
+	}
+
 	prefix := p.secretKeyPrefix()
 
-	var secrets []string
+	var secrets map[string]*secretmanager.Secret
 
 	err = p.runAndEnsureAPI(ctx, func() error {
 		secrets, err = listSecrets(cli, p.settings.ProjectID, prefix)
@@ -300,12 +368,29 @@ func (p *Plugin) GetSecrets(ctx context.Context, _ *apiv1.GetSecretsRequest) (*a
 		return nil, err
 	}
 
+	for _, secret := range secrets {
+		p.warnSecretMismatch(opts, secret)
+	}
+
+	values, err := p.secretValues(ctx, cli, secrets)
+	if err != nil {
+		return nil, err
+	}
+
+	return &apiv1.GetSecretsResponse{
+		Values: values,
+	}, nil
+}
+
+// secretValues accesses latest values of secrets, keyed by secret key without prefix.
+func (p *Plugin) secretValues(ctx context.Context, cli *secretmanager.Service, secrets map[string]*secretmanager.Secret) (map[string]string, error) {
 	var mu sync.Mutex
 
+	prefix := p.secretKeyPrefix()
 	values := make(map[string]string)
 	g, _ := errgroup.WithConcurrency(ctx, gcp.DefaultConcurrency)
 
-	for _, v := range secrets {
+	for v := range secrets {
 		g.Go(func() error {
 			val, _, err := accessSecretValue(cli, p.settings.ProjectID, v)
 			if err != nil {
@@ -322,14 +407,12 @@ func (p *Plugin) GetSecrets(ctx context.Context, _ *apiv1.GetSecretsRequest) (*a
 		})
 	}
 
-	err = g.Wait()
+	err := g.Wait()
 	if err != nil {
 		return nil, err
 	}
 
-	return &apiv1.GetSecretsResponse{
-		Values: values,
-	}, nil
+	return values, nil
 }
 
 func (p *Plugin) ReplaceSecrets(ctx context.Context, req *apiv1.ReplaceSecretsRequest) (*apiv1.ReplaceSecretsResponse, error) {
@@ -338,6 +421,11 @@ func (p *Plugin) ReplaceSecrets(ctx context.Context, req *apiv1.ReplaceSecretsRe
 		return nil, err
 	}
 
+	opts, err := newSecretsOptions(req.Properties.AsMap())
+	if err != nil {
+		return nil, err
+	}
+
 	prefix := p.secretKeyPrefix()
 	values := make(map[string]string, len(req.Values))
 
@@ -350,7 +438,7 @@ func (p *Plugin) ReplaceSecrets(ctx context.Context, req *apiv1.ReplaceSecretsRe
 		values[key] = v
 	}
 
-	var secrets []string
+	var secrets map[string]*secretmanager.Secret
 
 	err = p.runAndEnsureAPI(ctx, func() error {
 		secrets, err = listSecrets(cli, p.settings.ProjectID, prefix)
@@ -362,7 +450,7 @@ func (p *Plugin) ReplaceSecrets(ctx context.Context, req *apiv1.ReplaceSecretsRe
 
 	g, _ := errgroup.WithConcurrency(ctx, gcp.DefaultConcurrency)
 
-	for _, cur := range secrets {
+	for cur, secret := range secrets {
 		val, ok := values[cur]
 		if !ok {
 			g.Go(func() error {
@@ -374,6 +462,8 @@ func (p *Plugin) ReplaceSecrets(ctx context.Context, req *apiv1.ReplaceSecretsRe
 			continue
 		}
 
+		p.warnSecretMismatch(opts, secret)
+
 		g.Go(func() error {
 			existingVal, existingSet, err := accessSecretValue(cli, p.settings.ProjectID, cur)
 			if err != nil {
@@ -381,7 +471,7 @@ func (p *Plugin) ReplaceSecrets(ctx context.Context, req *apiv1.ReplaceSecretsRe
 			}
 
 			if !existingSet || existingVal != val {
-				err = setSecretValue(cli, p.settings.ProjectID, cur, val, existingSet)
+				err = setSecretValue(cli, p.settings.ProjectID, cur, val, lockdata(), existingSet)
 				if err != nil {
 					return err
 				}
@@ -395,12 +485,12 @@ func (p *Plugin) ReplaceSecrets(ctx context.Context, req *apiv1.ReplaceSecretsRe
 
 	for k, v := range values {
 		g.Go(func() error {
-			err := createSecret(cli, p.env, p.settings.ProjectID, k)
+			err := createSecret(cli, p.env, opts, p.settings.ProjectID, k)
 			if err != nil {
 				return err
 			}
 
-			err = setSecretValue(cli, p.settings.ProjectID, k, v, false)
+			err = setSecretValue(cli, p.settings.ProjectID, k, v, lockdata(), false)
 
 			return err
 		})
@@ -422,7 +512,7 @@ func (p *Plugin) DeleteSecrets(ctx context.Context, _ *apiv1.DeleteSecretsReques
 
 	prefix := p.secretKeyPrefix()
 
-	var secrets []string
+	var secrets map[string]*secretmanager.Secret
 
 	err = p.runAndEnsureAPI(ctx, func() error {
 		secrets, err = listSecrets(cli, p.settings.ProjectID, prefix)
@@ -434,7 +524,7 @@ func (p *Plugin) DeleteSecrets(ctx context.Context, _ *apiv1.DeleteSecretsReques
 
 	g, _ := errgroup.WithConcurrency(ctx, gcp.DefaultConcurrency)
 
-	for _, cur := range secrets {
+	for cur := range secrets {
 		g.Go(func() error {
 			_, err = deleteSecret(cli, p.settings.ProjectID, cur)
 
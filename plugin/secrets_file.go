@@ -0,0 +1,354 @@
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	secretsFormatDotenv = "dotenv"
+	secretsFormatJSON   = "json"
+	secretsFormatYAML   = "yaml"
+)
+
+var (
+	sopsYAMLMetadataRegex   = regexp.MustCompile(`(?m)^sops:`)
+	sopsDotenvMetadataRegex = regexp.MustCompile(`(?m)^sops_version=`)
+)
+
+func secretsFileFormat(file, format string) (string, error) {
+	if format == "" {
+		base := filepath.Base(file)
+
+		switch {
+		case base == ".env" || strings.HasPrefix(base, ".env.") || filepath.Ext(base) == ".env":
+			format = secretsFormatDotenv
+		case filepath.Ext(base) == ".json":
+			format = secretsFormatJSON
+		case filepath.Ext(base) == ".yaml" || filepath.Ext(base) == ".yml":
+			format = secretsFormatYAML
+		default:
+			return "", fmt.Errorf("cannot detect format of '%s', specify it with --format", file)
+		}
+	}
+
+	switch format {
+	case secretsFormatDotenv, secretsFormatJSON, secretsFormatYAML:
+		return format, nil
+	}
+
+	return "", fmt.Errorf("unsupported secrets file format '%s', supported formats: dotenv, json, yaml", format)
+}
+
+func isSOPSEncrypted(data []byte, format string) bool {
+	switch format {
+	case secretsFormatJSON:
+		var m map[string]json.RawMessage
+
+		if json.Unmarshal(data, &m) != nil {
+			return false
+		}
+
+		_, ok := m["sops"]
+
+		return ok
+	case secretsFormatYAML:
+		return sopsYAMLMetadataRegex.Match(data)
+	default:
+		return sopsDotenvMetadataRegex.Match(data)
+	}
+}
+
+// runSOPS runs sops binary, age key file is passed through environment while PGP keys are taken from local gpg keyring.
+func runSOPS(ctx context.Context, ageKeyFile string, args ...string) ([]byte, error) {
+	bin, err := exec.LookPath("sops")
+	if err != nil {
+		return nil, fmt.Errorf("sops binary not found in PATH, install it from https://github.com/getsops/sops")
+	}
+
+	cmd := exec.CommandContext(ctx, bin, args...) //nolint:gosec
+	cmd.Env = os.Environ()
+
+	if ageKeyFile != "" {
+		cmd.Env = append(cmd.Env, "SOPS_AGE_KEY_FILE="+ageKeyFile)
+	}
+
+	var stderr bytes.Buffer
+
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("sops failed: %w\n%s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return out, nil
+}
+
+// readSecretsFile reads flat map of secrets from dotenv, json or yaml file, decrypting it with sops if it is encrypted.
+func readSecretsFile(ctx context.Context, file, format, ageKeyFile string) (map[string]string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading secrets file: %w", err)
+	}
+
+	if isSOPSEncrypted(data, format) {
+		data, err = runSOPS(ctx, ageKeyFile, "--decrypt", "--input-type", format, "--output-type", secretsFormatJSON, file)
+		if err != nil {
+			return nil, err
+		}
+
+		format = secretsFormatJSON
+	}
+
+	switch format {
+	case secretsFormatDotenv:
+		return parseDotenv(data)
+	case secretsFormatJSON:
+		return parseSecretsJSON(data)
+	default:
+		return parseFlatYAML(data)
+	}
+}
+
+func parseSecretsJSON(data []byte) (map[string]string, error) {
+	var m map[string]any
+
+	err := json.Unmarshal(data, &m)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding secrets file: %w", err)
+	}
+
+	ret := make(map[string]string, len(m))
+
+	for k, v := range m {
+		switch val := v.(type) {
+		case string:
+			ret[k] = val
+		case float64, bool:
+			ret[k] = fmt.Sprint(val)
+		default:
+			return nil, fmt.Errorf("secret '%s' must be a string, nested values are not supported", k)
+		}
+	}
+
+	return ret, nil
+}
+
+// splitQuoted returns value without surrounding quotes and trailing comment along with quote character used, if any.
+func splitQuoted(v string) (string, byte) {
+	if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') {
+		end := strings.LastIndexByte(v, v[0])
+		rest := strings.TrimSpace(v[end+1:])
+
+		if end > 0 && (rest == "" || strings.HasPrefix(rest, "#")) {
+			return v[1:end], v[0]
+		}
+	}
+
+	if i := strings.Index(v, " #"); i != -1 {
+		v = strings.TrimSpace(v[:i])
+	}
+
+	return v, 0
+}
+
+var dotenvUnescaper = strings.NewReplacer(`\n`, "\n", `\r`, "\r", `\"`, `"`, `\\`, `\`)
+
+func parseDotenv(data []byte) (map[string]string, error) {
+	ret := make(map[string]string)
+	s := bufio.NewScanner(bytes.NewReader(data))
+	line := 0
+
+	for s.Scan() {
+		line++
+
+		l := strings.TrimSpace(s.Text())
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+
+		k, v, ok := strings.Cut(strings.TrimPrefix(l, "export "), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid dotenv line %d: expected KEY=VALUE", line)
+		}
+
+		k = strings.TrimSpace(k)
+		v = strings.TrimSpace(v)
+
+		v, quote := splitQuoted(v)
+		if quote == '"' {
+			v = dotenvUnescaper.Replace(v)
+		}
+
+		ret[k] = v
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// parseFlatYAML parses plaintext yaml mapping of keys to scalar values, nested and multiline values are not supported.
+func parseFlatYAML(data []byte) (map[string]string, error) {
+	ret := make(map[string]string)
+	s := bufio.NewScanner(bytes.NewReader(data))
+	line := 0
+
+	for s.Scan() {
+		line++
+
+		raw := s.Text()
+		l := strings.TrimSpace(raw)
+
+		if l == "" || l == "---" || strings.HasPrefix(l, "#") {
+			continue
+		}
+
+		k, v, ok := strings.Cut(l, ":")
+		if !ok || raw[0] == ' ' || raw[0] == '\t' {
+			return nil, fmt.Errorf("invalid yaml line %d: only flat mapping of keys to values is supported", line)
+		}
+
+		v = strings.TrimSpace(v)
+
+		if strings.HasPrefix(v, "|") || strings.HasPrefix(v, ">") {
+			return nil, fmt.Errorf("invalid yaml line %d: multiline values are not supported, use quoted string with \\n", line)
+		}
+
+		inner, quote := splitQuoted(v)
+
+		switch quote {
+		case '"':
+			err := json.Unmarshal([]byte(`"`+inner+`"`), &v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid yaml line %d: %w", line, err)
+			}
+		case '\'':
+			v = strings.ReplaceAll(inner, "''", "'")
+		default:
+			v = inner
+		}
+
+		ret[strings.TrimSpace(k)] = v
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+var dotenvEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+
+// encodeSecrets encodes secrets sorted by key, values are always quoted.
+func encodeSecrets(values map[string]string, format string) ([]byte, error) {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+
+	switch format {
+	case secretsFormatJSON:
+		data, err := json.MarshalIndent(values, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(data)
+		buf.WriteByte('\n')
+	case secretsFormatYAML:
+		// JSON strings are valid double quoted YAML scalars.
+		for _, k := range keys {
+			v, err := json.Marshal(values[k])
+			if err != nil {
+				return nil, err
+			}
+
+			fmt.Fprintf(&buf, "%s: %s\n", k, v)
+		}
+	case secretsFormatDotenv:
+		for _, k := range keys {
+			fmt.Fprintf(&buf, "%s=\"%s\"\n", k, dotenvEscaper.Replace(values[k]))
+		}
+	default:
+		return nil, errors.New("unsupported secrets file format")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeSecretsFile writes secrets to file, encrypting it with sops for given age or pgp recipients.
+func writeSecretsFile(ctx context.Context, file, format string, values map[string]string, age, pgp string) error {
+	var (
+		data []byte
+		err  error
+	)
+
+	if age == "" && pgp == "" {
+		data, err = encodeSecrets(values, format)
+	} else {
+		data, err = encryptSecrets(ctx, values, format, age, pgp)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(file, data, 0o600)
+	if err != nil {
+		return fmt.Errorf("error writing secrets file: %w", err)
+	}
+
+	return nil
+}
+
+func encryptSecrets(ctx context.Context, values map[string]string, format, age, pgp string) ([]byte, error) {
+	plain, err := encodeSecrets(values, secretsFormatJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "outblocks-secrets")
+	if err != nil {
+		return nil, err
+	}
+
+	defer os.RemoveAll(dir)
+
+	tmp := filepath.Join(dir, "secrets.json")
+
+	err = os.WriteFile(tmp, plain, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"--encrypt", "--input-type", secretsFormatJSON, "--output-type", format}
+
+	if age != "" {
+		args = append(args, "--age", age)
+	}
+
+	if pgp != "" {
+		args = append(args, "--pgp", pgp)
+	}
+
+	return runSOPS(ctx, "", append(args, tmp)...)
+}
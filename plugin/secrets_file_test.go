@@ -0,0 +1,265 @@
+package plugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/outblocks/cli-plugin-gcp/gcp"
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
+)
+
+func TestParseDotenv(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		in   string
+		want map[string]string
+		err  string
+	}{
+		{
+			name: "plain values",
+			in:   "A=1\nB = two words \nC=\nURL=postgres://host/db?sslmode=disable\n",
+			want: map[string]string{"A": "1", "B": "two words", "C": "", "URL": "postgres://host/db?sslmode=disable"},
+		},
+		{
+			name: "export prefix",
+			in:   "export A=1\nexport B=\"2\"\n",
+			want: map[string]string{"A": "1", "B": "2"},
+		},
+		{
+			name: "comments",
+			in:   "# comment\n\n  # indented comment\nA=1 # trailing comment\nB=\"2\" # trailing comment\nC=a#b\n",
+			want: map[string]string{"A": "1", "B": "2", "C": "a#b"},
+		},
+		{
+			name: "double quotes unescape",
+			in:   `A="line1\nline2"` + "\n" + `B="say \"hi\""` + "\n" + `C="back\\slash"` + "\n" + `D=" # not a comment "`,
+			want: map[string]string{"A": "line1\nline2", "B": `say "hi"`, "C": `back\slash`, "D": " # not a comment "},
+		},
+		{
+			name: "single quotes are literal",
+			in:   `A='line1\nline2'` + "\n" + `B='say "hi"'`,
+			want: map[string]string{"A": `line1\nline2`, "B": `say "hi"`},
+		},
+		{
+			name: "unmatched quote is kept",
+			in:   `A="abc`,
+			want: map[string]string{"A": `"abc`},
+		},
+		{
+			name: "multiline value",
+			in:   "A=\"line1\nline2\"\n",
+			err:  "invalid dotenv line 2",
+		},
+		{
+			name: "missing separator",
+			in:   "A=1\nB\n",
+			err:  "invalid dotenv line 2",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseDotenv([]byte(tc.in))
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error containing %q, got: %v, %v", tc.err, got, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestParseFlatYAML(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		in   string
+		want map[string]string
+		err  string
+	}{
+		{
+			name: "plain values",
+			in:   "---\nA: 1\nB:   two words  \nC:\nURL: http://host:8080/path\n",
+			want: map[string]string{"A": "1", "B": "two words", "C": "", "URL": "http://host:8080/path"},
+		},
+		{
+			name: "comments",
+			in:   "# comment\n\nA: 1 # trailing comment\nB: '2' # trailing comment\nC: a#b\n",
+			want: map[string]string{"A": "1", "B": "2", "C": "a#b"},
+		},
+		{
+			name: "double quotes unescape",
+			in:   `A: "line1\nline2"` + "\n" + `B: "say \"hi\"\t!"` + "\n" + `C: "\u00e9"`,
+			want: map[string]string{"A": "line1\nline2", "B": "say \"hi\"\t!", "C": "é"},
+		},
+		{
+			name: "single quotes",
+			in:   `A: 'it''s'` + "\n" + `B: 'line1\nline2'`,
+			want: map[string]string{"A": "it's", "B": `line1\nline2`},
+		},
+		{
+			name: "invalid escape",
+			in:   `A: "\q"`,
+			err:  "invalid yaml line 1",
+		},
+		{
+			name: "nested mapping",
+			in:   "A:\n  B: 1\n",
+			err:  "invalid yaml line 2: only flat mapping",
+		},
+		{
+			name: "list",
+			in:   "A: 1\n- item\n",
+			err:  "invalid yaml line 2: only flat mapping",
+		},
+		{
+			name: "block scalar",
+			in:   "A: |\n  line1\n",
+			err:  "invalid yaml line 1: multiline values are not supported",
+		},
+		{
+			name: "folded scalar",
+			in:   "A: >-\n  line1\n",
+			err:  "invalid yaml line 1: multiline values are not supported",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseFlatYAML([]byte(tc.in))
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error containing %q, got: %v, %v", tc.err, got, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestEncodedSecretsParseBack(t *testing.T) {
+	values := map[string]string{
+		"PLAIN":     "value",
+		"MULTILINE": "line1\nline2\r\n",
+		"QUOTES":    `it's "quoted"`,
+		"COMMENT":   "a # b",
+		"BACKSLASH": `C:\path\n`,
+		"EMPTY":     "",
+	}
+
+	for format, parse := range map[string]func([]byte) (map[string]string, error){
+		secretsFormatDotenv: parseDotenv,
+		secretsFormatYAML:   parseFlatYAML,
+		secretsFormatJSON:   parseSecretsJSON,
+	} {
+		data, err := encodeSecrets(values, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := parse(data)
+		if err != nil {
+			t.Fatalf("%s: %s", format, err)
+		}
+
+		if !reflect.DeepEqual(got, values) {
+			t.Fatalf("%s: expected %q, got %q", format, values, got)
+		}
+	}
+}
+
+func TestSecretsImportCreatesNewSecrets(t *testing.T) {
+	ctx := context.Background()
+	p, srv := newTestPlugin(t)
+
+	file := filepath.Join(t.TempDir(), "secrets.env")
+
+	err := os.WriteFile(file, []byte("DB_PASSWORD=\"correct-horse\"\nAPI_KEY=abc\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	importSecrets := func(props string, prune bool) error {
+		return p.SecretsImport(ctx, &apiv1.CommandRequest{Args: &apiv1.CommandArgs{Flags: mustStruct(t, map[string]any{
+			"file":         file,
+			"format":       "",
+			"age-key-file": "",
+			"properties":   props,
+			"prune":        prune,
+			"dry-run":      false,
+			"yes":          true,
+		})}})
+	}
+
+	err = importSecrets("{invalid", false)
+	if err == nil || !strings.Contains(err.Error(), "JSON") {
+		t.Fatalf("expected invalid properties to be refused, got: %v", err)
+	}
+
+	err = importSecrets(`{"replication":{"locations":["europe-west1"]},"labels":{"team":"core"}}`, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cli, err := p.initSecrets(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, values, err := p.currentSecrets(ctx, cli)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(values, map[string]string{"DB_PASSWORD": "correct-horse", "API_KEY": "abc"}) {
+		t.Fatalf("expected secrets to be created, got: %v", values)
+	}
+
+	secret, err := getSecret(cli, srv.ProjectID, gcp.SecretName(p.env, "DB_PASSWORD"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if secret.Replication.UserManaged == nil || secret.Replication.UserManaged.Replicas[0].Location != "europe-west1" ||
+		secret.Labels["team"] != "core" || secret.Labels["creator"] != "outblocks" {
+		t.Fatalf("expected secret to be created with given properties, got: %+v %v", secret.Replication, secret.Labels)
+	}
+
+	// Changed and pruned secrets.
+	err = os.WriteFile(file, []byte("DB_PASSWORD=battery-staple\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = importSecrets("", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, values, err = p.currentSecrets(ctx, cli)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(values, map[string]string{"DB_PASSWORD": "battery-staple"}) {
+		t.Fatalf("expected secrets to be changed and pruned, got: %v", values)
+	}
+}
@@ -0,0 +1,246 @@
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	plugin_util "github.com/outblocks/outblocks-plugin-go/util"
+	"google.golang.org/api/secretmanager/v1"
+)
+
+const minSecretRotationPeriod = time.Hour
+
+var reservedSecretLabels = []string{"creator", "env", "project"}
+
+type secretsReplicationOptions struct {
+	Locations []string          `json:"locations"`
+	KMSKey    string            `json:"kms_key"`
+	KMSKeys   map[string]string `json:"kms_keys"`
+}
+
+type secretsRotationOptions struct {
+	Period string   `json:"period"`
+	Topics []string `json:"topics"`
+}
+
+// secretsOptions configures secrets created by secrets plugin.
+type secretsOptions struct {
+	Replication secretsReplicationOptions `json:"replication"`
+	Labels      map[string]string         `json:"labels"`
+	TTL         string                    `json:"ttl"`
+	Rotation    secretsRotationOptions    `json:"rotation"`
+
+	ttl            time.Duration
+	rotationPeriod time.Duration
+}
+
+func newSecretsOptions(in map[string]any) (*secretsOptions, error) {
+	o := &secretsOptions{}
+
+	err := plugin_util.MapstructureJSONDecode(in, o)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secrets properties: %w", err)
+	}
+
+	rep := &o.Replication
+
+	if len(rep.Locations) == 0 {
+		if len(rep.KMSKeys) != 0 {
+			return nil, fmt.Errorf("secrets replication kms_keys require replication locations, use kms_key with automatic replication")
+		}
+	} else {
+		if rep.KMSKey != "" {
+			return nil, fmt.Errorf("secrets replication kms_key can only be used with automatic replication, use kms_keys per location instead")
+		}
+
+		for loc := range rep.KMSKeys {
+			if !plugin_util.StringSliceContains(rep.Locations, loc) {
+				return nil, fmt.Errorf("secrets replication kms_keys has key for location '%s' that is not in replication locations", loc)
+			}
+		}
+
+		if len(rep.KMSKeys) != 0 && len(rep.KMSKeys) != len(rep.Locations) {
+			return nil, fmt.Errorf("secrets replication kms_keys must have a key for every replication location")
+		}
+	}
+
+	for _, l := range reservedSecretLabels {
+		if _, ok := o.Labels[l]; ok {
+			return nil, fmt.Errorf("secrets label '%s' is reserved", l)
+		}
+	}
+
+	if o.TTL != "" {
+		o.ttl, err = time.ParseDuration(o.TTL)
+		if err != nil || o.ttl <= 0 {
+			return nil, fmt.Errorf("secrets ttl must be a positive duration, e.g. '8760h'")
+		}
+	}
+
+	if o.Rotation.Period != "" {
+		o.rotationPeriod, err = time.ParseDuration(o.Rotation.Period)
+		if err != nil || o.rotationPeriod < minSecretRotationPeriod {
+			return nil, fmt.Errorf("secrets rotation period must be a duration of at least %s, e.g. '720h'", minSecretRotationPeriod)
+		}
+
+		if len(o.Rotation.Topics) == 0 {
+			return nil, fmt.Errorf("secrets rotation requires at least one pub/sub topic to notify")
+		}
+	}
+
+	return o, nil
+}
+
+func secretDuration(d time.Duration) string {
+	return fmt.Sprintf("%ds", int64(d.Seconds()))
+}
+
+func (o *secretsOptions) replication() *secretmanager.Replication {
+	rep := o.Replication
+
+	if len(rep.Locations) == 0 {
+		auto := &secretmanager.Automatic{}
+
+		if rep.KMSKey != "" {
+			auto.CustomerManagedEncryption = &secretmanager.CustomerManagedEncryption{KmsKeyName: rep.KMSKey}
+		}
+
+		return &secretmanager.Replication{Automatic: auto}
+	}
+
+	replicas := make([]*secretmanager.Replica, len(rep.Locations))
+
+	for i, loc := range rep.Locations {
+		replicas[i] = &secretmanager.Replica{Location: loc}
+
+		if key := rep.KMSKeys[loc]; key != "" {
+			replicas[i].CustomerManagedEncryption = &secretmanager.CustomerManagedEncryption{KmsKeyName: key}
+		}
+	}
+
+	return &secretmanager.Replication{
+		UserManaged: &secretmanager.UserManaged{Replicas: replicas},
+	}
+}
+
+// apply sets configured replication, labels, expiry and rotation on secret that is about to be created.
+func (o *secretsOptions) apply(s *secretmanager.Secret) {
+	s.Replication = o.replication()
+
+	for k, v := range o.Labels {
+		s.Labels[k] = v
+	}
+
+	if o.ttl != 0 {
+		s.Ttl = secretDuration(o.ttl)
+	}
+
+	if o.rotationPeriod != 0 {
+		s.Rotation = &secretmanager.Rotation{
+			RotationPeriod:   secretDuration(o.rotationPeriod),
+			NextRotationTime: time.Now().Add(o.rotationPeriod).UTC().Format(time.RFC3339),
+		}
+
+		for _, t := range o.Rotation.Topics {
+			s.Topics = append(s.Topics, &secretmanager.Topic{Name: t})
+		}
+	}
+}
+
+func kmsKeyName(cme *secretmanager.CustomerManagedEncryption) string {
+	if cme == nil {
+		return ""
+	}
+
+	return cme.KmsKeyName
+}
+
+func joinSorted(in []string) string {
+	s := append([]string(nil), in...)
+	sort.Strings(s)
+
+	return strings.Join(s, ", ")
+}
+
+// mismatches returns differences between existing secret and configuration.
+func (o *secretsOptions) mismatches(s *secretmanager.Secret) []string {
+	var ret []string
+
+	rep := o.Replication
+
+	switch {
+	case s.Replication == nil:
+	case len(rep.Locations) == 0 && s.Replication.Automatic == nil:
+		ret = append(ret, "replication is user managed instead of automatic")
+	case len(rep.Locations) == 0:
+		if cur := kmsKeyName(s.Replication.Automatic.CustomerManagedEncryption); cur != rep.KMSKey {
+			ret = append(ret, fmt.Sprintf("kms key is '%s' instead of '%s'", cur, rep.KMSKey))
+		}
+	case s.Replication.UserManaged == nil:
+		ret = append(ret, "replication is automatic instead of user managed")
+	default:
+		var locs []string
+
+		for _, r := range s.Replication.UserManaged.Replicas {
+			locs = append(locs, r.Location)
+
+			if cur := kmsKeyName(r.CustomerManagedEncryption); cur != rep.KMSKeys[r.Location] {
+				ret = append(ret, fmt.Sprintf("kms key in '%s' is '%s' instead of '%s'", r.Location, cur, rep.KMSKeys[r.Location]))
+			}
+		}
+
+		if cur, want := joinSorted(locs), joinSorted(rep.Locations); cur != want {
+			ret = append(ret, fmt.Sprintf("replication locations are [%s] instead of [%s]", cur, want))
+		}
+	}
+
+	labels := make([]string, 0, len(o.Labels))
+
+	for k := range o.Labels {
+		labels = append(labels, k)
+	}
+
+	sort.Strings(labels)
+
+	for _, k := range labels {
+		if cur := s.Labels[k]; cur != o.Labels[k] {
+			ret = append(ret, fmt.Sprintf("label '%s' is '%s' instead of '%s'", k, cur, o.Labels[k]))
+		}
+	}
+
+	if o.ttl != 0 && s.ExpireTime == "" {
+		ret = append(ret, "expiry is not set")
+	}
+
+	var curPeriod time.Duration
+
+	if s.Rotation != nil {
+		curPeriod, _ = time.ParseDuration(s.Rotation.RotationPeriod)
+	}
+
+	switch {
+	case curPeriod == o.rotationPeriod:
+	case curPeriod == 0:
+		ret = append(ret, "rotation is not set")
+	case o.rotationPeriod == 0:
+		ret = append(ret, fmt.Sprintf("rotation every %s is set but not configured", curPeriod))
+	default:
+		ret = append(ret, fmt.Sprintf("rotation period is %s instead of %s", curPeriod, o.rotationPeriod))
+	}
+
+	if o.rotationPeriod != 0 {
+		var topics []string
+
+		for _, t := range s.Topics {
+			topics = append(topics, t.Name)
+		}
+
+		if cur, want := joinSorted(topics), joinSorted(o.Rotation.Topics); cur != want {
+			ret = append(ret, fmt.Sprintf("rotation topics are [%s] instead of [%s]", cur, want))
+		}
+	}
+
+	return ret
+}
@@ -0,0 +1,273 @@
+package plugin
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/api/secretmanager/v1"
+)
+
+func TestNewSecretsOptions(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		in   map[string]any
+		// want is expected replication, ttl and rotation period if no error is expected
+		want     *secretmanager.Replication
+		ttl      time.Duration
+		rotation time.Duration
+		err      string
+	}{
+		{
+			name: "defaults",
+			in:   map[string]any{},
+			want: &secretmanager.Replication{Automatic: &secretmanager.Automatic{}},
+		},
+		{
+			name: "automatic with kms key",
+			in:   map[string]any{"replication": map[string]any{"kms_key": "key-global"}},
+			want: &secretmanager.Replication{Automatic: &secretmanager.Automatic{
+				CustomerManagedEncryption: &secretmanager.CustomerManagedEncryption{KmsKeyName: "key-global"},
+			}},
+		},
+		{
+			name: "user managed locations",
+			in:   map[string]any{"replication": map[string]any{"locations": []any{"europe-west1", "europe-west4"}}},
+			want: &secretmanager.Replication{UserManaged: &secretmanager.UserManaged{Replicas: []*secretmanager.Replica{
+				{Location: "europe-west1"},
+				{Location: "europe-west4"},
+			}}},
+		},
+		{
+			name: "user managed with kms keys",
+			in: map[string]any{"replication": map[string]any{
+				"locations": []any{"europe-west1", "europe-west4"},
+				"kms_keys":  map[string]any{"europe-west1": "key-ew1", "europe-west4": "key-ew4"},
+			}},
+			want: &secretmanager.Replication{UserManaged: &secretmanager.UserManaged{Replicas: []*secretmanager.Replica{
+				{Location: "europe-west1", CustomerManagedEncryption: &secretmanager.CustomerManagedEncryption{KmsKeyName: "key-ew1"}},
+				{Location: "europe-west4", CustomerManagedEncryption: &secretmanager.CustomerManagedEncryption{KmsKeyName: "key-ew4"}},
+			}}},
+		},
+		{
+			name:     "ttl and rotation",
+			in:       map[string]any{"ttl": "8760h", "rotation": map[string]any{"period": "720h", "topics": []any{"projects/test/topics/rotate"}}},
+			want:     &secretmanager.Replication{Automatic: &secretmanager.Automatic{}},
+			ttl:      8760 * time.Hour,
+			rotation: 720 * time.Hour,
+		},
+		{
+			name: "kms keys without locations",
+			in:   map[string]any{"replication": map[string]any{"kms_keys": map[string]any{"europe-west1": "key-ew1"}}},
+			err:  "kms_keys require replication locations",
+		},
+		{
+			name: "kms key with locations",
+			in:   map[string]any{"replication": map[string]any{"locations": []any{"europe-west1"}, "kms_key": "key-global"}},
+			err:  "kms_key can only be used with automatic replication",
+		},
+		{
+			name: "kms key for unknown location",
+			in: map[string]any{"replication": map[string]any{
+				"locations": []any{"europe-west1"},
+				"kms_keys":  map[string]any{"us-east1": "key-ue1"},
+			}},
+			err: "key for location 'us-east1' that is not in replication locations",
+		},
+		{
+			name: "kms key missing for location",
+			in: map[string]any{"replication": map[string]any{
+				"locations": []any{"europe-west1", "europe-west4"},
+				"kms_keys":  map[string]any{"europe-west1": "key-ew1"},
+			}},
+			err: "must have a key for every replication location",
+		},
+		{
+			name: "reserved label",
+			in:   map[string]any{"labels": map[string]any{"env": "prod"}},
+			err:  "secrets label 'env' is reserved",
+		},
+		{
+			name: "invalid ttl",
+			in:   map[string]any{"ttl": "1y"},
+			err:  "secrets ttl must be a positive duration",
+		},
+		{
+			name: "negative ttl",
+			in:   map[string]any{"ttl": "-1h"},
+			err:  "secrets ttl must be a positive duration",
+		},
+		{
+			name: "too short rotation period",
+			in:   map[string]any{"rotation": map[string]any{"period": "30m", "topics": []any{"projects/test/topics/rotate"}}},
+			err:  "rotation period must be a duration of at least 1h0m0s",
+		},
+		{
+			name: "rotation without topics",
+			in:   map[string]any{"rotation": map[string]any{"period": "720h"}},
+			err:  "requires at least one pub/sub topic",
+		},
+		{
+			name: "invalid type",
+			in:   map[string]any{"labels": []any{"a"}},
+			err:  "invalid secrets properties",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			o, err := newSecretsOptions(tc.in)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error containing %q, got: %v", tc.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := o.replication(); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("expected replication %+v, got %+v", tc.want, got)
+			}
+
+			if o.ttl != tc.ttl || o.rotationPeriod != tc.rotation {
+				t.Fatalf("expected ttl %s and rotation %s, got %s and %s", tc.ttl, tc.rotation, o.ttl, o.rotationPeriod)
+			}
+		})
+	}
+}
+
+func TestSecretsOptionsMismatches(t *testing.T) {
+	mustOptions := func(in map[string]any) *secretsOptions {
+		t.Helper()
+
+		o, err := newSecretsOptions(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return o
+	}
+
+	userManaged := mustOptions(map[string]any{
+		"replication": map[string]any{
+			"locations": []any{"europe-west1", "europe-west4"},
+			"kms_keys":  map[string]any{"europe-west1": "key-ew1", "europe-west4": "key-ew4"},
+		},
+		"labels":   map[string]any{"team": "core"},
+		"ttl":      "8760h",
+		"rotation": map[string]any{"period": "720h", "topics": []any{"projects/test/topics/rotate"}},
+	})
+
+	automatic := mustOptions(map[string]any{"replication": map[string]any{"kms_key": "key-global"}})
+
+	// created returns secret as it would be created with given options.
+	created := func(o *secretsOptions) *secretmanager.Secret {
+		s := &secretmanager.Secret{Labels: map[string]string{"creator": "outblocks"}}
+		o.apply(s)
+
+		if s.Ttl != "" {
+			s.ExpireTime = time.Now().Add(o.ttl).UTC().Format(time.RFC3339)
+		}
+
+		return s
+	}
+
+	for _, tc := range []struct {
+		name   string
+		opts   *secretsOptions
+		modify func(s *secretmanager.Secret)
+		want   []string
+	}{
+		{
+			name:   "user managed as created",
+			opts:   userManaged,
+			modify: func(*secretmanager.Secret) {},
+		},
+		{
+			name:   "automatic as created",
+			opts:   automatic,
+			modify: func(*secretmanager.Secret) {},
+		},
+		{
+			name: "replication type changed",
+			opts: automatic,
+			modify: func(s *secretmanager.Secret) {
+				s.Replication = &secretmanager.Replication{UserManaged: &secretmanager.UserManaged{}}
+			},
+			want: []string{"replication is user managed instead of automatic"},
+		},
+		{
+			name: "automatic kms key changed",
+			opts: automatic,
+			modify: func(s *secretmanager.Secret) {
+				s.Replication.Automatic.CustomerManagedEncryption = nil
+			},
+			want: []string{"kms key is '' instead of 'key-global'"},
+		},
+		{
+			name: "replication locations and keys changed",
+			opts: userManaged,
+			modify: func(s *secretmanager.Secret) {
+				s.Replication.UserManaged.Replicas = []*secretmanager.Replica{
+					{Location: "europe-west1", CustomerManagedEncryption: &secretmanager.CustomerManagedEncryption{KmsKeyName: "key-other"}},
+				}
+			},
+			want: []string{
+				"kms key in 'europe-west1' is 'key-other' instead of 'key-ew1'",
+				"replication locations are [europe-west1] instead of [europe-west1, europe-west4]",
+			},
+		},
+		{
+			name: "automatic instead of user managed",
+			opts: userManaged,
+			modify: func(s *secretmanager.Secret) {
+				s.Replication = &secretmanager.Replication{Automatic: &secretmanager.Automatic{}}
+			},
+			want: []string{"replication is automatic instead of user managed"},
+		},
+		{
+			name: "label, expiry and rotation changed",
+			opts: userManaged,
+			modify: func(s *secretmanager.Secret) {
+				s.Labels["team"] = "other"
+				s.ExpireTime = ""
+				s.Rotation.RotationPeriod = "3600s"
+				s.Topics = nil
+			},
+			want: []string{
+				"label 'team' is 'other' instead of 'core'",
+				"expiry is not set",
+				"rotation period is 1h0m0s instead of 720h0m0s",
+				"rotation topics are [] instead of [projects/test/topics/rotate]",
+			},
+		},
+		{
+			name: "rotation removed",
+			opts: userManaged,
+			modify: func(s *secretmanager.Secret) {
+				s.Rotation = nil
+			},
+			want: []string{"rotation is not set"},
+		},
+		{
+			name: "rotation not configured",
+			opts: automatic,
+			modify: func(s *secretmanager.Secret) {
+				s.Rotation = &secretmanager.Rotation{RotationPeriod: "3600s"}
+			},
+			want: []string{"rotation every 1h0m0s is set but not configured"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := created(tc.opts)
+			tc.modify(s)
+
+			if got := tc.opts.mismatches(s); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("expected mismatches %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
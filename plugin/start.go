@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/outblocks/cli-plugin-gcp/deploy"
 	"github.com/outblocks/cli-plugin-gcp/gcp"
 	"github.com/outblocks/cli-plugin-gcp/internal/config"
 	"github.com/outblocks/outblocks-plugin-go/env"
@@ -36,6 +37,20 @@ func (p *Plugin) Start(ctx context.Context, r *apiv1.StartRequest) (*apiv1.Start
 	p.settings.ProjectID = project
 	p.settings.Region = region
 
+	if v, ok := r.Properties.AsMap()["log_export"]; ok && v != nil {
+		logExport, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("GCP 'log_export' has to be an object")
+		}
+
+		_, err = deploy.NewLogExportOptions(logExport)
+		if err != nil {
+			return nil, err
+		}
+
+		p.settings.LogExport = logExport
+	}
+
 	cred, err := config.GoogleCredentials(ctx, compute.CloudPlatformScope)
 	if err != nil {
 		return nil, err
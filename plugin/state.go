@@ -42,11 +42,17 @@ func getBucket(ctx context.Context, b *storage.BucketHandle, project string, att
 }
 
 func readBucketFile(ctx context.Context, b *storage.BucketHandle, file string) ([]byte, error) {
-	r, err := b.Object(file).NewReader(ctx)
+	return readObject(ctx, b.Object(file))
+}
+
+func readObject(ctx context.Context, o *storage.ObjectHandle) ([]byte, error) {
+	r, err := o.NewReader(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	defer r.Close()
+
 	return io.ReadAll(r)
 }
 
@@ -69,27 +75,43 @@ var (
 	errReleaseLockMismatch = errors.New("lock id doesn't match")
 )
 
-func acquireLock(ctx context.Context, o *storage.ObjectHandle) (lockinfo, owner string, createdAt time.Time, err error) {
-	w := o.If(storage.Conditions{DoesNotExist: true}).NewWriter(ctx)
+// acquireLock creates lock object with lease valid for ttl, taking over lock whose lease has already expired.
+func acquireLock(ctx context.Context, o *storage.ObjectHandle, ttl time.Duration) (lockinfo, owner string, createdAt time.Time, err error) {
 	lockdata := lockdata()
 
-	_, _ = w.Write([]byte(lockdata))
+	for attempt := 0; ; attempt++ {
+		w := o.If(storage.Conditions{DoesNotExist: true}).NewWriter(ctx)
+		w.Metadata = lockLeaseMetadata(ttl)
 
-	err = w.Close()
-	if err != nil { //nolint: nestif
-		if e, ok := err.(*googleapi.Error); ok && e.Code == http.StatusPreconditionFailed {
-			lockinfo, owner, createdAt, err := checkLock(ctx, o)
+		_, _ = w.Write([]byte(lockdata))
+
+		err = w.Close()
+		if err == nil {
+			return strconv.FormatInt(w.Attrs().Generation, 36), lockdata, w.Attrs().Created, nil
+		}
+
+		if e, ok := err.(*googleapi.Error); !ok || e.Code != http.StatusPreconditionFailed {
+			return "", "", time.Time{}, fmt.Errorf("unable to acquire lock: %w", err)
+		}
+
+		if attempt == 0 {
+			expired, err := takeOverExpiredLock(ctx, o)
 			if err != nil {
 				return "", "", time.Time{}, fmt.Errorf("unable to acquire lock: %w", err)
 			}
 
-			return lockinfo, owner, createdAt, errAcquireLockFailed
+			if expired {
+				continue
+			}
 		}
 
-		return "", "", time.Time{}, fmt.Errorf("unable to acquire lock: %w", err)
-	}
+		lockinfo, owner, createdAt, err := checkLock(ctx, o)
+		if err != nil {
+			return "", "", time.Time{}, fmt.Errorf("unable to acquire lock: %w", err)
+		}
 
-	return strconv.FormatInt(w.Attrs().Generation, 36), lockdata, w.Attrs().Created, nil
+		return lockinfo, owner, createdAt, errAcquireLockFailed
+	}
 }
 
 func checkLock(ctx context.Context, o *storage.ObjectHandle) (lockinfo, owner string, createdAt time.Time, err error) {
@@ -147,15 +169,47 @@ func (p *Plugin) defaultStateBucket(project string) string {
 	return p.defaultBucket(project, "")
 }
 
-func (p *Plugin) lockState(ctx context.Context, cli *storage.Client, project, lockingBucket string, lockWait time.Duration, stream apiv1.StatePluginService_GetStateServer) (string, error) {
+func stateBucketAttrs(location string) *storage.BucketAttrs {
+	return &storage.BucketAttrs{
+		Location:          location,
+		VersioningEnabled: true,
+		Lifecycle: storage.Lifecycle{
+			Rules: []storage.LifecycleRule{
+				{
+					Condition: storage.LifecycleCondition{
+						DaysSinceNoncurrentTime: 14,
+					},
+					Action: storage.LifecycleAction{
+						Type: "Delete",
+					},
+				},
+				{
+					Condition: storage.LifecycleCondition{
+						NumNewerVersions: 100,
+						Liveness:         storage.Archived,
+					},
+					Action: storage.LifecycleAction{
+						Type: "Delete",
+					},
+				},
+			},
+		},
+	}
+}
+
+func locksBucketAttrs(location string) *storage.BucketAttrs {
+	return &storage.BucketAttrs{
+		Location:          location,
+		VersioningEnabled: false,
+	}
+}
+
+func (p *Plugin) lockState(ctx context.Context, cli *storage.Client, project, lockingBucket string, lockWait, lockTTL time.Duration, stream apiv1.StatePluginService_GetStateServer) (string, error) {
 	var lockInfo string
 
 	lockingB := cli.Bucket(lockingBucket)
 
-	_, _, err := getBucket(ctx, lockingB, project, &storage.BucketAttrs{
-		Location:          p.settings.Region,
-		VersioningEnabled: false,
-	}, true)
+	_, _, err := getBucket(ctx, lockingB, project, locksBucketAttrs(p.settings.Region), true)
 	if err != nil {
 		return "", err
 	}
@@ -171,8 +225,10 @@ func (p *Plugin) lockState(ctx context.Context, cli *storage.Client, project, lo
 
 	defer t.Stop()
 
+	lock := lockingB.Object(p.stateLockfile())
+
 	for {
-		lockInfo, owner, createdAt, err = acquireLock(ctx, lockingB.Object(p.stateLockfile()))
+		lockInfo, owner, createdAt, err = acquireLock(ctx, lock, lockTTL)
 		if err == nil {
 			break
 		}
@@ -205,9 +261,35 @@ func (p *Plugin) lockState(ctx context.Context, cli *storage.Client, project, lo
 		}
 	}
 
+	p.lockLeases.start(p.log, lock, lockInfo, lockTTL)
+
 	return lockInfo, nil
 }
 
+// withStateLock runs f while holding state lock, failing right away if lock is already taken.
+func (p *Plugin) withStateLock(ctx context.Context, cli *storage.Client, lockingBucket string, f func() error) error {
+	lock := cli.Bucket(lockingBucket).Object(p.stateLockfile())
+
+	lockInfo, owner, createdAt, err := acquireLock(ctx, lock, defaultLockTTL)
+	if errors.Is(err, errAcquireLockFailed) {
+		return types.NewStatusStateLockError(lockInfo, owner, createdAt)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	p.lockLeases.start(p.log, lock, lockInfo, defaultLockTTL)
+
+	defer func() {
+		p.lockLeases.stop(lock, lockInfo)
+
+		_ = releaseLock(ctx, lock, lockInfo)
+	}()
+
+	return f()
+}
+
 func (p *Plugin) GetState(r *apiv1.GetStateRequest, stream apiv1.StatePluginService_GetStateServer) error {
 	ctx := stream.Context()
 
@@ -226,6 +308,16 @@ func (p *Plugin) GetState(r *apiv1.GetStateRequest, stream apiv1.StatePluginServ
 		return err
 	}
 
+	lockTTL, err := lockTTLFromProperties(r.Properties.Fields)
+	if err != nil {
+		return err
+	}
+
+	enc, err := stateEncryptionFromProperties(r.Properties.Fields)
+	if err != nil {
+		return err
+	}
+
 	pctx := p.PluginContext()
 
 	cli, err := pctx.GCPStorageClient(ctx)
@@ -236,31 +328,7 @@ func (p *Plugin) GetState(r *apiv1.GetStateRequest, stream apiv1.StatePluginServ
 	// Read state.
 	b := cli.Bucket(bucket)
 
-	created, exists, err := getBucket(ctx, b, project, &storage.BucketAttrs{
-		Location:          p.settings.Region,
-		VersioningEnabled: true,
-		Lifecycle: storage.Lifecycle{
-			Rules: []storage.LifecycleRule{
-				{
-					Condition: storage.LifecycleCondition{
-						DaysSinceNoncurrentTime: 14,
-					},
-					Action: storage.LifecycleAction{
-						Type: "Delete",
-					},
-				},
-				{
-					Condition: storage.LifecycleCondition{
-						NumNewerVersions: 100,
-						Liveness:         storage.Archived,
-					},
-					Action: storage.LifecycleAction{
-						Type: "Delete",
-					},
-				},
-			},
-		},
-	}, !r.SkipCreate)
+	created, exists, err := getBucket(ctx, b, project, stateBucketAttrs(p.settings.Region), !r.SkipCreate)
 	if err != nil {
 		return err
 	}
@@ -280,7 +348,7 @@ func (p *Plugin) GetState(r *apiv1.GetStateRequest, stream apiv1.StatePluginServ
 	var lockInfo string
 
 	if r.Lock {
-		lockInfo, err = p.lockState(ctx, cli, project, lockingBucket, r.LockWait.AsDuration(), stream)
+		lockInfo, err = p.lockState(ctx, cli, project, lockingBucket, r.LockWait.AsDuration(), lockTTL, stream)
 		if err != nil {
 			return err
 		}
@@ -306,6 +374,22 @@ func (p *Plugin) GetState(r *apiv1.GetStateRequest, stream apiv1.StatePluginServ
 		created = true
 	}
 
+	if moved, ok := stateMovedTo(state); ok {
+		if lockInfo != "" {
+			lock := cli.Bucket(lockingBucket).Object(p.stateLockfile())
+
+			p.lockLeases.stop(lock, lockInfo)
+			_ = releaseLock(ctx, lock, lockInfo)
+		}
+
+		return moved.Error()
+	}
+
+	state, err = p.decryptState(ctx, enc, state)
+	if err != nil {
+		return err
+	}
+
 	return stream.Send(&apiv1.GetStateResponse{
 		Response: &apiv1.GetStateResponse_State_{
 			State: &apiv1.GetStateResponse_State{
@@ -329,6 +413,11 @@ func (p *Plugin) SaveState(ctx context.Context, r *apiv1.SaveStateRequest) (*api
 		return nil, err
 	}
 
+	enc, err := stateEncryptionFromProperties(r.Properties.Fields)
+	if err != nil {
+		return nil, err
+	}
+
 	pctx := p.PluginContext()
 
 	cli, err := pctx.GCPStorageClient(ctx)
@@ -336,11 +425,16 @@ func (p *Plugin) SaveState(ctx context.Context, r *apiv1.SaveStateRequest) (*api
 		return nil, err
 	}
 
+	state, err := p.encryptState(ctx, enc, r.State)
+	if err != nil {
+		return nil, err
+	}
+
 	// Write state.
 	b := cli.Bucket(bucket)
 	w := b.Object(p.statefile()).NewWriter(ctx)
 
-	_, err = w.Write(r.State)
+	_, err = w.Write(state)
 	if err != nil {
 		return nil, err
 	}
@@ -371,8 +465,11 @@ func (p *Plugin) ReleaseStateLock(ctx context.Context, r *apiv1.ReleaseStateLock
 		return nil, err
 	}
 
-	b := cli.Bucket(bucket)
-	err = releaseLock(ctx, b.Object(p.stateLockfile()), r.LockInfo)
+	lock := cli.Bucket(bucket).Object(p.stateLockfile())
+
+	p.lockLeases.stop(lock, r.LockInfo)
+
+	err = releaseLock(ctx, lock, r.LockInfo)
 
 	return &apiv1.ReleaseStateLockResponse{}, err
 }
@@ -0,0 +1,254 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/outblocks/outblocks-plugin-go/validate"
+	"google.golang.org/api/cloudkms/v1"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// stateEnvelopeHeader prefixes encrypted state objects, anything without it is treated as plaintext state.
+const stateEnvelopeHeader = "outblocks-gcp-state-envelope:v1\n"
+
+type stateEnvelope struct {
+	KMSKey     string `json:"kms_key,omitempty"`
+	KeyID      string `json:"key_id,omitempty"` // fingerprint of local key
+	WrappedKey []byte `json:"wrapped_key"`
+	KeyNonce   []byte `json:"key_nonce,omitempty"`
+	Nonce      []byte `json:"nonce"`
+	Data       []byte `json:"data"`
+}
+
+// stateEncryption holds key used to wrap data encryption key of state, either Cloud KMS key or local AES-256 key.
+type stateEncryption struct {
+	KMSKey   string
+	LocalKey []byte
+}
+
+func newStateEncryption(kmsKey, localKey string) (*stateEncryption, error) {
+	if kmsKey != "" && localKey != "" {
+		return nil, fmt.Errorf("only one of state encryption kms key and local key can be set")
+	}
+
+	e := &stateEncryption{
+		KMSKey: kmsKey,
+	}
+
+	if localKey != "" {
+		key, err := base64.StdEncoding.DecodeString(localKey)
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("state encryption key must be a base64 encoded 32 byte key")
+		}
+
+		e.LocalKey = key
+	}
+
+	return e, nil
+}
+
+func stateEncryptionFromProperties(props map[string]*structpb.Value) (*stateEncryption, error) {
+	kmsKey, err := validate.OptionalString("", props, "encryption_kms_key", "encryption kms key must be a string")
+	if err != nil {
+		return nil, err
+	}
+
+	localKey, err := validate.OptionalString("", props, "encryption_key", "encryption key must be a string")
+	if err != nil {
+		return nil, err
+	}
+
+	return newStateEncryption(kmsKey, localKey)
+}
+
+func (e *stateEncryption) Enabled() bool {
+	return e.KMSKey != "" || len(e.LocalKey) != 0
+}
+
+func (e *stateEncryption) localKeyID() string {
+	sum := sha256.Sum256(e.LocalKey)
+
+	return hex.EncodeToString(sum[:8])
+}
+
+func isEncryptedState(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(stateEnvelopeHeader))
+}
+
+func sealAESGCM(key, plaintext []byte) (nonce, ciphertext []byte, err error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nonce = make([]byte, gcm.NonceSize())
+
+	_, err = io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return nonce, gcm.Seal(nil, nonce, plaintext, nil), nil
+}
+
+func openAESGCM(key, nonce, ciphertext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+// encryptState encrypts state with random data key which gets wrapped by configured key. Returns state as is when encryption is disabled.
+func (p *Plugin) encryptState(ctx context.Context, e *stateEncryption, state []byte) ([]byte, error) {
+	if !e.Enabled() {
+		return state, nil
+	}
+
+	dek := make([]byte, 32)
+
+	_, err := io.ReadFull(rand.Reader, dek)
+	if err != nil {
+		return nil, err
+	}
+
+	env := &stateEnvelope{}
+
+	env.Nonce, env.Data, err = sealAESGCM(dek, state)
+	if err != nil {
+		return nil, fmt.Errorf("error encrypting state: %w", err)
+	}
+
+	if e.KMSKey != "" {
+		cli, err := p.PluginContext().GCPKMSClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := cli.Projects.Locations.KeyRings.CryptoKeys.Encrypt(e.KMSKey, &cloudkms.EncryptRequest{
+			Plaintext: base64.StdEncoding.EncodeToString(dek),
+		}).Context(ctx).Do()
+		if err != nil {
+			return nil, fmt.Errorf("error wrapping state key with kms: %w", err)
+		}
+
+		env.KMSKey = e.KMSKey
+
+		env.WrappedKey, err = base64.StdEncoding.DecodeString(res.Ciphertext)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		env.KeyID = e.localKeyID()
+
+		env.KeyNonce, env.WrappedKey, err = sealAESGCM(e.LocalKey, dek)
+		if err != nil {
+			return nil, fmt.Errorf("error wrapping state key: %w", err)
+		}
+	}
+
+	data, err := json.Marshal(env)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(stateEnvelopeHeader), data...), nil
+}
+
+// decryptState decrypts state envelope, state that is not encrypted is returned as is.
+// KMS wrapped states are decrypted with key recorded in envelope so they don't depend on current configuration.
+func (p *Plugin) decryptState(ctx context.Context, e *stateEncryption, state []byte) ([]byte, error) {
+	if !isEncryptedState(state) {
+		return state, nil
+	}
+
+	var env stateEnvelope
+
+	err := json.Unmarshal(state[len(stateEnvelopeHeader):], &env)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding encrypted state: %w", err)
+	}
+
+	var dek []byte
+
+	switch {
+	case env.KMSKey != "":
+		cli, err := p.PluginContext().GCPKMSClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := cli.Projects.Locations.KeyRings.CryptoKeys.Decrypt(env.KMSKey, &cloudkms.DecryptRequest{
+			Ciphertext: base64.StdEncoding.EncodeToString(env.WrappedKey),
+		}).Context(ctx).Do()
+		if err != nil {
+			return nil, fmt.Errorf("error unwrapping state key with kms key '%s': %w", env.KMSKey, err)
+		}
+
+		dek, err = base64.StdEncoding.DecodeString(res.Plaintext)
+		if err != nil {
+			return nil, err
+		}
+
+	case len(e.LocalKey) == 0:
+		return nil, fmt.Errorf("state is encrypted with a local key, set 'encryption_key' in state properties to read it")
+
+	case env.KeyID != e.localKeyID():
+		return nil, fmt.Errorf("state is encrypted with a different local key")
+
+	default:
+		dek, err = openAESGCM(e.LocalKey, env.KeyNonce, env.WrappedKey)
+		if err != nil {
+			return nil, fmt.Errorf("error unwrapping state key: %w", err)
+		}
+	}
+
+	state, err = openAESGCM(dek, env.Nonce, env.Data)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting state: %w", err)
+	}
+
+	return state, nil
+}
+
+// stateEncryptionOf returns encryption state was stored with so it can be written back the same way.
+// Local key has to be provided as it's not recorded in envelope.
+func stateEncryptionOf(state []byte, local *stateEncryption) (*stateEncryption, error) {
+	if !isEncryptedState(state) {
+		return &stateEncryption{}, nil
+	}
+
+	var env stateEnvelope
+
+	err := json.Unmarshal(state[len(stateEnvelopeHeader):], &env)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding encrypted state: %w", err)
+	}
+
+	if env.KMSKey != "" {
+		return &stateEncryption{KMSKey: env.KMSKey}, nil
+	}
+
+	return local, nil
+}
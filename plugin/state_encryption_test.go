@@ -0,0 +1,172 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
+)
+
+const testKMSKey = "projects/test/locations/global/keyRings/outblocks/cryptoKeys/state"
+
+func testLocalKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, 32))
+}
+
+func mustStateEncryption(t *testing.T, kmsKey, localKey string) *stateEncryption {
+	t.Helper()
+
+	e, err := newStateEncryption(kmsKey, localKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return e
+}
+
+func TestStateEncryptionRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	p, _ := newTestPlugin(t)
+	state := []byte(`{"registry":{"secret":"value"}}`)
+
+	for _, tc := range []struct {
+		name string
+		enc  *stateEncryption
+		// encryption that state is later read with
+		read *stateEncryption
+	}{
+		{"local key", mustStateEncryption(t, "", testLocalKey(1)), mustStateEncryption(t, "", testLocalKey(1))},
+		{"kms key", mustStateEncryption(t, testKMSKey, ""), &stateEncryption{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			encrypted, err := p.encryptState(ctx, tc.enc, state)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !isEncryptedState(encrypted) || bytes.Contains(encrypted, []byte("secret")) {
+				t.Fatalf("expected state to be encrypted, got: %s", encrypted)
+			}
+
+			decrypted, err := p.decryptState(ctx, tc.read, encrypted)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(decrypted, state) {
+				t.Fatalf("expected decrypted state to match, got: %s", decrypted)
+			}
+
+			got, err := stateEncryptionOf(encrypted, tc.read)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.KMSKey != tc.enc.KMSKey || !bytes.Equal(got.LocalKey, tc.enc.LocalKey) {
+				t.Fatalf("expected state to be written back with same encryption, got: %+v", got)
+			}
+		})
+	}
+}
+
+func TestStateEncryptionWrongKey(t *testing.T) {
+	ctx := context.Background()
+	p, _ := newTestPlugin(t)
+	state := []byte(`{}`)
+
+	local, err := p.encryptState(ctx, mustStateEncryption(t, "", testLocalKey(1)), state)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kms, err := p.encryptState(ctx, mustStateEncryption(t, testKMSKey, ""), state)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		state []byte
+		enc   *stateEncryption
+		err   string
+	}{
+		{"missing local key", local, &stateEncryption{}, "set 'encryption_key'"},
+		{"different local key", local, mustStateEncryption(t, "", testLocalKey(2)), "different local key"},
+		{"different kms key", bytes.Replace(kms, []byte("cryptoKeys/state"), []byte("cryptoKeys/other"), 1), &stateEncryption{}, "error unwrapping state key with kms key"},
+		{"tampered data", bytes.Replace(local, []byte(`"data":"`), []byte(`"data":"AAAA`), 1), mustStateEncryption(t, "", testLocalKey(1)), "error decrypting state"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := p.decryptState(ctx, tc.enc, tc.state)
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("expected error containing %q, got: %v", tc.err, err)
+			}
+		})
+	}
+
+	_, err = newStateEncryption(testKMSKey, testLocalKey(1))
+	if err == nil {
+		t.Fatal("expected setting both kms and local key to be refused")
+	}
+
+	_, err = newStateEncryption("", base64.StdEncoding.EncodeToString([]byte("short")))
+	if err == nil {
+		t.Fatal("expected invalid local key to be refused")
+	}
+}
+
+func TestSaveStateEncryptsStoredState(t *testing.T) {
+	ctx := context.Background()
+	p, srv := newTestPlugin(t)
+
+	cli, err := p.PluginContext().GCPStorageClient(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = getBucket(ctx, cli.Bucket("test-state"), srv.ProjectID, stateBucketAttrs(srv.Region), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saveState := func(props map[string]any, state string) []byte {
+		t.Helper()
+
+		props["bucket"] = "test-state"
+
+		_, err := p.SaveState(ctx, &apiv1.SaveStateRequest{State: []byte(state), Properties: mustStruct(t, props)})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, ok := srv.Object("test-state", p.statefile())
+		if !ok {
+			t.Fatal("expected state to be saved")
+		}
+
+		return data
+	}
+
+	enc := mustStateEncryption(t, "", testLocalKey(1))
+
+	// State saved before encryption was enabled is still readable.
+	data := saveState(map[string]any{}, `{"plain":true}`)
+
+	got, err := p.decryptState(ctx, enc, data)
+	if err != nil || string(got) != `{"plain":true}` {
+		t.Fatalf("expected plaintext state to be read as is, got: %s, %v", got, err)
+	}
+
+	// Once enabled, state is stored encrypted.
+	data = saveState(map[string]any{"encryption_key": testLocalKey(1)}, `{"plain":false}`)
+	if !isEncryptedState(data) {
+		t.Fatalf("expected stored state to be encrypted, got: %s", data)
+	}
+
+	got, err = p.decryptState(ctx, enc, data)
+	if err != nil || string(got) != `{"plain":false}` {
+		t.Fatalf("expected encrypted state to be decrypted, got: %s, %v", got, err)
+	}
+}